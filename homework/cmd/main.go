@@ -1,48 +1,439 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"homework/internal/adapters/cache"
+	"homework/internal/adapters/changelog"
+	"homework/internal/adapters/cluster"
 	"homework/internal/adapters/hashmap"
 	"homework/internal/ports/http"
+	nethttp "net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 
-	"github.com/dubter/config"
 	"homework/internal/app"
+	"homework/internal/config"
+	"homework/internal/graphql"
+	"homework/internal/idempotency"
+	"homework/internal/ipam"
+	"homework/internal/logger"
+	"homework/internal/ports/admin"
+	"homework/internal/raft"
+	"homework/internal/reachability"
+	"homework/internal/tracing"
+)
+
+const (
+	envPrefix       = "DEVICES"
+	shutdownTimeout = 10 * time.Second
 )
 
 func main() {
-	var configPath string
+	var (
+		configPath  string
+		printConfig bool
+		checkConfig bool
+	)
+
+	loader := config.NewLoader(envPrefix)
+	loader.BindFlags(flag.CommandLine)
+
 	flag.StringVar(&configPath, "config_path", "", "path to yaml config for server settings")
+	flag.BoolVar(&printConfig, "print-config", false, "print the effective configuration with value sources and exit")
+	flag.BoolVar(&checkConfig, "check-config", false, "validate the configuration, report all problems and exit")
 
 	flag.Parse()
 
-	if configPath == "" {
-		flag.Usage()
+	cfg, sources, err := loader.Load(configPath)
+	if err != nil {
+		fmt.Println(err.Error())
 		os.Exit(1)
 	}
 
-	yaml, err := config.LoadConfig(configPath)
+	if checkConfig {
+		for _, warning := range loader.Warnings() {
+			fmt.Println("warning:", warning)
+		}
+		fmt.Println("configuration is valid")
+		return
+	}
+
+	if printConfig {
+		if err = config.Print(os.Stdout, cfg, sources); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	level, _ := logger.ParseLevel(cfg.Log.Level)
+	log := logger.New(os.Stderr, level)
+	for _, warning := range loader.Warnings() {
+		log.Warnf("%s", warning)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tracer, closeTracer, err := newTracer(&cfg.Tracing, log)
 	if err != nil {
-		fmt.Println(err.Error())
+		fmt.Println(err)
 		os.Exit(1)
 	}
+	defer closeTracer()
+
+	handlerConfig := &http.Config{
+		Port:         strconv.Itoa(cfg.Server.Port),
+		Host:         cfg.Server.Host,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		Tracer:       tracer,
+		UI:           cfg.UI.Enabled,
+	}
+
+	if cfg.Tenancy.Enabled {
+		handlerConfig.Tenants = make(map[string]*http.Config)
+		handlerConfig.APIKeys = cfg.Tenancy.APIKeyMap()
+		if len(handlerConfig.APIKeys) == 0 {
+			log.Warnf("tenancy has no api_keys, every tenant is open to anyone")
+		}
+
+		for _, tenant := range cfg.Tenancy.TenantNames() {
+			tenantLog := log.With("tenant " + tenant + ": ")
+			if handlerConfig.Tenants[tenant], err = newServices(ctx, cfg, tenantLog, tracer, cfg.Tenancy.QuotaFor(tenant)); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+	} else {
+		services, err := newServices(ctx, cfg, log, tracer, 0)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		handlerConfig.Service = services.Service
+		handlerConfig.IPAM = services.IPAM
+		handlerConfig.Locations = services.Locations
+		handlerConfig.Models = services.Models
+		handlerConfig.Liveness = services.Liveness
+		handlerConfig.Reachability = services.Reachability
+		handlerConfig.Versions = services.Versions
+		handlerConfig.Backup = services.Backup
+		handlerConfig.ReplicationSource = services.ReplicationSource
+		handlerConfig.Replica = services.Replica
+		handlerConfig.Leader = services.Leader
+		handlerConfig.Cluster = services.Cluster
+		handlerConfig.ClusterSecret = cfg.Cluster.Secret
+		handlerConfig.Idempotency = services.Idempotency
+		handlerConfig.GraphQL = services.GraphQL
+	}
+
+	handler := http.NewHandler(handlerConfig)
+
+	watcher := config.NewWatcher(loader, configPath, cfg,
+		func(_, cur *config.Config, changes []config.Change) {
+			for _, change := range changes {
+				if change.Reloadable {
+					log.Infof("config reloaded: %s changed from %s to %s", change.Key, change.Old, change.New)
+				} else {
+					log.Warnf("config reloaded: %s changed from %s to %s, restart required to apply", change.Key, change.Old, change.New)
+				}
+
+				// The admin listener may have changed the level since, a
+				// reload of other keys keeps it.
+				if change.Key == "log.level" {
+					level, _ := logger.ParseLevel(cur.Log.Level)
+					log.SetLevel(level)
+				}
+
+				if change.Key == "tenancy.api_keys" && cur.Tenancy.Enabled {
+					keys := cur.Tenancy.APIKeyMap()
+					for _, tenant := range keys {
+						if handlerConfig.Tenants[tenant] == nil {
+							log.Warnf("config reloaded: api keys of tenant %s are rejected until a restart serves the tenant", tenant)
+						}
+					}
+					handler.SetAPIKeys(keys)
+				}
+			}
 
-	hash := hashmap.NewHash()
-	deviceService := app.NewService(hash)
-	handler := http.NewHandler(
-		&http.Config{
-			Service:      deviceService,
-			Port:         yaml.Port,
-			Host:         yaml.Host,
-			ReadTimeout:  yaml.ReadTimeout,
-			WriteTimeout: yaml.WriteTimeout,
+			handler.SetTimeouts(cur.Server.ReadTimeout, cur.Server.WriteTimeout)
+		},
+		func(err error) {
+			log.Errorf("config reload rejected, keeping previous config: %s", err)
 		})
+	go watcher.Run(ctx, cfg.Reload.Interval)
+
+	if cfg.Admin.Enabled {
+		go serveAdmin(ctx, &admin.Config{
+			Host:    cfg.Admin.Host,
+			Port:    strconv.Itoa(cfg.Admin.Port),
+			Log:     log,
+			Current: watcher.Current,
+			API:     handler.AdminRoutes(),
+		}, log)
+	}
 
 	server := handler.NewServer()
 
-	if err = server.ListenAndServe(); err != nil {
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("shutdown: %s", err)
+		}
+	}()
+
+	log.Infof("listening on %s", server.Addr)
+
+	if err = server.ListenAndServe(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
 		fmt.Println(err)
 		os.Exit(1)
 	}
 }
+
+// serveAdmin runs the admin listener until ctx is done. It failing to
+// listen is logged and leaves the device api serving.
+func serveAdmin(ctx context.Context, cfg *admin.Config, log *logger.Logger) {
+	server := admin.NewServer(cfg)
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Errorf("admin shutdown: %s", err)
+		}
+	}()
+
+	log.Infof("admin listening on %s", server.Addr)
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, nethttp.ErrServerClosed) {
+		log.Errorf("admin listener: %s", err)
+	}
+}
+
+// newTracer returns a nil tracer when tracing is disabled, the returned
+// func flushes the pending spans and closes the trace file.
+func newTracer(cfg *config.TracingConfig, log *logger.Logger) (trace.Tracer, func(), error) {
+	if !cfg.Enabled {
+		return nil, func() {}, nil
+	}
+
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		log.Errorf("span export failed: %s", err)
+	}))
+
+	var exporter sdktrace.SpanExporter
+	closeFile := func() {}
+
+	if cfg.Exporter == "otlp_file" {
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		exporter = tracing.NewOTLPFileExporter(file)
+		closeFile = func() { _ = file.Close() }
+	} else {
+		var err error
+		if exporter, err = tracing.NewStdoutExporter(os.Stdout); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	provider := tracing.NewProvider(exporter, cfg.ServiceName)
+
+	return provider.Tracer(tracing.Name), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Errorf("span export failed: %s", err)
+		}
+		closeFile()
+	}, nil
+}
+
+// newServices builds the repositories and services of one tenant, or of
+// the whole server without tenancy, and starts their background jobs.
+func newServices(ctx context.Context, cfg *config.Config, log *logger.Logger, tracer trace.Tracer, quota int) (*http.Config, error) {
+	var repoOptions []hashmap.Option
+	if cfg.Storage.UniqueIP {
+		repoOptions = append(repoOptions, hashmap.WithUniqueIP())
+	}
+
+	services := &http.Config{}
+
+	repo := hashmap.NewHash(repoOptions...)
+	if cfg.Storage.Shards > 0 {
+		repo = hashmap.NewShardedHash(cfg.Storage.Shards, repoOptions...)
+	}
+
+	// The log sits below the cache, it records writes however they reach
+	// the repository.
+	if cfg.Replication.Leading() {
+		changeLog := changelog.NewLog(repo, cfg.Replication.LogSize)
+		services.ReplicationSource = changeLog
+		repo = changeLog
+	}
+
+	if cfg.Cache.Enabled {
+		repo = cache.NewCache(repo, cache.Options{
+			Capacity:    cfg.Cache.Capacity,
+			TTL:         cfg.Cache.TTL,
+			NegativeTTL: cfg.Cache.NegativeTTL,
+		})
+	}
+
+	// Every node applies committed writes to its own cache, so cached
+	// devices are invalidated on followers too.
+	if cfg.Cluster.Enabled {
+		storage, err := raft.NewFileStorage(cfg.Cluster.DataDir)
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := raft.NewNode(raft.Config{
+			ID:              cfg.Cluster.NodeID,
+			Address:         cfg.Cluster.Address,
+			Members:         cfg.Cluster.Members(),
+			Tick:            cfg.Cluster.Tick,
+			ElectionTicks:   cfg.Cluster.ElectionTicks,
+			HeartbeatTicks:  cfg.Cluster.HeartbeatTicks,
+			SnapshotEntries: cfg.Cluster.SnapshotEntries,
+			Storage:         storage,
+		}, cluster.NewStateMachine(repo), raft.NewHTTPTransport(cfg.Cluster.Tick*time.Duration(cfg.Cluster.ElectionTicks), cfg.Cluster.Secret))
+		if err != nil {
+			return nil, err
+		}
+		services.Cluster = node
+		repo = cluster.NewRepository(node, repo, cfg.Cluster.ProposalTimeout)
+
+		go node.Run(ctx, func(err error) {
+			log.Errorf("raft state not saved, messages depending on it were dropped: %s", err)
+		})
+	}
+
+	if cfg.Idempotency.Enabled {
+		services.Idempotency = idempotency.NewStore(idempotency.Options{
+			Capacity: cfg.Idempotency.Capacity,
+			TTL:      cfg.Idempotency.TTL,
+		})
+	}
+
+	if cfg.GraphQL.Enabled {
+		services.GraphQL = &graphql.Options{
+			MaxDepth:      cfg.GraphQL.MaxDepth,
+			MaxComplexity: cfg.GraphQL.MaxComplexity,
+		}
+	}
+
+	refs := app.NewReferences()
+	serviceOptions := []app.Option{app.WithQuota(quota), app.WithTracer(tracer), app.WithReferences(refs)}
+
+	if cfg.IPAM.Enabled {
+		manager := ipam.NewManager()
+		for _, cidr := range cfg.IPAM.Subnets {
+			if err := manager.CreateSubnet(&ipam.Subnet{CIDR: cidr}); err != nil {
+				return nil, err
+			}
+		}
+
+		services.IPAM = manager
+		serviceOptions = append(serviceOptions, app.WithIPAM(manager))
+	}
+
+	if cfg.History.Enabled {
+		serviceOptions = append(serviceOptions, app.WithHistory(hashmap.NewHistoryHash()))
+	}
+
+	heartbeatRepo := hashmap.NewHeartbeatHash()
+	if cfg.Heartbeat.Enabled {
+		serviceOptions = append(serviceOptions, app.WithLiveness(heartbeatRepo))
+	}
+
+	locationRepo := hashmap.NewLocationHash()
+	if cfg.Locations.Enabled {
+		serviceOptions = append(serviceOptions, app.WithLocations(locationRepo))
+	}
+
+	if cfg.Versions.Enabled {
+		versionRepo := hashmap.NewVersionHash(cfg.Versions.Keep, cfg.Versions.MaxAge)
+		serviceOptions = append(serviceOptions, app.WithVersions(versionRepo))
+		services.Versions = app.NewVersionService(versionRepo)
+	}
+
+	modelRepo := hashmap.NewModelHash()
+	if cfg.Catalog.Enforce {
+		serviceOptions = append(serviceOptions, app.WithModels(modelRepo))
+	}
+
+	services.Service = app.NewService(repo, serviceOptions...)
+	if cfg.Locations.Enabled {
+		services.Locations = app.NewLocationService(locationRepo, repo, refs)
+	}
+	if cfg.Backup.Enabled {
+		services.Backup = app.NewBackupService(repo, serviceOptions...)
+	}
+	services.Models = app.NewModelService(modelRepo, repo, refs)
+	if cfg.Heartbeat.Enabled {
+		services.Liveness = app.NewLivenessService(heartbeatRepo, repo, cfg.Heartbeat.Timeout)
+	}
+
+	// The follower writes through the cache, so cached devices are
+	// invalidated as changes arrive.
+	if cfg.Replication.Following() {
+		follower := changelog.NewFollower(repo, cfg.Replication.Leader, changelog.FollowerOptions{
+			Wait:  cfg.Replication.Wait,
+			Retry: cfg.Replication.Retry,
+		})
+		services.Replica = follower
+		services.Leader = cfg.Replication.Leader
+
+		go follower.Run(ctx, func(err error) {
+			log.Errorf("replication failed: %s", err)
+		})
+	}
+
+	if cfg.Heartbeat.Enabled {
+		go app.RunSweeper(ctx, services.Liveness, cfg.Heartbeat.SweepInterval, func(err error) {
+			log.Errorf("heartbeat sweep failed: %s", err)
+		})
+	}
+
+	if cfg.Reachability.Enabled {
+		checker := reachability.NewChecker(repo, reachability.TCPProber{Timeout: cfg.Reachability.Timeout}, reachability.Options{
+			Interval:    cfg.Reachability.Interval,
+			Jitter:      cfg.Reachability.Jitter,
+			Timeout:     cfg.Reachability.Timeout,
+			Workers:     cfg.Reachability.Workers,
+			History:     cfg.Reachability.History,
+			DefaultPort: cfg.Reachability.DefaultPort,
+			Ports:       cfg.Reachability.PortMap(),
+		})
+		services.Reachability = checker
+
+		go checker.Run(ctx, func(err error) {
+			log.Errorf("reachability round failed: %s", err)
+		})
+	}
+
+	return services, nil
+}
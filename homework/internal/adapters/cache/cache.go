@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"container/list"
+	stderrors "errors"
+	"sync"
+	"time"
+
+	"homework/internal/app"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/filter"
+	"homework/internal/labels"
+)
+
+const (
+	defaultCapacity    = 1024
+	defaultTTL         = time.Minute
+	defaultNegativeTTL = 5 * time.Second
+)
+
+type Options struct {
+	Capacity    int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+type Stats struct {
+	Hits         uint64
+	NegativeHits uint64
+	Misses       uint64
+	Evictions    uint64
+}
+
+type entry struct {
+	serialNum string
+	device    *devices.Device
+	notFound  bool
+	expiresAt time.Time
+}
+
+// Cache is a read-through decorator over any app.Repository. It keeps the
+// most recently read devices in a bounded LRU, remembers NotFoundError
+// results for a shorter time and drops entries on every write.
+type Cache struct {
+	repo app.Repository
+	opts Options
+	now  func() time.Time
+
+	mu         sync.Mutex
+	items      map[string]*list.Element
+	lru        *list.List
+	generation uint64
+	stats      Stats
+}
+
+func NewCache(repo app.Repository, opts Options) *Cache {
+	if opts.Capacity < 1 {
+		opts.Capacity = defaultCapacity
+	}
+
+	if opts.TTL < 1 {
+		opts.TTL = defaultTTL
+	}
+
+	if opts.NegativeTTL < 1 {
+		opts.NegativeTTL = defaultNegativeTTL
+	}
+
+	return &Cache{
+		repo:  repo,
+		opts:  opts,
+		now:   time.Now,
+		items: make(map[string]*list.Element),
+		lru:   list.New(),
+	}
+}
+
+func (c *Cache) Get(serialNum string) (*devices.Device, error) {
+	c.mu.Lock()
+	if elem, ok := c.items[serialNum]; ok {
+		e := elem.Value.(*entry)
+		if c.now().Before(e.expiresAt) {
+			c.lru.MoveToFront(elem)
+			if e.notFound {
+				c.stats.NegativeHits++
+				c.mu.Unlock()
+				return nil, errors.NewNotFoundError(serialNum)
+			}
+
+			c.stats.Hits++
+			c.mu.Unlock()
+			return e.device, nil
+		}
+		c.remove(elem)
+	}
+	c.stats.Misses++
+	generation := c.generation
+	c.mu.Unlock()
+
+	device, err := c.repo.Get(serialNum)
+
+	var notFound *errors.NotFoundError
+	if err != nil && !stderrors.As(err, &notFound) {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A write happened while the repository was being read, the result may
+	// already be stale.
+	if generation != c.generation {
+		return device, err
+	}
+
+	ttl := c.opts.TTL
+	if err != nil {
+		ttl = c.opts.NegativeTTL
+	}
+
+	c.store(&entry{
+		serialNum: serialNum,
+		device:    device,
+		notFound:  err != nil,
+		expiresAt: c.now().Add(ttl),
+	})
+
+	return device, err
+}
+
+// List and the index lookups are passed through, they are not cached.
+func (c *Cache) List() ([]*devices.Device, error) {
+	return c.repo.List()
+}
+
+func (c *Cache) ListByIP(ip string) ([]*devices.Device, error) {
+	return c.repo.ListByIP(ip)
+}
+
+func (c *Cache) ListByModel(model string) ([]*devices.Device, error) {
+	return c.repo.ListByModel(model)
+}
+
+func (c *Cache) Count() (int, error) {
+	return app.CountDevices(c.repo)
+}
+
+// Search and the selector and filter lookups use the indexes of the
+// wrapped repository when it has them.
+func (c *Cache) Search(query string, limit int) ([]devices.SearchHit, error) {
+	return app.SearchRepository(c.repo, query, limit)
+}
+
+func (c *Cache) ListByLocation(id string) ([]*devices.Device, error) {
+	return app.ListDevicesByLocation(c.repo, id)
+}
+
+func (c *Cache) ListBySelector(selector labels.Selector) ([]*devices.Device, error) {
+	return app.ListDevicesBySelector(c.repo, selector)
+}
+
+func (c *Cache) ListByFilter(expr filter.Expr) ([]*devices.Device, error) {
+	return app.ListDevicesByFilter(c.repo, expr)
+}
+
+func (c *Cache) Create(device *devices.Device) error {
+	defer c.invalidate(device.SerialNum)
+
+	return c.repo.Create(device)
+}
+
+func (c *Cache) Delete(serialNum string) error {
+	defer c.invalidate(serialNum)
+
+	return c.repo.Delete(serialNum)
+}
+
+func (c *Cache) Update(device *devices.Device) error {
+	defer c.invalidate(device.SerialNum)
+
+	return c.repo.Update(device)
+}
+
+// Snapshot never uses cached devices.
+func (c *Cache) Snapshot() ([]*devices.Device, error) {
+	return app.SnapshotDevices(c.repo)
+}
+
+// Replace drops every cached entry, whether or not the wrapped repository
+// replaced all devices before failing.
+func (c *Cache) Replace(list []*devices.Device) error {
+	defer c.purge()
+
+	return app.ReplaceDevices(c.repo, list)
+}
+
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *Cache) invalidate(serialNum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if elem, ok := c.items[serialNum]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *Cache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.items = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+func (c *Cache) store(e *entry) {
+	if elem, ok := c.items[e.serialNum]; ok {
+		elem.Value = e
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.items[e.serialNum] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.opts.Capacity {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.items, elem.Value.(*entry).serialNum)
+}
@@ -0,0 +1,179 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+const (
+	testSeqNum1 = "test 1"
+	testSeqNum2 = "test 2"
+	testSeqNum3 = "test 3"
+	testIP1     = "test ip 1"
+	testModel1  = "test model 1"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestCache(t *testing.T, opts Options) (*Cache, *deviceMock.MockRepository, *fakeClock) {
+	ctrl := gomock.NewController(t)
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	cache := NewCache(repo, opts)
+	cache.now = clock.Now
+
+	return cache, repo, clock
+}
+
+func TestCacheGetHit(t *testing.T) {
+	cache, repo, _ := newTestCache(t, Options{})
+
+	device := &devices.Device{SerialNum: testSeqNum1, IP: testIP1, Model: testModel1}
+	repo.EXPECT().Get(testSeqNum1).Return(device, nil).Times(1)
+
+	for i := 0; i < 3; i++ {
+		actual, err := cache.Get(testSeqNum1)
+		require.NoError(t, err)
+		require.Equal(t, device, actual)
+	}
+
+	require.Equal(t, Stats{Hits: 2, Misses: 1}, cache.Stats())
+}
+
+func TestCacheTTL(t *testing.T) {
+	cache, repo, clock := newTestCache(t, Options{TTL: time.Minute})
+
+	device := &devices.Device{SerialNum: testSeqNum1}
+	repo.EXPECT().Get(testSeqNum1).Return(device, nil).Times(2)
+
+	_, err := cache.Get(testSeqNum1)
+	require.NoError(t, err)
+
+	clock.now = clock.now.Add(2 * time.Minute)
+
+	_, err = cache.Get(testSeqNum1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), cache.Stats().Misses)
+}
+
+func TestCacheNegative(t *testing.T) {
+	cache, repo, clock := newTestCache(t, Options{NegativeTTL: time.Second})
+
+	repo.EXPECT().Get(testSeqNum1).Return(nil, errors.NewNotFoundError(testSeqNum1)).Times(2)
+
+	for i := 0; i < 2; i++ {
+		actual, err := cache.Get(testSeqNum1)
+		require.Nil(t, actual)
+		require.IsType(t, &errors.NotFoundError{}, err)
+	}
+	require.Equal(t, uint64(1), cache.Stats().NegativeHits)
+
+	clock.now = clock.now.Add(2 * time.Second)
+
+	_, err := cache.Get(testSeqNum1)
+	require.Error(t, err)
+}
+
+func TestCacheDoesNotStoreOtherErrors(t *testing.T) {
+	cache, repo, _ := newTestCache(t, Options{})
+
+	repo.EXPECT().Get(testSeqNum1).Return(nil, errors.NewAlreadyExistDeviceError(testSeqNum1)).Times(2)
+
+	_, err := cache.Get(testSeqNum1)
+	require.Error(t, err)
+	_, err = cache.Get(testSeqNum1)
+	require.Error(t, err)
+
+	require.Equal(t, 0, cache.Len())
+}
+
+func TestCacheEviction(t *testing.T) {
+	cache, repo, _ := newTestCache(t, Options{Capacity: 2})
+
+	for _, serialNum := range []string{testSeqNum1, testSeqNum2, testSeqNum3} {
+		repo.EXPECT().Get(serialNum).Return(&devices.Device{SerialNum: serialNum}, nil).Times(1)
+	}
+	repo.EXPECT().Get(testSeqNum2).Return(&devices.Device{SerialNum: testSeqNum2}, nil).Times(1)
+
+	_, _ = cache.Get(testSeqNum1)
+	_, _ = cache.Get(testSeqNum2)
+	_, _ = cache.Get(testSeqNum1)
+	_, _ = cache.Get(testSeqNum3)
+
+	require.Equal(t, 2, cache.Len())
+	require.Equal(t, uint64(1), cache.Stats().Evictions)
+
+	_, _ = cache.Get(testSeqNum1)
+	_, _ = cache.Get(testSeqNum2)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	cache, repo, _ := newTestCache(t, Options{})
+
+	device := &devices.Device{SerialNum: testSeqNum1, IP: testIP1}
+	updated := &devices.Device{SerialNum: testSeqNum1, IP: "test ip 2"}
+
+	gomock.InOrder(
+		repo.EXPECT().Get(testSeqNum1).Return(nil, errors.NewNotFoundError(testSeqNum1)),
+		repo.EXPECT().Create(device).Return(nil),
+		repo.EXPECT().Get(testSeqNum1).Return(device, nil),
+		repo.EXPECT().Update(updated).Return(nil),
+		repo.EXPECT().Get(testSeqNum1).Return(updated, nil),
+		repo.EXPECT().Delete(testSeqNum1).Return(nil),
+		repo.EXPECT().Get(testSeqNum1).Return(nil, errors.NewNotFoundError(testSeqNum1)),
+	)
+
+	_, err := cache.Get(testSeqNum1)
+	require.Error(t, err)
+
+	require.NoError(t, cache.Create(device))
+	actual, err := cache.Get(testSeqNum1)
+	require.NoError(t, err)
+	require.Equal(t, device, actual)
+
+	require.NoError(t, cache.Update(updated))
+	actual, err = cache.Get(testSeqNum1)
+	require.NoError(t, err)
+	require.Equal(t, updated, actual)
+
+	require.NoError(t, cache.Delete(testSeqNum1))
+	_, err = cache.Get(testSeqNum1)
+	require.Error(t, err)
+}
+
+func TestCacheReplace(t *testing.T) {
+	cache, repo, _ := newTestCache(t, Options{})
+
+	old := &devices.Device{SerialNum: testSeqNum1, IP: testIP1}
+	restored := &devices.Device{SerialNum: testSeqNum1, IP: "restored ip"}
+
+	repo.EXPECT().Get(testSeqNum1).Return(old, nil).Times(1)
+	repo.EXPECT().List().Return([]*devices.Device{old}, nil).Times(1)
+	repo.EXPECT().Create(restored).Return(errors.NewAlreadyExistDeviceError(testSeqNum1)).Times(1)
+	repo.EXPECT().Update(restored).Return(nil).Times(1)
+	repo.EXPECT().Get(testSeqNum1).Return(restored, nil).Times(1)
+
+	_, err := cache.Get(testSeqNum1)
+	require.NoError(t, err)
+
+	require.NoError(t, cache.Replace([]*devices.Device{restored}))
+	require.Equal(t, 0, cache.Len())
+
+	actual, err := cache.Get(testSeqNum1)
+	require.NoError(t, err)
+	require.Equal(t, restored, actual)
+}
@@ -0,0 +1,250 @@
+package changelog
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"homework/internal/app"
+	"homework/internal/errors"
+	"homework/internal/replication"
+)
+
+const (
+	defaultWait       = 30 * time.Second
+	defaultRetry      = time.Second
+	defaultBatchLimit = 1000
+
+	// requestSlack is added to the long poll wait for the client timeout.
+	requestSlack = 10 * time.Second
+)
+
+type FollowerOptions struct {
+	// Wait is how long a request for changes waits on the leader.
+	Wait time.Duration
+	// Retry is the pause after a failed request.
+	Retry time.Duration
+	// BatchLimit bounds the changes fetched with one request.
+	BatchLimit int
+}
+
+// Follower keeps a repository in sync with a leader. Only the follower
+// may write to the repository.
+type Follower struct {
+	repo   app.Repository
+	leader string
+	opts   FollowerOptions
+	client *http.Client
+	now    func() time.Time
+
+	mu     sync.Mutex
+	status replication.Status
+}
+
+// NewFollower replicates from the leader at the base url, such as
+// http://leader:8080.
+func NewFollower(repo app.Repository, leader string, opts FollowerOptions) *Follower {
+	if opts.Wait < 1 {
+		opts.Wait = defaultWait
+	}
+	if opts.Retry < 1 {
+		opts.Retry = defaultRetry
+	}
+	if opts.BatchLimit < 1 {
+		opts.BatchLimit = defaultBatchLimit
+	}
+
+	leader = strings.TrimSuffix(leader, "/")
+
+	return &Follower{
+		repo:   repo,
+		leader: leader,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Wait + requestSlack},
+		now:    time.Now,
+		status: replication.Status{Leader: leader},
+	}
+}
+
+func (f *Follower) Status() replication.Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.status
+}
+
+// Run replicates until the context is done, onError is called for every
+// failed attempt before it is retried. An empty batch answered well before
+// the wait is over, by a leader whose write timeout cuts the long poll
+// short, is also followed by a pause of Retry so the leader is not polled
+// in a tight loop.
+func (f *Follower) Run(ctx context.Context, onError func(error)) {
+	for ctx.Err() == nil {
+		started := f.now()
+		empty, err := f.sync(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			if !empty || f.now().Sub(started) >= f.opts.Wait/2 {
+				continue
+			}
+		} else {
+			f.setError(err)
+			if onError != nil {
+				onError(err)
+			}
+		}
+
+		select {
+		case <-time.After(f.opts.Retry):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// Sync makes one step: it loads a checkpoint when the follower has none or
+// its position is gone from the leader, and applies one batch of changes
+// otherwise.
+func (f *Follower) Sync(ctx context.Context) error {
+	_, err := f.sync(ctx)
+	return err
+}
+
+// sync is Sync, it also reports whether the leader answered with an empty
+// batch.
+func (f *Follower) sync(ctx context.Context) (bool, error) {
+	status := f.Status()
+	if status.LogID == "" {
+		return false, f.bootstrap(ctx)
+	}
+
+	batch, err := f.changes(ctx, status.LogID, status.Seq)
+	if stderrors.Is(err, replication.ErrCheckpointRequired) {
+		return false, f.bootstrap(ctx)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	seq := status.Seq
+	for _, entry := range batch.Entries {
+		if err = f.apply(entry); err != nil {
+			// The entries before are applied, the next batch starts after them.
+			f.setPosition(batch.LogID, seq, batch.Seq)
+			return false, fmt.Errorf("replication: apply %d: %w", entry.Seq, err)
+		}
+		seq = entry.Seq
+	}
+	f.setPosition(batch.LogID, seq, batch.Seq)
+
+	return len(batch.Entries) == 0, nil
+}
+
+func (f *Follower) bootstrap(ctx context.Context) error {
+	var checkpoint replication.Checkpoint
+	if err := f.get(ctx, "/replication/checkpoint", &checkpoint); err != nil {
+		return err
+	}
+
+	if err := app.ReplaceDevices(f.repo, checkpoint.Devices); err != nil {
+		return fmt.Errorf("replication: load checkpoint: %w", err)
+	}
+	f.setPosition(checkpoint.LogID, checkpoint.Seq, checkpoint.Seq)
+
+	return nil
+}
+
+func (f *Follower) changes(ctx context.Context, logID string, after uint64) (*replication.Batch, error) {
+	query := url.Values{
+		"log":   {logID},
+		"after": {strconv.FormatUint(after, 10)},
+		"wait":  {f.opts.Wait.String()},
+		"limit": {strconv.Itoa(f.opts.BatchLimit)},
+	}
+
+	var batch replication.Batch
+	if err := f.get(ctx, "/replication/changes?"+query.Encode(), &batch); err != nil {
+		return nil, err
+	}
+
+	return &batch, nil
+}
+
+// apply is idempotent, so a batch applied twice after a failure does no
+// harm.
+func (f *Follower) apply(entry replication.Entry) error {
+	switch entry.Op {
+	case replication.OpPut:
+		if entry.Device == nil {
+			return stderrors.New("put without device")
+		}
+		err := f.repo.Create(entry.Device)
+
+		var exists *errors.AlreadyExistDeviceError
+		if stderrors.As(err, &exists) {
+			err = f.repo.Update(entry.Device)
+		}
+		return err
+	case replication.OpDelete:
+		err := f.repo.Delete(entry.SerialNum)
+
+		var notFound *errors.NotFoundError
+		if stderrors.As(err, &notFound) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown op %q", entry.Op)
+	}
+}
+
+func (f *Follower) get(ctx context.Context, path string, value interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.leader+path, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("replication: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return json.NewDecoder(res.Body).Decode(value)
+	case http.StatusGone:
+		return replication.ErrCheckpointRequired
+	default:
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("replication: GET %s: %s: %s", path, res.Status, strings.TrimSpace(string(body)))
+	}
+}
+
+func (f *Follower) setPosition(logID string, seq, leaderSeq uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.status.LogID = logID
+	f.status.Seq = seq
+	f.status.LeaderSeq = leaderSeq
+	f.status.LastSync = f.now()
+	f.status.Error = ""
+}
+
+func (f *Follower) setError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.status.Error = err.Error()
+}
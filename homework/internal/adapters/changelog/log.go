@@ -0,0 +1,238 @@
+// Package changelog implements replication, see package replication for
+// the protocol. The leader wraps its repository in a Log, a Follower
+// applies its changes to another repository.
+package changelog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"homework/internal/app"
+	"homework/internal/devices"
+	"homework/internal/filter"
+	"homework/internal/labels"
+	"homework/internal/replication"
+)
+
+const defaultLogSize = 10000
+
+// Log is a repository decorator that records every write. Writes are
+// serialized, so positions follow the order in which the wrapped
+// repository applied them.
+type Log struct {
+	repo app.Repository
+	size int
+	now  func() time.Time
+
+	mu sync.Mutex
+	id string
+	// entries are the last positions of the log, oldest first.
+	entries []replication.Entry
+	seq     uint64
+	// changed is closed and replaced on every append to wake up waiters.
+	changed chan struct{}
+}
+
+// NewLog keeps the last size changes, followers further behind need a new
+// checkpoint.
+func NewLog(repo app.Repository, size int) *Log {
+	if size < 1 {
+		size = defaultLogSize
+	}
+
+	return &Log{
+		repo:    repo,
+		size:    size,
+		now:     time.Now,
+		id:      newLogID(),
+		changed: make(chan struct{}),
+	}
+}
+
+// newLogID tells logs apart, so a follower never applies the positions of
+// one log on top of a checkpoint of another.
+func newLogID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
+
+func (l *Log) Checkpoint() (*replication.Checkpoint, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	list, err := app.SnapshotDevices(l.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &replication.Checkpoint{LogID: l.id, Seq: l.seq, Devices: list}, nil
+}
+
+func (l *Log) Changes(ctx context.Context, logID string, after uint64, limit int) (*replication.Batch, error) {
+	for {
+		l.mu.Lock()
+		if logID != l.id || after > l.seq || (len(l.entries) > 0 && after+1 < l.entries[0].Seq) {
+			l.mu.Unlock()
+			return nil, replication.ErrCheckpointRequired
+		}
+
+		batch := &replication.Batch{LogID: l.id, Seq: l.seq, Entries: []replication.Entry{}}
+		if after < l.seq {
+			// Positions are consecutive, so the first entry after the
+			// position is found by offset.
+			start := len(l.entries) - int(l.seq-after)
+			end := len(l.entries)
+			if limit > 0 && end-start > limit {
+				end = start + limit
+			}
+			batch.Entries = append(batch.Entries, l.entries[start:end]...)
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		if len(batch.Entries) > 0 {
+			return batch, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return batch, nil
+		}
+	}
+}
+
+// append records a write, callers hold mu.
+func (l *Log) append(op replication.Op, serialNum string, device *devices.Device) {
+	l.seq++
+
+	entry := replication.Entry{Seq: l.seq, Op: op, SerialNum: serialNum, At: l.now()}
+	if device != nil {
+		clone := *device
+		clone.Labels = cloneMap(device.Labels)
+		clone.Attributes = cloneMap(device.Attributes)
+		entry.Device = &clone
+	}
+
+	if len(l.entries) == l.size {
+		// Copy instead of reslicing, so trimmed entries are released.
+		l.entries = append(l.entries[:0:0], l.entries[1:]...)
+	}
+	l.entries = append(l.entries, entry)
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	clone := make(map[string]string, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+
+	return clone
+}
+
+func (l *Log) Create(device *devices.Device) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.repo.Create(device); err != nil {
+		return err
+	}
+	l.append(replication.OpPut, device.SerialNum, device)
+
+	return nil
+}
+
+func (l *Log) Update(device *devices.Device) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.repo.Update(device); err != nil {
+		return err
+	}
+	l.append(replication.OpPut, device.SerialNum, device)
+
+	return nil
+}
+
+func (l *Log) Delete(serialNum string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.repo.Delete(serialNum); err != nil {
+		return err
+	}
+	l.append(replication.OpDelete, serialNum, nil)
+
+	return nil
+}
+
+// Replace starts a new log, followers pick up the devices with their next
+// checkpoint.
+func (l *Log) Replace(list []*devices.Device) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := app.ReplaceDevices(l.repo, list)
+
+	// Even a failed replace may have changed some devices.
+	l.id = newLogID()
+	l.seq = 0
+	l.entries = nil
+	close(l.changed)
+	l.changed = make(chan struct{})
+
+	return err
+}
+
+// Reads are passed through.
+func (l *Log) Get(serialNum string) (*devices.Device, error) {
+	return l.repo.Get(serialNum)
+}
+
+func (l *Log) List() ([]*devices.Device, error) {
+	return l.repo.List()
+}
+
+func (l *Log) ListByIP(ip string) ([]*devices.Device, error) {
+	return l.repo.ListByIP(ip)
+}
+
+func (l *Log) ListByModel(model string) ([]*devices.Device, error) {
+	return l.repo.ListByModel(model)
+}
+
+func (l *Log) ListByLocation(id string) ([]*devices.Device, error) {
+	return app.ListDevicesByLocation(l.repo, id)
+}
+
+func (l *Log) ListBySelector(selector labels.Selector) ([]*devices.Device, error) {
+	return app.ListDevicesBySelector(l.repo, selector)
+}
+
+func (l *Log) ListByFilter(expr filter.Expr) ([]*devices.Device, error) {
+	return app.ListDevicesByFilter(l.repo, expr)
+}
+
+func (l *Log) Search(query string, limit int) ([]devices.SearchHit, error) {
+	return app.SearchRepository(l.repo, query, limit)
+}
+
+func (l *Log) Count() (int, error) {
+	return app.CountDevices(l.repo)
+}
+
+func (l *Log) Snapshot() ([]*devices.Device, error) {
+	return app.SnapshotDevices(l.repo)
+}
@@ -0,0 +1,94 @@
+package changelog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/adapters/hashmap"
+	"homework/internal/devices"
+	"homework/internal/replication"
+)
+
+func TestLogChanges(t *testing.T) {
+	log := NewLog(hashmap.NewHash(), 2)
+
+	device := &devices.Device{SerialNum: "test 1", IP: "10.0.0.1"}
+	require.NoError(t, log.Create(device))
+	require.NoError(t, log.Update(&devices.Device{SerialNum: "test 1", IP: "10.0.0.2"}))
+	require.Error(t, log.Delete("missing"))
+
+	checkpoint, err := log.Checkpoint()
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), checkpoint.Seq)
+	require.Len(t, checkpoint.Devices, 1)
+
+	batch, err := log.Changes(context.Background(), checkpoint.LogID, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, batch.Entries, 2)
+	require.Equal(t, replication.OpPut, batch.Entries[0].Op)
+	require.Equal(t, "10.0.0.1", batch.Entries[0].Device.IP)
+	require.Equal(t, uint64(2), batch.Entries[1].Seq)
+
+	batch, err = log.Changes(context.Background(), checkpoint.LogID, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, batch.Entries, 1)
+
+	// The log keeps two entries, position 0 is trimmed after the delete.
+	require.NoError(t, log.Delete("test 1"))
+
+	_, err = log.Changes(context.Background(), checkpoint.LogID, 0, 0)
+	require.ErrorIs(t, err, replication.ErrCheckpointRequired)
+
+	batch, err = log.Changes(context.Background(), checkpoint.LogID, 2, 0)
+	require.NoError(t, err)
+	require.Equal(t, []replication.Entry{{Seq: 3, Op: replication.OpDelete, SerialNum: "test 1", At: batch.Entries[0].At}}, batch.Entries)
+
+	_, err = log.Changes(context.Background(), "other log", 3, 0)
+	require.ErrorIs(t, err, replication.ErrCheckpointRequired)
+
+	_, err = log.Changes(context.Background(), checkpoint.LogID, 4, 0)
+	require.ErrorIs(t, err, replication.ErrCheckpointRequired)
+}
+
+func TestLogChangesWait(t *testing.T) {
+	log := NewLog(hashmap.NewHash(), 0)
+	checkpoint, err := log.Checkpoint()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	batch, err := log.Changes(ctx, checkpoint.LogID, 0, 0)
+	require.NoError(t, err)
+	require.Empty(t, batch.Entries)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = log.Create(&devices.Device{SerialNum: "test 1"})
+	}()
+
+	batch, err = log.Changes(context.Background(), checkpoint.LogID, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, batch.Entries, 1)
+}
+
+func TestLogReplace(t *testing.T) {
+	log := NewLog(hashmap.NewHash(), 0)
+	require.NoError(t, log.Create(&devices.Device{SerialNum: "test 1"}))
+
+	before, err := log.Checkpoint()
+	require.NoError(t, err)
+
+	require.NoError(t, log.Replace([]*devices.Device{{SerialNum: "test 2"}}))
+
+	_, err = log.Changes(context.Background(), before.LogID, before.Seq, 0)
+	require.ErrorIs(t, err, replication.ErrCheckpointRequired)
+
+	after, err := log.Checkpoint()
+	require.NoError(t, err)
+	require.NotEqual(t, before.LogID, after.LogID)
+	require.Equal(t, []*devices.Device{{SerialNum: "test 2"}}, after.Devices)
+}
@@ -0,0 +1,195 @@
+// Package cluster replicates a repository over a raft cluster. Every node
+// keeps its devices in a local repository, writes are proposed to the
+// raft log and applied to the local repository of every node in the same
+// order.
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"homework/internal/app"
+	"homework/internal/backup"
+	"homework/internal/devices"
+	"homework/internal/filter"
+	"homework/internal/labels"
+)
+
+const defaultTimeout = 5 * time.Second
+
+type op string
+
+const (
+	opCreate  op = "create"
+	opUpdate  op = "update"
+	opDelete  op = "delete"
+	opReplace op = "replace"
+)
+
+type command struct {
+	Op        op                `json:"op"`
+	SerialNum string            `json:"serial_num,omitempty"`
+	Device    *devices.Device   `json:"device,omitempty"`
+	Devices   []*devices.Device `json:"devices,omitempty"`
+}
+
+// StateMachine applies commands to the local repository, the result of a
+// command is the error of the repository.
+type StateMachine struct {
+	repo app.Repository
+}
+
+func NewStateMachine(repo app.Repository) *StateMachine {
+	return &StateMachine{repo: repo}
+}
+
+func (m *StateMachine) Apply(data []byte) interface{} {
+	var cmd command
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		return fmt.Errorf("cluster: can not decode command: %w", err)
+	}
+
+	switch cmd.Op {
+	case opCreate:
+		return m.repo.Create(cmd.Device)
+	case opUpdate:
+		return m.repo.Update(cmd.Device)
+	case opDelete:
+		return m.repo.Delete(cmd.SerialNum)
+	case opReplace:
+		return app.ReplaceDevices(m.repo, cmd.Devices)
+	default:
+		return fmt.Errorf("cluster: unknown command %q", cmd.Op)
+	}
+}
+
+// Snapshot writes the devices as a backup archive, so a snapshot is
+// checked the same way as a restored backup.
+func (m *StateMachine) Snapshot() ([]byte, error) {
+	list, err := app.SnapshotDevices(m.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err = backup.Write(&buf, list, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (m *StateMachine) Restore(data []byte) error {
+	archive, err := backup.Read(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	return app.ReplaceDevices(m.repo, archive.Devices)
+}
+
+// Proposer is the part of a raft node the repository writes through.
+type Proposer interface {
+	Propose(ctx context.Context, data []byte) (interface{}, error)
+}
+
+// Repository writes through the raft log and reads the local repository.
+// Writes are linearizable, they only succeed on the leader once a
+// majority of the cluster has them. Reads on a follower may miss the
+// latest writes.
+type Repository struct {
+	node    Proposer
+	repo    app.Repository
+	timeout time.Duration
+}
+
+// NewRepository reads from repo, the repository the state machine of the
+// node applies to. Writes that are not applied within timeout fail.
+func NewRepository(node Proposer, repo app.Repository, timeout time.Duration) *Repository {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return &Repository{node: node, repo: repo, timeout: timeout}
+}
+
+func (r *Repository) propose(cmd command) error {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	result, err := r.node.Propose(ctx, data)
+	if err != nil {
+		return err
+	}
+
+	if err, ok := result.(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) Create(device *devices.Device) error {
+	return r.propose(command{Op: opCreate, Device: device})
+}
+
+func (r *Repository) Update(device *devices.Device) error {
+	return r.propose(command{Op: opUpdate, Device: device})
+}
+
+func (r *Repository) Delete(serialNum string) error {
+	return r.propose(command{Op: opDelete, SerialNum: serialNum})
+}
+
+func (r *Repository) Replace(list []*devices.Device) error {
+	return r.propose(command{Op: opReplace, Devices: list})
+}
+
+// Reads are passed through.
+func (r *Repository) Get(serialNum string) (*devices.Device, error) {
+	return r.repo.Get(serialNum)
+}
+
+func (r *Repository) List() ([]*devices.Device, error) {
+	return r.repo.List()
+}
+
+func (r *Repository) ListByIP(ip string) ([]*devices.Device, error) {
+	return r.repo.ListByIP(ip)
+}
+
+func (r *Repository) ListByModel(model string) ([]*devices.Device, error) {
+	return r.repo.ListByModel(model)
+}
+
+func (r *Repository) ListByLocation(id string) ([]*devices.Device, error) {
+	return app.ListDevicesByLocation(r.repo, id)
+}
+
+func (r *Repository) ListBySelector(selector labels.Selector) ([]*devices.Device, error) {
+	return app.ListDevicesBySelector(r.repo, selector)
+}
+
+func (r *Repository) ListByFilter(expr filter.Expr) ([]*devices.Device, error) {
+	return app.ListDevicesByFilter(r.repo, expr)
+}
+
+func (r *Repository) Search(query string, limit int) ([]devices.SearchHit, error) {
+	return app.SearchRepository(r.repo, query, limit)
+}
+
+func (r *Repository) Count() (int, error) {
+	return app.CountDevices(r.repo)
+}
+
+func (r *Repository) Snapshot() ([]*devices.Device, error) {
+	return app.SnapshotDevices(r.repo)
+}
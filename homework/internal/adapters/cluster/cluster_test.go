@@ -0,0 +1,140 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/adapters/hashmap"
+	"homework/internal/app"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/raft"
+)
+
+func newTestCluster(t *testing.T, ids ...string) (*raft.Network, map[string]*raft.Node, map[string]*Repository) {
+	network := raft.NewNetwork()
+	nodes := make(map[string]*raft.Node)
+	repos := make(map[string]*Repository)
+
+	members := make(map[string]string)
+	for _, id := range ids {
+		members[id] = id
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	for _, id := range ids {
+		local := hashmap.NewHash(hashmap.WithUniqueIP())
+		node, err := raft.NewNode(raft.Config{
+			ID:              id,
+			Address:         id,
+			Members:         members,
+			Tick:            2 * time.Millisecond,
+			ElectionTicks:   10,
+			SnapshotEntries: 4,
+		}, NewStateMachine(local), network.Transport(id))
+		require.NoError(t, err)
+
+		network.Attach(id, node)
+		nodes[id] = node
+		repos[id] = NewRepository(node, local, time.Second)
+
+		go node.Run(ctx, nil)
+	}
+
+	return network, nodes, repos
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func leaderOf(t *testing.T, nodes map[string]*raft.Node) string {
+	var leader string
+	waitFor(t, func() bool {
+		for id, node := range nodes {
+			if node.Status().State == raft.Leader {
+				leader = id
+				return true
+			}
+		}
+		return false
+	})
+
+	return leader
+}
+
+func TestRepository(t *testing.T) {
+	network, nodes, repos := newTestCluster(t, "a", "b", "c")
+	leader := leaderOf(t, nodes)
+	repo := repos[leader]
+
+	device := &devices.Device{SerialNum: "test 1", Model: "RT-100", IP: "10.0.0.1"}
+	require.NoError(t, repo.Create(device))
+
+	err := repo.Create(device)
+	require.IsType(t, &errors.AlreadyExistDeviceError{}, err)
+
+	err = repo.Create(&devices.Device{SerialNum: "test 2", IP: "10.0.0.1"})
+	require.IsType(t, &errors.ConflictError{}, err)
+
+	require.NoError(t, repo.Update(&devices.Device{SerialNum: "test 1", Model: "SW-48", IP: "10.0.0.1"}))
+
+	for id, follower := range repos {
+		if id == leader {
+			continue
+		}
+
+		err = follower.Create(&devices.Device{SerialNum: "test 3"})
+		require.IsType(t, &raft.NotLeaderError{}, err)
+
+		follower := follower
+		waitFor(t, func() bool {
+			got, err := follower.Get("test 1")
+			return err == nil && got.Model == "SW-48"
+		})
+	}
+
+	// A node cut off while devices change catches up from a snapshot.
+	var lagging string
+	var rest []string
+	for id := range nodes {
+		if id != leader && lagging == "" {
+			lagging = id
+			continue
+		}
+		rest = append(rest, id)
+	}
+	network.Partition(rest, []string{lagging})
+
+	leader = leaderOf(t, map[string]*raft.Node{rest[0]: nodes[rest[0]], rest[1]: nodes[rest[1]]})
+	repo = repos[leader]
+
+	require.NoError(t, repo.Delete("test 1"))
+	for _, serialNum := range []string{"test 4", "test 5", "test 6", "test 7"} {
+		require.NoError(t, repo.Create(&devices.Device{SerialNum: serialNum}))
+	}
+
+	network.Heal()
+
+	waitFor(t, func() bool {
+		count, err := app.CountDevices(repos[lagging])
+		return err == nil && count == 4
+	})
+
+	_, err = repos[lagging].Get("test 1")
+	require.IsType(t, &errors.NotFoundError{}, err)
+	require.NotZero(t, nodes[lagging].Status().SnapshotIndex)
+}
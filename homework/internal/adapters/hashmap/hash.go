@@ -6,16 +6,20 @@ import (
 	"homework/internal/app"
 	"homework/internal/devices"
 	"homework/internal/errors"
+	"homework/internal/filter"
+	"homework/internal/labels"
 )
 
 type hash struct {
 	hashTable map[string]*devices.Device
+	indexes   *indexes
 	mu        sync.RWMutex
 }
 
-func NewHash() app.Repository {
+func NewHash(opts ...Option) app.Repository {
 	return &hash{
 		hashTable: make(map[string]*devices.Device),
+		indexes:   newIndexes(newOptions(opts)),
 		mu:        sync.RWMutex{},
 	}
 }
@@ -31,6 +35,139 @@ func (h *hash) Get(serialNum string) (*devices.Device, error) {
 	return h.hashTable[serialNum], nil
 }
 
+func (h *hash) List() ([]*devices.Device, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]*devices.Device, 0, len(h.hashTable))
+	for _, device := range h.hashTable {
+		list = append(list, device)
+	}
+	sortBySerialNum(list)
+
+	return list, nil
+}
+
+// Snapshot is List, which already copies all devices under one lock.
+func (h *hash) Snapshot() ([]*devices.Device, error) {
+	return h.List()
+}
+
+func (h *hash) Replace(list []*devices.Device) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fresh, err := h.indexes.rebuilt(list)
+	if err != nil {
+		return err
+	}
+
+	table := make(map[string]*devices.Device, len(list))
+	for _, device := range list {
+		table[device.SerialNum] = device
+	}
+	h.hashTable, h.indexes = table, fresh
+
+	return nil
+}
+
+func (h *hash) Count() (int, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.hashTable), nil
+}
+
+func (h *hash) ListByIP(ip string) ([]*devices.Device, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.lookup(h.indexes.byIP[ip]), nil
+}
+
+func (h *hash) ListByModel(model string) ([]*devices.Device, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.lookup(h.indexes.byModel[model]), nil
+}
+
+func (h *hash) ListByLocation(id string) ([]*devices.Device, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.lookup(h.indexes.byLocation[id]), nil
+}
+
+func (h *hash) ListBySelector(selector labels.Selector) ([]*devices.Device, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	candidates, ok := h.indexes.candidates(selector)
+	if !ok {
+		candidates = make(set, len(h.hashTable))
+		for serialNum := range h.hashTable {
+			candidates[serialNum] = struct{}{}
+		}
+	}
+
+	list := make([]*devices.Device, 0, len(candidates))
+	for _, device := range h.lookup(candidates) {
+		if selector.Matches(device.Labels) {
+			list = append(list, device)
+		}
+	}
+
+	return list, nil
+}
+
+func (h *hash) ListByFilter(expr filter.Expr) ([]*devices.Device, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	candidates, ok := h.indexes.filterCandidates(expr)
+	if !ok {
+		candidates = make(set, len(h.hashTable))
+		for serialNum := range h.hashTable {
+			candidates[serialNum] = struct{}{}
+		}
+	}
+
+	list := make([]*devices.Device, 0, len(candidates))
+	for _, device := range h.lookup(candidates) {
+		if expr.Match(device) {
+			list = append(list, device)
+		}
+	}
+
+	return list, nil
+}
+
+func (h *hash) Search(query string, limit int) ([]devices.SearchHit, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	hits := h.indexes.text.Search(query, limit)
+
+	result := make([]devices.SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, devices.SearchHit{Device: h.hashTable[hit.ID], Score: hit.Score})
+	}
+
+	return result, nil
+}
+
+func (h *hash) lookup(serialNums set) []*devices.Device {
+	list := make([]*devices.Device, 0, len(serialNums))
+	for _, serialNum := range sortedSerialNums(serialNums) {
+		if device, ok := h.hashTable[serialNum]; ok {
+			list = append(list, device)
+		}
+	}
+
+	return list
+}
+
 func (h *hash) Create(device *devices.Device) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -39,7 +176,12 @@ func (h *hash) Create(device *devices.Device) error {
 		return errors.NewAlreadyExistDeviceError(device.SerialNum)
 	}
 
+	if err := h.indexes.check(device); err != nil {
+		return err
+	}
+
 	h.hashTable[device.SerialNum] = device
+	h.indexes.add(device)
 
 	return nil
 }
@@ -52,6 +194,7 @@ func (h *hash) Delete(serialNum string) error {
 		return errors.NewNotFoundError(serialNum)
 	}
 
+	h.indexes.remove(h.hashTable[serialNum])
 	delete(h.hashTable, serialNum)
 
 	return nil
@@ -61,11 +204,18 @@ func (h *hash) Update(device *devices.Device) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if _, ok := h.hashTable[device.SerialNum]; !ok {
+	old, ok := h.hashTable[device.SerialNum]
+	if !ok {
 		return errors.NewNotFoundError(device.SerialNum)
 	}
 
+	if err := h.indexes.check(device); err != nil {
+		return err
+	}
+
+	h.indexes.remove(old)
 	h.hashTable[device.SerialNum] = device
+	h.indexes.add(device)
 
 	return nil
 }
@@ -0,0 +1,85 @@
+package hashmap
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"homework/internal/app"
+	"homework/internal/devices"
+	"homework/internal/errors"
+)
+
+type heartbeatHash struct {
+	hashTable map[string]*devices.Heartbeat
+	mu        sync.RWMutex
+}
+
+func NewHeartbeatHash() app.HeartbeatRepository {
+	return &heartbeatHash{
+		hashTable: make(map[string]*devices.Heartbeat),
+	}
+}
+
+func (h *heartbeatHash) Get(serialNum string) (*devices.Heartbeat, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	heartbeat, ok := h.hashTable[serialNum]
+	if !ok {
+		return nil, errors.NewEntityNotFoundError("heartbeat", "SerialNum", serialNum)
+	}
+
+	return heartbeat, nil
+}
+
+func (h *heartbeatHash) List() ([]*devices.Heartbeat, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]*devices.Heartbeat, 0, len(h.hashTable))
+	for _, heartbeat := range h.hashTable {
+		list = append(list, heartbeat)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SerialNum < list[j].SerialNum
+	})
+
+	return list, nil
+}
+
+func (h *heartbeatHash) Put(heartbeat *devices.Heartbeat) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.hashTable[heartbeat.SerialNum] = heartbeat
+
+	return nil
+}
+
+func (h *heartbeatHash) Delete(serialNum string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.hashTable, serialNum)
+
+	return nil
+}
+
+func (h *heartbeatHash) MarkOffline(serialNum string, seenBefore time.Time) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	heartbeat, ok := h.hashTable[serialNum]
+	if !ok || heartbeat.Liveness == devices.Offline || !heartbeat.LastSeen.Before(seenBefore) {
+		return false, nil
+	}
+
+	// Stored heartbeats are handed out to readers, so they are replaced
+	// rather than changed in place.
+	offline := *heartbeat
+	offline.Liveness = devices.Offline
+	h.hashTable[serialNum] = &offline
+
+	return true, nil
+}
@@ -0,0 +1,40 @@
+package hashmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+)
+
+func TestHeartbeatHashMarkOffline(t *testing.T) {
+	repo := NewHeartbeatHash()
+
+	seen := time.Unix(100, 0)
+	heartbeat := &devices.Heartbeat{SerialNum: testSeqNum1, Liveness: devices.Online, LastSeen: seen}
+	require.NoError(t, repo.Put(heartbeat))
+
+	ok, err := repo.MarkOffline(testSeqNum1, seen)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = repo.MarkOffline(testSeqNum1, seen.Add(time.Second))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	got, err := repo.Get(testSeqNum1)
+	require.NoError(t, err)
+	require.Equal(t, devices.Offline, got.Liveness)
+	require.Equal(t, devices.Online, heartbeat.Liveness)
+
+	ok, err = repo.MarkOffline(testSeqNum1, seen.Add(time.Second))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, repo.Delete(testSeqNum1))
+	_, err = repo.Get(testSeqNum1)
+	require.IsType(t, &errors.NotFoundError{}, err)
+}
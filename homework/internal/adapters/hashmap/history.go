@@ -0,0 +1,38 @@
+package hashmap
+
+import (
+	"sync"
+
+	"homework/internal/app"
+	"homework/internal/devices"
+)
+
+type historyHash struct {
+	transitions map[string][]devices.Transition
+	mu          sync.RWMutex
+}
+
+func NewHistoryHash() app.HistoryRepository {
+	return &historyHash{
+		transitions: make(map[string][]devices.Transition),
+	}
+}
+
+func (h *historyHash) Append(transition devices.Transition) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.transitions[transition.SerialNum] = append(h.transitions[transition.SerialNum], transition)
+
+	return nil
+}
+
+func (h *historyHash) List(serialNum string) ([]devices.Transition, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]devices.Transition, len(h.transitions[serialNum]))
+	copy(list, h.transitions[serialNum])
+
+	return list, nil
+}
@@ -0,0 +1,30 @@
+package hashmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+)
+
+func TestHistoryHash(t *testing.T) {
+	repo := NewHistoryHash()
+
+	created := devices.Transition{SerialNum: testSeqNum1, To: devices.StatusOrdered, At: time.Unix(1, 0)}
+	received := devices.Transition{SerialNum: testSeqNum1, From: devices.StatusOrdered, To: devices.StatusInStock, At: time.Unix(2, 0)}
+	other := devices.Transition{SerialNum: testSeqNum2, To: devices.StatusOrdered, At: time.Unix(3, 0)}
+
+	for _, transition := range []devices.Transition{created, other, received} {
+		require.NoError(t, repo.Append(transition))
+	}
+
+	list, err := repo.List(testSeqNum1)
+	require.NoError(t, err)
+	require.Equal(t, []devices.Transition{created, received}, list)
+
+	list, err = repo.List(testSeqNum3)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
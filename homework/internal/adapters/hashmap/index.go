@@ -0,0 +1,280 @@
+package hashmap
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/filter"
+	"homework/internal/labels"
+	"homework/internal/search"
+)
+
+type Option func(*options)
+
+type options struct {
+	uniqueIP bool
+}
+
+// WithUniqueIP makes Create and Update reject a device whose IP is already
+// used by another device.
+func WithUniqueIP() Option {
+	return func(o *options) {
+		o.uniqueIP = true
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+type set map[string]struct{}
+
+// indexes keeps secondary lookups by IP, Model, location and labels and
+// the full text index. It is not safe for concurrent use, callers hold the
+// lock that guards the primary table.
+type indexes struct {
+	uniqueIP   bool
+	byIP       map[string]set
+	byModel    map[string]set
+	byLocation map[string]set
+	byPosition map[string]string
+	byLabel    map[string]map[string]set
+	text       *search.Index
+}
+
+func newIndexes(o options) *indexes {
+	return &indexes{
+		uniqueIP:   o.uniqueIP,
+		byIP:       make(map[string]set),
+		byModel:    make(map[string]set),
+		byLocation: make(map[string]set),
+		byPosition: make(map[string]string),
+		byLabel:    make(map[string]map[string]set),
+		text:       search.NewIndex(),
+	}
+}
+
+// positionKey identifies a rack position, it is empty for devices that
+// are not placed at one.
+func positionKey(device *devices.Device) string {
+	if device.LocationID == "" || device.Position <= 0 {
+		return ""
+	}
+
+	return device.LocationID + "#" + strconv.Itoa(device.Position)
+}
+
+// rebuilt indexes the devices from scratch with the same options, it
+// fails if they violate IP or rack position uniqueness.
+func (idx *indexes) rebuilt(list []*devices.Device) (*indexes, error) {
+	fresh := newIndexes(options{uniqueIP: idx.uniqueIP})
+
+	for _, device := range list {
+		if err := fresh.check(device); err != nil {
+			return nil, err
+		}
+		fresh.add(device)
+	}
+
+	return fresh, nil
+}
+
+// check reports whether the device may be stored without violating IP
+// uniqueness or taking the rack position of another device.
+func (idx *indexes) check(device *devices.Device) error {
+	if key := positionKey(device); key != "" {
+		if serialNum, ok := idx.byPosition[key]; ok && serialNum != device.SerialNum {
+			return errors.NewConflictErrorf("position %d of rack %s is used by device with 'SerialNum' = %s",
+				device.Position, device.LocationID, serialNum)
+		}
+	}
+
+	if !idx.uniqueIP || device.IP == "" {
+		return nil
+	}
+
+	for serialNum := range idx.byIP[device.IP] {
+		if serialNum != device.SerialNum {
+			return errors.NewConflictError("IP", device.IP, serialNum)
+		}
+	}
+
+	return nil
+}
+
+func (idx *indexes) add(device *devices.Device) {
+	addTo(idx.byIP, device.IP, device.SerialNum)
+	addTo(idx.byModel, device.Model, device.SerialNum)
+	addTo(idx.byLocation, device.LocationID, device.SerialNum)
+	if key := positionKey(device); key != "" {
+		idx.byPosition[key] = device.SerialNum
+	}
+	idx.text.Add(device.SerialNum, search.DeviceFields(device)...)
+
+	for key, value := range device.Labels {
+		if idx.byLabel[key] == nil {
+			idx.byLabel[key] = make(map[string]set)
+		}
+		if idx.byLabel[key][value] == nil {
+			idx.byLabel[key][value] = make(set)
+		}
+		idx.byLabel[key][value][device.SerialNum] = struct{}{}
+	}
+}
+
+func (idx *indexes) remove(device *devices.Device) {
+	removeFrom(idx.byIP, device.IP, device.SerialNum)
+	removeFrom(idx.byModel, device.Model, device.SerialNum)
+	removeFrom(idx.byLocation, device.LocationID, device.SerialNum)
+	if key := positionKey(device); key != "" && idx.byPosition[key] == device.SerialNum {
+		delete(idx.byPosition, key)
+	}
+	idx.text.Remove(device.SerialNum)
+
+	for key, value := range device.Labels {
+		values, ok := idx.byLabel[key]
+		if !ok {
+			continue
+		}
+
+		if serialNums, ok := values[value]; ok {
+			delete(serialNums, device.SerialNum)
+			if len(serialNums) == 0 {
+				delete(values, value)
+			}
+		}
+		if len(values) == 0 {
+			delete(idx.byLabel, key)
+		}
+	}
+}
+
+// candidates narrows a selector down to the devices that carry the keys of
+// its positive requirements. It returns false when the selector has no
+// positive requirement and every device has to be checked.
+func (idx *indexes) candidates(selector labels.Selector) (set, bool) {
+	var result set
+
+	for _, r := range selector {
+		if !r.Positive() {
+			continue
+		}
+
+		matched := make(set)
+		for value, serialNums := range idx.byLabel[r.Key] {
+			if r.Operator != labels.Exists && !r.Matches(map[string]string{r.Key: value}) {
+				continue
+			}
+			for serialNum := range serialNums {
+				if result == nil || hasMember(result, serialNum) {
+					matched[serialNum] = struct{}{}
+				}
+			}
+		}
+
+		result = matched
+		if len(result) == 0 {
+			break
+		}
+	}
+
+	return result, result != nil
+}
+
+// filterCandidates narrows a filter down with the equality comparisons on
+// serial_num, model and labels that every match satisfies. It returns
+// false when the filter has none and every device has to be checked.
+func (idx *indexes) filterCandidates(expr filter.Expr) (set, bool) {
+	var result set
+
+	for _, c := range filter.Conjuncts(expr) {
+		values, ok := c.Equals()
+		if !ok || !idx.indexed(c.Field) {
+			continue
+		}
+
+		matched := make(set)
+		for _, value := range values {
+			for serialNum := range idx.equal(c.Field, value) {
+				if result == nil || hasMember(result, serialNum) {
+					matched[serialNum] = struct{}{}
+				}
+			}
+		}
+
+		result = matched
+		if len(result) == 0 {
+			break
+		}
+	}
+
+	return result, result != nil
+}
+
+func (idx *indexes) indexed(field string) bool {
+	return field == "serial_num" || field == "model" || strings.HasPrefix(field, "labels.")
+}
+
+// equal returns the devices whose indexed field equals value.
+func (idx *indexes) equal(field, value string) set {
+	switch field {
+	case "serial_num":
+		return set{value: struct{}{}}
+	case "model":
+		return idx.byModel[value]
+	default:
+		return idx.byLabel[strings.TrimPrefix(field, "labels.")][value]
+	}
+}
+
+func hasMember(s set, member string) bool {
+	_, ok := s[member]
+	return ok
+}
+
+func addTo(index map[string]set, value, serialNum string) {
+	if value == "" {
+		return
+	}
+
+	if index[value] == nil {
+		index[value] = make(set)
+	}
+	index[value][serialNum] = struct{}{}
+}
+
+func removeFrom(index map[string]set, value, serialNum string) {
+	serialNums, ok := index[value]
+	if !ok {
+		return
+	}
+
+	delete(serialNums, serialNum)
+	if len(serialNums) == 0 {
+		delete(index, value)
+	}
+}
+
+func sortedSerialNums(serialNums set) []string {
+	result := make([]string, 0, len(serialNums))
+	for serialNum := range serialNums {
+		result = append(result, serialNum)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+func sortBySerialNum(list []*devices.Device) {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SerialNum < list[j].SerialNum
+	})
+}
@@ -0,0 +1,321 @@
+package hashmap
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/app"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/filter"
+	"homework/internal/labels"
+)
+
+func TestIndexes(t *testing.T) {
+	repos := map[string]func(opts ...Option) app.Repository{
+		"hash": NewHash,
+		"sharded": func(opts ...Option) app.Repository {
+			return NewShardedHash(4, opts...)
+		},
+	}
+
+	for name, newRepo := range repos {
+		t.Run(name, func(t *testing.T) {
+			repo := newRepo()
+
+			device1 := &devices.Device{SerialNum: testSeqNum1, IP: testIP1, Model: testModel1}
+			device2 := &devices.Device{SerialNum: testSeqNum2, IP: testIP1, Model: testModel1}
+			require.NoError(t, repo.Create(device1))
+			require.NoError(t, repo.Create(device2))
+
+			list, err := repo.ListByIP(testIP1)
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{device1, device2}, list)
+
+			updated := &devices.Device{SerialNum: testSeqNum2, IP: testIP2, Model: testModel2}
+			require.NoError(t, repo.Update(updated))
+
+			list, err = repo.ListByIP(testIP1)
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{device1}, list)
+
+			list, err = repo.ListByModel(testModel2)
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{updated}, list)
+
+			require.NoError(t, repo.Delete(testSeqNum1))
+
+			list, err = repo.ListByIP(testIP1)
+			require.NoError(t, err)
+			require.Empty(t, list)
+
+			list, err = repo.List()
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{updated}, list)
+		})
+
+		t.Run(name+" unique ip", func(t *testing.T) {
+			repo := newRepo(WithUniqueIP())
+
+			require.NoError(t, repo.Create(&devices.Device{SerialNum: testSeqNum1, IP: testIP1}))
+			require.NoError(t, repo.Create(&devices.Device{SerialNum: testSeqNum2, IP: testIP2}))
+			require.NoError(t, repo.Create(&devices.Device{SerialNum: testSeqNum3}))
+
+			err := repo.Create(&devices.Device{SerialNum: "test 4", IP: testIP1})
+			require.IsType(t, &errors.ConflictError{}, err)
+
+			err = repo.Update(&devices.Device{SerialNum: testSeqNum2, IP: testIP1})
+			require.IsType(t, &errors.ConflictError{}, err)
+
+			require.NoError(t, repo.Update(&devices.Device{SerialNum: testSeqNum1, IP: testIP1, Model: testModel1}))
+
+			require.NoError(t, repo.Delete(testSeqNum1))
+			require.NoError(t, repo.Update(&devices.Device{SerialNum: testSeqNum2, IP: testIP1}))
+		})
+
+		t.Run(name+" rack positions", func(t *testing.T) {
+			repo := newRepo()
+
+			// Of devices racing for one position exactly one is stored.
+			var wg sync.WaitGroup
+			var created int32
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					if repo.Create(&devices.Device{SerialNum: strconv.Itoa(i), LocationID: "rack", Position: 1}) == nil {
+						atomic.AddInt32(&created, 1)
+					}
+				}(i)
+			}
+			wg.Wait()
+			require.Equal(t, int32(1), created)
+
+			list, err := repo.(app.LocationLister).ListByLocation("rack")
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+
+			moved := &devices.Device{SerialNum: list[0].SerialNum, LocationID: "rack", Position: 2}
+			require.NoError(t, repo.Update(moved))
+			require.NoError(t, repo.Create(&devices.Device{SerialNum: testSeqNum1, LocationID: "rack", Position: 1}))
+
+			err = repo.Update(&devices.Device{SerialNum: testSeqNum1, LocationID: "rack", Position: 2})
+			require.IsType(t, &errors.ConflictError{}, err)
+
+			require.NoError(t, repo.Delete(moved.SerialNum))
+			require.NoError(t, repo.Update(&devices.Device{SerialNum: testSeqNum1, LocationID: "rack", Position: 2}))
+		})
+
+		t.Run(name+" failed update keeps nothing", func(t *testing.T) {
+			repo := newRepo(WithUniqueIP())
+
+			require.NoError(t, repo.Create(&devices.Device{SerialNum: testSeqNum1, IP: testIP1, LocationID: "rack", Position: 1}))
+			require.NoError(t, repo.Create(&devices.Device{SerialNum: testSeqNum2, IP: testIP2}))
+
+			// The new IP is free, the position is not, so neither is taken.
+			err := repo.Update(&devices.Device{SerialNum: testSeqNum2, IP: testIP3, LocationID: "rack", Position: 1})
+			require.IsType(t, &errors.ConflictError{}, err)
+
+			require.NoError(t, repo.Create(&devices.Device{SerialNum: testSeqNum3, IP: testIP3}))
+			err = repo.Create(&devices.Device{SerialNum: "test 4", IP: testIP2})
+			require.IsType(t, &errors.ConflictError{}, err)
+		})
+	}
+}
+
+func TestListBySelector(t *testing.T) {
+	repos := map[string]app.Repository{
+		"hash":    NewHash(),
+		"sharded": NewShardedHash(4),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			prodWeb := &devices.Device{SerialNum: testSeqNum1, Labels: map[string]string{"env": "prod", "tier": "web"}}
+			prodDB := &devices.Device{SerialNum: testSeqNum2, Labels: map[string]string{"env": "prod", "tier": "db"}}
+			dev := &devices.Device{SerialNum: testSeqNum3, Labels: map[string]string{"env": "dev"}}
+
+			for _, device := range []*devices.Device{prodWeb, prodDB, dev} {
+				require.NoError(t, repo.Create(device))
+			}
+
+			lister := repo.(app.SelectorLister)
+
+			cases := []struct {
+				selector string
+				expect   []*devices.Device
+			}{
+				{selector: "env=prod", expect: []*devices.Device{prodWeb, prodDB}},
+				{selector: "env=prod,tier in (db,cache)", expect: []*devices.Device{prodDB}},
+				{selector: "tier", expect: []*devices.Device{prodWeb, prodDB}},
+				{selector: "!tier", expect: []*devices.Device{dev}},
+				{selector: "env!=prod", expect: []*devices.Device{dev}},
+				{selector: "env=staging", expect: []*devices.Device{}},
+			}
+
+			for _, tCase := range cases {
+				selector, err := labels.Parse(tCase.selector)
+				require.NoError(t, err)
+
+				list, err := lister.ListBySelector(selector)
+				require.NoError(t, err)
+				require.Equal(t, tCase.expect, list, tCase.selector)
+			}
+
+			require.NoError(t, repo.Update(&devices.Device{SerialNum: testSeqNum1, Labels: map[string]string{"env": "dev"}}))
+
+			selector, _ := labels.Parse("env=prod")
+			list, err := lister.ListBySelector(selector)
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{prodDB}, list)
+		})
+	}
+}
+
+func TestListByFilter(t *testing.T) {
+	repos := map[string]app.Repository{
+		"hash":    NewHash(),
+		"sharded": NewShardedHash(4),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			router := &devices.Device{SerialNum: testSeqNum1, Model: "RT-100", IP: "10.0.0.1", Labels: map[string]string{"env": "prod"}}
+			sw := &devices.Device{SerialNum: testSeqNum2, Model: "SW-48", IP: "10.0.0.2", Labels: map[string]string{"env": "prod"}}
+			remote := &devices.Device{SerialNum: testSeqNum3, Model: "RT-100", IP: "192.168.0.1"}
+
+			for _, device := range []*devices.Device{router, sw, remote} {
+				require.NoError(t, repo.Create(device))
+			}
+
+			lister := repo.(app.FilterLister)
+
+			cases := []struct {
+				filter string
+				expect []*devices.Device
+			}{
+				{filter: `model == "RT-100" and ip in 10.0.0.0/8`, expect: []*devices.Device{router}},
+				{filter: `labels.env == prod and not model == "RT-100"`, expect: []*devices.Device{sw}},
+				{filter: `model in ["RT-100", "SW-48"] and labels.env == prod`, expect: []*devices.Device{router, sw}},
+				{filter: `serial_num == "test 3" or serial_num == missing`, expect: []*devices.Device{remote}},
+				{filter: `serial_num in ["test 2", missing]`, expect: []*devices.Device{sw}},
+				{filter: `ip in 192.168.0.0/16`, expect: []*devices.Device{remote}},
+				{filter: `model == "AP-1"`, expect: []*devices.Device{}},
+			}
+
+			for _, tCase := range cases {
+				expr, err := filter.Parse(tCase.filter)
+				require.NoError(t, err)
+
+				list, err := lister.ListByFilter(expr)
+				require.NoError(t, err)
+				require.Equal(t, tCase.expect, list, tCase.filter)
+			}
+		})
+	}
+}
+
+func TestSnapshotReplace(t *testing.T) {
+	repos := map[string]app.Repository{
+		"hash":    NewHash(WithUniqueIP()),
+		"sharded": NewShardedHash(4, WithUniqueIP()),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			first := &devices.Device{SerialNum: testSeqNum1, Model: "RT-100", IP: "10.0.0.1"}
+			second := &devices.Device{SerialNum: testSeqNum2, Model: "RT-100", IP: "10.0.0.2"}
+			require.NoError(t, repo.Create(first))
+			require.NoError(t, repo.Create(second))
+
+			snapshot, err := repo.(app.Snapshotter).Snapshot()
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{first, second}, snapshot)
+
+			replacer := repo.(app.Replacer)
+
+			restored := &devices.Device{SerialNum: testSeqNum3, Model: "SW-48", IP: "10.0.0.1"}
+			require.NoError(t, replacer.Replace([]*devices.Device{restored}))
+
+			list, err := repo.List()
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{restored}, list)
+
+			list, err = repo.ListByModel("RT-100")
+			require.NoError(t, err)
+			require.Empty(t, list)
+
+			list, err = repo.ListByIP("10.0.0.1")
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{restored}, list)
+
+			// A replacement breaking ip uniqueness leaves the devices alone.
+			err = replacer.Replace([]*devices.Device{first, {SerialNum: testSeqNum2, IP: "10.0.0.1"}})
+			require.IsType(t, &errors.ConflictError{}, err)
+
+			list, err = repo.List()
+			require.NoError(t, err)
+			require.Equal(t, []*devices.Device{restored}, list)
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	repos := map[string]app.Repository{
+		"hash":    NewHash(),
+		"sharded": NewShardedHash(4),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			for _, serialNum := range []string{testSeqNum1, testSeqNum2, testSeqNum3} {
+				require.NoError(t, repo.Create(&devices.Device{SerialNum: serialNum}))
+			}
+			require.NoError(t, repo.Delete(testSeqNum2))
+
+			count, err := repo.(app.Counter).Count()
+			require.NoError(t, err)
+			require.Equal(t, 2, count)
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	repos := map[string]app.Repository{
+		"hash":    NewHash(),
+		"sharded": NewShardedHash(4),
+	}
+
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			router := &devices.Device{SerialNum: "SN-0001", Model: "RT-100", IP: "10.0.0.1"}
+			sw := &devices.Device{SerialNum: "SN-0002", Model: "SW-48", IP: "10.0.0.2"}
+			require.NoError(t, repo.Create(router))
+			require.NoError(t, repo.Create(sw))
+
+			searcher := repo.(app.Searcher)
+
+			hits, err := searcher.Search("rt-100", 10)
+			require.NoError(t, err)
+			require.Len(t, hits, 1)
+			require.Equal(t, router, hits[0].Device)
+
+			moved := &devices.Device{SerialNum: "SN-0002", Model: "RT-100", IP: "10.0.0.2"}
+			require.NoError(t, repo.Update(moved))
+			require.NoError(t, repo.Delete("SN-0001"))
+
+			hits, err = searcher.Search("rt-100", 10)
+			require.NoError(t, err)
+			require.Len(t, hits, 1)
+			require.Equal(t, moved, hits[0].Device)
+
+			hits, err = searcher.Search("sw-48", 10)
+			require.NoError(t, err)
+			require.Empty(t, hits)
+		})
+	}
+}
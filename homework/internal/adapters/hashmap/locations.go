@@ -0,0 +1,107 @@
+package hashmap
+
+import (
+	"sort"
+	"sync"
+
+	"homework/internal/app"
+	"homework/internal/errors"
+	"homework/internal/locations"
+)
+
+type locationHash struct {
+	hashTable map[string]*locations.Location
+	children  map[string]set
+	mu        sync.RWMutex
+}
+
+func NewLocationHash() app.LocationRepository {
+	return &locationHash{
+		hashTable: make(map[string]*locations.Location),
+		children:  make(map[string]set),
+	}
+}
+
+func (h *locationHash) Get(id string) (*locations.Location, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	location, ok := h.hashTable[id]
+	if !ok {
+		return nil, errors.NewEntityNotFoundError("location", "ID", id)
+	}
+
+	return location, nil
+}
+
+func (h *locationHash) List() ([]*locations.Location, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]*locations.Location, 0, len(h.hashTable))
+	for _, location := range h.hashTable {
+		list = append(list, location)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	return list, nil
+}
+
+func (h *locationHash) ListChildren(id string) ([]*locations.Location, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]*locations.Location, 0, len(h.children[id]))
+	for _, childID := range sortedSerialNums(h.children[id]) {
+		list = append(list, h.hashTable[childID])
+	}
+
+	return list, nil
+}
+
+func (h *locationHash) Create(location *locations.Location) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.hashTable[location.ID]; ok {
+		return errors.NewConflictErrorf("location with 'ID' = %s already exist", location.ID)
+	}
+
+	h.hashTable[location.ID] = location
+	addTo(h.children, location.ParentID, location.ID)
+
+	return nil
+}
+
+func (h *locationHash) Delete(id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	location, ok := h.hashTable[id]
+	if !ok {
+		return errors.NewEntityNotFoundError("location", "ID", id)
+	}
+
+	removeFrom(h.children, location.ParentID, id)
+	delete(h.hashTable, id)
+
+	return nil
+}
+
+func (h *locationHash) Update(location *locations.Location) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	old, ok := h.hashTable[location.ID]
+	if !ok {
+		return errors.NewEntityNotFoundError("location", "ID", location.ID)
+	}
+
+	removeFrom(h.children, old.ParentID, old.ID)
+	h.hashTable[location.ID] = location
+	addTo(h.children, location.ParentID, location.ID)
+
+	return nil
+}
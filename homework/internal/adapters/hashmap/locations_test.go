@@ -0,0 +1,48 @@
+package hashmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/errors"
+	"homework/internal/locations"
+)
+
+func TestLocationHash(t *testing.T) {
+	repo := NewLocationHash()
+
+	region := &locations.Location{ID: "eu", Kind: locations.KindRegion}
+	site1 := &locations.Location{ID: "ams", Kind: locations.KindSite, ParentID: "eu"}
+	site2 := &locations.Location{ID: "ber", Kind: locations.KindSite, ParentID: "eu"}
+
+	require.NoError(t, repo.Create(region))
+	require.NoError(t, repo.Create(site2))
+	require.NoError(t, repo.Create(site1))
+	require.IsType(t, &errors.ConflictError{}, repo.Create(site1))
+
+	children, err := repo.ListChildren("eu")
+	require.NoError(t, err)
+	require.Equal(t, []*locations.Location{site1, site2}, children)
+
+	moved := &locations.Location{ID: "ber", Name: "Berlin", Kind: locations.KindSite, ParentID: "other"}
+	require.NoError(t, repo.Update(moved))
+
+	children, err = repo.ListChildren("eu")
+	require.NoError(t, err)
+	require.Equal(t, []*locations.Location{site1}, children)
+
+	require.NoError(t, repo.Delete("ams"))
+	children, err = repo.ListChildren("eu")
+	require.NoError(t, err)
+	require.Empty(t, children)
+
+	_, err = repo.Get("ams")
+	require.IsType(t, &errors.NotFoundError{}, err)
+	require.IsType(t, &errors.NotFoundError{}, repo.Update(site1))
+	require.IsType(t, &errors.NotFoundError{}, repo.Delete("ams"))
+
+	list, err := repo.List()
+	require.NoError(t, err)
+	require.Equal(t, []*locations.Location{moved, region}, list)
+}
@@ -0,0 +1,87 @@
+package hashmap
+
+import (
+	"sort"
+	"sync"
+
+	"homework/internal/app"
+	"homework/internal/catalog"
+	"homework/internal/errors"
+)
+
+type modelHash struct {
+	hashTable map[string]*catalog.Model
+	mu        sync.RWMutex
+}
+
+func NewModelHash() app.ModelRepository {
+	return &modelHash{
+		hashTable: make(map[string]*catalog.Model),
+	}
+}
+
+func (h *modelHash) Get(name string) (*catalog.Model, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	model, ok := h.hashTable[name]
+	if !ok {
+		return nil, errors.NewEntityNotFoundError("model", "Name", name)
+	}
+
+	return model, nil
+}
+
+func (h *modelHash) List() ([]*catalog.Model, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]*catalog.Model, 0, len(h.hashTable))
+	for _, model := range h.hashTable {
+		list = append(list, model)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list, nil
+}
+
+func (h *modelHash) Create(model *catalog.Model) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.hashTable[model.Name]; ok {
+		return errors.NewConflictErrorf("model with 'Name' = %s already exist", model.Name)
+	}
+
+	h.hashTable[model.Name] = model
+
+	return nil
+}
+
+func (h *modelHash) Delete(name string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.hashTable[name]; !ok {
+		return errors.NewEntityNotFoundError("model", "Name", name)
+	}
+
+	delete(h.hashTable, name)
+
+	return nil
+}
+
+func (h *modelHash) Update(model *catalog.Model) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.hashTable[model.Name]; !ok {
+		return errors.NewEntityNotFoundError("model", "Name", model.Name)
+	}
+
+	h.hashTable[model.Name] = model
+
+	return nil
+}
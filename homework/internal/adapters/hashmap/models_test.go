@@ -0,0 +1,38 @@
+package hashmap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/catalog"
+	"homework/internal/errors"
+)
+
+func TestModelHash(t *testing.T) {
+	repo := NewModelHash()
+
+	router := &catalog.Model{Name: "RT-100", Vendor: "acme"}
+	sw := &catalog.Model{Name: "SW-48", Vendor: "acme", Specs: catalog.Specs{Ports: 48, RackUnits: 1}}
+
+	require.NoError(t, repo.Create(sw))
+	require.NoError(t, repo.Create(router))
+	require.IsType(t, &errors.ConflictError{}, repo.Create(router))
+
+	updated := &catalog.Model{Name: "RT-100", Vendor: "acme", Attributes: []string{"firmware"}}
+	require.NoError(t, repo.Update(updated))
+
+	model, err := repo.Get("RT-100")
+	require.NoError(t, err)
+	require.Equal(t, updated, model)
+
+	list, err := repo.List()
+	require.NoError(t, err)
+	require.Equal(t, []*catalog.Model{updated, sw}, list)
+
+	require.NoError(t, repo.Delete("SW-48"))
+	_, err = repo.Get("SW-48")
+	require.IsType(t, &errors.NotFoundError{}, err)
+	require.IsType(t, &errors.NotFoundError{}, repo.Update(sw))
+	require.IsType(t, &errors.NotFoundError{}, repo.Delete("SW-48"))
+}
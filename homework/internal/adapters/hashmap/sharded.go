@@ -0,0 +1,441 @@
+package hashmap
+
+import (
+	"sort"
+	"sync"
+
+	"homework/internal/app"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/filter"
+	"homework/internal/labels"
+)
+
+const defaultShards = 32
+
+type shard struct {
+	hashTable map[string]*devices.Device
+	indexes   *indexes
+	mu        sync.RWMutex
+}
+
+// shardedHash partitions devices by a hash of SerialNum, so writes to
+// different shards do not contend for the same lock. Every shard indexes
+// its own devices under its lock and lookups merge the shards. IP and
+// rack position uniqueness span shards and are kept in claims, which is
+// always taken after a shard lock and never held while acquiring one.
+type shardedHash struct {
+	shards   []*shard
+	mask     uint32
+	uniqueIP bool
+	claims   *claims
+}
+
+func NewShardedHash(shards int, opts ...Option) app.Repository {
+	if shards < 1 {
+		shards = defaultShards
+	}
+
+	size := 1
+	for size < shards {
+		size <<= 1
+	}
+
+	h := &shardedHash{
+		shards:   make([]*shard, size),
+		mask:     uint32(size - 1),
+		uniqueIP: newOptions(opts).uniqueIP,
+		claims:   newClaims(size),
+	}
+	for i := range h.shards {
+		h.shards[i] = &shard{
+			hashTable: make(map[string]*devices.Device),
+			indexes:   newIndexes(options{}),
+		}
+	}
+
+	return h
+}
+
+func (h *shardedHash) shardFor(serialNum string) *shard {
+	return h.shards[fnv(serialNum)&h.mask]
+}
+
+// fnv is FNV-1a, inlined to avoid allocating a hash.Hash32 per call.
+func fnv(s string) uint32 {
+	sum := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		sum ^= uint32(s[i])
+		sum *= 16777619
+	}
+
+	return sum
+}
+
+func (h *shardedHash) Get(serialNum string) (*devices.Device, error) {
+	s := h.shardFor(serialNum)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	device, ok := s.hashTable[serialNum]
+	if !ok {
+		return nil, errors.NewNotFoundError(serialNum)
+	}
+
+	return device, nil
+}
+
+func (h *shardedHash) List() ([]*devices.Device, error) {
+	var list []*devices.Device
+
+	for _, s := range h.shards {
+		s.mu.RLock()
+		for _, device := range s.hashTable {
+			list = append(list, device)
+		}
+		s.mu.RUnlock()
+	}
+	sortBySerialNum(list)
+
+	return list, nil
+}
+
+// Snapshot holds every shard lock while copying, unlike List, so the
+// devices are a single point in time. Shards are always locked in order,
+// writers only ever hold one of them.
+func (h *shardedHash) Snapshot() ([]*devices.Device, error) {
+	for _, s := range h.shards {
+		s.mu.RLock()
+	}
+
+	var list []*devices.Device
+	for _, s := range h.shards {
+		for _, device := range s.hashTable {
+			list = append(list, device)
+		}
+	}
+
+	for _, s := range h.shards {
+		s.mu.RUnlock()
+	}
+	sortBySerialNum(list)
+
+	return list, nil
+}
+
+func (h *shardedHash) Replace(list []*devices.Device) error {
+	for _, s := range h.shards {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	}
+
+	fresh := newClaims(len(h.shards))
+	indexes := make([]*indexes, len(h.shards))
+	for i := range indexes {
+		indexes[i] = newIndexes(options{})
+	}
+	tables := make([]map[string]*devices.Device, len(h.shards))
+	for i := range tables {
+		tables[i] = make(map[string]*devices.Device)
+	}
+
+	for _, device := range list {
+		if err := h.claim(fresh, device, nil); err != nil {
+			return err
+		}
+
+		i := fnv(device.SerialNum) & h.mask
+		tables[i][device.SerialNum] = device
+		indexes[i].add(device)
+	}
+
+	for i, s := range h.shards {
+		s.hashTable, s.indexes = tables[i], indexes[i]
+	}
+	h.claims = fresh
+
+	return nil
+}
+
+func (h *shardedHash) Count() (int, error) {
+	var count int
+
+	for _, s := range h.shards {
+		s.mu.RLock()
+		count += len(s.hashTable)
+		s.mu.RUnlock()
+	}
+
+	return count, nil
+}
+
+func (h *shardedHash) ListByIP(ip string) ([]*devices.Device, error) {
+	return h.collect(func(idx *indexes) (set, bool) {
+		return idx.byIP[ip], true
+	}), nil
+}
+
+func (h *shardedHash) ListByModel(model string) ([]*devices.Device, error) {
+	return h.collect(func(idx *indexes) (set, bool) {
+		return idx.byModel[model], true
+	}), nil
+}
+
+func (h *shardedHash) ListByLocation(id string) ([]*devices.Device, error) {
+	return h.collect(func(idx *indexes) (set, bool) {
+		return idx.byLocation[id], true
+	}), nil
+}
+
+func (h *shardedHash) ListBySelector(selector labels.Selector) ([]*devices.Device, error) {
+	list := h.collect(func(idx *indexes) (set, bool) {
+		return idx.candidates(selector)
+	})
+
+	result := make([]*devices.Device, 0, len(list))
+	for _, device := range list {
+		if selector.Matches(device.Labels) {
+			result = append(result, device)
+		}
+	}
+
+	return result, nil
+}
+
+func (h *shardedHash) ListByFilter(expr filter.Expr) ([]*devices.Device, error) {
+	list := h.collect(func(idx *indexes) (set, bool) {
+		return idx.filterCandidates(expr)
+	})
+
+	result := make([]*devices.Device, 0, len(list))
+	for _, device := range list {
+		if expr.Match(device) {
+			result = append(result, device)
+		}
+	}
+
+	return result, nil
+}
+
+// Search merges the best hits of every shard. Hits are scored by the
+// device alone, so the merged order is the one a single index would give.
+func (h *shardedHash) Search(query string, limit int) ([]devices.SearchHit, error) {
+	var result []devices.SearchHit
+	for _, s := range h.shards {
+		s.mu.RLock()
+		for _, hit := range s.indexes.text.Search(query, limit) {
+			result = append(result, devices.SearchHit{Device: s.hashTable[hit.ID], Score: hit.Score})
+		}
+		s.mu.RUnlock()
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		return result[i].Device.SerialNum < result[j].Device.SerialNum
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+// collect merges the devices the index of every shard narrows a lookup
+// down to, all devices of a shard when it can not narrow it. Candidates
+// may name devices the shard does not hold, such as a serial_num filter.
+func (h *shardedHash) collect(candidates func(idx *indexes) (set, bool)) []*devices.Device {
+	var list []*devices.Device
+	for _, s := range h.shards {
+		s.mu.RLock()
+		if serialNums, ok := candidates(s.indexes); ok {
+			for serialNum := range serialNums {
+				if device, ok := s.hashTable[serialNum]; ok {
+					list = append(list, device)
+				}
+			}
+		} else {
+			for _, device := range s.hashTable {
+				list = append(list, device)
+			}
+		}
+		s.mu.RUnlock()
+	}
+	sortBySerialNum(list)
+
+	return list
+}
+
+func (h *shardedHash) Create(device *devices.Device) error {
+	s := h.shardFor(device.SerialNum)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.hashTable[device.SerialNum]; ok {
+		return errors.NewAlreadyExistDeviceError(device.SerialNum)
+	}
+
+	if err := h.claim(h.claims, device, nil); err != nil {
+		return err
+	}
+
+	s.hashTable[device.SerialNum] = device
+	s.indexes.add(device)
+
+	return nil
+}
+
+func (h *shardedHash) Delete(serialNum string) error {
+	s := h.shardFor(serialNum)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	device, ok := s.hashTable[serialNum]
+	if !ok {
+		return errors.NewNotFoundError(serialNum)
+	}
+
+	h.release(device, nil)
+	s.indexes.remove(device)
+	delete(s.hashTable, serialNum)
+
+	return nil
+}
+
+func (h *shardedHash) Update(device *devices.Device) error {
+	s := h.shardFor(device.SerialNum)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.hashTable[device.SerialNum]
+	if !ok {
+		return errors.NewNotFoundError(device.SerialNum)
+	}
+
+	if err := h.claim(h.claims, device, old); err != nil {
+		return err
+	}
+	h.release(old, device)
+
+	s.indexes.remove(old)
+	s.hashTable[device.SerialNum] = device
+	s.indexes.add(device)
+
+	return nil
+}
+
+// uniqueKeys returns the claim keys of the values no two devices may
+// share, the rack position and, with WithUniqueIP, the IP.
+func (h *shardedHash) uniqueKeys(device *devices.Device) (ip, position string) {
+	if h.uniqueIP && device.IP != "" {
+		ip = "ip:" + device.IP
+	}
+	if key := positionKey(device); key != "" {
+		position = "position:" + key
+	}
+
+	return ip, position
+}
+
+// claim takes the unique values of device, which replaces old or is new
+// when old is nil. Nothing is claimed when it fails.
+func (h *shardedHash) claim(c *claims, device, old *devices.Device) error {
+	ip, position := h.uniqueKeys(device)
+
+	var oldIP string
+	if old != nil {
+		oldIP, _ = h.uniqueKeys(old)
+	}
+
+	if ip != "" {
+		if owner, ok := c.claim(ip, device.SerialNum); !ok {
+			return errors.NewConflictError("IP", device.IP, owner)
+		}
+	}
+	if position != "" {
+		if owner, ok := c.claim(position, device.SerialNum); !ok {
+			if ip != "" && ip != oldIP {
+				c.release(ip, device.SerialNum)
+			}
+			return errors.NewConflictErrorf("position %d of rack %s is used by device with 'SerialNum' = %s",
+				device.Position, device.LocationID, owner)
+		}
+	}
+
+	return nil
+}
+
+// release gives up the unique values of device that its replacement, nil
+// when it is deleted, no longer holds.
+func (h *shardedHash) release(device, replacement *devices.Device) {
+	ip, position := h.uniqueKeys(device)
+
+	var keepIP, keepPosition string
+	if replacement != nil {
+		keepIP, keepPosition = h.uniqueKeys(replacement)
+	}
+
+	if ip != "" && ip != keepIP {
+		h.claims.release(ip, device.SerialNum)
+	}
+	if position != "" && position != keepPosition {
+		h.claims.release(position, device.SerialNum)
+	}
+}
+
+// claims records which device holds a unique value. The keys are striped
+// over several locks, so writers of different values rarely contend.
+type claims struct {
+	stripes []*claimStripe
+	mask    uint32
+}
+
+type claimStripe struct {
+	owners map[string]string
+	mu     sync.Mutex
+}
+
+// newClaims creates claims with size stripes, size is a power of two.
+func newClaims(size int) *claims {
+	c := &claims{
+		stripes: make([]*claimStripe, size),
+		mask:    uint32(size - 1),
+	}
+	for i := range c.stripes {
+		c.stripes[i] = &claimStripe{owners: make(map[string]string)}
+	}
+
+	return c
+}
+
+// claim gives key to serialNum unless another device holds it, whose
+// serial number it returns then.
+func (c *claims) claim(key, serialNum string) (string, bool) {
+	stripe := c.stripes[fnv(key)&c.mask]
+
+	stripe.mu.Lock()
+	defer stripe.mu.Unlock()
+
+	if owner, ok := stripe.owners[key]; ok && owner != serialNum {
+		return owner, false
+	}
+	stripe.owners[key] = serialNum
+
+	return serialNum, true
+}
+
+func (c *claims) release(key, serialNum string) {
+	stripe := c.stripes[fnv(key)&c.mask]
+
+	stripe.mu.Lock()
+	defer stripe.mu.Unlock()
+
+	if stripe.owners[key] == serialNum {
+		delete(stripe.owners, key)
+	}
+}
@@ -0,0 +1,150 @@
+package hashmap
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"homework/internal/app"
+	"homework/internal/devices"
+)
+
+type shardedTestSuite struct {
+	suite.Suite
+	repo app.Repository
+}
+
+func TestShardedRun(t *testing.T) {
+	suite.Run(t, new(shardedTestSuite))
+}
+
+func (d *shardedTestSuite) SetupTest() {
+	d.repo = NewShardedHash(4)
+
+	require.NoError(d.T(), d.repo.Create(&devices.Device{SerialNum: testSeqNum1, IP: testIP1, Model: testModel1}))
+	require.NoError(d.T(), d.repo.Create(&devices.Device{SerialNum: testSeqNum2, IP: testIP2, Model: testModel2}))
+}
+
+func (d *shardedTestSuite) TestGet() {
+	actual, err := d.repo.Get(testSeqNum1)
+
+	require.NoError(d.T(), err)
+	require.Equal(d.T(), testIP1, actual.IP)
+
+	_, err = d.repo.Get(testSeqNum3)
+	require.Error(d.T(), err)
+}
+
+func (d *shardedTestSuite) TestCreate() {
+	require.NoError(d.T(), d.repo.Create(&devices.Device{SerialNum: testSeqNum3}))
+	require.Error(d.T(), d.repo.Create(&devices.Device{SerialNum: testSeqNum1}))
+}
+
+func (d *shardedTestSuite) TestUpdate() {
+	device := &devices.Device{SerialNum: testSeqNum1, IP: testIP3, Model: testModel3}
+
+	require.NoError(d.T(), d.repo.Update(device))
+
+	actual, err := d.repo.Get(testSeqNum1)
+	require.NoError(d.T(), err)
+	require.Equal(d.T(), device, actual)
+
+	require.Error(d.T(), d.repo.Update(&devices.Device{SerialNum: testSeqNum3}))
+}
+
+func (d *shardedTestSuite) TestDelete() {
+	require.NoError(d.T(), d.repo.Delete(testSeqNum1))
+	require.Error(d.T(), d.repo.Delete(testSeqNum1))
+}
+
+func TestShardedShardCount(t *testing.T) {
+	require.Len(t, NewShardedHash(5).(*shardedHash).shards, 8)
+	require.Len(t, NewShardedHash(0).(*shardedHash).shards, defaultShards)
+}
+
+func TestShardedConcurrentAccess(t *testing.T) {
+	repo := NewShardedHash(8)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				serialNum := fmt.Sprintf("%d-%d", w, i)
+				assert.NoError(t, repo.Create(&devices.Device{SerialNum: serialNum}))
+				assert.NoError(t, repo.Update(&devices.Device{SerialNum: serialNum, IP: testIP1}))
+				_, err := repo.Get(serialNum)
+				assert.NoError(t, err)
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+const benchmarkDevices = 10000
+
+// BenchmarkMixedWorkload compares the single-lock and the sharded
+// repositories for several read/write ratios and GOMAXPROCS values.
+func BenchmarkMixedWorkload(b *testing.B) {
+	repos := []struct {
+		name string
+		new  func() app.Repository
+	}{
+		{name: "hash", new: func() app.Repository { return NewHash() }},
+		{name: "sharded", new: func() app.Repository { return NewShardedHash(defaultShards) }},
+	}
+
+	for _, readPercent := range []int{50, 90, 99} {
+		for _, procs := range []int{1, 2, 4, 8} {
+			for _, repo := range repos {
+				name := fmt.Sprintf("reads=%d%%/procs=%d/%s", readPercent, procs, repo.name)
+				b.Run(name, func(b *testing.B) {
+					benchmarkMixed(b, repo.new(), readPercent, procs)
+				})
+			}
+		}
+	}
+}
+
+func benchmarkMixed(b *testing.B, repo app.Repository, readPercent, procs int) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+	serialNums := make([]string, benchmarkDevices)
+	for i := range serialNums {
+		serialNums[i] = strconv.Itoa(i)
+		_ = repo.Create(&devices.Device{SerialNum: serialNums[i], IP: testIP1, Model: testModel1})
+	}
+
+	var worker atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		seed := uint32(worker.Add(1))
+
+		for i := 0; pb.Next(); i++ {
+			// xorshift keeps the access pattern random without locking a
+			// shared rand.Source.
+			seed ^= seed << 13
+			seed ^= seed >> 17
+			seed ^= seed << 5
+
+			serialNum := serialNums[seed%benchmarkDevices]
+			if int(seed%100) < readPercent {
+				_, _ = repo.Get(serialNum)
+				continue
+			}
+
+			// The repository keeps the pointer, every update needs its own
+			// device.
+			_ = repo.Update(&devices.Device{SerialNum: serialNum, IP: testIP2, Model: testModel2})
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package hashmap
+
+import (
+	"sync"
+	"time"
+
+	"homework/internal/app"
+	"homework/internal/devices"
+)
+
+type versionHash struct {
+	versions map[string][]devices.Version
+	// last keeps numbering going after old versions are dropped.
+	last   map[string]int
+	keep   int
+	maxAge time.Duration
+	mu     sync.RWMutex
+}
+
+// NewVersionHash keeps at most keep versions per device and drops those
+// older than maxAge, zero disables either limit. Limits are applied when
+// a device changes and the latest version is always kept.
+func NewVersionHash(keep int, maxAge time.Duration) app.VersionRepository {
+	return &versionHash{
+		versions: make(map[string][]devices.Version),
+		last:     make(map[string]int),
+		keep:     keep,
+		maxAge:   maxAge,
+	}
+}
+
+func (h *versionHash) Append(version devices.Version) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.last[version.SerialNum]++
+	version.Number = h.last[version.SerialNum]
+
+	versions := append(h.versions[version.SerialNum], version)
+
+	drop := 0
+	if h.keep > 0 && len(versions) > h.keep {
+		drop = len(versions) - h.keep
+	}
+	if h.maxAge > 0 {
+		for drop < len(versions)-1 && version.At.Sub(versions[drop].At) > h.maxAge {
+			drop++
+		}
+	}
+
+	if drop > 0 {
+		// Copy, so the dropped versions are not kept alive by the array.
+		versions = append([]devices.Version(nil), versions[drop:]...)
+	}
+	h.versions[version.SerialNum] = versions
+
+	return nil
+}
+
+func (h *versionHash) List(serialNum string) ([]devices.Version, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list := make([]devices.Version, len(h.versions[serialNum]))
+	copy(list, h.versions[serialNum])
+
+	return list, nil
+}
@@ -0,0 +1,69 @@
+package hashmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+)
+
+func TestVersionHash(t *testing.T) {
+	repo := NewVersionHash(0, 0)
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, repo.Append(devices.Version{SerialNum: testSeqNum1, At: time.Unix(int64(i), 0)}))
+	}
+	require.NoError(t, repo.Append(devices.Version{SerialNum: testSeqNum2, At: time.Unix(4, 0)}))
+
+	list, err := repo.List(testSeqNum1)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	for i, version := range list {
+		require.Equal(t, i+1, version.Number)
+	}
+
+	list, err = repo.List(testSeqNum2)
+	require.NoError(t, err)
+	require.Equal(t, []devices.Version{{SerialNum: testSeqNum2, Number: 1, At: time.Unix(4, 0)}}, list)
+
+	list, err = repo.List(testSeqNum3)
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func TestVersionHashRetention(t *testing.T) {
+	repo := NewVersionHash(3, 0)
+
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, repo.Append(devices.Version{SerialNum: testSeqNum1, At: time.Unix(int64(i), 0)}))
+	}
+
+	list, err := repo.List(testSeqNum1)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	require.Equal(t, 3, list[0].Number)
+	require.Equal(t, 5, list[2].Number)
+
+	repo = NewVersionHash(0, time.Hour)
+	start := time.Unix(0, 0)
+
+	require.NoError(t, repo.Append(devices.Version{SerialNum: testSeqNum1, At: start}))
+	require.NoError(t, repo.Append(devices.Version{SerialNum: testSeqNum1, At: start.Add(30 * time.Minute)}))
+	require.NoError(t, repo.Append(devices.Version{SerialNum: testSeqNum1, At: start.Add(90 * time.Minute)}))
+
+	list, err = repo.List(testSeqNum1)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, 2, list[0].Number)
+
+	// The latest version is kept however old it is.
+	require.NoError(t, repo.Append(devices.Version{SerialNum: testSeqNum2, At: start}))
+	require.NoError(t, repo.Append(devices.Version{SerialNum: testSeqNum2, At: start.Add(48 * time.Hour)}))
+
+	list, err = repo.List(testSeqNum2)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, 2, list[0].Number)
+}
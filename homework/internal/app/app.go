@@ -1,32 +1,191 @@
 package app
 
 import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
 	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/filter"
+	"homework/internal/labels"
 )
 
 //go:generate mockgen -package internal -destination ../mocks/repository.go . Repository
 type Repository interface {
 	Get(string) (*devices.Device, error)
+	List() ([]*devices.Device, error)
+	ListByIP(string) ([]*devices.Device, error)
+	ListByModel(string) ([]*devices.Device, error)
 	Create(*devices.Device) error
 	Delete(string) error
 	Update(*devices.Device) error
 }
 
+// SelectorLister is implemented by repositories that can evaluate label
+// selectors with an index. ListDevices scans all devices otherwise.
+type SelectorLister interface {
+	ListBySelector(labels.Selector) ([]*devices.Device, error)
+}
+
+// FilterLister is implemented by repositories that can evaluate filter
+// expressions, narrowing them down with an index. ListDevices evaluates
+// the expression in memory otherwise.
+type FilterLister interface {
+	ListByFilter(filter.Expr) ([]*devices.Device, error)
+}
+
+// Searcher is implemented by repositories that keep a full text index of
+// devices. SearchDevices ranks all devices otherwise.
+type Searcher interface {
+	Search(query string, limit int) ([]devices.SearchHit, error)
+}
+
+// Snapshotter is implemented by repositories that can list all devices as
+// of a single point in time while writes go on. Backups use List otherwise.
+type Snapshotter interface {
+	Snapshot() ([]*devices.Device, error)
+}
+
+// Replacer is implemented by repositories that can swap all devices at
+// once. Replacing restores delete and create devices one by one otherwise.
+type Replacer interface {
+	Replace([]*devices.Device) error
+}
+
+// LocationLister is implemented by repositories that index devices by
+// location. They also reject a device taking the rack position of another
+// one under their write lock, so concurrent placements can not both win.
+// Locations are checked by scanning all devices otherwise.
+type LocationLister interface {
+	ListByLocation(string) ([]*devices.Device, error)
+}
+
+// Counter is implemented by repositories that count devices without
+// listing them.
+type Counter interface {
+	Count() (int, error)
+}
+
+func CountDevices(repo Repository) (int, error) {
+	if counter, ok := repo.(Counter); ok {
+		return counter.Count()
+	}
+
+	list, err := repo.List()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(list), nil
+}
+
+// ListDevicesBySelector uses the selector index of the repository when it
+// has one, so decorators can pass it through.
+func ListDevicesBySelector(repo Repository, selector labels.Selector) ([]*devices.Device, error) {
+	if lister, ok := repo.(SelectorLister); ok {
+		return lister.ListBySelector(selector)
+	}
+
+	list, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*devices.Device, 0, len(list))
+	for _, device := range list {
+		if selector.Matches(device.Labels) {
+			result = append(result, device)
+		}
+	}
+
+	return result, nil
+}
+
+// ListDevicesByFilter uses the filter index of the repository when it has
+// one.
+func ListDevicesByFilter(repo Repository, expr filter.Expr) ([]*devices.Device, error) {
+	if lister, ok := repo.(FilterLister); ok {
+		return lister.ListByFilter(expr)
+	}
+
+	list, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*devices.Device, 0, len(list))
+	for _, device := range list {
+		if expr.Match(device) {
+			result = append(result, device)
+		}
+	}
+
+	return result, nil
+}
+
 //go:generate mockgen -package internal -destination ../mocks/service.go . Service
 type Service interface {
 	GetDevice(string) (*devices.Device, error)
+	ListDevices(devices.Filter) ([]*devices.Device, error)
 	CreateDevice(*devices.Device) error
 	DeleteDevice(string) error
 	UpdateDevice(*devices.Device) error
+	TransitionDevice(serialNum string, status devices.Status, reason string) (*devices.Device, error)
+	ListTransitions(string) ([]devices.Transition, error)
+	SearchDevices(query string, limit int) ([]devices.SearchHit, error)
 }
 
 type deviceService struct {
-	repo Repository
+	repo       Repository
+	ipam       IPAM
+	locations  LocationRepository
+	models     ModelRepository
+	history    HistoryRepository
+	heartbeats HeartbeatRepository
+	versions   VersionRepository
+	tracer     trace.Tracer
+	now        func() time.Time
+
+	quota int
+	// createMu serializes creates while a quota is set, so concurrent
+	// creates can not exceed it. It is shared with the copies made for
+	// tracing.
+	createMu *sync.Mutex
+	// serials serializes updates, transitions and deletes of a device,
+	// which read it before writing it back or releasing its IP.
+	serials *serialLocks
+	refs    *References
+}
+
+type Option func(*deviceService)
+
+func NewService(repo Repository, opts ...Option) Service {
+	return newDeviceService(repo, opts...)
+}
+
+func newDeviceService(repo Repository, opts ...Option) *deviceService {
+	ds := &deviceService{
+		repo:     repo,
+		now:      time.Now,
+		createMu: &sync.Mutex{},
+		serials:  newSerialLocks(),
+		refs:     NewReferences(),
+	}
+
+	for _, opt := range opts {
+		opt(ds)
+	}
+
+	return ds
 }
 
-func NewService(repo Repository) Service {
-	return &deviceService{
-		repo: repo,
+// WithQuota limits the number of devices, 0 means no limit.
+func WithQuota(limit int) Option {
+	return func(ds *deviceService) {
+		ds.quota = limit
 	}
 }
 
@@ -34,14 +193,256 @@ func (ds *deviceService) GetDevice(serialNum string) (*devices.Device, error) {
 	return ds.repo.Get(serialNum)
 }
 
+// ListDevices uses the most selective repository index for the filter and
+// checks the remaining conditions in memory.
+func (ds *deviceService) ListDevices(filter devices.Filter) ([]*devices.Device, error) {
+	selector, err := labels.Parse(filter.Selector)
+	if err != nil {
+		return nil, errors.NewValidationError("%s", err)
+	}
+
+	expr, err := parseQuery(filter.Query)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Liveness != "" && filter.Liveness != devices.Online && filter.Liveness != devices.Offline {
+		return nil, errors.NewValidationError("unknown liveness %q", filter.Liveness)
+	}
+
+	var list []*devices.Device
+
+	selectorLister, hasSelectorIndex := ds.repo.(SelectorLister)
+	filterLister, hasFilterIndex := ds.repo.(FilterLister)
+	// pushedDown is set when the repository already evaluated expr.
+	var pushedDown bool
+
+	switch {
+	case filter.IP != "":
+		list, err = ds.repo.ListByIP(filter.IP)
+	case filter.Model != "":
+		list, err = ds.repo.ListByModel(filter.Model)
+	case expr != nil && hasFilterIndex:
+		list, err = filterLister.ListByFilter(expr)
+		pushedDown = true
+	case !selector.Empty() && hasSelectorIndex:
+		list, err = selectorLister.ListBySelector(selector)
+	default:
+		list, err = ds.repo.List()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var locationIDs map[string]bool
+	if filter.LocationID != "" {
+		locationIDs = map[string]bool{filter.LocationID: true}
+		if ds.locations != nil {
+			if locationIDs, err = ds.subtree(filter.LocationID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var liveness map[string]devices.Liveness
+	if filter.Liveness != "" && ds.heartbeats != nil {
+		if liveness, err = ds.livenessOf(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]*devices.Device, 0, len(list))
+	for _, device := range list {
+		if filter.Model != "" && device.Model != filter.Model {
+			continue
+		}
+		if locationIDs != nil && !locationIDs[device.LocationID] {
+			continue
+		}
+		if !selector.Matches(device.Labels) {
+			continue
+		}
+		if filter.Liveness != "" && !matchesLiveness(liveness[device.SerialNum], filter.Liveness) {
+			continue
+		}
+		if expr != nil && !pushedDown && !expr.Match(device) {
+			continue
+		}
+		result = append(result, device)
+	}
+
+	return result, nil
+}
+
+// parseQuery returns a nil expression for an empty query.
+func parseQuery(query string) (filter.Expr, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	expr, err := filter.Parse(query)
+	if err != nil {
+		return nil, errors.NewValidationError("%s", err)
+	}
+
+	return expr, nil
+}
+
+// reservedSerialNums are the fixed paths next to /devices/{id}, a device
+// with one of these serial numbers could not be addressed.
+var reservedSerialNums = map[string]bool{
+	"by-ip":    true,
+	"search":   true,
+	"liveness": true,
+}
+
+func checkSerialNum(serialNum string) error {
+	if reservedSerialNums[serialNum] {
+		return errors.NewValidationError("device 'SerialNum' %q is reserved", serialNum)
+	}
+
+	return nil
+}
+
 func (ds *deviceService) CreateDevice(device *devices.Device) error {
-	return ds.repo.Create(device)
+	if err := checkSerialNum(device.SerialNum); err != nil {
+		return err
+	}
+
+	if ds.quota > 0 {
+		ds.createMu.Lock()
+		defer ds.createMu.Unlock()
+
+		count, err := CountDevices(ds.repo)
+		if err != nil {
+			return err
+		}
+		if count >= ds.quota {
+			return errors.NewQuotaExceededError(ds.quota)
+		}
+	}
+
+	if err := labels.Validate(device.Labels); err != nil {
+		return errors.NewValidationError("%s", err)
+	}
+
+	if err := initStatus(device); err != nil {
+		return err
+	}
+
+	defer ds.use(device)()
+
+	if err := ds.checkModel(device); err != nil {
+		return err
+	}
+
+	if err := ds.checkLocation(device); err != nil {
+		return err
+	}
+
+	if ds.ipam == nil {
+		if err := ds.repo.Create(device); err != nil {
+			return err
+		}
+		return ds.recordCreate(device)
+	}
+
+	// A device created without an IP gets one allocated, so a failed create
+	// must not leave the address behind.
+	ip := device.IP
+	undo, err := ds.assignIP(device)
+	if err != nil {
+		return err
+	}
+
+	if err = ds.repo.Create(device); err != nil {
+		undo()
+		device.IP = ip
+		return err
+	}
+
+	return ds.recordCreate(device)
+}
+
+func (ds *deviceService) recordCreate(device *devices.Device) error {
+	if err := ds.recordTransition(device.SerialNum, "", device.Status, "created"); err != nil {
+		return err
+	}
+
+	return ds.recordVersion(device.SerialNum, device)
 }
 
 func (ds *deviceService) DeleteDevice(serialNum string) error {
-	return ds.repo.Delete(serialNum)
+	defer ds.serials.lock(serialNum)()
+
+	if ds.ipam == nil {
+		if err := ds.repo.Delete(serialNum); err != nil {
+			return err
+		}
+		ds.dropHeartbeat(serialNum)
+		return ds.recordVersion(serialNum, nil)
+	}
+
+	device, err := ds.repo.Get(serialNum)
+	if err != nil {
+		return err
+	}
+
+	if err = ds.repo.Delete(serialNum); err != nil {
+		return err
+	}
+	ds.releaseIP(device)
+	ds.dropHeartbeat(serialNum)
+
+	return ds.recordVersion(serialNum, nil)
 }
 
 func (ds *deviceService) UpdateDevice(device *devices.Device) error {
-	return ds.repo.Update(device)
+	if err := labels.Validate(device.Labels); err != nil {
+		return errors.NewValidationError("%s", err)
+	}
+
+	defer ds.use(device)()
+
+	if err := ds.checkModel(device); err != nil {
+		return err
+	}
+
+	if err := ds.checkLocation(device); err != nil {
+		return err
+	}
+
+	defer ds.serials.lock(device.SerialNum)()
+
+	old, err := ds.repo.Get(device.SerialNum)
+	if err != nil {
+		return err
+	}
+
+	if err = keepStatus(device, old); err != nil {
+		return err
+	}
+
+	if ds.ipam == nil || old.IP == device.IP {
+		if err = ds.repo.Update(device); err != nil {
+			return err
+		}
+		return ds.recordVersion(device.SerialNum, device)
+	}
+
+	undo := func() {}
+	if device.IP != "" {
+		if err = ds.ipam.Reserve(device.IP, device.SerialNum); err != nil {
+			return err
+		}
+		undo = func() { ds.ipam.Release(device.IP, device.SerialNum) }
+	}
+
+	if err = ds.repo.Update(device); err != nil {
+		undo()
+		return err
+	}
+	ds.releaseIP(old)
+
+	return ds.recordVersion(device.SerialNum, device)
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"homework/internal/devices"
+	"homework/internal/errors"
 	deviceMock "homework/internal/mocks"
 )
 
@@ -75,6 +76,7 @@ func TestUpdateDevice(t *testing.T) {
 		IP:        testIP1,
 		Model:     testModel1,
 	}
+	repo.EXPECT().Get(testSeqNum1).Return(&devices.Device{SerialNum: testSeqNum1}, nil).Times(1)
 	repo.EXPECT().Update(expect).Return(nil).Times(1)
 
 	app := NewService(repo)
@@ -99,3 +101,112 @@ func TestDeleteDevice(t *testing.T) {
 
 	require.NoError(t, err)
 }
+
+func TestListDevices(t *testing.T) {
+	const (
+		testIP1    = "test ip 1"
+		testModel1 = "test model 1"
+		testModel2 = "test model 2"
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	device1 := &devices.Device{SerialNum: "test 1", IP: testIP1, Model: testModel1}
+	device2 := &devices.Device{SerialNum: "test 2", IP: testIP1, Model: testModel2}
+
+	repo.EXPECT().List().Return([]*devices.Device{device1, device2}, nil).Times(1)
+	repo.EXPECT().ListByModel(testModel2).Return([]*devices.Device{device2}, nil).Times(1)
+	repo.EXPECT().ListByIP(testIP1).Return([]*devices.Device{device1, device2}, nil).Times(1)
+
+	app := NewService(repo)
+
+	actual, err := app.ListDevices(devices.Filter{})
+	require.NoError(t, err)
+	require.Equal(t, []*devices.Device{device1, device2}, actual)
+
+	actual, err = app.ListDevices(devices.Filter{Model: testModel2})
+	require.NoError(t, err)
+	require.Equal(t, []*devices.Device{device2}, actual)
+
+	actual, err = app.ListDevices(devices.Filter{IP: testIP1, Model: testModel1})
+	require.NoError(t, err)
+	require.Equal(t, []*devices.Device{device1}, actual)
+}
+
+func TestListDevicesBySelector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	prod := &devices.Device{SerialNum: "test 1", Labels: map[string]string{"env": "prod"}}
+	dev := &devices.Device{SerialNum: "test 2", Labels: map[string]string{"env": "dev"}}
+	repo.EXPECT().List().Return([]*devices.Device{prod, dev}, nil).Times(1)
+
+	app := NewService(repo)
+
+	actual, err := app.ListDevices(devices.Filter{Selector: "env notin (dev)"})
+	require.NoError(t, err)
+	require.Equal(t, []*devices.Device{prod}, actual)
+
+	_, err = app.ListDevices(devices.Filter{Selector: "env in prod"})
+	require.IsType(t, &errors.ValidationError{}, err)
+}
+
+func TestCreateDeviceInvalidLabels(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	app := NewService(repo)
+	err := app.CreateDevice(&devices.Device{SerialNum: "test 1", Labels: map[string]string{"bad key": "x"}})
+
+	require.IsType(t, &errors.ValidationError{}, err)
+}
+
+func TestCreateDeviceReservedSerialNum(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	app := NewService(repo)
+	err := app.CreateDevice(&devices.Device{SerialNum: "search"})
+
+	require.IsType(t, &errors.ValidationError{}, err)
+}
+
+func TestCreateDeviceQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	device := &devices.Device{SerialNum: "test 2"}
+	repo.EXPECT().List().Return([]*devices.Device{{SerialNum: "test 1"}}, nil).Times(1)
+	repo.EXPECT().Create(device).Return(nil).Times(1)
+	repo.EXPECT().List().Return([]*devices.Device{{SerialNum: "test 1"}, device}, nil).Times(1)
+
+	app := NewService(repo, WithQuota(2))
+
+	require.NoError(t, app.CreateDevice(device))
+	require.IsType(t, &errors.QuotaExceededError{}, app.CreateDevice(&devices.Device{SerialNum: "test 3"}))
+}
+
+func TestListDevicesByQuery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	router := &devices.Device{SerialNum: "test 1", Model: "RT-100", IP: "10.0.0.1"}
+	remote := &devices.Device{SerialNum: "test 2", Model: "RT-100", IP: "192.168.0.1"}
+	repo.EXPECT().ListByModel("RT-100").Return([]*devices.Device{router, remote}, nil).Times(1)
+
+	app := NewService(repo)
+
+	actual, err := app.ListDevices(devices.Filter{Model: "RT-100", Query: "ip in 10.0.0.0/8"})
+	require.NoError(t, err)
+	require.Equal(t, []*devices.Device{router}, actual)
+
+	_, err = app.ListDevices(devices.Filter{Query: `status == "retired"`})
+	require.IsType(t, &errors.ValidationError{}, err)
+}
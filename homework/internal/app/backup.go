@@ -0,0 +1,224 @@
+package app
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"time"
+
+	"homework/internal/backup"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/labels"
+)
+
+// BackupService copies the devices of a repository to and from backup
+// archives. Restores check the devices like the device service, write the
+// repository directly and then release and reserve IPAM addresses to match
+// the restored devices.
+//
+//go:generate mockgen -package internal -destination ../mocks/backup.go . BackupService
+type BackupService interface {
+	Backup(io.Writer) error
+	Restore(io.Reader, backup.Mode) (*backup.Header, error)
+}
+
+type backupService struct {
+	repo    Repository
+	devices *deviceService
+	now     func() time.Time
+}
+
+// NewBackupService takes the options of the device service, restores
+// enforce its model catalog and keep its IPAM in sync.
+func NewBackupService(repo Repository, opts ...Option) BackupService {
+	return &backupService{
+		repo:    repo,
+		devices: newDeviceService(repo, opts...),
+		now:     time.Now,
+	}
+}
+
+func (bs *backupService) Backup(w io.Writer) error {
+	list, err := SnapshotDevices(bs.repo)
+	if err != nil {
+		return err
+	}
+
+	return backup.Write(w, list, bs.now())
+}
+
+func SnapshotDevices(repo Repository) ([]*devices.Device, error) {
+	if snapshotter, ok := repo.(Snapshotter); ok {
+		return snapshotter.Snapshot()
+	}
+
+	return repo.List()
+}
+
+// ReplaceDevices makes the repository hold exactly the devices. Without a
+// Replacer, readers can see a mix of old and new devices meanwhile.
+func ReplaceDevices(repo Repository, list []*devices.Device) error {
+	if replacer, ok := repo.(Replacer); ok {
+		return replacer.Replace(list)
+	}
+
+	keep := make(map[string]bool, len(list))
+	for _, device := range list {
+		keep[device.SerialNum] = true
+	}
+
+	current, err := repo.List()
+	if err != nil {
+		return err
+	}
+
+	for _, device := range current {
+		if keep[device.SerialNum] {
+			continue
+		}
+		if err = repo.Delete(device.SerialNum); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+
+	return mergeDevices(repo, list)
+}
+
+func (bs *backupService) Restore(r io.Reader, mode backup.Mode) (*backup.Header, error) {
+	if _, err := backup.ParseMode(string(mode)); err != nil {
+		return nil, errors.NewValidationError("%s", err)
+	}
+
+	archive, err := backup.Read(r)
+	if err != nil {
+		return nil, errors.NewValidationError("%s", err)
+	}
+
+	defer bs.devices.useModels(archive.Devices)()
+
+	if err = bs.validate(archive.Devices); err != nil {
+		return nil, err
+	}
+
+	var before []*devices.Device
+	if bs.devices.ipam != nil {
+		if before, err = bs.repo.List(); err != nil {
+			return nil, err
+		}
+	}
+
+	if mode == backup.Replace {
+		err = ReplaceDevices(bs.repo, archive.Devices)
+	} else {
+		err = mergeDevices(bs.repo, archive.Devices)
+	}
+
+	// A merge that stopped part way still changed devices.
+	if ipamErr := bs.reconcileIPAM(before); err == nil {
+		err = ipamErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &archive.Header, nil
+}
+
+func (bs *backupService) validate(list []*devices.Device) error {
+	seen := make(map[string]bool, len(list))
+
+	for _, device := range list {
+		if device.SerialNum == "" {
+			return errors.NewValidationError("backup holds a device without 'SerialNum'")
+		}
+		if seen[device.SerialNum] {
+			return errors.NewValidationError("backup holds device %s twice", device.SerialNum)
+		}
+		seen[device.SerialNum] = true
+
+		if err := checkSerialNum(device.SerialNum); err != nil {
+			return err
+		}
+
+		if err := labels.Validate(device.Labels); err != nil {
+			return errors.NewValidationError("device %s: %s", device.SerialNum, err)
+		}
+
+		if !validStatus(device.Status) {
+			return errors.NewValidationError("device %s: unknown device status %q", device.SerialNum, device.Status)
+		}
+
+		if err := bs.devices.checkModel(device); err != nil {
+			var invalid *errors.ValidationError
+			if stderrors.As(err, &invalid) {
+				return errors.NewValidationError("device %s: %s", device.SerialNum, err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reconcileIPAM releases the addresses of devices the restore dropped or
+// readdressed and reserves those of the devices now stored.
+func (bs *backupService) reconcileIPAM(before []*devices.Device) error {
+	if bs.devices.ipam == nil {
+		return nil
+	}
+
+	after, err := bs.repo.List()
+	if err != nil {
+		return err
+	}
+
+	ips := make(map[string]string, len(after))
+	for _, device := range after {
+		ips[device.SerialNum] = device.IP
+	}
+
+	for _, device := range before {
+		if ip, ok := ips[device.SerialNum]; device.IP != "" && (!ok || ip != device.IP) {
+			bs.devices.ipam.Release(device.IP, device.SerialNum)
+		}
+	}
+
+	// A conflict does not keep the addresses of the other devices from
+	// being reserved.
+	var first error
+	for _, device := range after {
+		if device.IP == "" {
+			continue
+		}
+		if err = bs.devices.ipam.Reserve(device.IP, device.SerialNum); err != nil && first == nil {
+			first = fmt.Errorf("devices restored, reserving the address of device %s failed: %w", device.SerialNum, err)
+		}
+	}
+
+	return first
+}
+
+// mergeDevices creates the devices, overwriting those that already exist.
+// The repository may still reject a device, for a taken IP or rack
+// position, so the error tells how many devices were applied before.
+func mergeDevices(repo Repository, list []*devices.Device) error {
+	for i, device := range list {
+		err := repo.Create(device)
+
+		var exists *errors.AlreadyExistDeviceError
+		if stderrors.As(err, &exists) {
+			err = repo.Update(device)
+		}
+		if err != nil {
+			return fmt.Errorf("restore stopped after %d of %d devices: device %s: %w", i, len(list), device.SerialNum, err)
+		}
+	}
+
+	return nil
+}
+
+func isNotFound(err error) bool {
+	var notFound *errors.NotFoundError
+	return stderrors.As(err, &notFound)
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/backup"
+	"homework/internal/catalog"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/ipam"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestBackupRestoreMerge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	existing := &devices.Device{SerialNum: "test 1", Status: devices.StatusActive, IP: "10.0.0.1"}
+	added := &devices.Device{SerialNum: "test 2", Status: devices.StatusActive, IP: "10.0.0.2"}
+
+	repo.EXPECT().List().Return([]*devices.Device{existing, added}, nil).Times(1)
+	repo.EXPECT().Create(existing).Return(errors.NewAlreadyExistDeviceError(existing.SerialNum)).Times(1)
+	repo.EXPECT().Update(existing).Return(nil).Times(1)
+	repo.EXPECT().Create(added).Return(nil).Times(1)
+
+	service := NewBackupService(repo)
+
+	var buf bytes.Buffer
+	require.NoError(t, service.Backup(&buf))
+
+	header, err := service.Restore(&buf, backup.Merge)
+	require.NoError(t, err)
+	require.Equal(t, 2, header.Devices)
+}
+
+func TestRestoreReplace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	stale := &devices.Device{SerialNum: "test 1", Status: devices.StatusActive}
+	restored := &devices.Device{SerialNum: "test 2", Status: devices.StatusActive}
+
+	repo.EXPECT().List().Return([]*devices.Device{stale, restored}, nil).Times(1)
+	repo.EXPECT().Delete(stale.SerialNum).Return(nil).Times(1)
+	repo.EXPECT().Create(restored).Return(errors.NewAlreadyExistDeviceError(restored.SerialNum)).Times(1)
+	repo.EXPECT().Update(restored).Return(nil).Times(1)
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Write(&buf, []*devices.Device{restored}, time.Unix(100, 0)))
+
+	_, err := NewBackupService(repo).Restore(&buf, backup.Replace)
+	require.NoError(t, err)
+}
+
+func TestRestoreInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	service := NewBackupService(repo)
+
+	_, err := service.Restore(bytes.NewReader([]byte("not a backup")), backup.Merge)
+	require.IsType(t, &errors.ValidationError{}, err)
+
+	var buf bytes.Buffer
+	twice := &devices.Device{SerialNum: "test 1", Status: devices.StatusActive}
+	require.NoError(t, backup.Write(&buf, []*devices.Device{twice, twice}, time.Unix(100, 0)))
+
+	_, err = service.Restore(&buf, backup.Merge)
+	require.IsType(t, &errors.ValidationError{}, err)
+
+	_, err = service.Restore(&buf, "append")
+	require.IsType(t, &errors.ValidationError{}, err)
+}
+
+func TestRestoreMergePartial(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	applied := &devices.Device{SerialNum: "test 1", Status: devices.StatusActive, IP: "10.0.0.1"}
+	rejected := &devices.Device{SerialNum: "test 2", Status: devices.StatusActive, IP: "10.0.0.1"}
+
+	repo.EXPECT().Create(applied).Return(nil).Times(1)
+	repo.EXPECT().Create(rejected).Return(errors.NewConflictError("IP", rejected.IP, applied.SerialNum)).Times(1)
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Write(&buf, []*devices.Device{applied, rejected, {SerialNum: "test 3", Status: devices.StatusActive}}, time.Unix(100, 0)))
+
+	_, err := NewBackupService(repo).Restore(&buf, backup.Merge)
+	require.ErrorContains(t, err, "restore stopped after 1 of 3 devices: device test 2")
+
+	var conflict *errors.ConflictError
+	require.ErrorAs(t, err, &conflict)
+}
+
+func TestRestoreChecksDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	modelRepo := deviceMock.NewMockModelRepository(ctrl)
+
+	model := &catalog.Model{Name: "RT-100", Vendor: "acme"}
+	modelRepo.EXPECT().Get("RT-100").Return(model, nil).AnyTimes()
+	modelRepo.EXPECT().Get(gomock.Any()).Return(nil, errors.NewEntityNotFoundError("model", "Name", "")).AnyTimes()
+	modelRepo.EXPECT().List().Return([]*catalog.Model{model}, nil).AnyTimes()
+
+	service := NewBackupService(repo, WithModels(modelRepo))
+
+	cases := []struct {
+		name   string
+		device *devices.Device
+		errMsg string
+	}{
+		{
+			name:   "unknown status",
+			device: &devices.Device{SerialNum: "test 1", Status: "lost"},
+			errMsg: `device test 1: unknown device status "lost"`,
+		},
+		{
+			name:   "unknown model",
+			device: &devices.Device{SerialNum: "test 1", Status: devices.StatusActive, Model: "XX-1"},
+			errMsg: `device test 1: model "XX-1" is not in the catalog`,
+		},
+	}
+
+	for _, tCase := range cases {
+		var buf bytes.Buffer
+		require.NoError(t, backup.Write(&buf, []*devices.Device{tCase.device}, time.Unix(100, 0)))
+
+		_, err := service.Restore(&buf, backup.Merge)
+		require.IsType(t, &errors.ValidationError{}, err, tCase.name)
+		require.Contains(t, err.Error(), tCase.errMsg, tCase.name)
+	}
+}
+
+func TestRestoreReconcilesIPAM(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	manager := ipam.NewManager()
+	require.NoError(t, manager.CreateSubnet(&ipam.Subnet{CIDR: "10.0.0.0/24"}))
+
+	kept := &devices.Device{SerialNum: "test 1", Status: devices.StatusActive, IP: "10.0.0.1"}
+	dropped := &devices.Device{SerialNum: "test 2", Status: devices.StatusActive, IP: "10.0.0.2"}
+	readdressed := &devices.Device{SerialNum: "test 3", Status: devices.StatusActive, IP: "10.0.0.3"}
+	for _, device := range []*devices.Device{kept, dropped, readdressed} {
+		require.NoError(t, manager.Reserve(device.IP, device.SerialNum))
+	}
+
+	restored := &devices.Device{SerialNum: "test 3", Status: devices.StatusActive, IP: "10.0.0.4"}
+
+	// Before and while replacing, then the stored devices.
+	repo.EXPECT().List().Return([]*devices.Device{kept, dropped, readdressed}, nil).Times(2)
+	repo.EXPECT().List().Return([]*devices.Device{kept, restored}, nil).Times(1)
+	repo.EXPECT().Delete(dropped.SerialNum).Return(nil).Times(1)
+	repo.EXPECT().Create(kept).Return(errors.NewAlreadyExistDeviceError(kept.SerialNum)).Times(1)
+	repo.EXPECT().Update(kept).Return(nil).Times(1)
+	repo.EXPECT().Create(restored).Return(errors.NewAlreadyExistDeviceError(restored.SerialNum)).Times(1)
+	repo.EXPECT().Update(restored).Return(nil).Times(1)
+
+	var buf bytes.Buffer
+	require.NoError(t, backup.Write(&buf, []*devices.Device{kept, restored}, time.Unix(100, 0)))
+
+	_, err := NewBackupService(repo, WithIPAM(manager)).Restore(&buf, backup.Replace)
+	require.NoError(t, err)
+
+	// The addresses of the dropped and the readdressed device are free,
+	// the restored ones are taken.
+	require.NoError(t, manager.Reserve("10.0.0.2", "other"))
+	require.NoError(t, manager.Reserve("10.0.0.3", "other"))
+	require.Error(t, manager.Reserve("10.0.0.1", "other"))
+	require.Error(t, manager.Reserve("10.0.0.4", "other"))
+}
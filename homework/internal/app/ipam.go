@@ -0,0 +1,57 @@
+package app
+
+import (
+	"homework/internal/devices"
+	"homework/internal/ipam"
+)
+
+// IPAM hands out and takes back device addresses.
+type IPAM interface {
+	Allocate(serialNum string) (string, error)
+	Reserve(ip, serialNum string) error
+	Release(ip, serialNum string)
+}
+
+//go:generate mockgen -package internal -destination ../mocks/ipam.go . IPAMService
+type IPAMService interface {
+	IPAM
+	CreateSubnet(*ipam.Subnet) error
+	GetSubnet(string) (*ipam.Subnet, error)
+	ListSubnets() []*ipam.Subnet
+	DeleteSubnet(string) error
+	AddPool(string, ipam.Pool) error
+	Utilization(string) (*ipam.Utilization, error)
+	ListUtilization() []*ipam.Utilization
+}
+
+// WithIPAM makes the service allocate an address for devices created
+// without one and keep allocations in sync with device changes.
+func WithIPAM(ipam IPAM) Option {
+	return func(ds *deviceService) {
+		ds.ipam = ipam
+	}
+}
+
+// assignIP allocates or reserves the device address and returns a function
+// that undoes it.
+func (ds *deviceService) assignIP(device *devices.Device) (func(), error) {
+	if device.IP == "" {
+		ip, err := ds.ipam.Allocate(device.SerialNum)
+		if err != nil {
+			return nil, err
+		}
+		device.IP = ip
+	} else if err := ds.ipam.Reserve(device.IP, device.SerialNum); err != nil {
+		return nil, err
+	}
+
+	ip := device.IP
+
+	return func() { ds.ipam.Release(ip, device.SerialNum) }, nil
+}
+
+func (ds *deviceService) releaseIP(device *devices.Device) {
+	if ds.ipam != nil && device.IP != "" {
+		ds.ipam.Release(device.IP, device.SerialNum)
+	}
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestCreateDeviceAllocatesIP(t *testing.T) {
+	const (
+		testSeqNum1 = "test 1"
+		testIP1     = "10.0.0.1"
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	ipam := deviceMock.NewMockIPAMService(ctrl)
+
+	ipam.EXPECT().Allocate(testSeqNum1).Return(testIP1, nil).Times(1)
+	repo.EXPECT().Create(&devices.Device{SerialNum: testSeqNum1, IP: testIP1, Status: devices.StatusOrdered}).Return(nil).Times(1)
+
+	device := &devices.Device{SerialNum: testSeqNum1}
+	err := NewService(repo, WithIPAM(ipam)).CreateDevice(device)
+
+	require.NoError(t, err)
+	require.Equal(t, testIP1, device.IP)
+}
+
+func TestCreateDeviceReleasesIPOnError(t *testing.T) {
+	const (
+		testSeqNum1 = "test 1"
+		testIP1     = "10.0.0.1"
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	ipam := deviceMock.NewMockIPAMService(ctrl)
+
+	ipam.EXPECT().Allocate(testSeqNum1).Return(testIP1, nil).Times(1)
+	repo.EXPECT().Create(gomock.Any()).Return(errors.NewAlreadyExistDeviceError(testSeqNum1)).Times(1)
+	ipam.EXPECT().Release(testIP1, testSeqNum1).Times(1)
+
+	device := &devices.Device{SerialNum: testSeqNum1}
+	err := NewService(repo, WithIPAM(ipam)).CreateDevice(device)
+
+	require.Error(t, err)
+	require.Empty(t, device.IP)
+}
+
+func TestCreateDeviceReservesExplicitIP(t *testing.T) {
+	const (
+		testSeqNum1 = "test 1"
+		testIP1     = "10.0.0.1"
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	ipam := deviceMock.NewMockIPAMService(ctrl)
+
+	ipam.EXPECT().Reserve(testIP1, testSeqNum1).Return(errors.NewConflictError("IP", testIP1, "test 2")).Times(1)
+
+	err := NewService(repo, WithIPAM(ipam)).CreateDevice(&devices.Device{SerialNum: testSeqNum1, IP: testIP1})
+
+	require.IsType(t, &errors.ConflictError{}, err)
+}
+
+func TestDeleteDeviceReleasesIP(t *testing.T) {
+	const (
+		testSeqNum1 = "test 1"
+		testIP1     = "10.0.0.1"
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	ipam := deviceMock.NewMockIPAMService(ctrl)
+
+	repo.EXPECT().Get(testSeqNum1).Return(&devices.Device{SerialNum: testSeqNum1, IP: testIP1}, nil).Times(1)
+	repo.EXPECT().Delete(testSeqNum1).Return(nil).Times(1)
+	ipam.EXPECT().Release(testIP1, testSeqNum1).Times(1)
+
+	err := NewService(repo, WithIPAM(ipam)).DeleteDevice(testSeqNum1)
+
+	require.NoError(t, err)
+}
+
+func TestUpdateDeviceMovesIP(t *testing.T) {
+	const (
+		testSeqNum1 = "test 1"
+		testIP1     = "10.0.0.1"
+		testIP2     = "10.0.0.2"
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	ipam := deviceMock.NewMockIPAMService(ctrl)
+
+	updated := &devices.Device{SerialNum: testSeqNum1, IP: testIP2}
+
+	repo.EXPECT().Get(testSeqNum1).Return(&devices.Device{SerialNum: testSeqNum1, IP: testIP1}, nil).Times(1)
+	ipam.EXPECT().Reserve(testIP2, testSeqNum1).Return(nil).Times(1)
+	repo.EXPECT().Update(updated).Return(nil).Times(1)
+	ipam.EXPECT().Release(testIP1, testSeqNum1).Times(1)
+
+	err := NewService(repo, WithIPAM(ipam)).UpdateDevice(updated)
+
+	require.NoError(t, err)
+}
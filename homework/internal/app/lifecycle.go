@@ -0,0 +1,132 @@
+package app
+
+import (
+	"homework/internal/devices"
+	"homework/internal/errors"
+)
+
+//go:generate mockgen -package internal -destination ../mocks/history.go . HistoryRepository
+type HistoryRepository interface {
+	Append(devices.Transition) error
+	List(string) ([]devices.Transition, error)
+}
+
+// transitions lists the statuses a device may move to from each status.
+// Decommissioned is final.
+var transitions = map[devices.Status][]devices.Status{
+	devices.StatusOrdered:        {devices.StatusInStock, devices.StatusDecommissioned},
+	devices.StatusInStock:        {devices.StatusProvisioned, devices.StatusDecommissioned},
+	devices.StatusProvisioned:    {devices.StatusActive, devices.StatusInStock, devices.StatusDecommissioned},
+	devices.StatusActive:         {devices.StatusMaintenance, devices.StatusDecommissioned},
+	devices.StatusMaintenance:    {devices.StatusActive, devices.StatusDecommissioned},
+	devices.StatusDecommissioned: {},
+}
+
+func validStatus(status devices.Status) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+func CanTransition(from, to devices.Status) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithHistory makes the service record every status change of a device.
+func WithHistory(repo HistoryRepository) Option {
+	return func(ds *deviceService) {
+		ds.history = repo
+	}
+}
+
+func (ds *deviceService) TransitionDevice(serialNum string, to devices.Status, reason string) (*devices.Device, error) {
+	if !validStatus(to) {
+		return nil, errors.NewValidationError("unknown device status %q", to)
+	}
+
+	defer ds.serials.lock(serialNum)()
+
+	old, err := ds.repo.Get(serialNum)
+	if err != nil {
+		return nil, err
+	}
+
+	if !CanTransition(old.Status, to) {
+		return nil, errors.NewIllegalTransitionError(serialNum, string(old.Status), string(to))
+	}
+
+	// The stored device may be shared with concurrent readers, so the
+	// change is made on a copy.
+	device := *old
+	device.Status = to
+
+	if err = ds.repo.Update(&device); err != nil {
+		return nil, err
+	}
+
+	if err = ds.recordTransition(serialNum, old.Status, to, reason); err != nil {
+		return nil, err
+	}
+
+	return &device, ds.recordVersion(serialNum, &device)
+}
+
+func (ds *deviceService) ListTransitions(serialNum string) ([]devices.Transition, error) {
+	if ds.history == nil {
+		return []devices.Transition{}, nil
+	}
+
+	return ds.history.List(serialNum)
+}
+
+// initStatus gives new devices the initial status. Devices can not be
+// created in a later one, which would skip the transitions to it and their
+// history.
+func initStatus(device *devices.Device) error {
+	if device.Status == "" {
+		device.Status = devices.StatusOrdered
+	}
+
+	if !validStatus(device.Status) {
+		return errors.NewValidationError("unknown device status %q", device.Status)
+	}
+	if device.Status != devices.StatusOrdered {
+		return errors.NewValidationError("device 'Status' of a new device must be %s, later statuses are reached with transitions", devices.StatusOrdered)
+	}
+
+	return nil
+}
+
+// keepStatus rejects status changes made by updating the device, they have
+// to go through TransitionDevice.
+func keepStatus(device, old *devices.Device) error {
+	if device.Status == "" {
+		device.Status = old.Status
+		return nil
+	}
+
+	if device.Status != old.Status {
+		return errors.NewValidationError("device 'Status' can only be changed with a transition from %s", old.Status)
+	}
+
+	return nil
+}
+
+func (ds *deviceService) recordTransition(serialNum string, from, to devices.Status, reason string) error {
+	if ds.history == nil {
+		return nil
+	}
+
+	return ds.history.Append(devices.Transition{
+		SerialNum: serialNum,
+		From:      from,
+		To:        to,
+		Reason:    reason,
+		At:        ds.now(),
+	})
+}
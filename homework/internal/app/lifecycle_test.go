@@ -0,0 +1,91 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestCanTransition(t *testing.T) {
+	require.True(t, CanTransition(devices.StatusOrdered, devices.StatusInStock))
+	require.True(t, CanTransition(devices.StatusActive, devices.StatusMaintenance))
+	require.True(t, CanTransition(devices.StatusMaintenance, devices.StatusActive))
+	require.False(t, CanTransition(devices.StatusOrdered, devices.StatusActive))
+	require.False(t, CanTransition(devices.StatusDecommissioned, devices.StatusActive))
+	require.False(t, CanTransition(devices.StatusActive, devices.StatusActive))
+}
+
+func TestTransitionDevice(t *testing.T) {
+	const testSeqNum1 = "test 1"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	history := deviceMock.NewMockHistoryRepository(ctrl)
+
+	now := time.Unix(100, 0)
+	stored := &devices.Device{SerialNum: testSeqNum1, Status: devices.StatusProvisioned}
+	expect := &devices.Device{SerialNum: testSeqNum1, Status: devices.StatusActive}
+
+	repo.EXPECT().Get(testSeqNum1).Return(stored, nil).Times(2)
+	repo.EXPECT().Update(expect).Return(nil).Times(1)
+	history.EXPECT().Append(devices.Transition{
+		SerialNum: testSeqNum1,
+		From:      devices.StatusProvisioned,
+		To:        devices.StatusActive,
+		Reason:    "racked",
+		At:        now,
+	}).Return(nil).Times(1)
+
+	ds := NewService(repo, WithHistory(history)).(*deviceService)
+	ds.now = func() time.Time { return now }
+
+	device, err := ds.TransitionDevice(testSeqNum1, devices.StatusActive, "racked")
+	require.NoError(t, err)
+	require.Equal(t, expect, device)
+	require.Equal(t, devices.StatusProvisioned, stored.Status)
+
+	_, err = ds.TransitionDevice(testSeqNum1, devices.StatusOrdered, "")
+	require.IsType(t, &errors.IllegalTransitionError{}, err)
+
+	_, err = ds.TransitionDevice(testSeqNum1, "lost", "")
+	require.IsType(t, &errors.ValidationError{}, err)
+}
+
+func TestUpdateDeviceKeepsStatus(t *testing.T) {
+	const testSeqNum1 = "test 1"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	repo.EXPECT().Get(testSeqNum1).Return(&devices.Device{SerialNum: testSeqNum1, Status: devices.StatusActive}, nil).Times(2)
+	repo.EXPECT().Update(&devices.Device{SerialNum: testSeqNum1, Model: "m", Status: devices.StatusActive}).Return(nil).Times(1)
+
+	app := NewService(repo)
+
+	require.NoError(t, app.UpdateDevice(&devices.Device{SerialNum: testSeqNum1, Model: "m"}))
+	require.IsType(t, &errors.ValidationError{}, app.UpdateDevice(&devices.Device{SerialNum: testSeqNum1, Status: devices.StatusMaintenance}))
+}
+
+func TestCreateDeviceStartsOrdered(t *testing.T) {
+	const testSeqNum1 = "test 1"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	repo.EXPECT().Create(&devices.Device{SerialNum: testSeqNum1, Status: devices.StatusOrdered}).Return(nil).Times(1)
+
+	app := NewService(repo)
+
+	require.NoError(t, app.CreateDevice(&devices.Device{SerialNum: testSeqNum1, Status: devices.StatusOrdered}))
+	require.IsType(t, &errors.ValidationError{}, app.CreateDevice(&devices.Device{SerialNum: "test 2", Status: devices.StatusActive}))
+	require.IsType(t, &errors.ValidationError{}, app.CreateDevice(&devices.Device{SerialNum: "test 3", Status: devices.StatusDecommissioned}))
+}
@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+)
+
+const maxHeartbeatMetadata = 64
+
+//go:generate mockgen -package internal -destination ../mocks/liveness.go . HeartbeatRepository,LivenessService
+type HeartbeatRepository interface {
+	Get(string) (*devices.Heartbeat, error)
+	List() ([]*devices.Heartbeat, error)
+	Put(*devices.Heartbeat) error
+	Delete(string) error
+	// MarkOffline marks the device offline unless it was seen at or after
+	// the given time, so a heartbeat racing with the sweeper is not lost.
+	MarkOffline(serialNum string, seenBefore time.Time) (bool, error)
+}
+
+type LivenessService interface {
+	Heartbeat(serialNum string, metadata map[string]string) error
+	GetHeartbeat(string) (*devices.Heartbeat, error)
+	CountLiveness() (*devices.LivenessCounts, error)
+	// Sweep marks devices silent for longer than the timeout offline and
+	// returns how many were marked.
+	Sweep() (int, error)
+}
+
+type livenessService struct {
+	repo    HeartbeatRepository
+	devices Repository
+	timeout time.Duration
+	now     func() time.Time
+}
+
+func NewLivenessService(repo HeartbeatRepository, devices Repository, timeout time.Duration) LivenessService {
+	return &livenessService{
+		repo:    repo,
+		devices: devices,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// WithLiveness enables filtering devices by liveness and drops the
+// heartbeat of deleted devices.
+func WithLiveness(repo HeartbeatRepository) Option {
+	return func(ds *deviceService) {
+		ds.heartbeats = repo
+	}
+}
+
+func (ls *livenessService) Heartbeat(serialNum string, metadata map[string]string) error {
+	if len(metadata) > maxHeartbeatMetadata {
+		return errors.NewValidationError("heartbeat metadata must have at most %d keys", maxHeartbeatMetadata)
+	}
+
+	if _, err := ls.devices.Get(serialNum); err != nil {
+		return err
+	}
+
+	return ls.repo.Put(&devices.Heartbeat{
+		SerialNum: serialNum,
+		Liveness:  devices.Online,
+		LastSeen:  ls.now(),
+		Metadata:  metadata,
+	})
+}
+
+func (ls *livenessService) GetHeartbeat(serialNum string) (*devices.Heartbeat, error) {
+	return ls.repo.Get(serialNum)
+}
+
+// CountLiveness counts existing devices only, heartbeats of devices
+// deleted meanwhile are ignored.
+func (ls *livenessService) CountLiveness() (*devices.LivenessCounts, error) {
+	list, err := ls.devices.List()
+	if err != nil {
+		return nil, err
+	}
+
+	heartbeats, err := ls.repo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	online := make(map[string]bool, len(heartbeats))
+	for _, heartbeat := range heartbeats {
+		online[heartbeat.SerialNum] = heartbeat.Liveness == devices.Online
+	}
+
+	counts := &devices.LivenessCounts{}
+	for _, device := range list {
+		if online[device.SerialNum] {
+			counts.Online++
+		} else {
+			counts.Offline++
+		}
+	}
+
+	return counts, nil
+}
+
+func (ls *livenessService) Sweep() (int, error) {
+	heartbeats, err := ls.repo.List()
+	if err != nil {
+		return 0, err
+	}
+
+	deadline := ls.now().Add(-ls.timeout)
+
+	var marked int
+	for _, heartbeat := range heartbeats {
+		if heartbeat.Liveness != devices.Online || !heartbeat.LastSeen.Before(deadline) {
+			continue
+		}
+
+		ok, err := ls.repo.MarkOffline(heartbeat.SerialNum, deadline)
+		if err != nil {
+			return marked, err
+		}
+		if ok {
+			marked++
+		}
+	}
+
+	return marked, nil
+}
+
+// RunSweeper sweeps every interval until the context is done.
+func RunSweeper(ctx context.Context, service LivenessService, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := service.Sweep(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// livenessOf returns the liveness of every device that reported, the rest
+// are offline.
+func (ds *deviceService) livenessOf() (map[string]devices.Liveness, error) {
+	heartbeats, err := ds.heartbeats.List()
+	if err != nil {
+		return nil, err
+	}
+
+	liveness := make(map[string]devices.Liveness, len(heartbeats))
+	for _, heartbeat := range heartbeats {
+		liveness[heartbeat.SerialNum] = heartbeat.Liveness
+	}
+
+	return liveness, nil
+}
+
+func (ds *deviceService) dropHeartbeat(serialNum string) {
+	if ds.heartbeats != nil {
+		_ = ds.heartbeats.Delete(serialNum)
+	}
+}
+
+// matchesLiveness treats devices without a heartbeat as offline.
+func matchesLiveness(liveness, want devices.Liveness) bool {
+	if liveness == "" {
+		liveness = devices.Offline
+	}
+
+	return liveness == want
+}
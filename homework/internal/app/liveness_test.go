@@ -0,0 +1,121 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHeartbeat(t *testing.T) {
+	const testSeqNum1 = "test 1"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockHeartbeatRepository(ctrl)
+	devicesRepo := deviceMock.NewMockRepository(ctrl)
+
+	now := time.Unix(100, 0)
+	metadata := map[string]string{"firmware": "1.2"}
+
+	devicesRepo.EXPECT().Get(testSeqNum1).Return(&devices.Device{SerialNum: testSeqNum1}, nil).Times(1)
+	devicesRepo.EXPECT().Get("unknown").Return(nil, errors.NewNotFoundError("unknown")).Times(1)
+	repo.EXPECT().Put(&devices.Heartbeat{
+		SerialNum: testSeqNum1,
+		Liveness:  devices.Online,
+		LastSeen:  now,
+		Metadata:  metadata,
+	}).Return(nil).Times(1)
+
+	service := NewLivenessService(repo, devicesRepo, time.Minute).(*livenessService)
+	service.now = func() time.Time { return now }
+
+	require.NoError(t, service.Heartbeat(testSeqNum1, metadata))
+	require.IsType(t, &errors.NotFoundError{}, service.Heartbeat("unknown", nil))
+}
+
+func TestSweep(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockHeartbeatRepository(ctrl)
+	devicesRepo := deviceMock.NewMockRepository(ctrl)
+
+	now := time.Unix(1000, 0)
+	deadline := now.Add(-time.Minute)
+
+	repo.EXPECT().List().Return([]*devices.Heartbeat{
+		{SerialNum: "silent", Liveness: devices.Online, LastSeen: deadline.Add(-time.Second)},
+		{SerialNum: "fresh", Liveness: devices.Online, LastSeen: deadline.Add(time.Second)},
+		{SerialNum: "gone", Liveness: devices.Offline, LastSeen: deadline.Add(-time.Hour)},
+		{SerialNum: "raced", Liveness: devices.Online, LastSeen: deadline.Add(-time.Second)},
+	}, nil).Times(1)
+	repo.EXPECT().MarkOffline("silent", deadline).Return(true, nil).Times(1)
+	repo.EXPECT().MarkOffline("raced", deadline).Return(false, nil).Times(1)
+
+	service := NewLivenessService(repo, devicesRepo, time.Minute).(*livenessService)
+	service.now = func() time.Time { return now }
+
+	marked, err := service.Sweep()
+	require.NoError(t, err)
+	require.Equal(t, 1, marked)
+}
+
+func TestCountLiveness(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockHeartbeatRepository(ctrl)
+	devicesRepo := deviceMock.NewMockRepository(ctrl)
+
+	devicesRepo.EXPECT().List().Return([]*devices.Device{
+		{SerialNum: "online"},
+		{SerialNum: "offline"},
+		{SerialNum: "silent"},
+	}, nil).Times(1)
+	// The heartbeat of a deleted device is left until it is dropped.
+	repo.EXPECT().List().Return([]*devices.Heartbeat{
+		{SerialNum: "online", Liveness: devices.Online},
+		{SerialNum: "offline", Liveness: devices.Offline},
+		{SerialNum: "deleted", Liveness: devices.Online},
+	}, nil).Times(1)
+
+	service := NewLivenessService(repo, devicesRepo, time.Minute)
+
+	counts, err := service.CountLiveness()
+	require.NoError(t, err)
+	require.Equal(t, &devices.LivenessCounts{Online: 1, Offline: 2}, counts)
+}
+
+func TestListDevicesByLiveness(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	heartbeats := deviceMock.NewMockHeartbeatRepository(ctrl)
+
+	online := &devices.Device{SerialNum: "1"}
+	offline := &devices.Device{SerialNum: "2"}
+	silent := &devices.Device{SerialNum: "3"}
+
+	repo.EXPECT().List().Return([]*devices.Device{online, offline, silent}, nil).Times(2)
+	heartbeats.EXPECT().List().Return([]*devices.Heartbeat{
+		{SerialNum: "1", Liveness: devices.Online},
+		{SerialNum: "2", Liveness: devices.Offline},
+	}, nil).Times(2)
+
+	app := NewService(repo, WithLiveness(heartbeats))
+
+	actual, err := app.ListDevices(devices.Filter{Liveness: devices.Online})
+	require.NoError(t, err)
+	require.Equal(t, []*devices.Device{online}, actual)
+
+	actual, err = app.ListDevices(devices.Filter{Liveness: devices.Offline})
+	require.NoError(t, err)
+	require.Equal(t, []*devices.Device{offline, silent}, actual)
+
+	_, err = app.ListDevices(devices.Filter{Liveness: "asleep"})
+	require.IsType(t, &errors.ValidationError{}, err)
+}
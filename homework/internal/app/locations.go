@@ -0,0 +1,241 @@
+package app
+
+import (
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/locations"
+)
+
+//go:generate mockgen -package internal -destination ../mocks/locations.go . LocationRepository,LocationService
+type LocationRepository interface {
+	Get(string) (*locations.Location, error)
+	List() ([]*locations.Location, error)
+	ListChildren(string) ([]*locations.Location, error)
+	Create(*locations.Location) error
+	Delete(string) error
+	Update(*locations.Location) error
+}
+
+type LocationService interface {
+	GetLocation(string) (*locations.Location, error)
+	ListLocations() ([]*locations.Location, error)
+	CreateLocation(*locations.Location) error
+	DeleteLocation(string) error
+	UpdateLocation(*locations.Location) error
+}
+
+type locationService struct {
+	repo    LocationRepository
+	devices Repository
+	refs    *References
+}
+
+// NewLocationService shares refs with the device service, see
+// WithReferences.
+func NewLocationService(repo LocationRepository, devices Repository, refs *References) LocationService {
+	return &locationService{
+		repo:    repo,
+		devices: devices,
+		refs:    refs,
+	}
+}
+
+// WithLocations makes the service check that devices reference existing
+// locations and enables listing devices by location subtree.
+func WithLocations(repo LocationRepository) Option {
+	return func(ds *deviceService) {
+		ds.locations = repo
+	}
+}
+
+func (ls *locationService) GetLocation(id string) (*locations.Location, error) {
+	return ls.repo.Get(id)
+}
+
+func (ls *locationService) ListLocations() ([]*locations.Location, error) {
+	return ls.repo.List()
+}
+
+func (ls *locationService) CreateLocation(location *locations.Location) error {
+	if location.ID == "" {
+		return errors.NewValidationError("location 'ID' is required")
+	}
+
+	if err := ls.checkParent(location); err != nil {
+		return err
+	}
+
+	return ls.repo.Create(location)
+}
+
+func (ls *locationService) UpdateLocation(location *locations.Location) error {
+	old, err := ls.repo.Get(location.ID)
+	if err != nil {
+		return err
+	}
+
+	if old.Kind != location.Kind {
+		return errors.NewValidationError("location 'Kind' can not be changed from %s to %s", old.Kind, location.Kind)
+	}
+
+	if err = ls.checkParent(location); err != nil {
+		return err
+	}
+
+	return ls.repo.Update(location)
+}
+
+func (ls *locationService) DeleteLocation(id string) error {
+	defer ls.refs.locations.lock(id)()
+
+	if _, err := ls.repo.Get(id); err != nil {
+		return err
+	}
+
+	children, err := ls.repo.ListChildren(id)
+	if err != nil {
+		return err
+	}
+
+	if len(children) > 0 {
+		return errors.NewConflictErrorf("location %s still contains %d locations", id, len(children))
+	}
+
+	list, err := ListDevicesByLocation(ls.devices, id)
+	if err != nil {
+		return err
+	}
+
+	if len(list) > 0 {
+		return errors.NewConflictErrorf("location %s is still used by device with 'SerialNum' = %s", id, list[0].SerialNum)
+	}
+
+	return ls.repo.Delete(id)
+}
+
+// checkParent enforces the region > site > room > rack hierarchy.
+func (ls *locationService) checkParent(location *locations.Location) error {
+	parentKind, ok := locations.ParentKind(location.Kind)
+	if !ok {
+		return errors.NewValidationError("unknown location kind %q", location.Kind)
+	}
+
+	if parentKind == "" {
+		if location.ParentID != "" {
+			return errors.NewValidationError("location of kind %s can not have a parent", location.Kind)
+		}
+		return nil
+	}
+
+	if location.ParentID == "" {
+		return errors.NewValidationError("location of kind %s must have a parent of kind %s", location.Kind, parentKind)
+	}
+
+	parent, err := ls.repo.Get(location.ParentID)
+	if err != nil {
+		return err
+	}
+
+	if parent.Kind != parentKind {
+		return errors.NewValidationError("location of kind %s must have a parent of kind %s, got %s", location.Kind, parentKind, parent.Kind)
+	}
+
+	return nil
+}
+
+// checkLocation verifies that the device references an existing location
+// and, when placed in a rack, a free position.
+func (ds *deviceService) checkLocation(device *devices.Device) error {
+	if device.Position < 0 {
+		return errors.NewValidationError("device 'Position' must not be negative")
+	}
+
+	if device.LocationID == "" {
+		if device.Position > 0 {
+			return errors.NewValidationError("device 'Position' requires a rack location")
+		}
+		return nil
+	}
+
+	if ds.locations == nil {
+		return nil
+	}
+
+	location, err := ds.locations.Get(device.LocationID)
+	if err != nil {
+		return err
+	}
+
+	if device.Position == 0 {
+		return nil
+	}
+
+	if location.Kind != locations.KindRack {
+		return errors.NewValidationError("device 'Position' requires a rack location, %s is a %s", location.ID, location.Kind)
+	}
+
+	list, err := ListDevicesByLocation(ds.repo, device.LocationID)
+	if err != nil {
+		return err
+	}
+
+	// Repositories with a location index check the position again when the
+	// device is stored, this only reports the conflict early.
+	for _, other := range list {
+		if other.SerialNum != device.SerialNum && other.Position == device.Position {
+			return errors.NewConflictErrorf("position %d of rack %s is used by device with 'SerialNum' = %s",
+				device.Position, device.LocationID, other.SerialNum)
+		}
+	}
+
+	return nil
+}
+
+// ListDevicesByLocation uses the location index of the repository when it
+// has one.
+func ListDevicesByLocation(repo Repository, id string) ([]*devices.Device, error) {
+	if lister, ok := repo.(LocationLister); ok {
+		return lister.ListByLocation(id)
+	}
+
+	list, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*devices.Device, 0)
+	for _, device := range list {
+		if device.LocationID == id {
+			result = append(result, device)
+		}
+	}
+
+	return result, nil
+}
+
+// subtree returns the ids of the location and all of its descendants.
+func (ds *deviceService) subtree(id string) (map[string]bool, error) {
+	if _, err := ds.locations.Get(id); err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{id: true}
+	queue := []string{id}
+
+	for len(queue) > 0 {
+		children, err := ds.locations.ListChildren(queue[0])
+		if err != nil {
+			return nil, err
+		}
+		queue = queue[1:]
+
+		for _, child := range children {
+			if !ids[child.ID] {
+				ids[child.ID] = true
+				queue = append(queue, child.ID)
+			}
+		}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/locations"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestCreateLocationHierarchy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockLocationRepository(ctrl)
+	devicesRepo := deviceMock.NewMockRepository(ctrl)
+
+	region := &locations.Location{ID: "eu", Kind: locations.KindRegion}
+	room := &locations.Location{ID: "room", Kind: locations.KindRoom, ParentID: "ams"}
+	site := &locations.Location{ID: "ams", Kind: locations.KindSite, ParentID: "eu"}
+
+	repo.EXPECT().Create(region).Return(nil).Times(1)
+	repo.EXPECT().Get("eu").Return(region, nil).AnyTimes()
+	repo.EXPECT().Create(site).Return(nil).Times(1)
+
+	service := NewLocationService(repo, devicesRepo, NewReferences())
+
+	require.NoError(t, service.CreateLocation(region))
+	require.NoError(t, service.CreateLocation(site))
+
+	cases := []*locations.Location{
+		{ID: "r2", Kind: locations.KindRegion, ParentID: "eu"},
+		{ID: "s2", Kind: locations.KindSite},
+		{ID: "rack", Kind: locations.KindRack, ParentID: "eu"},
+		{ID: "x", Kind: "floor", ParentID: "eu"},
+		{Kind: locations.KindRegion},
+	}
+	for _, location := range cases {
+		require.IsType(t, &errors.ValidationError{}, service.CreateLocation(location), location)
+	}
+
+	repo.EXPECT().Get("ams").Return(nil, errors.NewEntityNotFoundError("location", "ID", "ams")).Times(1)
+	require.IsType(t, &errors.NotFoundError{}, service.CreateLocation(room))
+}
+
+func TestDeleteLocationInUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockLocationRepository(ctrl)
+	devicesRepo := deviceMock.NewMockRepository(ctrl)
+
+	region := &locations.Location{ID: "eu", Kind: locations.KindRegion}
+	site := &locations.Location{ID: "ams", Kind: locations.KindSite, ParentID: "eu"}
+
+	repo.EXPECT().Get("eu").Return(region, nil).Times(1)
+	repo.EXPECT().ListChildren("eu").Return([]*locations.Location{site}, nil).Times(1)
+
+	repo.EXPECT().Get("ams").Return(site, nil).Times(2)
+	repo.EXPECT().ListChildren("ams").Return(nil, nil).Times(2)
+	devicesRepo.EXPECT().List().Return([]*devices.Device{{SerialNum: "1", LocationID: "ams"}}, nil).Times(1)
+	devicesRepo.EXPECT().List().Return(nil, nil).Times(1)
+	repo.EXPECT().Delete("ams").Return(nil).Times(1)
+
+	service := NewLocationService(repo, devicesRepo, NewReferences())
+
+	require.IsType(t, &errors.ConflictError{}, service.DeleteLocation("eu"))
+	require.IsType(t, &errors.ConflictError{}, service.DeleteLocation("ams"))
+	require.NoError(t, service.DeleteLocation("ams"))
+}
+
+func TestCreateDeviceChecksLocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	locationRepo := deviceMock.NewMockLocationRepository(ctrl)
+
+	rack := &locations.Location{ID: "rack", Kind: locations.KindRack, ParentID: "room"}
+	locationRepo.EXPECT().Get("rack").Return(rack, nil).AnyTimes()
+	locationRepo.EXPECT().Get("missing").Return(nil, errors.NewEntityNotFoundError("location", "ID", "missing")).Times(1)
+	repo.EXPECT().List().Return([]*devices.Device{{SerialNum: "2", LocationID: "rack", Position: 3}}, nil).Times(2)
+	repo.EXPECT().Create(gomock.Any()).Return(nil).Times(1)
+
+	service := NewService(repo, WithLocations(locationRepo))
+
+	require.IsType(t, &errors.NotFoundError{}, service.CreateDevice(&devices.Device{SerialNum: "1", LocationID: "missing"}))
+	require.IsType(t, &errors.ValidationError{}, service.CreateDevice(&devices.Device{SerialNum: "1", Position: 1}))
+	require.IsType(t, &errors.ConflictError{}, service.CreateDevice(&devices.Device{SerialNum: "1", LocationID: "rack", Position: 3}))
+	require.NoError(t, service.CreateDevice(&devices.Device{SerialNum: "1", LocationID: "rack", Position: 4}))
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"sort"
+	"sync"
+
+	"homework/internal/devices"
+)
+
+// serialLocks hands out a mutex per serial number, so reading a device,
+// checking it and writing it back is not interleaved with another change
+// of the same device. Entries are dropped once nobody holds them.
+type serialLocks struct {
+	mu    sync.Mutex
+	locks map[string]*serialLock
+}
+
+type serialLock struct {
+	sync.RWMutex
+	refs int
+}
+
+func newSerialLocks() *serialLocks {
+	return &serialLocks{locks: make(map[string]*serialLock)}
+}
+
+// lock blocks until the serial number is free and returns the function
+// that releases it.
+func (l *serialLocks) lock(serialNum string) func() {
+	entry := l.acquire(serialNum)
+	entry.Lock()
+
+	return func() {
+		entry.Unlock()
+		l.release(serialNum, entry)
+	}
+}
+
+// rlock is lock shared with other readers, it only excludes lock.
+func (l *serialLocks) rlock(serialNum string) func() {
+	entry := l.acquire(serialNum)
+	entry.RLock()
+
+	return func() {
+		entry.RUnlock()
+		l.release(serialNum, entry)
+	}
+}
+
+func (l *serialLocks) acquire(serialNum string) *serialLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.locks[serialNum]
+	if !ok {
+		entry = &serialLock{}
+		l.locks[serialNum] = entry
+	}
+	entry.refs++
+
+	return entry
+}
+
+func (l *serialLocks) release(serialNum string, entry *serialLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.refs--
+	if entry.refs == 0 {
+		delete(l.locks, serialNum)
+	}
+}
+
+// References makes deleting a model or location wait for the device writes
+// that checked it exists, so no device is stored pointing at one deleted
+// in between. Device writes share the lock of what they reference,
+// deletes take it alone. The device service and the model and location
+// services of the same devices have to share one.
+type References struct {
+	models    *serialLocks
+	locations *serialLocks
+}
+
+func NewReferences() *References {
+	return &References{models: newSerialLocks(), locations: newSerialLocks()}
+}
+
+// WithReferences shares the references with the model and location
+// services, the service uses references of its own otherwise.
+func WithReferences(refs *References) Option {
+	return func(ds *deviceService) {
+		ds.refs = refs
+	}
+}
+
+// use holds the model and location the device references, as far as the
+// service checks them, until the returned function is called.
+func (ds *deviceService) use(device *devices.Device) func() {
+	unlockModel, unlockLocation := func() {}, func() {}
+	if ds.models != nil && device.Model != "" {
+		unlockModel = ds.refs.models.rlock(device.Model)
+	}
+	if ds.locations != nil && device.LocationID != "" {
+		unlockLocation = ds.refs.locations.rlock(device.LocationID)
+	}
+
+	return func() {
+		unlockLocation()
+		unlockModel()
+	}
+}
+
+// useModels holds the models of all the devices like use does, once each.
+func (ds *deviceService) useModels(list []*devices.Device) func() {
+	if ds.models == nil {
+		return func() {}
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, device := range list {
+		if device.Model != "" && !seen[device.Model] {
+			seen[device.Model] = true
+			names = append(names, device.Model)
+		}
+	}
+	sort.Strings(names)
+
+	unlocks := make([]func(), 0, len(names))
+	for _, name := range names {
+		unlocks = append(unlocks, ds.refs.models.rlock(name))
+	}
+
+	return func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	stderrors "errors"
+	"sort"
+
+	"homework/internal/catalog"
+	"homework/internal/devices"
+	"homework/internal/errors"
+)
+
+//go:generate mockgen -package internal -destination ../mocks/models.go . ModelRepository,ModelService
+type ModelRepository interface {
+	Get(string) (*catalog.Model, error)
+	List() ([]*catalog.Model, error)
+	Create(*catalog.Model) error
+	Delete(string) error
+	Update(*catalog.Model) error
+}
+
+type ModelService interface {
+	GetModel(string) (*catalog.Model, error)
+	ListModels() ([]*catalog.Model, error)
+	CreateModel(*catalog.Model) error
+	DeleteModel(string) error
+	UpdateModel(*catalog.Model) error
+}
+
+type modelService struct {
+	repo    ModelRepository
+	devices Repository
+	refs    *References
+}
+
+// NewModelService shares refs with the device service, see WithReferences.
+func NewModelService(repo ModelRepository, devices Repository, refs *References) ModelService {
+	return &modelService{
+		repo:    repo,
+		devices: devices,
+		refs:    refs,
+	}
+}
+
+// WithModels makes the service reject devices that reference models missing
+// from the catalog or set attributes their model does not allow.
+func WithModels(repo ModelRepository) Option {
+	return func(ds *deviceService) {
+		ds.models = repo
+	}
+}
+
+func (ms *modelService) GetModel(name string) (*catalog.Model, error) {
+	return ms.repo.Get(name)
+}
+
+func (ms *modelService) ListModels() ([]*catalog.Model, error) {
+	return ms.repo.List()
+}
+
+func (ms *modelService) CreateModel(model *catalog.Model) error {
+	if err := validateModel(model); err != nil {
+		return err
+	}
+
+	list, err := ms.repo.List()
+	if err != nil {
+		return err
+	}
+
+	normalized := catalog.Normalize(model.Name)
+	for _, other := range list {
+		if other.Name != model.Name && catalog.Normalize(other.Name) == normalized {
+			return errors.NewConflictErrorf("model %q is a different spelling of existing model %q", model.Name, other.Name)
+		}
+	}
+
+	return ms.repo.Create(model)
+}
+
+func (ms *modelService) UpdateModel(model *catalog.Model) error {
+	if err := validateModel(model); err != nil {
+		return err
+	}
+
+	if _, err := ms.repo.Get(model.Name); err != nil {
+		return err
+	}
+
+	list, err := ms.devices.ListByModel(model.Name)
+	if err != nil {
+		return err
+	}
+
+	for _, device := range list {
+		for key := range device.Attributes {
+			if !model.AllowsAttribute(key) {
+				return errors.NewConflictErrorf("attribute %s of model %s is still used by device with 'SerialNum' = %s",
+					key, model.Name, device.SerialNum)
+			}
+		}
+	}
+
+	return ms.repo.Update(model)
+}
+
+func (ms *modelService) DeleteModel(name string) error {
+	defer ms.refs.models.lock(name)()
+
+	if _, err := ms.repo.Get(name); err != nil {
+		return err
+	}
+
+	list, err := ms.devices.ListByModel(name)
+	if err != nil {
+		return err
+	}
+
+	if len(list) > 0 {
+		return errors.NewConflictErrorf("model %s is still used by %d devices", name, len(list))
+	}
+
+	return ms.repo.Delete(name)
+}
+
+func validateModel(model *catalog.Model) error {
+	if model.Name == "" {
+		return errors.NewValidationError("model 'Name' is required")
+	}
+
+	if model.Vendor == "" {
+		return errors.NewValidationError("model 'Vendor' is required")
+	}
+
+	if model.Specs.Ports < 0 || model.Specs.RackUnits < 0 || model.Specs.PowerWatts < 0 {
+		return errors.NewValidationError("model %s specs must not be negative", model.Name)
+	}
+
+	seen := make(map[string]bool, len(model.Attributes))
+	for _, attribute := range model.Attributes {
+		if attribute == "" || seen[attribute] {
+			return errors.NewValidationError("model %s attributes must be unique and not empty", model.Name)
+		}
+		seen[attribute] = true
+	}
+
+	return nil
+}
+
+// checkModel verifies that the device references a catalog model and only
+// sets attributes allowed by it.
+func (ds *deviceService) checkModel(device *devices.Device) error {
+	if ds.models == nil {
+		return nil
+	}
+
+	if device.Model == "" {
+		if len(device.Attributes) > 0 {
+			return errors.NewValidationError("device 'Attributes' require a model")
+		}
+		return nil
+	}
+
+	model, err := ds.models.Get(device.Model)
+	var notFound *errors.NotFoundError
+	if stderrors.As(err, &notFound) {
+		return ds.unknownModel(device.Model)
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(device.Attributes))
+	for key := range device.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !model.AllowsAttribute(key) {
+			return errors.NewValidationError("attribute %s is not allowed for model %s", key, model.Name)
+		}
+	}
+
+	return nil
+}
+
+// unknownModel points at the catalog entry the name is likely a misspelling
+// of, if there is one.
+func (ds *deviceService) unknownModel(name string) error {
+	list, err := ds.models.List()
+	if err != nil {
+		return err
+	}
+
+	normalized := catalog.Normalize(name)
+	for _, model := range list {
+		if catalog.Normalize(model.Name) == normalized {
+			return errors.NewValidationError("model %q is not in the catalog, did you mean %q", name, model.Name)
+		}
+	}
+
+	return errors.NewValidationError("model %q is not in the catalog", name)
+}
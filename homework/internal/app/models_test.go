@@ -0,0 +1,112 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/catalog"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestCreateModel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockModelRepository(ctrl)
+	devicesRepo := deviceMock.NewMockRepository(ctrl)
+
+	existing := &catalog.Model{Name: "RT-100", Vendor: "acme"}
+	model := &catalog.Model{Name: "SW-48", Vendor: "acme", Attributes: []string{"firmware"}}
+
+	repo.EXPECT().List().Return([]*catalog.Model{existing}, nil).Times(2)
+	repo.EXPECT().Create(model).Return(nil).Times(1)
+
+	service := NewModelService(repo, devicesRepo, NewReferences())
+
+	require.NoError(t, service.CreateModel(model))
+	require.IsType(t, &errors.ConflictError{}, service.CreateModel(&catalog.Model{Name: "rt 100", Vendor: "acme"}))
+
+	cases := []*catalog.Model{
+		{Vendor: "acme"},
+		{Name: "X"},
+		{Name: "X", Vendor: "acme", Specs: catalog.Specs{Ports: -1}},
+		{Name: "X", Vendor: "acme", Attributes: []string{"a", "a"}},
+	}
+	for _, model := range cases {
+		require.IsType(t, &errors.ValidationError{}, service.CreateModel(model), model)
+	}
+}
+
+func TestDeleteModelInUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockModelRepository(ctrl)
+	devicesRepo := deviceMock.NewMockRepository(ctrl)
+
+	model := &catalog.Model{Name: "RT-100", Vendor: "acme"}
+
+	repo.EXPECT().Get("RT-100").Return(model, nil).Times(2)
+	devicesRepo.EXPECT().ListByModel("RT-100").Return([]*devices.Device{{SerialNum: "1", Model: "RT-100"}}, nil).Times(1)
+	devicesRepo.EXPECT().ListByModel("RT-100").Return(nil, nil).Times(1)
+	repo.EXPECT().Delete("RT-100").Return(nil).Times(1)
+
+	service := NewModelService(repo, devicesRepo, NewReferences())
+
+	require.IsType(t, &errors.ConflictError{}, service.DeleteModel("RT-100"))
+	require.NoError(t, service.DeleteModel("RT-100"))
+}
+
+func TestUpdateModelAttributeInUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockModelRepository(ctrl)
+	devicesRepo := deviceMock.NewMockRepository(ctrl)
+
+	model := &catalog.Model{Name: "RT-100", Vendor: "acme", Attributes: []string{"firmware"}}
+	device := &devices.Device{SerialNum: "1", Model: "RT-100", Attributes: map[string]string{"firmware": "1.2"}}
+
+	repo.EXPECT().Get("RT-100").Return(model, nil).Times(1)
+	devicesRepo.EXPECT().ListByModel("RT-100").Return([]*devices.Device{device}, nil).Times(1)
+
+	service := NewModelService(repo, devicesRepo, NewReferences())
+
+	err := service.UpdateModel(&catalog.Model{Name: "RT-100", Vendor: "acme"})
+	require.IsType(t, &errors.ConflictError{}, err)
+}
+
+func TestCreateDeviceChecksModel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	modelRepo := deviceMock.NewMockModelRepository(ctrl)
+
+	model := &catalog.Model{Name: "RT-100", Vendor: "acme", Attributes: []string{"firmware"}}
+	modelRepo.EXPECT().Get("RT-100").Return(model, nil).AnyTimes()
+	modelRepo.EXPECT().Get(gomock.Any()).Return(nil, errors.NewEntityNotFoundError("model", "Name", "")).AnyTimes()
+	modelRepo.EXPECT().List().Return([]*catalog.Model{model}, nil).AnyTimes()
+
+	device := &devices.Device{SerialNum: "1", Model: "RT-100", Attributes: map[string]string{"firmware": "1.2"}}
+	repo.EXPECT().Create(device).Return(nil).Times(1)
+
+	app := NewService(repo, WithModels(modelRepo))
+
+	require.NoError(t, app.CreateDevice(device))
+
+	err := app.CreateDevice(&devices.Device{SerialNum: "2", Model: "rt100"})
+	require.IsType(t, &errors.ValidationError{}, err)
+	require.Contains(t, err.Error(), `did you mean "RT-100"`)
+
+	require.IsType(t, &errors.ValidationError{}, app.CreateDevice(&devices.Device{SerialNum: "3", Model: "XX-1"}))
+	require.IsType(t, &errors.ValidationError{}, app.CreateDevice(&devices.Device{
+		SerialNum:  "4",
+		Model:      "RT-100",
+		Attributes: map[string]string{"color": "red"},
+	}))
+	require.IsType(t, &errors.ValidationError{}, app.CreateDevice(&devices.Device{
+		SerialNum:  "5",
+		Attributes: map[string]string{"firmware": "1.2"},
+	}))
+}
@@ -0,0 +1,10 @@
+package app
+
+import (
+	"homework/internal/reachability"
+)
+
+//go:generate mockgen -package internal -destination ../mocks/reachability.go . ReachabilityService
+type ReachabilityService interface {
+	Report(string) (*reachability.Report, error)
+}
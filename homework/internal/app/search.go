@@ -0,0 +1,46 @@
+package app
+
+import (
+	"strings"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/search"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// SearchDevices finds devices by parts of, or typos in, their serial
+// number, model or ip, best matches first.
+func (ds *deviceService) SearchDevices(query string, limit int) ([]devices.SearchHit, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.NewValidationError("search query is required")
+	}
+
+	if limit < 0 || limit > maxSearchLimit {
+		return nil, errors.NewValidationError("search limit must be between 1 and %d", maxSearchLimit)
+	}
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+
+	return SearchRepository(ds.repo, query, limit)
+}
+
+// SearchRepository uses the text index of the repository when it has one
+// and ranks all devices otherwise.
+func SearchRepository(repo Repository, query string, limit int) ([]devices.SearchHit, error) {
+	if searcher, ok := repo.(Searcher); ok {
+		return searcher.Search(query, limit)
+	}
+
+	list, err := repo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	return search.SearchDevices(list, query, limit), nil
+}
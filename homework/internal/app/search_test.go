@@ -0,0 +1,38 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestSearchDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	router := &devices.Device{SerialNum: "SN-0001", Model: "RT-100"}
+	sw := &devices.Device{SerialNum: "SN-0002", Model: "SW-48"}
+	repo.EXPECT().List().Return([]*devices.Device{router, sw}, nil).Times(2)
+
+	app := NewService(repo)
+
+	hits, err := app.SearchDevices("rt", 0)
+	require.NoError(t, err)
+	require.Equal(t, []devices.SearchHit{{Device: router, Score: 2}}, hits)
+
+	hits, err = app.SearchDevices("sn-000", 1)
+	require.NoError(t, err)
+	require.Len(t, hits, 1)
+
+	_, err = app.SearchDevices(" ", 0)
+	require.IsType(t, &errors.ValidationError{}, err)
+
+	_, err = app.SearchDevices("rt", 1000)
+	require.IsType(t, &errors.ValidationError{}, err)
+}
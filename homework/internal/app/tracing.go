@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"homework/internal/devices"
+	"homework/internal/filter"
+	"homework/internal/labels"
+	"homework/internal/tracing"
+)
+
+// ContextBinder is implemented by services that trace their work. The
+// bound service records its spans as children of the span of the context.
+type ContextBinder interface {
+	WithContext(ctx context.Context) Service
+}
+
+// WithTracer records a span for every service method and every call to
+// the device repository.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(ds *deviceService) {
+		ds.tracer = tracer
+	}
+}
+
+func (ds *deviceService) WithContext(ctx context.Context) Service {
+	if ds.tracer == nil {
+		return ds
+	}
+
+	return &tracedService{ds: ds, ctx: ctx}
+}
+
+// bind returns a copy of the service whose repository calls are children
+// of the span of the context.
+func (ds *deviceService) bind(ctx context.Context) *deviceService {
+	bound := *ds
+	bound.repo = &tracedRepository{repo: ds.repo, tracer: ds.tracer, ctx: ctx}
+
+	return &bound
+}
+
+type tracedService struct {
+	ds  *deviceService
+	ctx context.Context
+}
+
+func (s *tracedService) start(name string, attrs ...attribute.KeyValue) (*deviceService, trace.Span) {
+	ctx, span := s.ds.tracer.Start(s.ctx, "deviceService."+name, trace.WithAttributes(attrs...))
+	return s.ds.bind(ctx), span
+}
+
+func (s *tracedService) GetDevice(serialNum string) (device *devices.Device, err error) {
+	ds, span := s.start("GetDevice", serialAttr(serialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return ds.GetDevice(serialNum)
+}
+
+func (s *tracedService) ListDevices(filter devices.Filter) (list []*devices.Device, err error) {
+	ds, span := s.start("ListDevices")
+	defer func() {
+		span.SetAttributes(attribute.Int("devices.count", len(list)))
+		tracing.End(span, err)
+	}()
+
+	return ds.ListDevices(filter)
+}
+
+func (s *tracedService) CreateDevice(device *devices.Device) (err error) {
+	ds, span := s.start("CreateDevice", serialAttr(device.SerialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return ds.CreateDevice(device)
+}
+
+func (s *tracedService) DeleteDevice(serialNum string) (err error) {
+	ds, span := s.start("DeleteDevice", serialAttr(serialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return ds.DeleteDevice(serialNum)
+}
+
+func (s *tracedService) UpdateDevice(device *devices.Device) (err error) {
+	ds, span := s.start("UpdateDevice", serialAttr(device.SerialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return ds.UpdateDevice(device)
+}
+
+func (s *tracedService) TransitionDevice(serialNum string, status devices.Status, reason string) (device *devices.Device, err error) {
+	ds, span := s.start("TransitionDevice", serialAttr(serialNum), attribute.String("device.status", string(status)))
+	defer func() { tracing.End(span, err) }()
+
+	return ds.TransitionDevice(serialNum, status, reason)
+}
+
+func (s *tracedService) ListTransitions(serialNum string) (list []devices.Transition, err error) {
+	ds, span := s.start("ListTransitions", serialAttr(serialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return ds.ListTransitions(serialNum)
+}
+
+func (s *tracedService) SearchDevices(query string, limit int) (hits []devices.SearchHit, err error) {
+	ds, span := s.start("SearchDevices")
+	defer func() {
+		span.SetAttributes(attribute.Int("devices.count", len(hits)))
+		tracing.End(span, err)
+	}()
+
+	return ds.SearchDevices(query, limit)
+}
+
+func serialAttr(serialNum string) attribute.KeyValue {
+	return attribute.String("device.serial_num", serialNum)
+}
+
+// tracedRepository records a span for every call. It implements every
+// optional repository interface, falling back like the app helpers do.
+type tracedRepository struct {
+	repo   Repository
+	tracer trace.Tracer
+	ctx    context.Context
+}
+
+func (r *tracedRepository) start(name string, attrs ...attribute.KeyValue) trace.Span {
+	_, span := r.tracer.Start(r.ctx, "repository."+name, trace.WithAttributes(attrs...))
+	return span
+}
+
+func (r *tracedRepository) Get(serialNum string) (device *devices.Device, err error) {
+	span := r.start("Get", serialAttr(serialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return r.repo.Get(serialNum)
+}
+
+func (r *tracedRepository) List() (list []*devices.Device, err error) {
+	span := r.start("List")
+	defer func() { tracing.End(span, err) }()
+
+	return r.repo.List()
+}
+
+func (r *tracedRepository) ListByIP(ip string) (list []*devices.Device, err error) {
+	span := r.start("ListByIP")
+	defer func() { tracing.End(span, err) }()
+
+	return r.repo.ListByIP(ip)
+}
+
+func (r *tracedRepository) ListByModel(model string) (list []*devices.Device, err error) {
+	span := r.start("ListByModel")
+	defer func() { tracing.End(span, err) }()
+
+	return r.repo.ListByModel(model)
+}
+
+func (r *tracedRepository) Create(device *devices.Device) (err error) {
+	span := r.start("Create", serialAttr(device.SerialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return r.repo.Create(device)
+}
+
+func (r *tracedRepository) Delete(serialNum string) (err error) {
+	span := r.start("Delete", serialAttr(serialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return r.repo.Delete(serialNum)
+}
+
+func (r *tracedRepository) Update(device *devices.Device) (err error) {
+	span := r.start("Update", serialAttr(device.SerialNum))
+	defer func() { tracing.End(span, err) }()
+
+	return r.repo.Update(device)
+}
+
+func (r *tracedRepository) ListByLocation(id string) (list []*devices.Device, err error) {
+	span := r.start("ListByLocation")
+	defer func() { tracing.End(span, err) }()
+
+	return ListDevicesByLocation(r.repo, id)
+}
+
+func (r *tracedRepository) ListBySelector(selector labels.Selector) (list []*devices.Device, err error) {
+	span := r.start("ListBySelector")
+	defer func() { tracing.End(span, err) }()
+
+	return ListDevicesBySelector(r.repo, selector)
+}
+
+func (r *tracedRepository) ListByFilter(expr filter.Expr) (list []*devices.Device, err error) {
+	span := r.start("ListByFilter")
+	defer func() { tracing.End(span, err) }()
+
+	return ListDevicesByFilter(r.repo, expr)
+}
+
+func (r *tracedRepository) Search(query string, limit int) (hits []devices.SearchHit, err error) {
+	span := r.start("Search")
+	defer func() { tracing.End(span, err) }()
+
+	return SearchRepository(r.repo, query, limit)
+}
+
+func (r *tracedRepository) Count() (count int, err error) {
+	span := r.start("Count")
+	defer func() { tracing.End(span, err) }()
+
+	return CountDevices(r.repo)
+}
+
+func (r *tracedRepository) Snapshot() (list []*devices.Device, err error) {
+	span := r.start("Snapshot")
+	defer func() { tracing.End(span, err) }()
+
+	return SnapshotDevices(r.repo)
+}
+
+func (r *tracedRepository) Replace(list []*devices.Device) (err error) {
+	span := r.start("Replace")
+	defer func() { tracing.End(span, err) }()
+
+	return ReplaceDevices(r.repo, list)
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestTracedService(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+
+	device := &devices.Device{SerialNum: "1", Model: "RT-100"}
+	repo.EXPECT().Get("1").Return(device, nil).Times(1)
+	repo.EXPECT().Get("2").Return(nil, errors.NewNotFoundError("2")).Times(1)
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	service := NewService(repo, WithTracer(tracer))
+
+	ctx, root := tracer.Start(context.Background(), "GET /devices/{id}")
+	bound := service.(ContextBinder).WithContext(ctx)
+
+	actual, err := bound.GetDevice("1")
+	require.NoError(t, err)
+	require.Equal(t, device, actual)
+
+	_, err = bound.GetDevice("2")
+	require.Error(t, err)
+	root.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 5)
+
+	require.Equal(t, "repository.Get", spans[0].Name())
+	require.Equal(t, "deviceService.GetDevice", spans[1].Name())
+	require.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, root.SpanContext().SpanID(), spans[1].Parent().SpanID())
+	require.Contains(t, spans[1].Attributes(), attribute.String("device.serial_num", "1"))
+
+	require.Contains(t, spans[2].Attributes(), attribute.String("error.type", "*errors.NotFoundError"))
+	require.Contains(t, spans[3].Attributes(), attribute.String("error.type", "*errors.NotFoundError"))
+	require.Equal(t, spans[3].SpanContext().SpanID(), spans[2].Parent().SpanID())
+
+	// Without a tracer the service is not wrapped.
+	untraced := NewService(repo)
+	require.Equal(t, untraced, untraced.(ContextBinder).WithContext(ctx))
+}
@@ -0,0 +1,188 @@
+package app
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+)
+
+//go:generate mockgen -package internal -destination ../mocks/versions.go . VersionRepository,VersionService
+type VersionRepository interface {
+	// Append stores the next version of a device, numbering it after the
+	// last one and dropping versions beyond the retention.
+	Append(devices.Version) error
+	// List returns the retained versions of a device, oldest first.
+	List(string) ([]devices.Version, error)
+}
+
+type VersionService interface {
+	ListVersions(string) ([]devices.Version, error)
+	// GetDeviceAsOf returns the device as it was at the given time.
+	GetDeviceAsOf(serialNum string, at time.Time) (*devices.Device, error)
+	DiffVersions(serialNum string, from, to int) ([]devices.Change, error)
+}
+
+type versionService struct {
+	repo VersionRepository
+}
+
+func NewVersionService(repo VersionRepository) VersionService {
+	return &versionService{
+		repo: repo,
+	}
+}
+
+// WithVersions makes the service keep a version of a device on every
+// create, update, transition and delete.
+func WithVersions(repo VersionRepository) Option {
+	return func(ds *deviceService) {
+		ds.versions = repo
+	}
+}
+
+func (vs *versionService) ListVersions(serialNum string) ([]devices.Version, error) {
+	return vs.repo.List(serialNum)
+}
+
+func (vs *versionService) GetDeviceAsOf(serialNum string, at time.Time) (*devices.Device, error) {
+	versions, err := vs.repo.List(serialNum)
+	if err != nil {
+		return nil, err
+	}
+
+	// The last version taken at or before the time describes the device.
+	i := sort.Search(len(versions), func(i int) bool {
+		return versions[i].At.After(at)
+	})
+	if i == 0 || versions[i-1].Deleted {
+		return nil, errors.NewEntityNotFoundError("device", "SerialNum", serialNum+" at "+at.Format(time.RFC3339))
+	}
+
+	return versions[i-1].Device, nil
+}
+
+func (vs *versionService) DiffVersions(serialNum string, from, to int) ([]devices.Change, error) {
+	versions, err := vs.repo.List(serialNum)
+	if err != nil {
+		return nil, err
+	}
+
+	var fromVersion, toVersion *devices.Version
+	for i := range versions {
+		if versions[i].Number == from {
+			fromVersion = &versions[i]
+		}
+		if versions[i].Number == to {
+			toVersion = &versions[i]
+		}
+	}
+
+	if fromVersion == nil {
+		return nil, errors.NewEntityNotFoundError("version", "Number", strconv.Itoa(from))
+	}
+	if toVersion == nil {
+		return nil, errors.NewEntityNotFoundError("version", "Number", strconv.Itoa(to))
+	}
+
+	return diffDevices(fromVersion.Device, toVersion.Device), nil
+}
+
+// recordVersion keeps a copy of the device, so later changes to the
+// caller's device do not rewrite history. A nil device records a delete.
+func (ds *deviceService) recordVersion(serialNum string, device *devices.Device) error {
+	if ds.versions == nil {
+		return nil
+	}
+
+	version := devices.Version{
+		SerialNum: serialNum,
+		At:        ds.now(),
+		Deleted:   device == nil,
+	}
+	if device != nil {
+		version.Device = cloneDevice(device)
+	}
+
+	return ds.versions.Append(version)
+}
+
+func cloneDevice(device *devices.Device) *devices.Device {
+	clone := *device
+	clone.Labels = cloneMap(device.Labels)
+	clone.Attributes = cloneMap(device.Attributes)
+
+	return &clone
+}
+
+func cloneMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	clone := make(map[string]string, len(m))
+	for key, value := range m {
+		clone[key] = value
+	}
+
+	return clone
+}
+
+// diffDevices lists the changed fields in a stable order, a nil device
+// has every field empty.
+func diffDevices(from, to *devices.Device) []devices.Change {
+	if from == nil {
+		from = &devices.Device{}
+	}
+	if to == nil {
+		to = &devices.Device{}
+	}
+
+	changes := []devices.Change{}
+	add := func(field, old, cur string) {
+		if old != cur {
+			changes = append(changes, devices.Change{Field: field, From: old, To: cur})
+		}
+	}
+
+	add("serial_num", from.SerialNum, to.SerialNum)
+	add("model", from.Model, to.Model)
+	add("ip", from.IP, to.IP)
+	add("location_id", from.LocationID, to.LocationID)
+	add("position", positionString(from.Position), positionString(to.Position))
+	add("status", string(from.Status), string(to.Status))
+
+	for _, key := range unionKeys(from.Labels, to.Labels) {
+		add("labels."+key, from.Labels[key], to.Labels[key])
+	}
+	for _, key := range unionKeys(from.Attributes, to.Attributes) {
+		add("attributes."+key, from.Attributes[key], to.Attributes[key])
+	}
+
+	return changes
+}
+
+func positionString(position int) string {
+	if position == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(position)
+}
+
+func unionKeys(a, b map[string]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
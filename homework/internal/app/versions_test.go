@@ -0,0 +1,119 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestRecordVersions(t *testing.T) {
+	const testSeqNum1 = "test 1"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockRepository(ctrl)
+	versions := deviceMock.NewMockVersionRepository(ctrl)
+
+	now := time.Unix(100, 0)
+	device := &devices.Device{SerialNum: testSeqNum1, IP: "10.0.0.1", Labels: map[string]string{"env": "prod"}}
+	snapshot := &devices.Device{SerialNum: testSeqNum1, IP: "10.0.0.1", Status: devices.StatusOrdered, Labels: map[string]string{"env": "prod"}}
+
+	repo.EXPECT().Create(device).Return(nil).Times(1)
+	repo.EXPECT().Delete(testSeqNum1).Return(nil).Times(1)
+	versions.EXPECT().Append(devices.Version{SerialNum: testSeqNum1, At: now, Device: snapshot}).Return(nil).Times(1)
+	versions.EXPECT().Append(devices.Version{SerialNum: testSeqNum1, At: now, Deleted: true}).Return(nil).Times(1)
+
+	ds := NewService(repo, WithVersions(versions)).(*deviceService)
+	ds.now = func() time.Time { return now }
+
+	require.NoError(t, ds.CreateDevice(device))
+	// Versions are copies, changing the device afterwards does not touch them.
+	device.Labels["env"] = "dev"
+	require.NoError(t, ds.DeleteDevice(testSeqNum1))
+}
+
+func TestGetDeviceAsOf(t *testing.T) {
+	const testSeqNum1 = "test 1"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockVersionRepository(ctrl)
+
+	first := &devices.Device{SerialNum: testSeqNum1, IP: "10.0.0.1"}
+	second := &devices.Device{SerialNum: testSeqNum1, IP: "10.0.0.2"}
+	repo.EXPECT().List(testSeqNum1).Return([]devices.Version{
+		{SerialNum: testSeqNum1, Number: 1, At: time.Unix(10, 0), Device: first},
+		{SerialNum: testSeqNum1, Number: 2, At: time.Unix(20, 0), Device: second},
+		{SerialNum: testSeqNum1, Number: 3, At: time.Unix(30, 0), Deleted: true},
+	}, nil).AnyTimes()
+
+	service := NewVersionService(repo)
+
+	cases := []struct {
+		at     int64
+		expect *devices.Device
+	}{
+		{at: 10, expect: first},
+		{at: 19, expect: first},
+		{at: 20, expect: second},
+		{at: 29, expect: second},
+	}
+
+	for _, tCase := range cases {
+		device, err := service.GetDeviceAsOf(testSeqNum1, time.Unix(tCase.at, 0))
+		require.NoError(t, err)
+		require.Equal(t, tCase.expect, device)
+	}
+
+	_, err := service.GetDeviceAsOf(testSeqNum1, time.Unix(5, 0))
+	require.IsType(t, &errors.NotFoundError{}, err)
+
+	_, err = service.GetDeviceAsOf(testSeqNum1, time.Unix(30, 0))
+	require.IsType(t, &errors.NotFoundError{}, err)
+}
+
+func TestDiffVersions(t *testing.T) {
+	const testSeqNum1 = "test 1"
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	repo := deviceMock.NewMockVersionRepository(ctrl)
+
+	repo.EXPECT().List(testSeqNum1).Return([]devices.Version{
+		{SerialNum: testSeqNum1, Number: 1, Device: &devices.Device{
+			SerialNum: testSeqNum1, IP: "10.0.0.1", Labels: map[string]string{"env": "prod", "team": "net"},
+		}},
+		{SerialNum: testSeqNum1, Number: 2, Device: &devices.Device{
+			SerialNum: testSeqNum1, IP: "10.0.0.2", Position: 4, Labels: map[string]string{"env": "dev"},
+		}},
+		{SerialNum: testSeqNum1, Number: 3, Deleted: true},
+	}, nil).AnyTimes()
+
+	service := NewVersionService(repo)
+
+	changes, err := service.DiffVersions(testSeqNum1, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []devices.Change{
+		{Field: "ip", From: "10.0.0.1", To: "10.0.0.2"},
+		{Field: "position", From: "", To: "4"},
+		{Field: "labels.env", From: "prod", To: "dev"},
+		{Field: "labels.team", From: "net", To: ""},
+	}, changes)
+
+	changes, err = service.DiffVersions(testSeqNum1, 2, 2)
+	require.NoError(t, err)
+	require.Empty(t, changes)
+
+	changes, err = service.DiffVersions(testSeqNum1, 2, 3)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	_, err = service.DiffVersions(testSeqNum1, 1, 7)
+	require.IsType(t, &errors.NotFoundError{}, err)
+}
@@ -0,0 +1,170 @@
+// Package backup reads and writes device backup archives.
+//
+// An archive is a gzip stream of JSON lines. The first line is the
+// Header, every following line is one device. The header carries the
+// format version and a SHA-256 checksum of the device lines, so a
+// truncated or modified archive is rejected on read. Readers of every
+// format version ever written are kept, so old backups stay restorable.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"time"
+
+	"homework/internal/devices"
+)
+
+const (
+	// Format identifies device backup archives.
+	Format = "devices-backup"
+	// Version is the format version written by Write.
+	Version = 1
+
+	// maxLine bounds a single line of an archive.
+	maxLine = 1 << 20
+)
+
+// Mode is how a restore treats devices missing from the backup.
+type Mode string
+
+const (
+	// Replace makes the store hold exactly the devices of the backup.
+	Replace Mode = "replace"
+	// Merge creates or overwrites the devices of the backup and keeps the
+	// others.
+	Merge Mode = "merge"
+)
+
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case Replace, Merge:
+		return Mode(s), nil
+	default:
+		return "", fmt.Errorf("unknown restore mode %q, use %s or %s", s, Replace, Merge)
+	}
+}
+
+type Header struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Devices   int       `json:"devices"`
+	// Checksum is the hex SHA-256 of the device lines.
+	Checksum string `json:"sha256"`
+}
+
+type Backup struct {
+	Header  Header
+	Devices []*devices.Device
+}
+
+var ErrChecksum = stderrors.New("backup: checksum mismatch")
+
+// Write writes an archive of the devices.
+func Write(w io.Writer, list []*devices.Device, createdAt time.Time) error {
+	var body bytes.Buffer
+
+	encoder := json.NewEncoder(&body)
+	for _, device := range list {
+		if err := encoder.Encode(device); err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+	}
+
+	sum := sha256.Sum256(body.Bytes())
+	header := Header{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: createdAt.UTC(),
+		Devices:   len(list),
+		Checksum:  hex.EncodeToString(sum[:]),
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(header); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	if _, err := body.WriteTo(zw); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+
+	return zw.Close()
+}
+
+// readers decode the device lines of each format version.
+var readers = map[int]func(*bufio.Scanner, Header) ([]*devices.Device, error){
+	1: readV1,
+}
+
+// Read reads and verifies an archive.
+func Read(r io.Reader) (*Backup, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("backup: not an archive: %w", err)
+	}
+	defer zr.Close()
+
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLine)
+
+	if !scanner.Scan() {
+		if err = scanner.Err(); err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+		return nil, stderrors.New("backup: missing header")
+	}
+
+	var header Header
+	if err = json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Format != Format {
+		return nil, stderrors.New("backup: not a device backup")
+	}
+
+	read, ok := readers[header.Version]
+	if !ok {
+		return nil, fmt.Errorf("backup: unsupported format version %d, this build reads up to %d", header.Version, Version)
+	}
+
+	list, err := read(scanner, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backup{Header: header, Devices: list}, nil
+}
+
+func readV1(scanner *bufio.Scanner, header Header) ([]*devices.Device, error) {
+	hash := sha256.New()
+	list := make([]*devices.Device, 0, header.Devices)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		hash.Write(line)
+		hash.Write([]byte{'\n'})
+
+		var device devices.Device
+		if err := json.Unmarshal(line, &device); err != nil {
+			return nil, fmt.Errorf("backup: device %d: %w", len(list)+1, err)
+		}
+		list = append(list, &device)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != header.Checksum {
+		return nil, ErrChecksum
+	}
+	if len(list) != header.Devices {
+		return nil, fmt.Errorf("backup: header lists %d devices, archive holds %d", header.Devices, len(list))
+	}
+
+	return list, nil
+}
@@ -0,0 +1,92 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+)
+
+func TestWriteRead(t *testing.T) {
+	list := []*devices.Device{
+		{SerialNum: "test 1", Model: "RT-100", IP: "10.0.0.1", Labels: map[string]string{"env": "prod"}},
+		{SerialNum: "test 2", Model: "SW-48", Status: devices.StatusActive},
+	}
+	createdAt := time.Date(2024, 5, 14, 9, 30, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, list, createdAt))
+
+	backup, err := Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, list, backup.Devices)
+	require.Equal(t, Format, backup.Header.Format)
+	require.Equal(t, Version, backup.Header.Version)
+	require.Equal(t, 2, backup.Header.Devices)
+	require.Equal(t, createdAt, backup.Header.CreatedAt)
+
+	buf.Reset()
+	require.NoError(t, Write(&buf, nil, createdAt))
+
+	backup, err = Read(&buf)
+	require.NoError(t, err)
+	require.Empty(t, backup.Devices)
+}
+
+// rewrite decompresses an archive, edits it and compresses it again.
+func rewrite(t *testing.T, archive []byte, edit func(string) string) io.Reader {
+	zr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(zr)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err = zw.Write([]byte(edit(string(plain))))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return &buf
+}
+
+func TestReadErrors(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, []*devices.Device{{SerialNum: "test 1", IP: "10.0.0.1"}}, time.Now()))
+	archive := buf.Bytes()
+
+	_, err := Read(rewrite(t, archive, func(s string) string {
+		return strings.Replace(s, "10.0.0.1", "10.0.0.2", 1)
+	}))
+	require.ErrorIs(t, err, ErrChecksum)
+
+	_, err = Read(rewrite(t, archive, func(s string) string {
+		return strings.Replace(s, `"version":1`, `"version":99`, 1)
+	}))
+	require.ErrorContains(t, err, "unsupported format version 99")
+
+	_, err = Read(rewrite(t, archive, func(s string) string {
+		return strings.Replace(s, Format, "something-else", 1)
+	}))
+	require.ErrorContains(t, err, "not a device backup")
+
+	_, err = Read(bytes.NewReader(archive[:len(archive)/2]))
+	require.Error(t, err)
+
+	_, err = Read(strings.NewReader("plain text"))
+	require.ErrorContains(t, err, "not an archive")
+}
+
+func TestParseMode(t *testing.T) {
+	mode, err := ParseMode("merge")
+	require.NoError(t, err)
+	require.Equal(t, Merge, mode)
+
+	_, err = ParseMode("append")
+	require.Error(t, err)
+}
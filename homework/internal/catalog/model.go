@@ -0,0 +1,43 @@
+package catalog
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Specs struct {
+	Ports      int `json:"ports,omitempty"`
+	RackUnits  int `json:"rack_units,omitempty"`
+	PowerWatts int `json:"power_watts,omitempty"`
+}
+
+type Model struct {
+	Name   string `json:"name"`
+	Vendor string `json:"vendor"`
+	Specs  Specs  `json:"specs"`
+	// Attributes lists the attribute keys devices of this model may set.
+	Attributes []string `json:"attributes,omitempty"`
+}
+
+func (m *Model) AllowsAttribute(key string) bool {
+	for _, attribute := range m.Attributes {
+		if attribute == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Normalize reduces a model name to lowercase letters and digits, so that
+// spellings like "RT-100", "rt100" and "RT 100" compare equal.
+func Normalize(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	return b.String()
+}
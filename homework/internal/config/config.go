@@ -0,0 +1,362 @@
+package config
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPort           = 8080
+	defaultReadTimeout    = 30 * time.Second
+	defaultWriteTimeout   = 30 * time.Second
+	defaultLogLevel       = "info"
+	defaultReloadInterval = 5 * time.Second
+	defaultCacheCapacity  = 1024
+	defaultCacheTTL       = time.Minute
+	defaultCacheNegTTL    = 5 * time.Second
+	defaultHeartbeatTTL   = 90 * time.Second
+	defaultSweepInterval  = 10 * time.Second
+	defaultProbeInterval  = time.Minute
+	defaultProbeJitter    = 10 * time.Second
+	defaultProbeTimeout   = 2 * time.Second
+	defaultProbeWorkers   = 16
+	defaultProbeHistory   = 20
+	defaultProbePort      = 22
+	defaultVersionsKept   = 100
+	defaultLogSize        = 10000
+	defaultChangesWait    = 20 * time.Second
+	defaultReplicaRetry   = time.Second
+	defaultRaftTick       = 100 * time.Millisecond
+	defaultElectionTicks  = 10
+	defaultHeartbeatTicks = 1
+	defaultSnapshotAfter  = 1000
+	defaultProposalTTL    = 5 * time.Second
+	defaultTraceExporter  = "stdout"
+	defaultServiceName    = "devices"
+	defaultAdminHost      = "127.0.0.1"
+	defaultAdminPort      = 9090
+	defaultIdemKeys       = 10000
+	defaultIdemTTL        = 24 * time.Hour
+	defaultGraphQLDepth   = 8
+	defaultGraphQLCost    = 1000
+)
+
+const (
+	roleStandalone = "standalone"
+	roleLeader     = "leader"
+	roleFollower   = "follower"
+)
+
+// Config is the whole service configuration. Fields tagged with
+// reload:"true" are applied to a running server on config reload,
+// the rest require a restart. Validation rules are declared in the
+// validate tag, see Validate.
+type Config struct {
+	Server       ServerConfig       `yaml:"server" validate:"required"`
+	Log          LogConfig          `yaml:"log"`
+	Reload       ReloadConfig       `yaml:"reload"`
+	Cache        CacheConfig        `yaml:"cache"`
+	Storage      StorageConfig      `yaml:"storage"`
+	IPAM         IPAMConfig         `yaml:"ipam"`
+	Catalog      CatalogConfig      `yaml:"catalog"`
+	Locations    LocationsConfig    `yaml:"locations"`
+	History      HistoryConfig      `yaml:"history"`
+	Heartbeat    HeartbeatConfig    `yaml:"heartbeat"`
+	Reachability ReachabilityConfig `yaml:"reachability"`
+	Tenancy      TenancyConfig      `yaml:"tenancy"`
+	Versions     VersionsConfig     `yaml:"versions"`
+	Backup       BackupConfig       `yaml:"backup"`
+	Replication  ReplicationConfig  `yaml:"replication"`
+	Cluster      ClusterConfig      `yaml:"cluster"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	Admin        AdminConfig        `yaml:"admin"`
+	Idempotency  IdempotencyConfig  `yaml:"idempotency"`
+	UI           UIConfig           `yaml:"ui"`
+	GraphQL      GraphQLConfig      `yaml:"graphql"`
+}
+
+type ServerConfig struct {
+	Host         string        `yaml:"host" validate:"host" usage:"address the http server listens on"`
+	Port         int           `yaml:"port" validate:"port" usage:"port the http server listens on"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" validate:"min=0s" usage:"maximum duration for reading a request" reload:"true"`
+	WriteTimeout time.Duration `yaml:"write_timeout" validate:"min=0s" usage:"maximum duration for writing a response" reload:"true"`
+}
+
+type LogConfig struct {
+	Level string `yaml:"level" validate:"oneof=debug|info|warn|error" usage:"log level: debug, info, warn or error" reload:"true"`
+}
+
+type ReloadConfig struct {
+	Interval time.Duration `yaml:"interval" validate:"min=0s" usage:"how often the config file is checked for changes, 0 disables polling"`
+}
+
+type CacheConfig struct {
+	Enabled     bool          `yaml:"enabled" usage:"cache device reads in front of the repository"`
+	Capacity    int           `yaml:"capacity" validate:"min=1" usage:"maximum number of cached devices"`
+	TTL         time.Duration `yaml:"ttl" validate:"min=1ms" usage:"how long a cached device is served"`
+	NegativeTTL time.Duration `yaml:"negative_ttl" validate:"min=1ms" usage:"how long a missing device is remembered"`
+}
+
+type StorageConfig struct {
+	Shards   int  `yaml:"shards" validate:"min=0" usage:"number of lock shards of the in-memory repository, 0 uses a single lock"`
+	UniqueIP bool `yaml:"unique_ip" usage:"reject devices whose ip is already used by another device"`
+}
+
+type IPAMConfig struct {
+	Enabled bool     `yaml:"enabled" usage:"allocate addresses for devices created without an ip"`
+	Subnets []string `yaml:"subnets" validate:"cidr" usage:"comma separated subnets created at start-up"`
+}
+
+type CatalogConfig struct {
+	Enforce bool `yaml:"enforce" usage:"reject devices whose model is not in the model catalog"`
+}
+
+type LocationsConfig struct {
+	Enabled bool `yaml:"enabled" usage:"keep the location tree devices are placed in"`
+}
+
+type HistoryConfig struct {
+	Enabled bool `yaml:"enabled" usage:"record the status transitions of devices"`
+}
+
+type HeartbeatConfig struct {
+	Enabled       bool          `yaml:"enabled" usage:"accept device heartbeats and track liveness"`
+	Timeout       time.Duration `yaml:"timeout" validate:"min=1s" usage:"silence after which a device is marked offline"`
+	SweepInterval time.Duration `yaml:"sweep_interval" validate:"min=1s" usage:"how often silent devices are looked for"`
+}
+
+type ReachabilityConfig struct {
+	Enabled     bool          `yaml:"enabled" usage:"periodically probe device ips with tcp connects"`
+	Interval    time.Duration `yaml:"interval" validate:"min=1s" usage:"how often every device is probed"`
+	Jitter      time.Duration `yaml:"jitter" validate:"min=0s" usage:"probes of a round are spread randomly over this duration"`
+	Timeout     time.Duration `yaml:"timeout" validate:"min=1ms" usage:"connect timeout of a probe"`
+	Workers     int           `yaml:"workers" validate:"min=1" usage:"maximum number of concurrent probes"`
+	History     int           `yaml:"history" validate:"min=1" usage:"number of probe results kept per device"`
+	DefaultPort int           `yaml:"default_port" validate:"port" usage:"port probed for models missing from ports"`
+	Ports       []string      `yaml:"ports" validate:"portmap" usage:"comma separated model=port pairs"`
+}
+
+// PortMap returns the probe port of every model listed in Ports. It
+// expects a validated config.
+func (c *ReachabilityConfig) PortMap() map[string]int {
+	ports := make(map[string]int, len(c.Ports))
+	for _, entry := range c.Ports {
+		model, port, _ := strings.Cut(entry, "=")
+		ports[model], _ = strconv.Atoi(port)
+	}
+
+	return ports
+}
+
+type VersionsConfig struct {
+	Enabled bool          `yaml:"enabled" usage:"keep a version of a device on every change for point-in-time reads"`
+	Keep    int           `yaml:"keep" validate:"min=0" usage:"number of versions kept per device, 0 keeps all"`
+	MaxAge  time.Duration `yaml:"max_age" validate:"min=0s" usage:"versions older than this are dropped, the latest version of a device is always kept, 0 keeps all"`
+}
+
+type BackupConfig struct {
+	Enabled bool `yaml:"enabled" usage:"serve GET /admin/backup and POST /admin/restore?mode=replace|merge on the admin listener"`
+}
+
+type ReplicationConfig struct {
+	Role    string        `yaml:"role" validate:"oneof=standalone|leader|follower" usage:"standalone, leader serving its change log, or follower of leader serving reads"`
+	Leader  string        `yaml:"leader" validate:"url" usage:"base url of the leader, writes sent to a follower are redirected there"`
+	LogSize int           `yaml:"log_size" validate:"min=1" usage:"number of changes kept by the leader, followers further behind reload all devices"`
+	Wait    time.Duration `yaml:"wait" validate:"min=1s" usage:"how long a follower request for changes waits on the leader"`
+	Retry   time.Duration `yaml:"retry" validate:"min=10ms" usage:"pause of a follower after a failed replication request"`
+}
+
+func (c *ReplicationConfig) Leading() bool {
+	return c.Role == roleLeader
+}
+
+func (c *ReplicationConfig) Following() bool {
+	return c.Role == roleFollower
+}
+
+type ClusterConfig struct {
+	Enabled         bool          `yaml:"enabled" usage:"replicate devices over a raft cluster of 3 to 5 nodes, writes are redirected to the elected leader"`
+	NodeID          string        `yaml:"node_id" usage:"id of this node, unique within the cluster"`
+	Address         string        `yaml:"address" validate:"url" usage:"base url other nodes reach this node at"`
+	Secret          string        `yaml:"secret" secret:"true" usage:"shared secret nodes send with raft messages in the X-Cluster-Secret header"`
+	DataDir         string        `yaml:"data_dir" usage:"directory the node saves its raft term, vote, log and snapshot in to rejoin the cluster after a restart"`
+	Peers           []string      `yaml:"peers" validate:"pairs" usage:"comma separated id=url pairs of the initial members including this node, empty to wait to be added to a running cluster"`
+	Tick            time.Duration `yaml:"tick" validate:"min=1ms" usage:"unit of the raft election and heartbeat timeouts"`
+	ElectionTicks   int           `yaml:"election_ticks" validate:"min=2" usage:"ticks without a leader before a node campaigns, randomized up to twice as long"`
+	HeartbeatTicks  int           `yaml:"heartbeat_ticks" validate:"min=1" usage:"ticks between heartbeats of the leader"`
+	SnapshotEntries int           `yaml:"snapshot_entries" validate:"min=1" usage:"applied log entries that trigger a snapshot compacting the log"`
+	ProposalTimeout time.Duration `yaml:"proposal_timeout" validate:"min=10ms" usage:"how long a write waits to be committed by the cluster"`
+}
+
+// Members returns the address of every initial member. It expects a
+// validated config.
+func (c *ClusterConfig) Members() map[string]string {
+	members := make(map[string]string, len(c.Peers))
+	for _, entry := range c.Peers {
+		id, address, _ := strings.Cut(entry, "=")
+		members[id] = address
+	}
+
+	return members
+}
+
+type TracingConfig struct {
+	Enabled     bool   `yaml:"enabled" usage:"record spans of requests, service methods and repository calls, continuing the trace of a traceparent header"`
+	Exporter    string `yaml:"exporter" validate:"oneof=stdout|otlp_file" usage:"stdout for one JSON line per span, otlp_file for OTLP JSON lines a collector can replay"`
+	File        string `yaml:"file" usage:"file the otlp_file exporter appends to"`
+	ServiceName string `yaml:"service_name" validate:"min=1" usage:"service.name resource attribute of exported spans"`
+}
+
+type AdminConfig struct {
+	Enabled bool   `yaml:"enabled" usage:"serve pprof, goroutine dumps, build info, the effective config, the log level, backups and the cluster membership on a separate listener"`
+	Host    string `yaml:"host" validate:"host" usage:"address the admin listener binds to, keep it private"`
+	Port    int    `yaml:"port" validate:"port" usage:"port of the admin listener, must differ from server.port"`
+}
+
+type IdempotencyConfig struct {
+	Enabled  bool          `yaml:"enabled" usage:"replay the response of a POST retried with the same Idempotency-Key header"`
+	TTL      time.Duration `yaml:"ttl" validate:"min=1s" usage:"how long the response of an idempotency key is kept"`
+	Capacity int           `yaml:"capacity" validate:"min=1" usage:"maximum number of idempotency keys kept per tenant"`
+}
+
+type UIConfig struct {
+	Enabled bool `yaml:"enabled" usage:"serve a web UI for browsing and editing devices under /ui/"`
+}
+
+type GraphQLConfig struct {
+	Enabled       bool `yaml:"enabled" usage:"serve devices, locations and models over GraphQL at /graphql"`
+	MaxDepth      int  `yaml:"max_depth" validate:"min=1" usage:"deepest nesting of fields a GraphQL query may select"`
+	MaxComplexity int  `yaml:"max_complexity" validate:"min=1" usage:"maximum number of fields a GraphQL query may resolve, fields below a list count once per item asked for with first"`
+}
+
+type TenancyConfig struct {
+	Enabled bool     `yaml:"enabled" usage:"keep separate devices per tenant, resolved from the api key or a /tenants/{tenant} path prefix"`
+	Tenants []string `yaml:"tenants" validate:"names" usage:"comma separated tenants served in addition to those of api_keys"`
+	APIKeys []string `yaml:"api_keys" validate:"pairs" secret:"names" usage:"comma separated key=tenant pairs, callers authenticate with the X-API-Key header, keys of tenants added on reload only work after a restart" reload:"true"`
+	// AllowPathTenants must be set to run tenancy without api keys, every
+	// tenant is then open to anyone who knows its /tenants/{tenant} path.
+	AllowPathTenants bool     `yaml:"allow_path_tenants" usage:"serve tenants by path prefix alone when no api_keys are set, without any authentication"`
+	Quota            int      `yaml:"quota" validate:"min=0" usage:"maximum number of devices of a tenant, 0 is unlimited"`
+	Quotas           []string `yaml:"quotas" validate:"pairs=int" usage:"comma separated tenant=limit pairs overriding quota"`
+}
+
+// APIKeyMap returns the tenant of every api key. It expects a validated
+// config.
+func (c *TenancyConfig) APIKeyMap() map[string]string {
+	keys := make(map[string]string, len(c.APIKeys))
+	for _, entry := range c.APIKeys {
+		key, tenant, _ := strings.Cut(entry, "=")
+		keys[key] = tenant
+	}
+
+	return keys
+}
+
+// TenantNames returns the sorted tenants listed in Tenants or APIKeys.
+func (c *TenancyConfig) TenantNames() []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(c.Tenants))
+
+	for _, tenant := range c.Tenants {
+		if !seen[tenant] {
+			seen[tenant] = true
+			names = append(names, tenant)
+		}
+	}
+
+	for _, tenant := range c.APIKeyMap() {
+		if !seen[tenant] {
+			seen[tenant] = true
+			names = append(names, tenant)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (c *TenancyConfig) QuotaFor(tenant string) int {
+	for _, entry := range c.Quotas {
+		name, limit, _ := strings.Cut(entry, "=")
+		if name == tenant {
+			n, _ := strconv.Atoi(limit)
+			return n
+		}
+	}
+
+	return c.Quota
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:         defaultPort,
+			ReadTimeout:  defaultReadTimeout,
+			WriteTimeout: defaultWriteTimeout,
+		},
+		Log: LogConfig{
+			Level: defaultLogLevel,
+		},
+		Reload: ReloadConfig{
+			Interval: defaultReloadInterval,
+		},
+		Cache: CacheConfig{
+			Capacity:    defaultCacheCapacity,
+			TTL:         defaultCacheTTL,
+			NegativeTTL: defaultCacheNegTTL,
+		},
+		Locations: LocationsConfig{
+			Enabled: true,
+		},
+		History: HistoryConfig{
+			Enabled: true,
+		},
+		Heartbeat: HeartbeatConfig{
+			Enabled:       true,
+			Timeout:       defaultHeartbeatTTL,
+			SweepInterval: defaultSweepInterval,
+		},
+		Reachability: ReachabilityConfig{
+			Interval:    defaultProbeInterval,
+			Jitter:      defaultProbeJitter,
+			Timeout:     defaultProbeTimeout,
+			Workers:     defaultProbeWorkers,
+			History:     defaultProbeHistory,
+			DefaultPort: defaultProbePort,
+		},
+		Versions: VersionsConfig{
+			Keep: defaultVersionsKept,
+		},
+		Replication: ReplicationConfig{
+			Role:    roleStandalone,
+			LogSize: defaultLogSize,
+			Wait:    defaultChangesWait,
+			Retry:   defaultReplicaRetry,
+		},
+		Cluster: ClusterConfig{
+			Tick:            defaultRaftTick,
+			ElectionTicks:   defaultElectionTicks,
+			HeartbeatTicks:  defaultHeartbeatTicks,
+			SnapshotEntries: defaultSnapshotAfter,
+			ProposalTimeout: defaultProposalTTL,
+		},
+		Tracing: TracingConfig{
+			Exporter:    defaultTraceExporter,
+			ServiceName: defaultServiceName,
+		},
+		Admin: AdminConfig{
+			Host: defaultAdminHost,
+			Port: defaultAdminPort,
+		},
+		Idempotency: IdempotencyConfig{
+			TTL:      defaultIdemTTL,
+			Capacity: defaultIdemKeys,
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      defaultGraphQLDepth,
+			MaxComplexity: defaultGraphQLCost,
+		},
+	}
+}
@@ -0,0 +1,501 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func newTestLoader(env map[string]string) *Loader {
+	loader := NewLoader("TEST")
+	loader.lookupEnv = func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	return loader
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, sources, err := newTestLoader(nil).Load("")
+
+	require.NoError(t, err)
+	require.Equal(t, Default(), cfg)
+	require.Equal(t, SourceDefault, sources["server.port"])
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  host: 127.0.0.1
+  port: 9000
+  read_timeout: 5s
+  write_timeout: 5s
+`)
+
+	loader := newTestLoader(map[string]string{
+		"TEST_SERVER_PORT":         "9001",
+		"TEST_SERVER_READ_TIMEOUT": "7s",
+	})
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader.BindFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-server.port", "9002"}))
+
+	cfg, sources, err := loader.Load(path)
+
+	require.NoError(t, err)
+	require.Equal(t, "127.0.0.1", cfg.Server.Host)
+	require.Equal(t, 9002, cfg.Server.Port)
+	require.Equal(t, 7*time.Second, cfg.Server.ReadTimeout)
+	require.Equal(t, 5*time.Second, cfg.Server.WriteTimeout)
+
+	require.Equal(t, SourceFile, sources["server.host"])
+	require.Equal(t, SourceFlag, sources["server.port"])
+	require.Equal(t, SourceEnv, sources["server.read_timeout"])
+	require.Equal(t, SourceFile, sources["server.write_timeout"])
+}
+
+func TestLoadExpandsEnv(t *testing.T) {
+	t.Setenv("TEST_CONFIG_HOST", "10.0.0.1")
+
+	path := writeConfig(t, `
+server:
+  host: ${TEST_CONFIG_HOST}
+`)
+
+	cfg, _, err := newTestLoader(nil).Load(path)
+
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", cfg.Server.Host)
+}
+
+func TestLoadNormalizesLogLevel(t *testing.T) {
+	cfg, _, err := newTestLoader(map[string]string{"TEST_LOG_LEVEL": "INFO"}).Load("")
+
+	require.NoError(t, err)
+	require.Equal(t, "info", cfg.Log.Level)
+}
+
+func TestLoadKeepsBareDollar(t *testing.T) {
+	t.Setenv("TEST_CONFIG_NAME", "expanded")
+
+	path := writeConfig(t, `
+server:
+  port: 8080
+tracing:
+  service_name: devices-$TEST_CONFIG_NAME-${TEST_CONFIG_NAME}
+`)
+
+	cfg, _, err := newTestLoader(nil).Load(path)
+
+	require.NoError(t, err)
+	require.Equal(t, "devices-$TEST_CONFIG_NAME-expanded", cfg.Tracing.ServiceName)
+}
+
+func TestLoadLegacyLayout(t *testing.T) {
+	path := writeConfig(t, `
+port: 9000
+read_timeout: 5s
+log:
+  level: debug
+`)
+
+	loader := newTestLoader(nil)
+	cfg, sources, err := loader.Load(path)
+
+	require.NoError(t, err)
+	require.Equal(t, 9000, cfg.Server.Port)
+	require.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+	require.Equal(t, SourceFile, sources["server.port"])
+	require.Len(t, loader.Warnings(), 2)
+	require.Contains(t, loader.Warnings()[0], "key port is deprecated, use server.port")
+
+	_, _, err = loader.Load(writeConfig(t, "port: 9000\nserver:\n  port: 9001\n"))
+	require.ErrorContains(t, err, "key port is also set as server.port")
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		env     map[string]string
+	}{
+		{
+			name:    "unknown key",
+			content: "server:\n  prot: 8080\n",
+		},
+		{
+			name:    "unknown section",
+			content: "servre:\n  port: 8080\n",
+		},
+		{
+			name:    "invalid duration",
+			content: "server:\n  read_timeout: fast\n",
+		},
+		{
+			name:    "missing required section",
+			content: "log:\n  level: info\n",
+		},
+		{
+			name:    "section is not a mapping",
+			content: "server: 8080\n",
+		},
+		{
+			name:    "invalid env value",
+			content: "server:\n  port: 8080\n",
+			env:     map[string]string{"TEST_SERVER_PORT": "http"},
+		},
+	}
+
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			_, _, err := newTestLoader(tCase.env).Load(writeConfig(t, tCase.content))
+
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, _, err := newTestLoader(nil).Load(filepath.Join(t.TempDir(), "missing.yaml"))
+
+	require.Error(t, err)
+}
+
+func TestPrint(t *testing.T) {
+	loader := newTestLoader(map[string]string{"TEST_SERVER_HOST": "localhost"})
+
+	cfg, sources, err := loader.Load("")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Print(&buf, cfg, sources))
+
+	require.Contains(t, buf.String(), `server.host`)
+	require.Contains(t, buf.String(), `"localhost"`)
+	require.Contains(t, buf.String(), `(env)`)
+	require.Contains(t, buf.String(), `30s`)
+}
+
+func TestSecretsRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Tenancy.APIKeys = []string{"k1=ops", "k2=lab"}
+
+	var buf bytes.Buffer
+	require.NoError(t, Print(&buf, cfg, Sources{}))
+	require.NotContains(t, buf.String(), "k1")
+	require.Contains(t, buf.String(), "[redacted]=ops,[redacted]=lab")
+
+	require.Contains(t, Values(cfg), Value{Key: "tenancy.api_keys", Value: "[redacted]=ops,[redacted]=lab", Reloadable: true})
+
+	cur := Default()
+	cur.Tenancy.APIKeys = []string{"k3=ops"}
+	require.Equal(t, []Change{
+		{Key: "tenancy.api_keys", Old: "[redacted]=ops,[redacted]=lab", New: "[redacted]=ops", Reloadable: true},
+	}, Diff(cfg, cur))
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Default().Validate())
+
+	cfg := Default()
+	cfg.Server.Port = 0
+	cfg.Server.WriteTimeout = -time.Second
+	cfg.Log.Level = "loud"
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "server.port")
+	require.Contains(t, err.Error(), "server.write_timeout")
+	require.Contains(t, err.Error(), "log.level")
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	path := writeConfig(t, `
+server:
+  prot: 8080
+  host: 0.0.0.0
+  read_timeout: fast
+  write_timeout: 5
+logs:
+  level: info
+`)
+
+	_, _, err := newTestLoader(map[string]string{
+		"TEST_SERVER_PORT": "http",
+		"TEST_LOG_LEVEL":   "trace",
+	}).Load(path)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown key server.prot")
+	require.Contains(t, err.Error(), "unknown key logs")
+	require.Contains(t, err.Error(), `server.read_timeout: invalid duration "fast"`)
+	require.Contains(t, err.Error(), `server.write_timeout: invalid duration "5"`)
+	require.Contains(t, err.Error(), "TEST_SERVER_PORT")
+	require.Contains(t, err.Error(), `log.level: "trace" must be one of`)
+}
+
+func TestValidateRules(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(cfg *Config)
+		errMsg string
+	}{
+		{
+			name:   "port too big",
+			modify: func(cfg *Config) { cfg.Server.Port = 70000 },
+			errMsg: "server.port: 70000 is out of range 1-65535",
+		},
+		{
+			name:   "invalid host",
+			modify: func(cfg *Config) { cfg.Server.Host = "local host" },
+			errMsg: "server.host",
+		},
+		{
+			name:   "negative timeout",
+			modify: func(cfg *Config) { cfg.Server.ReadTimeout = -time.Second },
+			errMsg: "server.read_timeout: -1s is less than 0s",
+		},
+		{
+			name:   "unknown log level",
+			modify: func(cfg *Config) { cfg.Log.Level = "trace" },
+			errMsg: `log.level: "trace" must be one of debug, info, warn, error`,
+		},
+		{
+			name:   "invalid probe port",
+			modify: func(cfg *Config) { cfg.Reachability.Ports = []string{"RT-100=22", "SW-48"} },
+			errMsg: `reachability.ports: "SW-48" is not a name=port pair`,
+		},
+		{
+			name:   "invalid tenant quota",
+			modify: func(cfg *Config) { cfg.Tenancy.Quotas = []string{"team-a=-1"} },
+			errMsg: `tenancy.quotas: "team-a=-1" must have a non-negative integer value`,
+		},
+		{
+			name:   "invalid api key tenant",
+			modify: func(cfg *Config) { cfg.Tenancy.APIKeys = []string{"secret=Team A"} },
+			errMsg: `tenancy.api_keys: tenant "Team A" must be a lowercase dns label`,
+		},
+		{
+			name:   "tenancy without tenants",
+			modify: func(cfg *Config) { cfg.Tenancy.Enabled = true },
+			errMsg: "tenancy.tenants: at least one tenant is required",
+		},
+		{
+			name: "tenancy without api keys",
+			modify: func(cfg *Config) {
+				cfg.Tenancy.Enabled = true
+				cfg.Tenancy.Tenants = []string{"ops"}
+			},
+			errMsg: "tenancy.api_keys: api keys are required unless allow_path_tenants is set",
+		},
+		{
+			name:   "backup without admin",
+			modify: func(cfg *Config) { cfg.Backup.Enabled = true },
+			errMsg: "backup.enabled: backups are served on the admin listener",
+		},
+		{
+			name:   "follower without leader",
+			modify: func(cfg *Config) { cfg.Replication.Role = "follower" },
+			errMsg: "replication.leader: the leader url is required for followers",
+		},
+		{
+			name: "relative leader url",
+			modify: func(cfg *Config) {
+				cfg.Replication.Role = "follower"
+				cfg.Replication.Leader = "leader:8080"
+			},
+			errMsg: `replication.leader: "leader:8080" is not an absolute http or https url`,
+		},
+		{
+			name: "replication with tenancy",
+			modify: func(cfg *Config) {
+				cfg.Replication.Role = "leader"
+				cfg.Tenancy.Enabled = true
+				cfg.Tenancy.Tenants = []string{"ops"}
+			},
+			errMsg: "replication.role: replication is not supported with tenancy",
+		},
+		{
+			name: "replication with versions",
+			modify: func(cfg *Config) {
+				cfg.Replication.Role = "leader"
+				cfg.Versions.Enabled = true
+			},
+			errMsg: "versions.enabled: not supported with replication, only devices are replicated",
+		},
+		{
+			name:   "cluster without node",
+			modify: func(cfg *Config) { cfg.Cluster.Enabled = true },
+			errMsg: "cluster.node_id: the node id is required for clustering\ncluster.address: the address is required for clustering",
+		},
+		{
+			name: "cluster without secret",
+			modify: func(cfg *Config) {
+				cfg.Cluster.Enabled = true
+				cfg.Cluster.NodeID = "a"
+				cfg.Cluster.Address = "http://a:8080"
+			},
+			errMsg: "cluster.secret: the shared secret is required for clustering",
+		},
+		{
+			name: "cluster without data dir",
+			modify: func(cfg *Config) {
+				cfg.Cluster.Enabled = true
+				cfg.Cluster.NodeID = "a"
+				cfg.Cluster.Address = "http://a:8080"
+				cfg.Cluster.Secret = "s3cret"
+			},
+			errMsg: "cluster.data_dir: the data directory is required for clustering",
+		},
+		{
+			name: "cluster peers without node",
+			modify: func(cfg *Config) {
+				cfg.Cluster.Enabled = true
+				cfg.Cluster.NodeID = "a"
+				cfg.Cluster.Address = "http://a:8080"
+				cfg.Cluster.Peers = []string{"b=http://b:8080", "c=http://c:8080"}
+			},
+			errMsg: `cluster.peers: the initial members must include node "a"`,
+		},
+		{
+			name: "cluster heartbeat slower than election",
+			modify: func(cfg *Config) {
+				cfg.Cluster.Enabled = true
+				cfg.Cluster.NodeID = "a"
+				cfg.Cluster.Address = "http://a:8080"
+				cfg.Cluster.HeartbeatTicks = 10
+			},
+			errMsg: "cluster.heartbeat_ticks: 10 must be less than election_ticks 10",
+		},
+		{
+			name: "cluster with replication",
+			modify: func(cfg *Config) {
+				cfg.Cluster.Enabled = true
+				cfg.Cluster.NodeID = "a"
+				cfg.Cluster.Address = "http://a:8080"
+				cfg.Replication.Role = "leader"
+			},
+			errMsg: "cluster.enabled: clustering is not supported with replication",
+		},
+		{
+			name: "cluster with ipam",
+			modify: func(cfg *Config) {
+				cfg.Cluster.Enabled = true
+				cfg.IPAM.Enabled = true
+			},
+			errMsg: "ipam.enabled: not supported with clustering, only devices are replicated",
+		},
+		{
+			name:   "cluster with locations",
+			modify: func(cfg *Config) { cfg.Cluster.Enabled = true },
+			errMsg: "locations.enabled: not supported with clustering, only devices are replicated",
+		},
+		{
+			name:   "follower with history",
+			modify: func(cfg *Config) { cfg.Replication.Role = "follower" },
+			errMsg: "history.enabled: not supported with replication, only devices are replicated",
+		},
+		{
+			name:   "leader with heartbeats",
+			modify: func(cfg *Config) { cfg.Replication.Role = "leader" },
+			errMsg: "heartbeat.enabled: not supported with replication, only devices are replicated",
+		},
+		{
+			name: "otlp file exporter without file",
+			modify: func(cfg *Config) {
+				cfg.Tracing.Enabled = true
+				cfg.Tracing.Exporter = "otlp_file"
+			},
+			errMsg: "tracing.file: the file is required for the otlp_file exporter",
+		},
+		{
+			name:   "unknown exporter",
+			modify: func(cfg *Config) { cfg.Tracing.Exporter = "jaeger" },
+			errMsg: `tracing.exporter: "jaeger" must be one of stdout, otlp_file`,
+		},
+		{
+			name: "admin on the server port",
+			modify: func(cfg *Config) {
+				cfg.Admin.Enabled = true
+				cfg.Admin.Port = cfg.Server.Port
+			},
+			errMsg: "admin.port: 8080 is already used by server.port",
+		},
+		{
+			name:   "idempotency window too short",
+			modify: func(cfg *Config) { cfg.Idempotency.TTL = time.Millisecond },
+			errMsg: "idempotency.ttl: 1ms is less than 1s",
+		},
+		{
+			name:   "graphql without depth limit",
+			modify: func(cfg *Config) { cfg.GraphQL.MaxDepth = 0 },
+			errMsg: "graphql.max_depth: 0 is less than 1",
+		},
+	}
+
+	for _, tCase := range cases {
+		t.Run(tCase.name, func(t *testing.T) {
+			cfg := Default()
+			tCase.modify(cfg)
+
+			err := cfg.Validate()
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tCase.errMsg)
+		})
+	}
+
+	cfg := Default()
+	cfg.Server.Host = "127.0.0.1"
+	require.NoError(t, cfg.Validate())
+
+	cfg.Server.Host = "devices.example.com"
+	require.NoError(t, cfg.Validate())
+
+	cfg.Tenancy.Enabled = true
+	cfg.Tenancy.Tenants = []string{"ops"}
+	cfg.Tenancy.AllowPathTenants = true
+	require.NoError(t, cfg.Validate())
+
+	cfg = Default()
+	cfg.Cluster.Enabled = true
+	cfg.Cluster.NodeID = "a"
+	cfg.Cluster.Address = "http://a:8080"
+	cfg.Cluster.Secret = "s3cret"
+	cfg.Cluster.DataDir = t.TempDir()
+	cfg.Locations.Enabled = false
+	cfg.History.Enabled = false
+	cfg.Heartbeat.Enabled = false
+	require.NoError(t, cfg.Validate())
+}
+
+func TestTenancyConfig(t *testing.T) {
+	cfg := TenancyConfig{
+		Tenants: []string{"ops", "lab"},
+		APIKeys: []string{"k1=ops", "k2=net"},
+		Quota:   10,
+		Quotas:  []string{"lab=0", "net=5"},
+	}
+
+	require.Equal(t, []string{"lab", "net", "ops"}, cfg.TenantNames())
+	require.Equal(t, map[string]string{"k1": "ops", "k2": "net"}, cfg.APIKeyMap())
+	require.Equal(t, 10, cfg.QuotaFor("ops"))
+	require.Equal(t, 0, cfg.QuotaFor("lab"))
+	require.Equal(t, 5, cfg.QuotaFor("net"))
+}
@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type field struct {
+	key        string
+	usage      string
+	reloadable bool
+	rules      string
+	// secret is "true" for values that are never shown and "names" for
+	// lists of name=value pairs whose names are secrets.
+	secret string
+	index  []int
+}
+
+type section struct {
+	key      string
+	required bool
+}
+
+func (f field) envName(prefix string) string {
+	name := strings.ToUpper(strings.ReplaceAll(f.key, ".", "_"))
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + "_" + name
+}
+
+func (f field) value(cfg *Config) reflect.Value {
+	return reflect.ValueOf(cfg).Elem().FieldByIndex(f.index)
+}
+
+func fields() []field {
+	return collectFields(reflect.TypeOf(Config{}), "", nil)
+}
+
+func collectFields(t reflect.Type, prefix string, index []int) []field {
+	var result []field
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		fieldIndex := append(append([]int{}, index...), i)
+
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			result = append(result, collectFields(sf.Type, key, fieldIndex)...)
+			continue
+		}
+
+		result = append(result, field{
+			key:        key,
+			usage:      sf.Tag.Get("usage"),
+			reloadable: sf.Tag.Get("reload") == "true",
+			rules:      sf.Tag.Get("validate"),
+			secret:     sf.Tag.Get("secret"),
+			index:      fieldIndex,
+		})
+	}
+
+	return result
+}
+
+func sections() []section {
+	return collectSections(reflect.TypeOf(Config{}), "")
+}
+
+func collectSections(t reflect.Type, prefix string) []section {
+	var result []section
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" || sf.Type.Kind() != reflect.Struct || sf.Type == durationType {
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		result = append(result, section{key: key, required: sf.Tag.Get("validate") == "required"})
+		result = append(result, collectSections(sf.Type, key)...)
+	}
+
+	return result
+}
+
+func setValue(v reflect.Value, raw []string) error {
+	if v.Kind() == reflect.Slice {
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+
+		values := make([]string, 0, len(raw))
+		for _, item := range raw {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		v.Set(reflect.ValueOf(values))
+
+		return nil
+	}
+
+	if len(raw) != 1 {
+		return fmt.Errorf("expected a single value, got %d", len(raw))
+	}
+
+	s := strings.TrimSpace(raw[0])
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+const redacted = "[redacted]"
+
+// display formats the value of the field for people, secrets are
+// redacted.
+func (f field) display(cfg *Config) string {
+	v := f.value(cfg)
+
+	switch f.secret {
+	case "true":
+		if v.IsZero() {
+			return formatValue(v)
+		}
+		return redacted
+	case "names":
+		items := make([]string, v.Len())
+		for i := range items {
+			_, value, _ := strings.Cut(v.Index(i).String(), "=")
+			items[i] = redacted + "=" + value
+		}
+		return strings.Join(items, ",")
+	default:
+		return formatValue(v)
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	case v.Kind() == reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	case v.Kind() == reflect.String:
+		return strconv.Quote(v.String())
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
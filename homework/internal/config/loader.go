@@ -0,0 +1,311 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Sources maps every config key to the layer its effective value came from.
+type Sources map[string]Source
+
+// Loader merges configuration layers with the precedence
+// defaults < yaml file < environment variables < command line flags.
+type Loader struct {
+	envPrefix string
+	lookupEnv func(string) (string, bool)
+	flags     map[string]string
+	warnings  []string
+}
+
+func NewLoader(envPrefix string) *Loader {
+	return &Loader{
+		envPrefix: envPrefix,
+		lookupEnv: os.LookupEnv,
+		flags:     make(map[string]string),
+	}
+}
+
+// BindFlags registers a flag for every config key (e.g. -server.port) on fs.
+func (l *Loader) BindFlags(fs *flag.FlagSet) {
+	for _, f := range fields() {
+		key := f.key
+		fs.Func(key, f.usage, func(value string) error {
+			l.flags[key] = value
+			return nil
+		})
+	}
+}
+
+// Load merges all layers and validates the result. Every problem found in
+// the file, environment, flags and merged values is reported at once in
+// the returned error.
+func (l *Loader) Load(path string) (*Config, Sources, error) {
+	cfg := Default()
+	sources := make(Sources)
+	l.warnings = nil
+
+	var (
+		errs       []error
+		fileValues map[string][]string
+	)
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read config file: %w", err)
+		}
+
+		var (
+			present  map[string]bool
+			warnings []string
+		)
+		fileValues, present, warnings, err = parseYAML(data)
+		for _, warning := range warnings {
+			l.warnings = append(l.warnings, fmt.Sprintf("config file %s: %s", path, warning))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %w", path, err))
+		}
+
+		for _, s := range sections() {
+			if s.required && !present[s.key] {
+				errs = append(errs, fmt.Errorf("config file %s: missing required section %s", path, s.key))
+			}
+		}
+	}
+
+	for _, f := range fields() {
+		sources[f.key] = SourceDefault
+		v := f.value(cfg)
+
+		if raw, ok := fileValues[f.key]; ok {
+			if err := setValue(v, raw); err != nil {
+				errs = append(errs, fmt.Errorf("config file key %s: %w", f.key, err))
+			} else {
+				sources[f.key] = SourceFile
+			}
+		}
+
+		name := f.envName(l.envPrefix)
+		if raw, ok := l.lookupEnv(name); ok {
+			if err := setValue(v, strings.Split(raw, ",")); err != nil {
+				errs = append(errs, fmt.Errorf("environment variable %s: %w", name, err))
+			} else {
+				sources[f.key] = SourceEnv
+			}
+		}
+
+		if raw, ok := l.flags[f.key]; ok {
+			if err := setValue(v, strings.Split(raw, ",")); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", f.key, err))
+			} else {
+				sources[f.key] = SourceFlag
+			}
+		}
+	}
+
+	cfg.normalize()
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, sources, nil
+}
+
+// normalize rewrites values the service accepts in several spellings to
+// the one validation and the printed config use.
+func (c *Config) normalize() {
+	c.Log.Level = strings.ToLower(c.Log.Level)
+}
+
+// Warnings returns the deprecations found by the last Load.
+func (l *Loader) Warnings() []string {
+	return l.warnings
+}
+
+// legacyKeys maps the top level keys of the flat layout used before the
+// config had sections to their current keys.
+var legacyKeys = map[string]string{
+	"host":          "server.host",
+	"port":          "server.port",
+	"read_timeout":  "server.read_timeout",
+	"write_timeout": "server.write_timeout",
+}
+
+// envRef matches the ${VAR} references expanded in the config file. A bare
+// $VAR is left alone, so values may contain dollar signs.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// parseYAML expands ${VAR} references and flattens the document into
+// dotted keys, so every layer is applied by the same code path. It also
+// returns the set of sections present in the document and warnings about
+// deprecated keys.
+func parseYAML(data []byte) (map[string][]string, map[string]bool, []string, error) {
+	data = envRef.ReplaceAllFunc(data, func(ref []byte) []byte {
+		return []byte(os.Getenv(string(ref[2 : len(ref)-1])))
+	})
+
+	values := make(map[string][]string)
+	present := make(map[string]bool)
+
+	var root yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&root); err != nil {
+		if errors.Is(err, io.EOF) {
+			return values, present, nil, nil
+		}
+		return nil, nil, nil, err
+	}
+
+	if len(root.Content) == 0 {
+		return values, present, nil, nil
+	}
+
+	known := make(map[string]bool)
+	for _, f := range fields() {
+		known[f.key] = true
+	}
+
+	legacy := takeLegacyKeys(root.Content[0])
+	errs := flatten(root.Content[0], "", known, values, present)
+
+	var warnings []string
+	for _, pair := range legacy {
+		keyNode, valueNode := pair[0], pair[1]
+		key := legacyKeys[keyNode.Value]
+
+		if _, ok := values[key]; ok {
+			errs = append(errs, fmt.Errorf("line %d: key %s is also set as %s, remove it", keyNode.Line, keyNode.Value, key))
+			continue
+		}
+
+		raw, err := leafValues(valueNode)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("line %d: key %s: %w", valueNode.Line, keyNode.Value, err))
+			continue
+		}
+
+		values[key] = raw
+		present[key[:strings.IndexByte(key, '.')]] = true
+		warnings = append(warnings, fmt.Sprintf("line %d: key %s is deprecated, use %s", keyNode.Line, keyNode.Value, key))
+	}
+
+	return values, present, warnings, errors.Join(errs...)
+}
+
+// takeLegacyKeys removes the keys of the flat layout from the top level
+// mapping and returns them as key and value node pairs.
+func takeLegacyKeys(node *yaml.Node) [][2]*yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var (
+		legacy [][2]*yaml.Node
+		rest   []*yaml.Node
+	)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if _, ok := legacyKeys[keyNode.Value]; ok {
+			legacy = append(legacy, [2]*yaml.Node{keyNode, valueNode})
+			continue
+		}
+		rest = append(rest, keyNode, valueNode)
+	}
+	node.Content = rest
+
+	return legacy
+}
+
+func flatten(node *yaml.Node, prefix string, known map[string]bool,
+	values map[string][]string, present map[string]bool) []error {
+	if node.Kind != yaml.MappingNode {
+		if prefix == "" {
+			return []error{fmt.Errorf("line %d: expected a mapping", node.Line)}
+		}
+		return []error{fmt.Errorf("line %d: section %s: expected a mapping", node.Line, prefix)}
+	}
+
+	var errs []error
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+
+		key := keyNode.Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		if known[key] {
+			raw, err := leafValues(valueNode)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("line %d: key %s: %w", valueNode.Line, key, err))
+				continue
+			}
+			values[key] = raw
+			continue
+		}
+
+		if !isSection(key, known) {
+			errs = append(errs, fmt.Errorf("line %d: unknown key %s", keyNode.Line, key))
+			continue
+		}
+
+		present[key] = true
+		if valueNode.Kind == yaml.ScalarNode && valueNode.Tag == "!!null" {
+			continue
+		}
+
+		errs = append(errs, flatten(valueNode, key, known, values, present)...)
+	}
+
+	return errs
+}
+
+func isSection(key string, known map[string]bool) bool {
+	for k := range known {
+		if strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func leafValues(node *yaml.Node) ([]string, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return []string{node.Value}, nil
+	case yaml.SequenceNode:
+		values := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, errors.New("expected a list of scalars")
+			}
+			values = append(values, item.Value)
+		}
+		return values, nil
+	default:
+		return nil, errors.New("expected a scalar or a list")
+	}
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// Print writes the effective value and origin of every config key,
+// secrets are redacted.
+func Print(w io.Writer, cfg *Config, sources Sources) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, f := range fields() {
+		source := sources[f.key]
+		if source == "" {
+			source = SourceDefault
+		}
+
+		_, err := fmt.Fprintf(tw, "%s\t= %s\t(%s)\n", f.key, f.display(cfg), source)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+type Value struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	Reloadable bool   `json:"reloadable"`
+}
+
+// Values lists the effective value of every config key with secrets
+// redacted.
+func Values(cfg *Config) []Value {
+	var values []Value
+
+	for _, f := range fields() {
+		values = append(values, Value{Key: f.key, Value: f.display(cfg), Reloadable: f.reloadable})
+	}
+
+	return values
+}
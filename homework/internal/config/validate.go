@@ -0,0 +1,300 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	hostnameRe = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+	nameRe     = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+)
+
+// Validate checks every key against the rules declared in its validate
+// tag and reports all violations at once. Supported rules are port, host,
+// cidr (list of prefixes), portmap (list of name=port pairs), pairs (list
+// of name=value pairs, pairs=int for non-negative integer values), names
+// (list of lowercase dns labels), url (empty or an absolute http or https
+// url), min=<value> and oneof=<a|b|c>, separated by semicolons.
+func (c *Config) Validate() error {
+	var errs []error
+
+	for _, f := range fields() {
+		if f.rules == "" {
+			continue
+		}
+
+		for _, rule := range strings.Split(f.rules, ";") {
+			if err := checkRule(f.value(c), rule); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", f.key, err))
+			}
+		}
+	}
+
+	errs = append(errs, c.Tenancy.check()...)
+	errs = append(errs, c.Replication.check(&c.Tenancy, c.unreplicated())...)
+	errs = append(errs, c.Cluster.check(&c.Replication, &c.Tenancy, c.unreplicated())...)
+	errs = append(errs, c.Tracing.check()...)
+	errs = append(errs, c.Admin.check(&c.Server)...)
+	errs = append(errs, c.Backup.check(&c.Admin)...)
+
+	return errors.Join(errs...)
+}
+
+// check validates what the field rules of the tenancy section can not
+// express on their own.
+func (c *TenancyConfig) check() []error {
+	var errs []error
+
+	for _, entry := range c.APIKeys {
+		if _, tenant, ok := strings.Cut(entry, "="); ok && tenant != "" && !nameRe.MatchString(tenant) {
+			errs = append(errs, fmt.Errorf("tenancy.api_keys: tenant %q must be a lowercase dns label", tenant))
+		}
+	}
+
+	if c.Enabled && len(c.TenantNames()) == 0 {
+		errs = append(errs, fmt.Errorf("tenancy.tenants: at least one tenant is required when tenancy is enabled"))
+	}
+
+	if c.Enabled && len(c.APIKeys) == 0 && !c.AllowPathTenants {
+		errs = append(errs, fmt.Errorf("tenancy.api_keys: api keys are required unless allow_path_tenants is set, tenants are open to anyone otherwise"))
+	}
+
+	return errs
+}
+
+// unreplicated lists the enabled keys of features that keep state apart
+// from the devices, replicas would not see it.
+func (c *Config) unreplicated() []string {
+	var keys []string
+
+	if c.IPAM.Enabled {
+		keys = append(keys, "ipam.enabled")
+	}
+	if c.Catalog.Enforce {
+		keys = append(keys, "catalog.enforce")
+	}
+	if c.Locations.Enabled {
+		keys = append(keys, "locations.enabled")
+	}
+	if c.History.Enabled {
+		keys = append(keys, "history.enabled")
+	}
+	if c.Heartbeat.Enabled {
+		keys = append(keys, "heartbeat.enabled")
+	}
+	if c.Versions.Enabled {
+		keys = append(keys, "versions.enabled")
+	}
+
+	return keys
+}
+
+// check validates the replication role against the leader url, the
+// tenancy section and the features that are not replicated.
+func (c *ReplicationConfig) check(tenancy *TenancyConfig, unreplicated []string) []error {
+	var errs []error
+
+	if c.Role == roleFollower && c.Leader == "" {
+		errs = append(errs, fmt.Errorf("replication.leader: the leader url is required for followers"))
+	}
+
+	if c.Role != roleStandalone && tenancy.Enabled {
+		errs = append(errs, fmt.Errorf("replication.role: replication is not supported with tenancy"))
+	}
+
+	if c.Role != roleStandalone {
+		for _, key := range unreplicated {
+			errs = append(errs, fmt.Errorf("%s: not supported with replication, only devices are replicated", key))
+		}
+	}
+
+	return errs
+}
+
+// check validates the node of a cluster against its peers and rejects
+// clustering together with replication, tenancy and the features that are
+// not replicated.
+func (c *ClusterConfig) check(replication *ReplicationConfig, tenancy *TenancyConfig, unreplicated []string) []error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+
+	if c.NodeID == "" {
+		errs = append(errs, fmt.Errorf("cluster.node_id: the node id is required for clustering"))
+	}
+
+	if c.Address == "" {
+		errs = append(errs, fmt.Errorf("cluster.address: the address is required for clustering"))
+	}
+
+	if c.Secret == "" {
+		errs = append(errs, fmt.Errorf("cluster.secret: the shared secret is required for clustering, raft messages are accepted from anyone otherwise"))
+	}
+
+	if c.DataDir == "" {
+		errs = append(errs, fmt.Errorf("cluster.data_dir: the data directory is required for clustering, a restarted node forgets its vote otherwise"))
+	}
+
+	if _, ok := c.Members()[c.NodeID]; len(c.Peers) > 0 && !ok {
+		errs = append(errs, fmt.Errorf("cluster.peers: the initial members must include node %q", c.NodeID))
+	}
+
+	if c.HeartbeatTicks >= c.ElectionTicks {
+		errs = append(errs, fmt.Errorf("cluster.heartbeat_ticks: %d must be less than election_ticks %d", c.HeartbeatTicks, c.ElectionTicks))
+	}
+
+	if replication.Role != roleStandalone {
+		errs = append(errs, fmt.Errorf("cluster.enabled: clustering is not supported with replication"))
+	}
+
+	if tenancy.Enabled {
+		errs = append(errs, fmt.Errorf("cluster.enabled: clustering is not supported with tenancy"))
+	}
+
+	for _, key := range unreplicated {
+		errs = append(errs, fmt.Errorf("%s: not supported with clustering, only devices are replicated", key))
+	}
+
+	return errs
+}
+
+// check keeps the admin listener off the port of the device api.
+func (c *AdminConfig) check(server *ServerConfig) []error {
+	if c.Enabled && c.Port == server.Port {
+		return []error{fmt.Errorf("admin.port: %d is already used by server.port", c.Port)}
+	}
+
+	return nil
+}
+
+// check requires the admin listener, backups are not served on the port of
+// the device api.
+func (c *BackupConfig) check(admin *AdminConfig) []error {
+	if c.Enabled && !admin.Enabled {
+		return []error{fmt.Errorf("backup.enabled: backups are served on the admin listener, enable admin")}
+	}
+
+	return nil
+}
+
+// check requires a file for the otlp_file exporter.
+func (c *TracingConfig) check() []error {
+	if c.Enabled && c.Exporter == "otlp_file" && c.File == "" {
+		return []error{fmt.Errorf("tracing.file: the file is required for the otlp_file exporter")}
+	}
+
+	return nil
+}
+
+func checkRule(v reflect.Value, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+
+	switch name {
+	case "port":
+		if port := v.Int(); port < 1 || port > 65535 {
+			return fmt.Errorf("%d is out of range 1-65535", port)
+		}
+	case "host":
+		host := v.String()
+		if host != "" && net.ParseIP(host) == nil && !hostnameRe.MatchString(host) {
+			return fmt.Errorf("%q is neither an ip address nor a hostname", host)
+		}
+	case "cidr":
+		for i := 0; i < v.Len(); i++ {
+			if _, err := netip.ParsePrefix(v.Index(i).String()); err != nil {
+				return fmt.Errorf("%q is not a valid cidr", v.Index(i).String())
+			}
+		}
+	case "portmap":
+		for i := 0; i < v.Len(); i++ {
+			name, port, ok := strings.Cut(v.Index(i).String(), "=")
+			if n, err := strconv.Atoi(port); !ok || name == "" || err != nil || n < 1 || n > 65535 {
+				return fmt.Errorf("%q is not a name=port pair", v.Index(i).String())
+			}
+		}
+	case "pairs":
+		for i := 0; i < v.Len(); i++ {
+			name, value, ok := strings.Cut(v.Index(i).String(), "=")
+			if !ok || name == "" || value == "" {
+				return fmt.Errorf("%q is not a name=value pair", v.Index(i).String())
+			}
+			if n, err := strconv.Atoi(value); arg == "int" && (err != nil || n < 0) {
+				return fmt.Errorf("%q must have a non-negative integer value", v.Index(i).String())
+			}
+		}
+	case "names":
+		for i := 0; i < v.Len(); i++ {
+			if name := v.Index(i).String(); len(name) > 63 || !nameRe.MatchString(name) {
+				return fmt.Errorf("%q must be a lowercase dns label", name)
+			}
+		}
+	case "url":
+		if raw := v.String(); raw != "" {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%q is not an absolute http or https url", raw)
+			}
+		}
+	case "min":
+		return checkMin(v, arg)
+	case "oneof":
+		allowed := strings.Split(arg, "|")
+		for _, value := range allowed {
+			if v.String() == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("%q must be one of %s", v.String(), strings.Join(allowed, ", "))
+	default:
+		return fmt.Errorf("unknown validation rule %q", rule)
+	}
+
+	return nil
+}
+
+func checkMin(v reflect.Value, arg string) error {
+	if v.Type() == durationType {
+		limit, err := time.ParseDuration(arg)
+		if err != nil {
+			return fmt.Errorf("invalid min rule %q", arg)
+		}
+		if d := time.Duration(v.Int()); d < limit {
+			return fmt.Errorf("%s is less than %s", d, limit)
+		}
+		return nil
+	}
+
+	limit, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return fmt.Errorf("invalid min rule %q", arg)
+	}
+
+	var value float64
+	switch v.Kind() {
+	case reflect.Int:
+		value = float64(v.Int())
+	case reflect.Float64:
+		value = v.Float()
+	case reflect.Slice, reflect.String:
+		value = float64(v.Len())
+	default:
+		return fmt.Errorf("min rule is not supported for %s", v.Type())
+	}
+
+	if value < limit {
+		return fmt.Errorf("%v is less than %v", v.Interface(), arg)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type Change struct {
+	Key        string
+	Old        string
+	New        string
+	Reloadable bool
+}
+
+// Diff lists the keys whose effective values differ between two configs,
+// the values of secrets are redacted.
+func Diff(old, cur *Config) []Change {
+	var changes []Change
+
+	for _, f := range fields() {
+		oldValue, newValue := formatValue(f.value(old)), formatValue(f.value(cur))
+		if oldValue == newValue {
+			continue
+		}
+
+		changes = append(changes, Change{
+			Key:        f.key,
+			Old:        f.display(old),
+			New:        f.display(cur),
+			Reloadable: f.reloadable,
+		})
+	}
+
+	return changes
+}
+
+// Watcher re-reads the config file on SIGHUP or when its content changes,
+// and hands valid configs to the reload callback. Invalid files are
+// reported through the error callback and the previous config is kept.
+type Watcher struct {
+	loader   *Loader
+	path     string
+	onReload func(old, cur *Config, changes []Change)
+	onError  func(error)
+
+	mu sync.Mutex
+	// current is what the process runs with, loaded is the last valid
+	// config read from the file. They differ in the keys that need a
+	// restart, reloads are diffed against loaded so such a change is only
+	// reported once.
+	current *Config
+	loaded  *Config
+	content []byte
+}
+
+func NewWatcher(loader *Loader, path string, current *Config,
+	onReload func(old, cur *Config, changes []Change), onError func(error)) *Watcher {
+	content, _ := os.ReadFile(path)
+
+	return &Watcher{
+		loader:   loader,
+		path:     path,
+		onReload: onReload,
+		onError:  onError,
+		current:  current,
+		loaded:   current,
+		content:  content,
+	}
+}
+
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current
+}
+
+// Run blocks until ctx is done. The file is polled every interval, a zero
+// interval leaves only SIGHUP as a reload trigger.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if interval > 0 && w.path != "" {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			w.Reload()
+		case <-tick:
+			if w.fileChanged() {
+				w.Reload()
+			}
+		}
+	}
+}
+
+// Reload loads and validates the config and applies it if it differs from
+// the current one. Changes to keys that are not reloadable are reported
+// but not applied.
+func (w *Watcher) Reload() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	content, _ := os.ReadFile(w.path)
+	w.content = content
+
+	cfg, _, err := w.loader.Load(w.path)
+	if err != nil {
+		w.onError(err)
+		return
+	}
+
+	changes := Diff(w.loaded, cfg)
+	if len(changes) == 0 {
+		return
+	}
+	w.loaded = cfg
+
+	// Settings that need a restart keep their running values, so Current
+	// always describes what the process actually uses.
+	running := *cfg
+	for _, f := range fields() {
+		if !f.reloadable {
+			f.value(&running).Set(f.value(w.current))
+		}
+	}
+
+	old := w.current
+	w.current = &running
+	w.onReload(old, &running, changes)
+}
+
+func (w *Watcher) fileChanged() bool {
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return !bytes.Equal(content, w.content)
+}
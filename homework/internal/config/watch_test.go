@@ -0,0 +1,88 @@
+package config
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	old := Default()
+	cur := Default()
+	cur.Server.ReadTimeout = time.Second
+	cur.Server.Port = 9000
+
+	changes := Diff(old, cur)
+
+	require.Equal(t, []Change{
+		{Key: "server.port", Old: "8080", New: "9000", Reloadable: false},
+		{Key: "server.read_timeout", Old: "30s", New: "1s", Reloadable: true},
+	}, changes)
+}
+
+func TestWatcherReload(t *testing.T) {
+	path := writeConfig(t, "server:\n  read_timeout: 5s\n")
+	loader := newTestLoader(nil)
+
+	cfg, _, err := loader.Load(path)
+	require.NoError(t, err)
+
+	var (
+		reloaded []Change
+		failures []error
+	)
+	watcher := NewWatcher(loader, path, cfg,
+		func(_, _ *Config, changes []Change) { reloaded = changes },
+		func(err error) { failures = append(failures, err) })
+
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  port: 9000\n  read_timeout: 7s\n"), 0o600))
+	watcher.Reload()
+
+	require.Empty(t, failures)
+	require.Len(t, reloaded, 2)
+	require.Equal(t, 7*time.Second, watcher.Current().Server.ReadTimeout)
+	require.Equal(t, defaultPort, watcher.Current().Server.Port)
+
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  read_timeout: -1s\n"), 0o600))
+	watcher.Reload()
+
+	require.Len(t, failures, 1)
+	require.Equal(t, 7*time.Second, watcher.Current().Server.ReadTimeout)
+
+	// The port change still waiting for a restart is not reported again.
+	reloaded = nil
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  port: 9000\n  read_timeout: 8s\n"), 0o600))
+	watcher.Reload()
+
+	require.Equal(t, []Change{{Key: "server.read_timeout", Old: "7s", New: "8s", Reloadable: true}}, reloaded)
+	require.Equal(t, defaultPort, watcher.Current().Server.Port)
+}
+
+func TestWatcherRunDetectsFileChange(t *testing.T) {
+	path := writeConfig(t, "server:\n  port: 8080\nlog:\n  level: info\n")
+	loader := newTestLoader(nil)
+
+	cfg, _, err := loader.Load(path)
+	require.NoError(t, err)
+
+	reloaded := make(chan *Config, 1)
+	watcher := NewWatcher(loader, path, cfg,
+		func(_, cur *Config, _ []Change) { reloaded <- cur },
+		func(err error) { t.Error(err) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(path, []byte("server:\n  port: 8080\nlog:\n  level: debug\n"), 0o600))
+
+	select {
+	case cur := <-reloaded:
+		require.Equal(t, "debug", cur.Log.Level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change was not detected")
+	}
+}
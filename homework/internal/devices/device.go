@@ -1,7 +1,100 @@
 package devices
 
+import (
+	"time"
+)
+
+type Status string
+
+const (
+	StatusOrdered        Status = "ordered"
+	StatusInStock        Status = "in_stock"
+	StatusProvisioned    Status = "provisioned"
+	StatusActive         Status = "active"
+	StatusMaintenance    Status = "maintenance"
+	StatusDecommissioned Status = "decommissioned"
+)
+
 type Device struct {
-	SerialNum string `json:"serial_num"`
-	Model     string `json:"model"`
-	IP        string `json:"ip"`
+	SerialNum  string `json:"serial_num"`
+	Model      string `json:"model"`
+	IP         string `json:"ip"`
+	LocationID string `json:"location_id,omitempty"`
+	Position   int    `json:"position,omitempty"`
+	Status     Status `json:"status,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+	// Attributes are model specific properties, the catalog model lists
+	// the allowed keys.
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Transition records a status change of a device. The first transition of
+// a device has an empty From status.
+type Transition struct {
+	SerialNum string    `json:"serial_num"`
+	From      Status    `json:"from,omitempty"`
+	To        Status    `json:"to"`
+	Reason    string    `json:"reason,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+type Liveness string
+
+const (
+	Online  Liveness = "online"
+	Offline Liveness = "offline"
+)
+
+// Heartbeat is the last report of a device. Devices that never reported
+// have no heartbeat and count as offline.
+type Heartbeat struct {
+	SerialNum string            `json:"serial_num"`
+	Liveness  Liveness          `json:"liveness"`
+	LastSeen  time.Time         `json:"last_seen"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type LivenessCounts struct {
+	Online  int `json:"online"`
+	Offline int `json:"offline"`
+}
+
+type SearchHit struct {
+	Device *Device `json:"device"`
+	Score  float64 `json:"score"`
+}
+
+// Filter narrows device listings, empty fields match every device.
+type Filter struct {
+	IP    string
+	Model string
+	// LocationID matches devices placed at the location or anywhere below it.
+	LocationID string
+	// Selector is a label selector such as "env=prod,team in (a,b)".
+	Selector string
+	Liveness Liveness
+	// Query is an expression of the filter language such as
+	// `model == "RT-100" and ip in 10.0.0.0/8`.
+	Query string
+}
+
+// Version is an immutable snapshot of a device taken on every change.
+// Versions are numbered from 1 per serial number, a deleted device gets a
+// version without Device.
+type Version struct {
+	SerialNum string    `json:"serial_num"`
+	Number    int       `json:"version"`
+	At        time.Time `json:"at"`
+	Deleted   bool      `json:"deleted,omitempty"`
+	Device    *Device   `json:"device,omitempty"`
+}
+
+// Change is a field that differs between two versions, labels and
+// attributes are compared per key as "labels.<key>". Missing values are
+// empty.
+type Change struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
 }
@@ -31,3 +31,71 @@ func NewNotFoundError(serialNum string) *NotFoundError {
 		err: fmt.Errorf("device with 'SerialNum' = %s not found", serialNum),
 	}
 }
+
+type ConflictError struct {
+	err error
+}
+
+func (e *ConflictError) Error() string {
+	return e.err.Error()
+}
+
+func NewConflictError(field, value, serialNum string) *ConflictError {
+	return &ConflictError{
+		err: fmt.Errorf("'%s' = %s is already used by device with 'SerialNum' = %s", field, value, serialNum),
+	}
+}
+
+func NewEntityNotFoundError(entity, field, value string) *NotFoundError {
+	return &NotFoundError{
+		err: fmt.Errorf("%s with '%s' = %s not found", entity, field, value),
+	}
+}
+
+func NewConflictErrorf(format string, args ...interface{}) *ConflictError {
+	return &ConflictError{
+		err: fmt.Errorf(format, args...),
+	}
+}
+
+type ValidationError struct {
+	err error
+}
+
+func (e *ValidationError) Error() string {
+	return e.err.Error()
+}
+
+func NewValidationError(format string, args ...interface{}) *ValidationError {
+	return &ValidationError{
+		err: fmt.Errorf(format, args...),
+	}
+}
+
+type IllegalTransitionError struct {
+	err error
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return e.err.Error()
+}
+
+func NewIllegalTransitionError(serialNum, from, to string) *IllegalTransitionError {
+	return &IllegalTransitionError{
+		err: fmt.Errorf("device with 'SerialNum' = %s can not move from %s to %s", serialNum, from, to),
+	}
+}
+
+type QuotaExceededError struct {
+	err error
+}
+
+func (e *QuotaExceededError) Error() string {
+	return e.err.Error()
+}
+
+func NewQuotaExceededError(limit int) *QuotaExceededError {
+	return &QuotaExceededError{
+		err: fmt.Errorf("device quota of %d devices is exhausted", limit),
+	}
+}
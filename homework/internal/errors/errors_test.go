@@ -22,3 +22,39 @@ func TestNotFoundError(t *testing.T) {
 	require.NotNil(t, err)
 	require.EqualError(t, fmt.Errorf("device with 'SerialNum' = %s not found", errorMessageTestValue), err.Error())
 }
+
+func TestConflictError(t *testing.T) {
+	err := NewConflictError("IP", errorMessageTestValue, errorMessageTestValue)
+	require.NotNil(t, err)
+	require.EqualError(t, fmt.Errorf("'IP' = %s is already used by device with 'SerialNum' = %s", errorMessageTestValue, errorMessageTestValue), err.Error())
+}
+
+func TestEntityNotFoundError(t *testing.T) {
+	err := NewEntityNotFoundError("subnet", "ID", errorMessageTestValue)
+	require.NotNil(t, err)
+	require.EqualError(t, fmt.Errorf("subnet with 'ID' = %s not found", errorMessageTestValue), err.Error())
+}
+
+func TestConflictErrorf(t *testing.T) {
+	err := NewConflictErrorf("subnet %s is in use", errorMessageTestValue)
+	require.NotNil(t, err)
+	require.EqualError(t, fmt.Errorf("subnet %s is in use", errorMessageTestValue), err.Error())
+}
+
+func TestValidationError(t *testing.T) {
+	err := NewValidationError("invalid cidr %q", errorMessageTestValue)
+	require.NotNil(t, err)
+	require.EqualError(t, fmt.Errorf("invalid cidr %q", errorMessageTestValue), err.Error())
+}
+
+func TestIllegalTransitionError(t *testing.T) {
+	err := NewIllegalTransitionError(errorMessageTestValue, "ordered", "active")
+	require.NotNil(t, err)
+	require.EqualError(t, fmt.Errorf("device with 'SerialNum' = %s can not move from ordered to active", errorMessageTestValue), err.Error())
+}
+
+func TestQuotaExceededError(t *testing.T) {
+	err := NewQuotaExceededError(10)
+	require.NotNil(t, err)
+	require.EqualError(t, fmt.Errorf("device quota of 10 devices is exhausted"), err.Error())
+}
@@ -0,0 +1,395 @@
+package filter
+
+import (
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"homework/internal/devices"
+)
+
+// Expr is a parsed filter.
+type Expr interface {
+	Match(*devices.Device) bool
+	String() string
+}
+
+type And struct {
+	Left, Right Expr
+}
+
+func (e *And) Match(device *devices.Device) bool {
+	return e.Left.Match(device) && e.Right.Match(device)
+}
+
+func (e *And) String() string {
+	return "(" + e.Left.String() + " and " + e.Right.String() + ")"
+}
+
+type Or struct {
+	Left, Right Expr
+}
+
+func (e *Or) Match(device *devices.Device) bool {
+	return e.Left.Match(device) || e.Right.Match(device)
+}
+
+func (e *Or) String() string {
+	return "(" + e.Left.String() + " or " + e.Right.String() + ")"
+}
+
+type Not struct {
+	Expr Expr
+}
+
+func (e *Not) Match(device *devices.Device) bool {
+	return !e.Expr.Match(device)
+}
+
+func (e *Not) String() string {
+	return "not " + e.Expr.String()
+}
+
+// Comparison checks one field of a device. Values holds the single
+// operand, or the members of a list for in.
+type Comparison struct {
+	Field    string
+	Operator string
+	Values   []string
+
+	match func(*devices.Device) bool
+}
+
+func (e *Comparison) Match(device *devices.Device) bool {
+	return e.match(device)
+}
+
+func (e *Comparison) String() string {
+	values := make([]string, len(e.Values))
+	for i, value := range e.Values {
+		values[i] = strconv.Quote(value)
+	}
+
+	if e.Operator == keywordIn {
+		return e.Field + " in [" + strings.Join(values, ", ") + "]"
+	}
+
+	return e.Field + " " + e.Operator + " " + values[0]
+}
+
+// Equals returns the values the field must be equal to for the comparison
+// to match, so repositories can look them up in an index. It returns
+// false for other comparisons and for ip, whose values match any spelling
+// of the same address.
+func (e *Comparison) Equals() ([]string, bool) {
+	if e.Field == "ip" || e.Field == "position" {
+		return nil, false
+	}
+
+	if e.Operator == "==" || e.Operator == keywordIn {
+		return e.Values, true
+	}
+
+	return nil, false
+}
+
+// Conjuncts returns the comparisons every matching device satisfies, the
+// ones joined with and at the top of the filter.
+func Conjuncts(expr Expr) []*Comparison {
+	switch e := expr.(type) {
+	case *And:
+		return append(Conjuncts(e.Left), Conjuncts(e.Right)...)
+	case *Comparison:
+		return []*Comparison{e}
+	default:
+		return nil
+	}
+}
+
+type fieldType int
+
+const (
+	typeString fieldType = iota
+	typeInt
+	typeIP
+	typeStatus
+)
+
+func (t fieldType) String() string {
+	switch t {
+	case typeInt:
+		return "number"
+	case typeIP:
+		return "address"
+	case typeStatus:
+		return "status"
+	default:
+		return "string"
+	}
+}
+
+var fields = map[string]fieldType{
+	"serial_num":  typeString,
+	"model":       typeString,
+	"ip":          typeIP,
+	"location_id": typeString,
+	"position":    typeInt,
+	"status":      typeStatus,
+}
+
+var fieldNames = "serial_num, model, ip, location_id, position, status, labels.<key> and attributes.<key>"
+
+var statuses = []devices.Status{
+	devices.StatusOrdered,
+	devices.StatusInStock,
+	devices.StatusProvisioned,
+	devices.StatusActive,
+	devices.StatusMaintenance,
+	devices.StatusDecommissioned,
+}
+
+var operators = map[fieldType][]string{
+	typeString: {"==", "!=", keywordIn, keywordContains, keywordMatches},
+	typeInt:    {"==", "!=", "<", "<=", ">", ">=", keywordIn},
+	typeIP:     {"==", "!=", keywordIn},
+	typeStatus: {"==", "!=", keywordIn},
+}
+
+func lookupField(t token) (fieldType, error) {
+	if typ, ok := fields[t.text]; ok {
+		return typ, nil
+	}
+
+	for _, prefix := range []string{"labels.", "attributes."} {
+		if strings.HasPrefix(t.text, prefix) {
+			if len(t.text) == len(prefix) {
+				return 0, newError(t.pos, "missing key after %q", prefix)
+			}
+			return typeString, nil
+		}
+	}
+
+	return 0, newError(t.pos, "unknown field %q, fields are %s", t.text, fieldNames)
+}
+
+// compile type checks a comparison and builds its matcher.
+func compile(field string, typ fieldType, op token, values []token) (*Comparison, error) {
+	operator := strings.ToLower(op.text)
+	if !allowed(operators[typ], operator) {
+		return nil, newError(op.pos, "operator %q is not defined for %s field %q, use one of %s",
+			op.text, typ, field, strings.Join(operators[typ], ", "))
+	}
+
+	c := &Comparison{Field: field, Operator: operator, Values: make([]string, len(values))}
+	for i, value := range values {
+		c.Values[i] = value.text
+	}
+
+	var err error
+	switch typ {
+	case typeInt:
+		c.match, err = compileInt(field, operator, values)
+	case typeIP:
+		c.match, err = compileIP(operator, values)
+	case typeStatus:
+		c.match, err = compileStatus(operator, values)
+	default:
+		c.match, err = compileString(field, operator, values)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func compileString(field, operator string, values []token) (func(*devices.Device) bool, error) {
+	get := stringField(field)
+
+	switch operator {
+	case "==", keywordIn:
+		return func(device *devices.Device) bool {
+			value, ok := get(device)
+			return ok && containsToken(values, value)
+		}, nil
+	case "!=":
+		return func(device *devices.Device) bool {
+			value, ok := get(device)
+			return !ok || value != values[0].text
+		}, nil
+	case keywordContains:
+		return func(device *devices.Device) bool {
+			value, ok := get(device)
+			return ok && strings.Contains(value, values[0].text)
+		}, nil
+	default:
+		re, err := regexp.Compile(values[0].text)
+		if err != nil {
+			return nil, newError(values[0].pos, "invalid regular expression %q", values[0].text)
+		}
+		return func(device *devices.Device) bool {
+			value, ok := get(device)
+			return ok && re.MatchString(value)
+		}, nil
+	}
+}
+
+// stringField returns the value of a string field and whether the device
+// has it. Labels and attributes may be missing, the other fields are
+// always present.
+func stringField(field string) func(*devices.Device) (string, bool) {
+	if key, ok := strings.CutPrefix(field, "labels."); ok {
+		return func(device *devices.Device) (string, bool) {
+			value, ok := device.Labels[key]
+			return value, ok
+		}
+	}
+
+	if key, ok := strings.CutPrefix(field, "attributes."); ok {
+		return func(device *devices.Device) (string, bool) {
+			value, ok := device.Attributes[key]
+			return value, ok
+		}
+	}
+
+	return func(device *devices.Device) (string, bool) {
+		switch field {
+		case "serial_num":
+			return device.SerialNum, true
+		case "model":
+			return device.Model, true
+		default:
+			return device.LocationID, true
+		}
+	}
+}
+
+func compileInt(field, operator string, values []token) (func(*devices.Device) bool, error) {
+	numbers := make([]int, len(values))
+	for i, value := range values {
+		n, err := strconv.Atoi(value.text)
+		if err != nil || value.kind != tokenNumber {
+			return nil, newError(value.pos, "%s is a number, got %s", field, value)
+		}
+		numbers[i] = n
+	}
+
+	return func(device *devices.Device) bool {
+		switch operator {
+		case "!=":
+			return device.Position != numbers[0]
+		case "<":
+			return device.Position < numbers[0]
+		case "<=":
+			return device.Position <= numbers[0]
+		case ">":
+			return device.Position > numbers[0]
+		case ">=":
+			return device.Position >= numbers[0]
+		}
+
+		for _, n := range numbers {
+			if device.Position == n {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// compileIP compares parsed addresses, so any spelling of an address
+// matches. Devices with an ip that does not parse only match !=.
+func compileIP(operator string, values []token) (func(*devices.Device) bool, error) {
+	prefixes := make([]netip.Prefix, len(values))
+	for i, value := range values {
+		if operator == keywordIn && strings.Contains(value.text, "/") {
+			prefix, err := netip.ParsePrefix(value.text)
+			if err != nil {
+				return nil, newError(value.pos, "invalid subnet %s", value)
+			}
+			prefixes[i] = prefix.Masked()
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value.text)
+		if err != nil {
+			if operator == keywordIn {
+				return nil, newError(value.pos, "invalid address or subnet %s", value)
+			}
+			return nil, newError(value.pos, "invalid address %s", value)
+		}
+		prefixes[i] = netip.PrefixFrom(addr, addr.BitLen())
+	}
+
+	contains := func(device *devices.Device) bool {
+		addr, err := netip.ParseAddr(device.IP)
+		if err != nil {
+			return false
+		}
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if operator == "!=" {
+		return func(device *devices.Device) bool { return !contains(device) }, nil
+	}
+
+	return contains, nil
+}
+
+func compileStatus(operator string, values []token) (func(*devices.Device) bool, error) {
+	for _, value := range values {
+		if !knownStatus(devices.Status(value.text)) {
+			names := make([]string, len(statuses))
+			for i, status := range statuses {
+				names[i] = string(status)
+			}
+			return nil, newError(value.pos, "unknown status %s, statuses are %s", value, strings.Join(names, ", "))
+		}
+	}
+
+	if operator == "!=" {
+		return func(device *devices.Device) bool {
+			return string(device.Status) != values[0].text
+		}, nil
+	}
+
+	return func(device *devices.Device) bool {
+		return containsToken(values, string(device.Status))
+	}, nil
+}
+
+func knownStatus(status devices.Status) bool {
+	for _, known := range statuses {
+		if status == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func allowed(operators []string, operator string) bool {
+	for _, op := range operators {
+		if op == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsToken(values []token, value string) bool {
+	for _, t := range values {
+		if t.text == value {
+			return true
+		}
+	}
+
+	return false
+}
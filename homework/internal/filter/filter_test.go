@@ -0,0 +1,114 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+)
+
+func TestParse(t *testing.T) {
+	expr, err := Parse(`model == "RT-100" and ip in 10.0.0.0/8 or not (status == active AND position >= 3)`)
+
+	require.NoError(t, err)
+	require.Equal(t, `((model == "RT-100" and ip in ["10.0.0.0/8"]) or not (status == "active" and position >= "3"))`, expr.String())
+}
+
+func TestMatch(t *testing.T) {
+	device := &devices.Device{
+		SerialNum:  "SN-1",
+		Model:      "RT-100",
+		IP:         "10.1.2.3",
+		LocationID: "dc1",
+		Position:   4,
+		Status:     devices.StatusActive,
+		Labels:     map[string]string{"env": "prod"},
+		Attributes: map[string]string{"firmware": "2.1.0"},
+	}
+
+	cases := []struct {
+		filter string
+		expect bool
+	}{
+		{filter: `model == "RT-100" and ip in 10.0.0.0/8 and not status == "decommissioned"`, expect: true},
+		{filter: `model == RT-200 or serial_num == "SN-1"`, expect: true},
+		{filter: `ip == 10.1.2.3`, expect: true},
+		{filter: `ip != 10.1.2.3`, expect: false},
+		{filter: `ip in [192.168.0.0/16, 10.1.2.3]`, expect: true},
+		{filter: `ip in 192.168.0.0/16`, expect: false},
+		{filter: `position > 3 and position <= 4`, expect: true},
+		{filter: `position in [1, 2]`, expect: false},
+		{filter: `status in [active, maintenance]`, expect: true},
+		{filter: `status != active`, expect: false},
+		{filter: `labels.env == prod`, expect: true},
+		{filter: `labels.team == netops`, expect: false},
+		{filter: `labels.team != netops`, expect: true},
+		{filter: `attributes.firmware matches "^2\\."`, expect: true},
+		{filter: `location_id contains "dc"`, expect: true},
+		{filter: `not (model == "RT-100" or model == "RT-200")`, expect: false},
+	}
+
+	for _, tCase := range cases {
+		expr, err := Parse(tCase.filter)
+		require.NoError(t, err, tCase.filter)
+		require.Equal(t, tCase.expect, expr.Match(device), tCase.filter)
+	}
+
+	expr, err := Parse(`ip in 10.0.0.0/8 or ip != 10.0.0.1`)
+	require.NoError(t, err)
+	require.True(t, expr.Match(&devices.Device{IP: "not an ip"}))
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := []struct {
+		filter string
+		pos    int
+		msg    string
+	}{
+		{filter: ``, pos: 1, msg: "expected a field, got end of filter"},
+		{filter: `model = "RT-100"`, pos: 7, msg: `unknown operator "=", did you mean "=="`},
+		{filter: `modle == "RT-100"`, pos: 1, msg: `unknown field "modle"`},
+		{filter: `model == "RT-100`, pos: 10, msg: "unterminated string"},
+		{filter: `model == and`, pos: 10, msg: `expected a value, got "and"`},
+		{filter: `(model == x or ip == 10.0.0.1`, pos: 30, msg: "expected ')' to close '(' at position 1"},
+		{filter: `model == x model == y`, pos: 12, msg: `unexpected "model", expected "and" or "or"`},
+		{filter: `position > "3"`, pos: 12, msg: `position is a number, got "3"`},
+		{filter: `status == "retired"`, pos: 11, msg: `unknown status "retired"`},
+		{filter: `model < "RT-100"`, pos: 7, msg: `operator "<" is not defined for string field "model"`},
+		{filter: `ip contains "10."`, pos: 4, msg: `operator "contains" is not defined for address field "ip"`},
+		{filter: `ip in 10.0.0.0/33`, pos: 7, msg: `invalid subnet "10.0.0.0/33"`},
+		{filter: `status in [active, x`, pos: 21, msg: "expected ',' or ']' to close '[' at position 11"},
+		{filter: `labels. == x`, pos: 1, msg: `missing key after "labels."`},
+		{filter: `serial_num matches "("`, pos: 20, msg: "invalid regular expression"},
+		{filter: `model == x & y`, pos: 12, msg: `unexpected character '&'`},
+	}
+
+	for _, tCase := range cases {
+		_, err := Parse(tCase.filter)
+
+		var filterErr *Error
+		require.ErrorAs(t, err, &filterErr, tCase.filter)
+		require.Equal(t, tCase.pos, filterErr.Pos, tCase.filter)
+		require.Contains(t, filterErr.Msg, tCase.msg, tCase.filter)
+	}
+}
+
+func TestConjuncts(t *testing.T) {
+	expr, err := Parse(`model in [a, b] and (labels.env == prod and ip == 10.0.0.1) and (serial_num == x or serial_num == y)`)
+	require.NoError(t, err)
+
+	conjuncts := Conjuncts(expr)
+	require.Len(t, conjuncts, 3)
+
+	values, ok := conjuncts[0].Equals()
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "b"}, values)
+
+	values, ok = conjuncts[1].Equals()
+	require.True(t, ok)
+	require.Equal(t, []string{"prod"}, values)
+
+	_, ok = conjuncts[2].Equals()
+	require.False(t, ok)
+}
@@ -0,0 +1,161 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenAddress
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenComma
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of filter"
+	case tokenIdent:
+		return "name"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return "number"
+	case tokenAddress:
+		return "address"
+	case tokenOperator:
+		return "operator"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenLBracket:
+		return "'['"
+	case tokenRBracket:
+		return "']'"
+	default:
+		return "','"
+	}
+}
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the byte offset of the token in the filter, starting at 1.
+	pos int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return t.kind.String()
+	}
+
+	return fmt.Sprintf("%q", t.text)
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		start := i
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: start + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: start + 1})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenLBracket, text: "[", pos: start + 1})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenRBracket, text: "]", pos: start + 1})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: start + 1})
+			i++
+		case c == '"':
+			text, n, err := lexString(s[i:], start+1)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: start + 1})
+			i += n
+		case strings.ContainsRune("=!<>", c):
+			op := s[i : i+1]
+			if i+1 < len(s) && s[i+1] == '=' {
+				op = s[i : i+2]
+			}
+			if op == "=" || op == "!" {
+				return nil, newError(start+1, "unknown operator %q, did you mean \"%s=\"", op, op)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: start + 1})
+			i += len(op)
+		case unicode.IsDigit(c):
+			for i < len(s) && isAddressChar(rune(s[i])) {
+				i++
+			}
+			kind := tokenNumber
+			if strings.ContainsAny(s[start:i], ".:/") {
+				kind = tokenAddress
+			}
+			tokens = append(tokens, token{kind: kind, text: s[start:i], pos: start + 1})
+		case isIdentChar(c):
+			for i < len(s) && isIdentChar(rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: s[start:i], pos: start + 1})
+		default:
+			return nil, newError(start+1, "unexpected character %q", c)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(s) + 1}), nil
+}
+
+// lexString reads a double quoted string with backslash escapes and
+// returns its value and length in the input.
+func lexString(s string, pos int) (string, int, error) {
+	var b strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, newError(pos, "unterminated string")
+			}
+			i++
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return "", 0, newError(pos, "unterminated string")
+}
+
+func isIdentChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '.' || c == '-' || c == '/'
+}
+
+func isAddressChar(c rune) bool {
+	return unicode.IsDigit(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') || c == '.' || c == ':' || c == '/'
+}
@@ -0,0 +1,236 @@
+// Package filter implements the filter language of device listings:
+//
+//	model == "RT-100" and ip in 10.0.0.0/8 and not status == "decommissioned"
+//
+// Comparisons name a device field, an operator and a value. They combine
+// with and, or, not and parentheses, and binds tighter than or. Values
+// are quoted strings, numbers, addresses or bare words, lists are written
+// in brackets: status in [active, maintenance].
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is a syntax or type error. Pos is the byte offset of the
+// offending token in the filter, starting at 1.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func newError(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("filter: %s at position %d", e.Msg, e.Pos)
+}
+
+const (
+	keywordAnd      = "and"
+	keywordOr       = "or"
+	keywordNot      = "not"
+	keywordIn       = "in"
+	keywordContains = "contains"
+	keywordMatches  = "matches"
+)
+
+func isKeyword(t token) bool {
+	if t.kind != tokenIdent {
+		return false
+	}
+
+	switch strings.ToLower(t.text) {
+	case keywordAnd, keywordOr, keywordNot, keywordIn, keywordContains, keywordMatches:
+		return true
+	default:
+		return false
+	}
+}
+
+// Parse parses and type checks a filter.
+func Parse(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, newError(t.pos, "unexpected %s, expected \"and\" or \"or\"", t)
+	}
+
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+
+	return t
+}
+
+// accept consumes the next token if it is the keyword.
+func (p *parser) accept(keyword string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.text, keyword) {
+		p.next++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(keywordOr) {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.accept(keywordAnd) {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.accept(keywordNot) {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.advance()
+
+	if t.kind == tokenLParen {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenRParen {
+			return nil, newError(closing.pos, "expected ')' to close '(' at position %d, got %s", t.pos, closing)
+		}
+		return expr, nil
+	}
+
+	if t.kind != tokenIdent || isKeyword(t) {
+		return nil, newError(t.pos, "expected a field, got %s", t)
+	}
+
+	typ, err := lookupField(t)
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.advance()
+	if op.kind != tokenOperator && (op.kind != tokenIdent || !isKeyword(op)) {
+		return nil, newError(op.pos, "expected an operator after %q, got %s", t.text, op)
+	}
+	operator := strings.ToLower(op.text)
+
+	var values []token
+	if operator == keywordIn {
+		values, err = p.parseList()
+	} else {
+		var value token
+		value, err = p.parseValue()
+		values = []token{value}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return compile(t.text, typ, op, values)
+}
+
+// parseList reads the operand of in, either a bracketed list or a single
+// value such as a subnet.
+func (p *parser) parseList() ([]token, error) {
+	if p.peek().kind != tokenLBracket {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return []token{value}, nil
+	}
+	open := p.advance()
+
+	var values []token
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+
+		switch t := p.advance(); t.kind {
+		case tokenComma:
+		case tokenRBracket:
+			return values, nil
+		default:
+			return nil, newError(t.pos, "expected ',' or ']' to close '[' at position %d, got %s", open.pos, t)
+		}
+	}
+}
+
+func (p *parser) parseValue() (token, error) {
+	t := p.advance()
+
+	switch t.kind {
+	case tokenString, tokenNumber, tokenAddress:
+		return t, nil
+	case tokenIdent:
+		if !isKeyword(t) {
+			return t, nil
+		}
+	}
+
+	return token{}, newError(t.pos, "expected a value, got %s", t)
+}
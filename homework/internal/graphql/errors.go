@@ -0,0 +1,138 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Codes set as extensions.code of errors found before execution.
+const (
+	CodeParseFailed      = "GRAPHQL_PARSE_FAILED"
+	CodeValidationFailed = "GRAPHQL_VALIDATION_FAILED"
+	CodeBadUserInput     = "BAD_USER_INPUT"
+	CodeQueryTooDeep     = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex  = "QUERY_TOO_COMPLEX"
+	CodeInternal         = "INTERNAL_SERVER_ERROR"
+)
+
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is an error of the response. Path is set for errors raised while
+// resolving a field.
+type Error struct {
+	Message    string                 `json:"message"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Coded is implemented by resolver errors that set extensions.code.
+type Coded interface {
+	error
+	Code() string
+}
+
+func validationError(loc Location, format string, args ...interface{}) *Error {
+	return &Error{
+		Message:    fmt.Sprintf(format, args...),
+		Locations:  []Location{loc},
+		Extensions: map[string]interface{}{"code": CodeValidationFailed},
+	}
+}
+
+func inputError(loc Location, format string, args ...interface{}) *Error {
+	return &Error{
+		Message:    fmt.Sprintf(format, args...),
+		Locations:  []Location{loc},
+		Extensions: map[string]interface{}{"code": CodeBadUserInput},
+	}
+}
+
+// fieldError turns an error of a resolver into a response error of the
+// field at path.
+func fieldError(err error, loc Location, path []interface{}) *Error {
+	e := &Error{
+		Message:   err.Error(),
+		Locations: []Location{loc},
+		Path:      append([]interface{}(nil), path...),
+	}
+
+	code := CodeInternal
+	if coded, ok := err.(Coded); ok {
+		code = coded.Code()
+	}
+	e.Extensions = map[string]interface{}{"code": code}
+
+	return e
+}
+
+// Result is the response to a request. Data is left out of the JSON for
+// requests that failed before execution, as the spec asks.
+type Result struct {
+	Data   interface{}
+	Errors []*Error
+	// executed is set once execution started, data is then always present.
+	executed bool
+}
+
+func (r *Result) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Data   *interface{} `json:"data,omitempty"`
+		Errors []*Error     `json:"errors,omitempty"`
+	}{Errors: r.Errors}
+	if r.executed {
+		out.Data = &r.Data
+	}
+
+	return json.Marshal(out)
+}
+
+// Executed reports whether the request got to execution, requests that
+// did not are malformed or invalid and their result holds no data.
+func (r *Result) Executed() bool {
+	return r.executed
+}
+
+func failed(errs ...*Error) *Result {
+	return &Result{Errors: errs}
+}
+
+// object is the result of a selection set, it keeps the fields in the
+// order they were selected.
+type object []objectEntry
+
+type objectEntry struct {
+	key   string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, entry := range o {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+
+		key, err := json.Marshal(entry.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(entry.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf = append(buf, key...)
+		buf = append(buf, ':')
+		buf = append(buf, value...)
+	}
+
+	return append(buf, '}'), nil
+}
@@ -0,0 +1,541 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Request is a GraphQL request as sent over HTTP.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Options limit what a request may ask for, zero values do not limit.
+type Options struct {
+	// MaxDepth is the deepest nesting of fields, root fields are at depth 1.
+	MaxDepth int
+	// MaxComplexity bounds the summed Cost of the fields of the operation.
+	// Fields below a field with a "first" argument count that many times.
+	MaxComplexity int
+	// QueriesOnly rejects mutations, for requests that must not change
+	// anything such as HTTP GET.
+	QueriesOnly bool
+}
+
+// Execute runs the request. Fields are resolved one after another, so
+// resolvers need no locking and mutations run in order.
+func (s *Schema) Execute(ctx context.Context, req *Request, opts Options) *Result {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return failed(err.(*Error))
+	}
+
+	if errs := validate(s, doc); len(errs) > 0 {
+		return failed(dedupe(errs)...)
+	}
+
+	op, gqlErr := selectOperation(doc, req.OperationName)
+	if gqlErr != nil {
+		return failed(gqlErr)
+	}
+	if opts.QueriesOnly && op.kind != "query" {
+		return failed(validationError(op.loc, "only queries are allowed for this request, not %s", op.kind))
+	}
+
+	vars, errs := s.coerceVariables(op, req.Variables)
+	if len(errs) > 0 {
+		return failed(errs...)
+	}
+
+	root := s.query
+	if op.kind == "mutation" {
+		root = s.mutation
+	}
+
+	budget := maxCost
+	if opts.MaxComplexity > 0 {
+		budget = opts.MaxComplexity
+	}
+	m := &measure{schema: s, doc: doc, vars: vars, maxDepth: opts.MaxDepth}
+	depth, complexity := m.selectionSet(root, op.selectionSet, 1, budget)
+	if m.lists > maxIntrospectionLists {
+		return failed(&Error{
+			Message:    fmt.Sprintf("introspection nests more than %d lists", maxIntrospectionLists),
+			Locations:  []Location{op.loc},
+			Extensions: map[string]interface{}{"code": CodeQueryTooDeep, "maxIntrospectionLists": maxIntrospectionLists},
+		})
+	}
+	if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+		return failed(&Error{
+			Message:    fmt.Sprintf("query depth exceeds the limit of %d", opts.MaxDepth),
+			Locations:  []Location{op.loc},
+			Extensions: map[string]interface{}{"code": CodeQueryTooDeep, "maxDepth": opts.MaxDepth},
+		})
+	}
+	if opts.MaxComplexity > 0 && complexity > opts.MaxComplexity {
+		return failed(&Error{
+			Message:    fmt.Sprintf("query complexity exceeds the limit of %d", opts.MaxComplexity),
+			Locations:  []Location{op.loc},
+			Extensions: map[string]interface{}{"code": CodeQueryTooComplex, "maxComplexity": opts.MaxComplexity},
+		})
+	}
+
+	e := &executor{ctx: ctx, schema: s, doc: doc, vars: vars}
+	data, ok := e.selectionSet(root, nil, op.selectionSet, nil)
+
+	result := &Result{Errors: e.errs, executed: true}
+	if ok {
+		result.Data = data
+	}
+
+	return result
+}
+
+func dedupe(errs []*Error) []*Error {
+	seen := make(map[string]bool, len(errs))
+	result := errs[:0]
+	for _, err := range errs {
+		key := fmt.Sprint(err.Message, err.Locations)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, err)
+		}
+	}
+
+	return result
+}
+
+func selectOperation(doc *document, name string) (*operation, *Error) {
+	if name == "" {
+		if len(doc.operations) != 1 {
+			return nil, inputError(Location{Line: 1, Column: 1}, "operationName is required for a document with %d operations", len(doc.operations))
+		}
+		return doc.operations[0], nil
+	}
+
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+
+	return nil, inputError(Location{Line: 1, Column: 1}, "unknown operation %q", name)
+}
+
+func (s *Schema) coerceVariables(op *operation, given map[string]interface{}) (map[string]interface{}, []*Error) {
+	vars := make(map[string]interface{}, len(op.variables))
+
+	var errs []*Error
+	for _, def := range op.variables {
+		// Validation made sure the type exists.
+		t, _ := s.lookup(def.typ)
+
+		value, present := given[def.name]
+		switch {
+		case !present && def.fallback != nil:
+			coerced, _ := coerceLiteral(t, def.fallback, nil)
+			vars[def.name] = coerced
+		case !present:
+			if _, required := t.(*NonNull); required {
+				errs = append(errs, inputError(def.loc, "variable $%s of required type %s was not provided", def.name, def.typ))
+			}
+		default:
+			coerced, err := coerceValue(t, value)
+			if err != nil {
+				errs = append(errs, inputError(def.loc, "variable $%s got an invalid value: %s", def.name, err))
+				continue
+			}
+			vars[def.name] = coerced
+		}
+	}
+
+	return vars, errs
+}
+
+// coerceArguments coerces the arguments of a field or directive, applying
+// defaults for those not given.
+func coerceArguments(defs []*Argument, args []*argument, vars map[string]interface{}) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(defs))
+	for _, def := range defs {
+		var given *argument
+		for _, arg := range args {
+			if arg.name == def.Name {
+				given = arg
+			}
+		}
+
+		present := given != nil
+		if present && given.value.kind == valueVariable {
+			_, present = vars[given.value.raw]
+		}
+
+		if !present {
+			if def.Default != nil {
+				result[def.Name] = def.Default
+			} else if _, required := def.Type.(*NonNull); required {
+				return nil, fmt.Errorf("argument %q of required type %s was not provided", def.Name, def.Type)
+			}
+			continue
+		}
+
+		value, err := coerceLiteral(def.Type, given.value, vars)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %w", def.Name, err)
+		}
+		result[def.Name] = value
+	}
+
+	return result, nil
+}
+
+var ifArgument = []*Argument{{Name: "if", Type: NewNonNull(Boolean)}}
+
+// included evaluates @skip and @include.
+func included(directives []*directive, vars map[string]interface{}) bool {
+	for _, d := range directives {
+		args, err := coerceArguments(ifArgument, d.arguments, vars)
+		if err != nil {
+			continue
+		}
+
+		condition, _ := args["if"].(bool)
+		if d.name == "skip" && condition || d.name == "include" && !condition {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fieldGroup holds the fields selected under one response key.
+type fieldGroup struct {
+	key    string
+	fields []*field
+}
+
+// collectFields groups the selected fields of the selection set by
+// response key, in the order they first appear.
+func collectFields(doc *document, set []selection, vars map[string]interface{}) []*fieldGroup {
+	c := &collector{doc: doc, vars: vars, byKey: make(map[string]*fieldGroup), visited: make(map[string]bool)}
+	c.collect(set)
+
+	return c.groups
+}
+
+type collector struct {
+	doc     *document
+	vars    map[string]interface{}
+	groups  []*fieldGroup
+	byKey   map[string]*fieldGroup
+	visited map[string]bool
+}
+
+func (c *collector) collect(set []selection) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *field:
+			if !included(sel.directives, c.vars) {
+				continue
+			}
+
+			group, ok := c.byKey[sel.responseKey()]
+			if !ok {
+				group = &fieldGroup{key: sel.responseKey()}
+				c.byKey[group.key] = group
+				c.groups = append(c.groups, group)
+			}
+			group.fields = append(group.fields, sel)
+		case *inlineFragment:
+			if included(sel.directives, c.vars) {
+				c.collect(sel.selectionSet)
+			}
+		case *fragmentSpread:
+			if c.visited[sel.name] || !included(sel.directives, c.vars) {
+				continue
+			}
+			c.visited[sel.name] = true
+			c.collect(c.doc.fragments[sel.name].selectionSet)
+		}
+	}
+}
+
+// subSelection merges the selection sets of the fields of a group.
+func (g *fieldGroup) subSelection() []selection {
+	if len(g.fields) == 1 {
+		return g.fields[0].selectionSet
+	}
+
+	var set []selection
+	for _, f := range g.fields {
+		set = append(set, f.selectionSet...)
+	}
+
+	return set
+}
+
+type executor struct {
+	ctx    context.Context
+	schema *Schema
+	doc    *document
+	vars   map[string]interface{}
+	errs   []*Error
+}
+
+// selectionSet resolves the selections on a value of type t. It returns
+// false when a non-null field turned out null, the null then propagates
+// to the parent.
+func (e *executor) selectionSet(t *Object, source interface{}, set []selection, path []interface{}) (object, bool) {
+	groups := collectFields(e.doc, set, e.vars)
+
+	result := make(object, 0, len(groups))
+	for _, group := range groups {
+		value, ok := e.field(t, source, group, append(path, group.key))
+		if !ok {
+			return nil, false
+		}
+		result = append(result, objectEntry{key: group.key, value: value})
+	}
+
+	return result, true
+}
+
+func (e *executor) field(t *Object, source interface{}, group *fieldGroup, path []interface{}) (interface{}, bool) {
+	f := group.fields[0]
+	if f.name == "__typename" {
+		return t.Name, true
+	}
+
+	def := e.schema.field(t, f.name)
+	value, err := e.resolve(def, source, f)
+	if err != nil {
+		e.errs = append(e.errs, fieldError(err, f.loc, path))
+		_, nonNull := def.Type.(*NonNull)
+		return nil, !nonNull
+	}
+
+	return e.complete(def.Type, group, value, path)
+}
+
+func (e *executor) resolve(def *Field, source interface{}, f *field) (value interface{}, err error) {
+	if err = e.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	args, err := coerceArguments(def.Args, f.arguments, e.vars)
+	if err != nil {
+		return nil, &inputFailure{err}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("internal error resolving %s", def.Name)
+		}
+	}()
+
+	if def.Resolve == nil {
+		fields, _ := source.(map[string]interface{})
+		return fields[def.Name], nil
+	}
+
+	return def.Resolve(ResolveParams{Context: e.ctx, Source: source, Args: args})
+}
+
+// inputFailure is an argument that could not be coerced at execution,
+// such as a variable of a list turning out null for a non-null item.
+type inputFailure struct {
+	err error
+}
+
+func (e *inputFailure) Error() string { return e.err.Error() }
+
+func (e *inputFailure) Code() string { return CodeBadUserInput }
+
+// complete turns a resolved value into its result for type t. It reports
+// false when the value is null for a non-null type.
+func (e *executor) complete(t Type, group *fieldGroup, value interface{}, path []interface{}) (interface{}, bool) {
+	if nonNull, ok := t.(*NonNull); ok {
+		result, ok := e.completeNullable(nonNull.OfType, group, value, path)
+		if ok && result == nil {
+			e.errs = append(e.errs, &Error{
+				Message:    fmt.Sprintf("cannot return null for non-null field %s", group.key),
+				Locations:  []Location{group.fields[0].loc},
+				Path:       append([]interface{}(nil), path...),
+				Extensions: map[string]interface{}{"code": CodeInternal},
+			})
+		}
+		return result, ok && result != nil
+	}
+
+	result, ok := e.completeNullable(t, group, value, path)
+	if !ok {
+		return nil, true
+	}
+
+	return result, true
+}
+
+// completeNullable completes a value of a type that is not non-null
+// itself. False means the value must be null and the error is recorded.
+func (e *executor) completeNullable(t Type, group *fieldGroup, value interface{}, path []interface{}) (interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	if value == nil || (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Map || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return nil, true
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.errs = append(e.errs, fieldError(fmt.Errorf("expected a list for field %s, got %T", group.key, value), group.fields[0].loc, path))
+			return nil, false
+		}
+
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			item, ok := e.complete(t.OfType, group, rv.Index(i).Interface(), append(path, i))
+			if !ok {
+				return nil, false
+			}
+			items[i] = item
+		}
+		return items, true
+	case *Object:
+		return e.selectionSet(t, value, group.subSelection(), path)
+	case *Scalar:
+		return e.serialize(t.serialize, group, value, path)
+	case *Enum:
+		return e.serialize(t.serialize, group, value, path)
+	}
+
+	return nil, false
+}
+
+func (e *executor) serialize(serialize func(interface{}) (interface{}, error), group *fieldGroup, value interface{}, path []interface{}) (interface{}, bool) {
+	result, err := serialize(value)
+	if err != nil {
+		e.errs = append(e.errs, fieldError(err, group.fields[0].loc, path))
+		return nil, false
+	}
+
+	return result, true
+}
+
+// maxCost caps complexities, so that counting can not overflow.
+const maxCost = math.MaxInt32
+
+// measure computes the depth and complexity of an operation over the
+// fields execution would resolve. Fields of introspection types do not
+// add to the depth, lists records the deepest nesting of their list
+// fields instead.
+type measure struct {
+	schema   *Schema
+	doc      *document
+	vars     map[string]interface{}
+	maxDepth int
+
+	nesting int
+	lists   int
+}
+
+// selectionSet returns the depth and complexity of the selections on t.
+// Counting stops once the complexity passes budget or the depth passes
+// maxDepth, as fragments spread at every level can make the operation
+// grow exponentially with the size of the document.
+func (m *measure) selectionSet(t *Object, set []selection, depth, budget int) (int, int) {
+	if m.maxDepth > 0 && depth > m.maxDepth {
+		return depth, 0
+	}
+
+	maxDepth, complexity := 0, 0
+	for _, group := range collectFields(m.doc, set, m.vars) {
+		f := group.fields[0]
+		if f.name == "__typename" {
+			if depth > maxDepth {
+				maxDepth = depth
+			}
+			continue
+		}
+
+		def := m.schema.field(t, f.name)
+		cost := def.Cost
+		if cost == 0 && !isLeaf(def.Type) {
+			cost = 1
+		}
+		multiplier := m.multiplier(def, f)
+
+		fieldDepth, childComplexity := depth, 0
+		if child, ok := namedType(def.Type).(*Object); ok {
+			childDepth, list := depth+1, false
+			if introspective(child) {
+				childDepth, list = depth, isList(def.Type)
+			}
+			if list {
+				m.nesting++
+				if m.nesting > m.lists {
+					m.lists = m.nesting
+				}
+			}
+
+			if m.lists <= maxIntrospectionLists {
+				childBudget := (budget - complexity - cost) / multiplier
+				fieldDepth, childComplexity = m.selectionSet(child, group.subSelection(), childDepth, childBudget)
+			}
+			if list {
+				m.nesting--
+			}
+		}
+
+		if fieldDepth > maxDepth {
+			maxDepth = fieldDepth
+		}
+		complexity = addCost(complexity, addCost(cost, mulCost(multiplier, childComplexity)))
+
+		if complexity > budget || m.maxDepth > 0 && maxDepth > m.maxDepth || m.lists > maxIntrospectionLists {
+			break
+		}
+	}
+
+	return maxDepth, complexity
+}
+
+// multiplier is the value of the "first" argument of the field, the
+// number of items it asks for, and 1 for other fields.
+func (m *measure) multiplier(def *Field, f *field) int {
+	if def.arg("first") == nil {
+		return 1
+	}
+
+	args, err := coerceArguments(def.Args, f.arguments, m.vars)
+	if err != nil {
+		return 1
+	}
+
+	first, ok := args["first"].(int)
+	switch {
+	case !ok || first <= 0:
+		return 1
+	case first > maxCost:
+		return maxCost
+	}
+
+	return first
+}
+
+func addCost(a, b int) int {
+	if a > maxCost-b {
+		return maxCost
+	}
+
+	return a + b
+}
+
+func mulCost(a, b int) int {
+	if a != 0 && b > maxCost/a {
+		return maxCost
+	}
+
+	return a * b
+}
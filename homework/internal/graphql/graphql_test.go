@@ -0,0 +1,385 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type codedError struct{ code string }
+
+func (e *codedError) Error() string { return "no such item" }
+
+func (e *codedError) Code() string { return e.code }
+
+func testSchema(t *testing.T) *Schema {
+	color := &Enum{Name: "Color", Values: []*EnumValue{{Name: "RED", Value: 1}, {Name: "BLUE", Value: 2}}}
+	item := &Object{Name: "Item", Fields: []*Field{
+		{Name: "name", Type: NewNonNull(String)},
+		{Name: "color", Type: color},
+		{Name: "broken", Type: NewNonNull(String), Resolve: func(p ResolveParams) (interface{}, error) {
+			return nil, &codedError{code: "NOT_FOUND"}
+		}},
+		{Name: "panics", Type: String, Resolve: func(p ResolveParams) (interface{}, error) {
+			panic("boom")
+		}},
+	}}
+	item.Fields = append(item.Fields, &Field{
+		Name: "children",
+		Type: NewNonNull(NewList(NewNonNull(item))),
+		Args: []*Argument{{Name: "first", Type: Int, Default: 2}},
+		Resolve: func(p ResolveParams) (interface{}, error) {
+			children := make([]interface{}, p.Args["first"].(int))
+			for i := range children {
+				children[i] = map[string]interface{}{"name": "child", "color": 2}
+			}
+			return children, nil
+		},
+	})
+
+	filter := &InputObject{Name: "Filter", Fields: []*InputField{
+		{Name: "color", Type: color},
+		{Name: "names", Type: NewList(NewNonNull(String))},
+	}}
+	query := &Object{Name: "Query", Fields: []*Field{
+		{
+			Name: "item",
+			Type: item,
+			Args: []*Argument{{Name: "name", Type: NewNonNull(String)}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				if p.Args["name"] == "missing" {
+					return nil, &codedError{code: "NOT_FOUND"}
+				}
+				return map[string]interface{}{"name": p.Args["name"], "color": 1}, nil
+			},
+		},
+		{
+			Name: "echo",
+			Type: String,
+			Args: []*Argument{{Name: "filter", Type: filter}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				out, err := json.Marshal(p.Args["filter"])
+				return string(out), err
+			},
+		},
+		{
+			Name: "fail",
+			Type: String,
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				return nil, errors.New("plain failure")
+			},
+		},
+	}}
+	mutation := &Object{Name: "Mutation", Fields: []*Field{
+		{Name: "touch", Type: NewNonNull(Boolean), Resolve: func(p ResolveParams) (interface{}, error) {
+			return true, nil
+		}},
+	}}
+
+	schema, err := NewSchema(query, mutation)
+	require.NoError(t, err)
+
+	return schema
+}
+
+func execute(schema *Schema, req *Request, opts Options) string {
+	out, err := json.Marshal(schema.Execute(context.Background(), req, opts))
+	if err != nil {
+		panic(err)
+	}
+
+	return string(out)
+}
+
+func TestExecute(t *testing.T) {
+	schema := testSchema(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		variables string
+		want      string
+	}{
+		{
+			name:  "fields in selection order",
+			query: `{ item(name: "a") { color name __typename } }`,
+			want:  `{"data":{"item":{"color":"RED","name":"a","__typename":"Item"}}}`,
+		},
+		{
+			name:  "aliases and arguments",
+			query: `{ a: item(name: "a") { name } b: item(name: "b") { children(first: 1) { name color } } }`,
+			want:  `{"data":{"a":{"name":"a"},"b":{"children":[{"name":"child","color":"BLUE"}]}}}`,
+		},
+		{
+			name:  "fragments merge",
+			query: `query { item(name: "a") { ...F ... on Item { color } ... @skip(if: true) { broken } } } fragment F on Item { name }`,
+			want:  `{"data":{"item":{"name":"a","color":"RED"}}}`,
+		},
+		{
+			name:      "variables",
+			query:     `query Q($name: String!, $on: Boolean = false) { item(name: $name) { name color @include(if: $on) } }`,
+			variables: `{"name": "v"}`,
+			want:      `{"data":{"item":{"name":"v"}}}`,
+		},
+		{
+			name:      "input objects",
+			query:     `query($c: Color) { echo(filter: {color: $c, names: "x"}) }`,
+			variables: `{"c": "BLUE"}`,
+			want:      `{"data":{"echo":"{\"color\":2,\"names\":[\"x\"]}"}}`,
+		},
+		{
+			name:  "resolver errors keep their code",
+			query: `{ item(name: "missing") { name } fail }`,
+			want: `{"data":{"item":null,"fail":null},"errors":[
+				{"message":"no such item","locations":[{"line":1,"column":3}],"path":["item"],"extensions":{"code":"NOT_FOUND"}},
+				{"message":"plain failure","locations":[{"line":1,"column":34}],"path":["fail"],"extensions":{"code":"INTERNAL_SERVER_ERROR"}}]}`,
+		},
+		{
+			name:  "null propagates to the nullable parent",
+			query: `{ item(name: "a") { name broken } }`,
+			want: `{"data":{"item":null},"errors":[
+				{"message":"no such item","locations":[{"line":1,"column":26}],"path":["item","broken"],"extensions":{"code":"NOT_FOUND"}}]}`,
+		},
+		{
+			name:  "panics become errors",
+			query: `{ item(name: "a") { panics } }`,
+			want: `{"data":{"item":{"panics":null}},"errors":[
+				{"message":"internal error resolving panics","locations":[{"line":1,"column":21}],"path":["item","panics"],"extensions":{"code":"INTERNAL_SERVER_ERROR"}}]}`,
+		},
+		{
+			name:  "mutations",
+			query: `mutation { touch }`,
+			want:  `{"data":{"touch":true}}`,
+		},
+		{
+			name:  "syntax errors",
+			query: `{ item(name: "a") { name }`,
+			want:  `{"errors":[{"message":"syntax error: expected name, found <EOF>","locations":[{"line":1,"column":27}],"extensions":{"code":"GRAPHQL_PARSE_FAILED"}}]}`,
+		},
+		{
+			name:      "invalid variables",
+			query:     `query($name: String!) { item(name: $name) { name } }`,
+			variables: `{"name": 1}`,
+			want:      `{"errors":[{"message":"variable $name got an invalid value: String can not represent 1","locations":[{"line":1,"column":7}],"extensions":{"code":"BAD_USER_INPUT"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &Request{Query: tt.query}
+			if tt.variables != "" {
+				decoder := json.NewDecoder(strings.NewReader(tt.variables))
+				decoder.UseNumber()
+				require.NoError(t, decoder.Decode(&req.Variables))
+			}
+
+			require.JSONEq(t, tt.want, execute(schema, req, Options{}))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := testSchema(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"unknown field", `{ nope }`, `cannot query field "nope" on type Query`},
+		{"missing selection", `{ item(name: "a") }`, `field "item" of type Item must have a selection of subfields`},
+		{"selection on leaf", `{ fail { x } }`, `field "fail" of type String must not have a selection of subfields`},
+		{"missing argument", `{ item { name } }`, `field Query.item argument "name" of type String! is required but not provided`},
+		{"unknown argument", `{ item(name: "a", id: 1) { name } }`, `unknown argument "id" on field Query.item`},
+		{"invalid literal", `{ item(name: 1) { name } }`, `invalid value for argument name: String can not represent 1`},
+		{"undefined variable", `{ item(name: $x) { name } }`, `variable $x is not defined by operation (anonymous)`},
+		{"unused variable", `query($x: Int) { fail }`, `variable $x is never used by operation (anonymous)`},
+		{"variable type", `query($x: String) { item(name: $x) { name } }`, `variable $x of type String can not be used as argument name of type String!`},
+		{"variable in fragment", `query { ...F } fragment F on Query { item(name: $x) { name } }`, `variable $x is not defined by operation (anonymous)`},
+		{"unknown fragment", `{ ...F }`, `unknown fragment "F"`},
+		{"unused fragment", `{ fail } fragment F on Query { fail }`, `fragment "F" is never used`},
+		{"fragment cycle", `{ ...A } fragment A on Query { ...B } fragment B on Query { ...A }`, `fragment "A" spreads itself`},
+		{"wrong fragment type", `{ ...F } fragment F on Item { name }`, `a fragment on Item can not be spread within Query`},
+		{"conflicting fields", `{ fail: echo fail }`, `fields "fail" conflict because echo and fail are different fields`},
+		{"unknown directive", `{ fail @nope }`, `unknown directive @nope`},
+		{"duplicate operation", `query A { fail } query A { fail }`, `there can be only one operation named "A"`},
+		{"subscription", `subscription { fail }`, `subscription operations are not supported`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := schema.Execute(context.Background(), &Request{Query: tt.query}, Options{})
+			require.False(t, result.Executed())
+			require.NotEmpty(t, result.Errors)
+			require.Equal(t, tt.want, result.Errors[0].Message)
+			require.Equal(t, CodeValidationFailed, result.Errors[0].Extensions["code"])
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	schema := testSchema(t)
+
+	run := func(query string, opts Options) *Result {
+		return schema.Execute(context.Background(), &Request{Query: query}, opts)
+	}
+
+	deep := `{ item(name: "a") { children { children { name } } } }`
+	require.True(t, run(deep, Options{MaxDepth: 4}).Executed())
+	result := run(deep, Options{MaxDepth: 3})
+	require.False(t, result.Executed())
+	require.Equal(t, CodeQueryTooDeep, result.Errors[0].Extensions["code"])
+
+	// item and the outer children are resolved once, the inner children
+	// once for each of the 10 items.
+	wide := `{ item(name: "a") { children(first: 10) { children(first: 10) { name } } } }`
+	require.True(t, run(wide, Options{MaxComplexity: 12}).Executed())
+	result = run(wide, Options{MaxComplexity: 11})
+	require.False(t, result.Executed())
+	require.Equal(t, CodeQueryTooComplex, result.Errors[0].Extensions["code"])
+
+	// Fragments doubling the query at every level are cut short.
+	var b strings.Builder
+	b.WriteString(`{ item(name: "a") { ...F0 } }`)
+	for i := 0; i < 60; i++ {
+		b.WriteString(" fragment F" + strconv.Itoa(i) + " on Item { a: children { ...F" + strconv.Itoa(i+1) + " } b: children { ...F" + strconv.Itoa(i+1) + " } }")
+	}
+	b.WriteString(" fragment F60 on Item { name }")
+	result = run(b.String(), Options{MaxComplexity: 1000})
+	require.False(t, result.Executed())
+	require.Equal(t, CodeQueryTooComplex, result.Errors[0].Extensions["code"])
+
+	result = run(`mutation { touch }`, Options{QueriesOnly: true})
+	require.False(t, result.Executed())
+	require.Equal(t, "only queries are allowed for this request, not mutation", result.Errors[0].Message)
+}
+
+func TestIntrospection(t *testing.T) {
+	schema := testSchema(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "root types",
+			query: `{ __schema { queryType { name } mutationType { name } subscriptionType { name } } }`,
+			want:  `{"data":{"__schema":{"queryType":{"name":"Query"},"mutationType":{"name":"Mutation"},"subscriptionType":null}}}`,
+		},
+		{
+			name:  "fields and wrapped types",
+			query: `{ __type(name: "Item") { kind fields { name type { kind name ofType { name } } } } }`,
+			want: `{"data":{"__type":{"kind":"OBJECT","fields":[
+				{"name":"name","type":{"kind":"NON_NULL","name":null,"ofType":{"name":"String"}}},
+				{"name":"color","type":{"kind":"ENUM","name":"Color","ofType":null}},
+				{"name":"broken","type":{"kind":"NON_NULL","name":null,"ofType":{"name":"String"}}},
+				{"name":"panics","type":{"kind":"SCALAR","name":"String","ofType":null}},
+				{"name":"children","type":{"kind":"NON_NULL","name":null,"ofType":{"name":null}}}]}}}`,
+		},
+		{
+			name:  "arguments and defaults",
+			query: `{ __type(name: "Item") { fields { args { name defaultValue } } } }`,
+			want:  `{"data":{"__type":{"fields":[{"args":[]},{"args":[]},{"args":[]},{"args":[]},{"args":[{"name":"first","defaultValue":"2"}]}]}}}`,
+		},
+		{
+			name:  "enums and input objects",
+			query: `{ color: __type(name: "Color") { enumValues { name } } filter: __type(name: "Filter") { inputFields { name type { name } } } }`,
+			want: `{"data":{"color":{"enumValues":[{"name":"RED"},{"name":"BLUE"}]},
+				"filter":{"inputFields":[{"name":"color","type":{"name":"Color"}},{"name":"names","type":{"name":null}}]}}}`,
+		},
+		{
+			name:  "fragments on introspection types",
+			query: `{ __type(name: "Query") { ...T } missing: __type(name: "Nope") { ...T } } fragment T on __Type { name __typename }`,
+			want:  `{"data":{"__type":{"name":"Query","__typename":"__Type"},"missing":null}}`,
+		},
+		{
+			name:  "directives",
+			query: `{ __schema { directives { name locations args { name type { kind } } } } }`,
+			want: `{"data":{"__schema":{"directives":[
+				{"name":"skip","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","type":{"kind":"NON_NULL"}}]},
+				{"name":"include","locations":["FIELD","FRAGMENT_SPREAD","INLINE_FRAGMENT"],"args":[{"name":"if","type":{"kind":"NON_NULL"}}]}]}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.JSONEq(t, tt.want, execute(schema, &Request{Query: tt.query}, Options{}))
+		})
+	}
+
+	result := schema.Execute(context.Background(), &Request{Query: `{ __schema { types { name } } }`}, Options{})
+	require.Empty(t, result.Errors)
+	var names []string
+	for _, t := range result.Data.(object)[0].value.(object)[0].value.([]interface{}) {
+		names = append(names, t.(object)[0].value.(string))
+	}
+	require.Equal(t, []string{"Boolean", "Color", "Filter", "Float", "ID", "Int", "Item", "Mutation", "Query", "String",
+		"__Directive", "__DirectiveLocation", "__EnumValue", "__Field", "__InputValue", "__Schema", "__Type", "__TypeKind"}, names)
+
+	// The query tools such as GraphiQL send runs within the default
+	// limits, introspection does not count against the depth.
+	result = schema.Execute(context.Background(), &Request{Query: introspectionQuery}, Options{MaxDepth: 8, MaxComplexity: 1000})
+	require.Empty(t, result.Errors)
+
+	result = schema.Execute(context.Background(), &Request{Query: `{ __schema { types { fields { type { fields { type { fields { name } } } } } } } }`}, Options{})
+	require.False(t, result.Executed())
+	require.Equal(t, "introspection nests more than 3 lists", result.Errors[0].Message)
+	require.Equal(t, CodeQueryTooDeep, result.Errors[0].Extensions["code"])
+
+	result = schema.Execute(context.Background(), &Request{Query: `mutation { __schema { description } }`}, Options{})
+	require.Equal(t, `cannot query field "__schema" on type Mutation`, result.Errors[0].Message)
+}
+
+const introspectionQuery = `
+query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types { ...FullType }
+    directives { name description locations args { ...InputValue } }
+  }
+}
+fragment FullType on __Type {
+  kind name description
+  fields(includeDeprecated: true) { name description args { ...InputValue } type { ...TypeRef } isDeprecated deprecationReason }
+  inputFields { ...InputValue }
+  interfaces { ...TypeRef }
+  enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+  possibleTypes { ...TypeRef }
+}
+fragment InputValue on __InputValue { name description type { ...TypeRef } defaultValue }
+fragment TypeRef on __Type {
+  kind name
+  ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } } } }
+}`
+
+func TestSchemaSDL(t *testing.T) {
+	schema := testSchema(t)
+
+	sdl := schema.SDL()
+	require.Contains(t, sdl, "type Query {\n")
+	require.Contains(t, sdl, "  item(name: String!): Item\n")
+	require.Contains(t, sdl, "  children(first: Int = 2): [Item!]!\n")
+	require.Contains(t, sdl, "enum Color {\n  RED\n  BLUE\n}\n")
+	require.Contains(t, sdl, "input Filter {\n  color: Color\n  names: [String!]\n}\n")
+	require.Contains(t, sdl, "type Mutation {\n  touch: Boolean!\n}\n")
+	require.NotContains(t, sdl, "__")
+}
+
+func TestNewSchemaErrors(t *testing.T) {
+	_, err := NewSchema(nil, nil)
+	require.Error(t, err)
+
+	_, err = NewSchema(&Object{Name: "Query"}, nil)
+	require.EqualError(t, err, "graphql: object Query has no fields")
+
+	_, err = NewSchema(&Object{Name: "Query", Fields: []*Field{{Name: "a", Type: String}, {Name: "a", Type: Int}}}, nil)
+	require.EqualError(t, err, "graphql: field Query.a is defined twice")
+}
@@ -0,0 +1,349 @@
+package graphql
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// maxIntrospectionLists limits the list fields nested below __schema and
+// __type. Introspection is not counted against the depth limit, so tools
+// can run the standard introspection query, but nesting such as
+// types { fields { type { fields } } } grows with the schema size to the
+// power of the lists.
+const maxIntrospectionLists = 3
+
+// directiveDef describes a directive for introspection, the parser has
+// its own directive type for directives written in documents.
+type directiveDef struct {
+	name        string
+	description string
+	locations   []string
+	args        []*Argument
+}
+
+var directiveDefs = []*directiveDef{
+	{
+		name:        "skip",
+		description: "Skips the field or fragment when the argument is true.",
+		locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		args:        ifArgument,
+	},
+	{
+		name:        "include",
+		description: "Includes the field or fragment only when the argument is true.",
+		locations:   []string{"FIELD", "FRAGMENT_SPREAD", "INLINE_FRAGMENT"},
+		args:        ifArgument,
+	},
+}
+
+// inputValue is an argument or an input field for introspection.
+type inputValue struct {
+	name        string
+	description string
+	t           Type
+	def         interface{}
+}
+
+// addIntrospection registers the types of the introspection system and
+// the __schema and __type fields of the query root. The types resolve
+// against s, so every schema gets its own.
+func (s *Schema) addIntrospection() {
+	typeKind := &Enum{Name: "__TypeKind", Description: "The kind of a type.", Values: enumValues(
+		"SCALAR", "OBJECT", "INTERFACE", "UNION", "ENUM", "INPUT_OBJECT", "LIST", "NON_NULL")}
+	directiveLocation := &Enum{Name: "__DirectiveLocation", Description: "Where a directive may be used.", Values: enumValues(
+		"QUERY", "MUTATION", "SUBSCRIPTION", "FIELD", "FRAGMENT_DEFINITION", "FRAGMENT_SPREAD", "INLINE_FRAGMENT",
+		"VARIABLE_DEFINITION", "SCHEMA", "SCALAR", "OBJECT", "FIELD_DEFINITION", "ARGUMENT_DEFINITION", "INTERFACE",
+		"UNION", "ENUM", "ENUM_VALUE", "INPUT_OBJECT", "INPUT_FIELD_DEFINITION")}
+
+	typ := &Object{Name: "__Type", Description: "A type of the schema, or a list or non-null wrapper of one."}
+	field := &Object{Name: "__Field", Description: "A field of an object type."}
+	value := &Object{Name: "__InputValue", Description: "An argument or a field of an input object."}
+	enumValue := &Object{Name: "__EnumValue", Description: "A value of an enum."}
+	directive := &Object{Name: "__Directive", Description: "A directive the server supports."}
+	schema := &Object{Name: "__Schema", Description: "The types, root types and directives of the schema."}
+
+	includeDeprecated := []*Argument{{Name: "includeDeprecated", Type: Boolean, Default: false}}
+	deprecation := []*Field{
+		{Name: "isDeprecated", Type: NewNonNull(Boolean), Resolve: func(ResolveParams) (interface{}, error) {
+			return false, nil
+		}},
+		{Name: "deprecationReason", Type: String, Resolve: func(ResolveParams) (interface{}, error) {
+			return nil, nil
+		}},
+	}
+
+	typ.Fields = []*Field{
+		{Name: "kind", Type: NewNonNull(typeKind), Resolve: func(p ResolveParams) (interface{}, error) {
+			return typeKindOf(p.Source.(Type)), nil
+		}},
+		{Name: "name", Type: String, Resolve: func(p ResolveParams) (interface{}, error) {
+			switch p.Source.(type) {
+			case *List, *NonNull:
+				return nil, nil
+			}
+			return p.Source.(Type).String(), nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p ResolveParams) (interface{}, error) {
+			switch t := p.Source.(type) {
+			case *Scalar:
+				return optional(t.Description), nil
+			case *Enum:
+				return optional(t.Description), nil
+			case *Object:
+				return optional(t.Description), nil
+			case *InputObject:
+				return optional(t.Description), nil
+			}
+			return nil, nil
+		}},
+		{Name: "specifiedByURL", Type: String, Resolve: func(ResolveParams) (interface{}, error) {
+			return nil, nil
+		}},
+		{Name: "fields", Type: NewList(NewNonNull(field)), Args: includeDeprecated, Resolve: func(p ResolveParams) (interface{}, error) {
+			if o, ok := p.Source.(*Object); ok {
+				return o.Fields, nil
+			}
+			return nil, nil
+		}},
+		{Name: "interfaces", Type: NewList(NewNonNull(typ)), Resolve: func(p ResolveParams) (interface{}, error) {
+			if _, ok := p.Source.(*Object); ok {
+				return []Type{}, nil
+			}
+			return nil, nil
+		}},
+		{Name: "possibleTypes", Type: NewList(NewNonNull(typ)), Resolve: func(ResolveParams) (interface{}, error) {
+			return nil, nil
+		}},
+		{Name: "enumValues", Type: NewList(NewNonNull(enumValue)), Args: includeDeprecated, Resolve: func(p ResolveParams) (interface{}, error) {
+			if e, ok := p.Source.(*Enum); ok {
+				return e.Values, nil
+			}
+			return nil, nil
+		}},
+		{Name: "inputFields", Type: NewList(NewNonNull(value)), Args: includeDeprecated, Resolve: func(p ResolveParams) (interface{}, error) {
+			o, ok := p.Source.(*InputObject)
+			if !ok {
+				return nil, nil
+			}
+			values := make([]*inputValue, len(o.Fields))
+			for i, f := range o.Fields {
+				values[i] = &inputValue{name: f.Name, description: f.Description, t: f.Type, def: f.Default}
+			}
+			return values, nil
+		}},
+		{Name: "ofType", Type: typ, Resolve: func(p ResolveParams) (interface{}, error) {
+			switch t := p.Source.(type) {
+			case *List:
+				return t.OfType, nil
+			case *NonNull:
+				return t.OfType, nil
+			}
+			return nil, nil
+		}},
+	}
+
+	field.Fields = append([]*Field{
+		{Name: "name", Type: NewNonNull(String), Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Source.(*Field).Name, nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p ResolveParams) (interface{}, error) {
+			return optional(p.Source.(*Field).Description), nil
+		}},
+		{Name: "args", Type: NewNonNull(NewList(NewNonNull(value))), Args: includeDeprecated, Resolve: func(p ResolveParams) (interface{}, error) {
+			return argumentValues(p.Source.(*Field).Args), nil
+		}},
+		{Name: "type", Type: NewNonNull(typ), Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Source.(*Field).Type, nil
+		}},
+	}, deprecation...)
+
+	value.Fields = append([]*Field{
+		{Name: "name", Type: NewNonNull(String), Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Source.(*inputValue).name, nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p ResolveParams) (interface{}, error) {
+			return optional(p.Source.(*inputValue).description), nil
+		}},
+		{Name: "type", Type: NewNonNull(typ), Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Source.(*inputValue).t, nil
+		}},
+		{Name: "defaultValue", Type: String, Resolve: func(p ResolveParams) (interface{}, error) {
+			v := p.Source.(*inputValue)
+			return literal(v.t, v.def), nil
+		}},
+	}, deprecation...)
+
+	enumValue.Fields = append([]*Field{
+		{Name: "name", Type: NewNonNull(String), Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Source.(*EnumValue).Name, nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p ResolveParams) (interface{}, error) {
+			return optional(p.Source.(*EnumValue).Description), nil
+		}},
+	}, deprecation...)
+
+	directive.Fields = []*Field{
+		{Name: "name", Type: NewNonNull(String), Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Source.(*directiveDef).name, nil
+		}},
+		{Name: "description", Type: String, Resolve: func(p ResolveParams) (interface{}, error) {
+			return optional(p.Source.(*directiveDef).description), nil
+		}},
+		{Name: "locations", Type: NewNonNull(NewList(NewNonNull(directiveLocation))), Resolve: func(p ResolveParams) (interface{}, error) {
+			return p.Source.(*directiveDef).locations, nil
+		}},
+		{Name: "args", Type: NewNonNull(NewList(NewNonNull(value))), Args: includeDeprecated, Resolve: func(p ResolveParams) (interface{}, error) {
+			return argumentValues(p.Source.(*directiveDef).args), nil
+		}},
+		{Name: "isRepeatable", Type: NewNonNull(Boolean), Resolve: func(ResolveParams) (interface{}, error) {
+			return false, nil
+		}},
+	}
+
+	schema.Fields = []*Field{
+		{Name: "description", Type: String, Resolve: func(ResolveParams) (interface{}, error) {
+			return nil, nil
+		}},
+		{Name: "types", Type: NewNonNull(NewList(NewNonNull(typ))), Resolve: func(ResolveParams) (interface{}, error) {
+			names := make([]string, 0, len(s.types))
+			for name := range s.types {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			types := make([]Type, len(names))
+			for i, name := range names {
+				types[i] = s.types[name]
+			}
+			return types, nil
+		}},
+		{Name: "queryType", Type: NewNonNull(typ), Resolve: func(ResolveParams) (interface{}, error) {
+			return s.query, nil
+		}},
+		{Name: "mutationType", Type: typ, Resolve: func(ResolveParams) (interface{}, error) {
+			if s.mutation == nil {
+				return nil, nil
+			}
+			return s.mutation, nil
+		}},
+		{Name: "subscriptionType", Type: typ, Resolve: func(ResolveParams) (interface{}, error) {
+			return nil, nil
+		}},
+		{Name: "directives", Type: NewNonNull(NewList(NewNonNull(directive))), Resolve: func(ResolveParams) (interface{}, error) {
+			return directiveDefs, nil
+		}},
+	}
+
+	s.meta = map[string]*Field{
+		"__schema": {Name: "__schema", Type: NewNonNull(schema), Resolve: func(ResolveParams) (interface{}, error) {
+			return s, nil
+		}},
+		"__type": {
+			Name: "__type",
+			Type: typ,
+			Args: []*Argument{{Name: "name", Type: NewNonNull(String)}},
+			Resolve: func(p ResolveParams) (interface{}, error) {
+				if t, ok := s.types[p.Args["name"].(string)]; ok {
+					return t, nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	// The names start with "__", which add rejects for schema types.
+	for _, t := range []Type{typeKind, directiveLocation, typ, field, value, enumValue, directive, schema} {
+		s.types[t.String()] = t
+		if o, ok := t.(*Object); ok {
+			o.fields = make(map[string]*Field, len(o.Fields))
+			for _, f := range o.Fields {
+				o.fields[f.Name] = f
+			}
+		}
+	}
+}
+
+// field looks up a field of t, the query root also has the __schema and
+// __type fields.
+func (s *Schema) field(t *Object, name string) *Field {
+	if t == s.query {
+		if f, ok := s.meta[name]; ok {
+			return f
+		}
+	}
+
+	return t.field(name)
+}
+
+// introspective reports whether the fields of the type describe the
+// schema rather than data.
+func introspective(t *Object) bool {
+	return strings.HasPrefix(t.Name, "__")
+}
+
+func enumValues(names ...string) []*EnumValue {
+	values := make([]*EnumValue, len(names))
+	for i, name := range names {
+		values[i] = &EnumValue{Name: name, Value: name}
+	}
+
+	return values
+}
+
+func typeKindOf(t Type) string {
+	switch t.(type) {
+	case *Scalar:
+		return "SCALAR"
+	case *Object:
+		return "OBJECT"
+	case *Enum:
+		return "ENUM"
+	case *InputObject:
+		return "INPUT_OBJECT"
+	case *List:
+		return "LIST"
+	}
+
+	return "NON_NULL"
+}
+
+func argumentValues(args []*Argument) []*inputValue {
+	values := make([]*inputValue, len(args))
+	for i, arg := range args {
+		values[i] = &inputValue{name: arg.Name, description: arg.Description, t: arg.Type, def: arg.Default}
+	}
+
+	return values
+}
+
+func optional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+// literal formats a default value as a GraphQL literal, nil without one.
+func literal(t Type, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	if nonNull, ok := t.(*NonNull); ok {
+		t = nonNull.OfType
+	}
+	if e, ok := t.(*Enum); ok {
+		if name, err := e.serialize(v); err == nil {
+			return name
+		}
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	return string(buf)
+}
@@ -0,0 +1,278 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunct
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	loc   Location
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "<EOF>"
+	case tokenString:
+		return strconv.Quote(t.value)
+	}
+
+	return t.value
+}
+
+// lexer splits a document into tokens. Commas, white space and comments
+// are ignored as the spec asks.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+	col  int
+}
+
+func newLexer(src string) *lexer {
+	return &lexer{src: strings.TrimPrefix(src, "\ufeff"), line: 1, col: 1}
+}
+
+func (l *lexer) advance(n int) {
+	for i := 0; i < n && l.pos < len(l.src); i++ {
+		if l.src[l.pos] == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+		l.pos++
+	}
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.advance(1)
+		case c == '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.advance(1)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+
+	loc := Location{Line: l.line, Column: l.col}
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, loc: loc}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.advance(3)
+		return token{kind: tokenPunct, value: "...", loc: loc}, nil
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		l.advance(1)
+		return token{kind: tokenPunct, value: string(c), loc: loc}, nil
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.advance(1)
+		}
+		return token{kind: tokenName, value: l.src[start:l.pos], loc: loc}, nil
+	case c == '-' || isDigit(c):
+		return l.number(loc)
+	case strings.HasPrefix(l.src[l.pos:], `"""`):
+		return l.blockString(loc)
+	case c == '"':
+		return l.string(loc)
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, syntaxError(loc, "unexpected character %q", r)
+}
+
+func (l *lexer) number(loc Location) (token, error) {
+	start := l.pos
+	if l.src[l.pos] == '-' {
+		l.advance(1)
+	}
+
+	digits := l.digits()
+	if digits == 0 {
+		return token{}, syntaxError(loc, "invalid number, expected digit")
+	}
+	if first := l.pos - digits; digits > 1 && l.src[first] == '0' {
+		return token{}, syntaxError(loc, "invalid number, unexpected leading zero")
+	}
+
+	kind := tokenInt
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.advance(1)
+		if l.digits() == 0 {
+			return token{}, syntaxError(loc, "invalid number, expected digit after '.'")
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.advance(1)
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.advance(1)
+		}
+		if l.digits() == 0 {
+			return token{}, syntaxError(loc, "invalid number, expected digit in exponent")
+		}
+	}
+
+	if l.pos < len(l.src) && (l.src[l.pos] == '_' || l.src[l.pos] == '.' || isLetter(l.src[l.pos])) {
+		return token{}, syntaxError(loc, "invalid number, unexpected %q", l.src[l.pos])
+	}
+
+	return token{kind: kind, value: l.src[start:l.pos], loc: loc}, nil
+}
+
+func (l *lexer) digits() int {
+	n := 0
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.advance(1)
+		n++
+	}
+
+	return n
+}
+
+func (l *lexer) string(loc Location) (token, error) {
+	l.advance(1)
+
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return token{kind: tokenString, value: b.String(), loc: loc}, nil
+		case c == '\n' || c == '\r':
+			return token{}, syntaxError(loc, "unterminated string")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, syntaxError(loc, "unterminated string")
+			}
+			escape := l.src[l.pos+1]
+			if escape == 'u' {
+				if l.pos+6 > len(l.src) {
+					return token{}, syntaxError(loc, "invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(l.src[l.pos+2:l.pos+6], 16, 32)
+				if err != nil {
+					return token{}, syntaxError(loc, "invalid unicode escape %q", l.src[l.pos:l.pos+6])
+				}
+				b.WriteRune(rune(code))
+				l.advance(6)
+				continue
+			}
+
+			replacement, ok := map[byte]string{'"': `"`, '\\': `\`, '/': "/", 'b': "\b", 'f': "\f", 'n': "\n", 'r': "\r", 't': "\t"}[escape]
+			if !ok {
+				return token{}, syntaxError(loc, "invalid escape \\%c", escape)
+			}
+			b.WriteString(replacement)
+			l.advance(2)
+		default:
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			b.WriteRune(r)
+			l.advance(size)
+		}
+	}
+
+	return token{}, syntaxError(loc, "unterminated string")
+}
+
+// blockString reads a """ string, the common indentation and the blank
+// first and last lines are removed.
+func (l *lexer) blockString(loc Location) (token, error) {
+	l.advance(3)
+
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		switch {
+		case strings.HasPrefix(l.src[l.pos:], `"""`):
+			l.advance(3)
+			return token{kind: tokenString, value: blockStringValue(b.String()), loc: loc}, nil
+		case strings.HasPrefix(l.src[l.pos:], `\"""`):
+			b.WriteString(`"""`)
+			l.advance(4)
+		default:
+			b.WriteByte(l.src[l.pos])
+			l.advance(1)
+		}
+	}
+
+	return token{}, syntaxError(loc, "unterminated block string")
+}
+
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+
+	for len(lines) > 0 && strings.TrimLeft(lines[0], " \t") == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimLeft(lines[len(lines)-1], " \t") == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func syntaxError(loc Location, format string, args ...interface{}) *Error {
+	return &Error{
+		Message:    "syntax error: " + fmt.Sprintf(format, args...),
+		Locations:  []Location{loc},
+		Extensions: map[string]interface{}{"code": CodeParseFailed},
+	}
+}
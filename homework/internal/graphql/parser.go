@@ -0,0 +1,637 @@
+package graphql
+
+import (
+	"strings"
+)
+
+// maxNesting bounds how deep selection sets, values and types may nest
+// in a document, so parsing can not exhaust the stack. Query depth is
+// limited separately and much lower.
+const maxNesting = 128
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+	// fragmentOrder keeps the fragments in document order for validation.
+	fragmentOrder []*fragment
+}
+
+type operation struct {
+	kind         string
+	name         string
+	variables    []*variableDefinition
+	directives   []*directive
+	selectionSet []selection
+	loc          Location
+}
+
+type variableDefinition struct {
+	name     string
+	typ      *typeRef
+	fallback *value
+	loc      Location
+}
+
+// typeRef is a type as written in a document. It names a type or, with
+// elem set, is a list of elem.
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+
+	return s
+}
+
+type selection interface {
+	location() Location
+}
+
+type field struct {
+	alias        string
+	name         string
+	arguments    []*argument
+	directives   []*directive
+	selectionSet []selection
+	loc          Location
+}
+
+func (f *field) location() Location { return f.loc }
+
+// responseKey is the key of the field in the result.
+func (f *field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+	loc        Location
+}
+
+func (f *fragmentSpread) location() Location { return f.loc }
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selectionSet  []selection
+	loc           Location
+}
+
+func (f *inlineFragment) location() Location { return f.loc }
+
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selectionSet  []selection
+	loc           Location
+}
+
+type argument struct {
+	name  string
+	value *value
+	loc   Location
+}
+
+type directive struct {
+	name      string
+	arguments []*argument
+	loc       Location
+}
+
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+type value struct {
+	kind   valueKind
+	raw    string
+	list   []*value
+	fields []*objectField
+	loc    Location
+}
+
+type objectField struct {
+	name  string
+	value *value
+}
+
+type parser struct {
+	lexer   *lexer
+	tok     token
+	nesting int
+}
+
+func parse(src string) (*document, error) {
+	p := &parser{lexer: newLexer(src)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: make(map[string]*fragment)}
+	if p.tok.kind == tokenEOF {
+		return nil, syntaxError(p.tok.loc, "the document holds no operation")
+	}
+
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek("{") || p.peekName("query") || p.peekName("mutation") || p.peekName("subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peekName("fragment"):
+			frag, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, validationError(frag.loc, "there can be only one fragment named %q", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+			doc.fragmentOrder = append(doc.fragmentOrder, frag)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+
+	return nil
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokenPunct && p.tok.value == punct
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokenName && p.tok.value == name
+}
+
+func (p *parser) unexpected() error {
+	return syntaxError(p.tok.loc, "unexpected %s", p.tok)
+}
+
+// skip consumes the punctuator when it is next and reports whether it was.
+func (p *parser) skip(punct string) (bool, error) {
+	if !p.peek(punct) {
+		return false, nil
+	}
+
+	return true, p.advance()
+}
+
+func (p *parser) expect(punct string) error {
+	if !p.peek(punct) {
+		return syntaxError(p.tok.loc, "expected %q, found %s", punct, p.tok)
+	}
+
+	return p.advance()
+}
+
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", syntaxError(p.tok.loc, "expected name, found %s", p.tok)
+	}
+	name := p.tok.value
+
+	return name, p.advance()
+}
+
+func (p *parser) enter() error {
+	p.nesting++
+	if p.nesting > maxNesting {
+		return syntaxError(p.tok.loc, "the document nests deeper than %d levels", maxNesting)
+	}
+
+	return nil
+}
+
+func (p *parser) leave() {
+	p.nesting--
+}
+
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: "query", loc: p.tok.loc}
+	if p.peek("{") {
+		set, err := p.selectionSet()
+		op.selectionSet = set
+		return op, err
+	}
+
+	op.kind = p.tok.value
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName {
+		op.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if op.variables, err = p.variableDefinitions(); err != nil {
+		return nil, err
+	}
+	if op.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if op.selectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func (p *parser) variableDefinitions() ([]*variableDefinition, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+
+	var defs []*variableDefinition
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			return defs, err
+		}
+
+		def := &variableDefinition{loc: p.tok.loc}
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if def.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if def.typ, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+
+		if ok, err := p.skip("="); err != nil {
+			return nil, err
+		} else if ok {
+			if def.fallback, err = p.value(true); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err = p.directives(); err != nil {
+			return nil, err
+		}
+
+		defs = append(defs, def)
+	}
+}
+
+func (p *parser) typeRef() (*typeRef, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		if t.elem, err = p.typeRef(); err != nil {
+			return nil, err
+		}
+		if err = p.expect("]"); err != nil {
+			return nil, err
+		}
+	} else if t.name, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	ok, err := p.skip("!")
+	t.nonNull = ok
+
+	return t, err
+}
+
+func (p *parser) directives() ([]*directive, error) {
+	var list []*directive
+	for p.peek("@") {
+		d := &directive{loc: p.tok.loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if d.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if d.arguments, err = p.arguments(); err != nil {
+			return nil, err
+		}
+
+		list = append(list, d)
+	}
+
+	return list, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var set []selection
+	for {
+		if ok, err := p.skip("}"); ok || err != nil {
+			if err == nil && len(set) == 0 {
+				return nil, syntaxError(p.tok.loc, "selection set must not be empty")
+			}
+			return set, err
+		}
+
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, sel)
+	}
+}
+
+func (p *parser) selection() (selection, error) {
+	if !p.peek("...") {
+		return p.field()
+	}
+
+	loc := p.tok.loc
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	if p.tok.kind == tokenName && p.tok.value != "on" {
+		spread := &fragmentSpread{name: p.tok.value, loc: loc}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		spread.directives, err = p.directives()
+		return spread, err
+	}
+
+	inline := &inlineFragment{loc: loc}
+	if p.peekName("on") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if inline.typeCondition, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if inline.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if inline.selectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+
+	return inline, nil
+}
+
+func (p *parser) field() (*field, error) {
+	f := &field{loc: p.tok.loc}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = name
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	f.name = name
+
+	if f.arguments, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if f.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.selectionSet, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+
+	return f, nil
+}
+
+func (p *parser) arguments() ([]*argument, error) {
+	if ok, err := p.skip("("); !ok || err != nil {
+		return nil, err
+	}
+
+	var args []*argument
+	for {
+		if ok, err := p.skip(")"); ok || err != nil {
+			if err == nil && len(args) == 0 {
+				return nil, syntaxError(p.tok.loc, "argument list must not be empty")
+			}
+			return args, err
+		}
+
+		arg := &argument{loc: p.tok.loc}
+
+		var err error
+		if arg.name, err = p.name(); err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arg.value, err = p.value(false); err != nil {
+			return nil, err
+		}
+
+		args = append(args, arg)
+	}
+}
+
+func (p *parser) fragment() (*fragment, error) {
+	frag := &fragment{loc: p.tok.loc}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if frag.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.name == "on" {
+		return nil, syntaxError(frag.loc, "a fragment can not be named \"on\"")
+	}
+	if !p.peekName("on") {
+		return nil, syntaxError(p.tok.loc, "expected \"on\", found %s", p.tok)
+	}
+	if err = p.advance(); err != nil {
+		return nil, err
+	}
+	if frag.typeCondition, err = p.name(); err != nil {
+		return nil, err
+	}
+	if frag.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if frag.selectionSet, err = p.selectionSet(); err != nil {
+		return nil, err
+	}
+
+	return frag, nil
+}
+
+// value parses an input value, constant values such as variable defaults
+// must not refer to variables.
+func (p *parser) value(constant bool) (*value, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	v := &value{loc: p.tok.loc, raw: p.tok.value}
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+
+		var err error
+		v.kind = valueVariable
+		v.raw, err = p.name()
+		return v, err
+	case p.peek("["):
+		v.kind = valueList
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for {
+			if ok, err := p.skip("]"); ok || err != nil {
+				return v, err
+			}
+
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.list = append(v.list, item)
+		}
+	case p.peek("{"):
+		v.kind = valueObject
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for {
+			if ok, err := p.skip("}"); ok || err != nil {
+				return v, err
+			}
+
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(":"); err != nil {
+				return nil, err
+			}
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			v.fields = append(v.fields, &objectField{name: name, value: item})
+		}
+	case p.tok.kind == tokenInt:
+		v.kind = valueInt
+	case p.tok.kind == tokenFloat:
+		v.kind = valueFloat
+	case p.tok.kind == tokenString:
+		v.kind = valueString
+	case p.peekName("true") || p.peekName("false"):
+		v.kind = valueBoolean
+	case p.peekName("null"):
+		v.kind = valueNull
+	case p.tok.kind == tokenName:
+		v.kind = valueEnum
+	default:
+		return nil, p.unexpected()
+	}
+
+	return v, p.advance()
+}
+
+// String prints the value as written, it is used to compare arguments.
+func (v *value) String() string {
+	switch v.kind {
+	case valueVariable:
+		return "$" + v.raw
+	case valueString:
+		return `"` + strings.ReplaceAll(v.raw, `"`, `\"`) + `"`
+	case valueList:
+		items := make([]string, len(v.list))
+		for i, item := range v.list {
+			items[i] = item.String()
+		}
+		return "[" + strings.Join(items, ",") + "]"
+	case valueObject:
+		items := make([]string, len(v.fields))
+		for i, item := range v.fields {
+			items[i] = item.name + ":" + item.value.String()
+		}
+		return "{" + strings.Join(items, ",") + "}"
+	}
+
+	return v.raw
+}
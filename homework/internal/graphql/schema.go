@@ -0,0 +1,802 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type is a type of the schema: a *Scalar, *Enum, *Object, *InputObject,
+// or a *List or *NonNull of one of them.
+type Type interface {
+	String() string
+}
+
+type Scalar struct {
+	Name        string
+	Description string
+	serialize   func(interface{}) (interface{}, error)
+	// parse coerces an input value as decoded from JSON, numbers arrive as
+	// json.Number.
+	parse func(interface{}) (interface{}, error)
+}
+
+func (s *Scalar) String() string { return s.Name }
+
+var (
+	Int = &Scalar{
+		Name:        "Int",
+		Description: "A signed 32-bit integer.",
+		serialize: func(v interface{}) (interface{}, error) {
+			n, ok := toInt64(v)
+			if !ok || n > math.MaxInt32 || n < math.MinInt32 {
+				return nil, fmt.Errorf("Int can not represent %v", v)
+			}
+			return n, nil
+		},
+		parse: func(v interface{}) (interface{}, error) {
+			n, ok := toInt64(v)
+			if !ok || n > math.MaxInt32 || n < math.MinInt32 {
+				return nil, fmt.Errorf("Int can not represent %s", describe(v))
+			}
+			return int(n), nil
+		},
+	}
+	Float = &Scalar{
+		Name:        "Float",
+		Description: "A double precision floating point number.",
+		serialize: func(v interface{}) (interface{}, error) {
+			f, ok := toFloat64(v)
+			if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, fmt.Errorf("Float can not represent %v", v)
+			}
+			return f, nil
+		},
+		parse: func(v interface{}) (interface{}, error) {
+			f, ok := toFloat64(v)
+			if !ok {
+				return nil, fmt.Errorf("Float can not represent %s", describe(v))
+			}
+			return f, nil
+		},
+	}
+	String = &Scalar{
+		Name:        "String",
+		Description: "A UTF-8 character sequence.",
+		serialize: func(v interface{}) (interface{}, error) {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+				return rv.String(), nil
+			}
+			return nil, fmt.Errorf("String can not represent %v", v)
+		},
+		parse: func(v interface{}) (interface{}, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("String can not represent %s", describe(v))
+			}
+			return s, nil
+		},
+	}
+	Boolean = &Scalar{
+		Name:        "Boolean",
+		Description: "true or false.",
+		serialize: func(v interface{}) (interface{}, error) {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("Boolean can not represent %v", v)
+			}
+			return b, nil
+		},
+		parse: func(v interface{}) (interface{}, error) {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("Boolean can not represent %s", describe(v))
+			}
+			return b, nil
+		},
+	}
+	ID = &Scalar{
+		Name:        "ID",
+		Description: "A unique identifier, serialized as a string.",
+		serialize: func(v interface{}) (interface{}, error) {
+			if rv := reflect.ValueOf(v); rv.Kind() == reflect.String {
+				return rv.String(), nil
+			}
+			if n, ok := toInt64(v); ok {
+				return strconv.FormatInt(n, 10), nil
+			}
+			return nil, fmt.Errorf("ID can not represent %v", v)
+		},
+		parse: func(v interface{}) (interface{}, error) {
+			if s, ok := v.(string); ok {
+				return s, nil
+			}
+			if n, ok := toInt64(v); ok {
+				return strconv.FormatInt(n, 10), nil
+			}
+			return nil, fmt.Errorf("ID can not represent %s", describe(v))
+		},
+	}
+)
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), true
+		}
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		if f, err := n.Float64(); err == nil && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f), true
+		}
+	}
+
+	return 0, false
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	i, ok := toInt64(v)
+	return float64(i), ok
+}
+
+// describe names an input value in error messages.
+func describe(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case enumLiteral:
+		return string(v)
+	case json.Number:
+		return v.String()
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(buf)
+}
+
+// enumLiteral is an enum value written in a document, variables carry
+// enum values as strings.
+type enumLiteral string
+
+type EnumValue struct {
+	Name        string
+	Description string
+	// Value is what resolvers return and receive for the enum value.
+	Value interface{}
+}
+
+type Enum struct {
+	Name        string
+	Description string
+	Values      []*EnumValue
+}
+
+func (e *Enum) String() string { return e.Name }
+
+func (e *Enum) serialize(v interface{}) (interface{}, error) {
+	for _, ev := range e.Values {
+		if ev.Value == v {
+			return ev.Name, nil
+		}
+	}
+
+	return nil, fmt.Errorf("enum %s can not represent %v", e.Name, v)
+}
+
+func (e *Enum) parse(v interface{}) (interface{}, error) {
+	var name string
+	switch v := v.(type) {
+	case enumLiteral:
+		name = string(v)
+	case string:
+		name = v
+	default:
+		return nil, fmt.Errorf("enum %s can not represent %s", e.Name, describe(v))
+	}
+
+	for _, ev := range e.Values {
+		if ev.Name == name {
+			return ev.Value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("value %q does not exist in enum %s", name, e.Name)
+}
+
+// ResolveParams is handed to a resolver. Source is the value resolved for
+// the parent object, nil for root fields. Args holds the coerced
+// arguments, those without value and default are missing.
+type ResolveParams struct {
+	Context context.Context
+	Source  interface{}
+	Args    map[string]interface{}
+}
+
+type ResolveFunc func(p ResolveParams) (interface{}, error)
+
+type Argument struct {
+	Name        string
+	Description string
+	Type        Type
+	// Default is used when the argument is not given, nil for none.
+	Default interface{}
+}
+
+type Field struct {
+	Name        string
+	Description string
+	Type        Type
+	Args        []*Argument
+	// Resolve returns the value of the field. Without one the field is
+	// looked up in a map[string]interface{} source.
+	Resolve ResolveFunc
+	// Cost is added to the complexity of a query for every time the field
+	// is resolved. It defaults to 1 for object fields and to 0 for scalar
+	// and enum fields.
+	Cost int
+}
+
+func (f *Field) arg(name string) *Argument {
+	for _, arg := range f.Args {
+		if arg.Name == name {
+			return arg
+		}
+	}
+
+	return nil
+}
+
+type Object struct {
+	Name        string
+	Description string
+	Fields      []*Field
+
+	fields map[string]*Field
+}
+
+func (o *Object) String() string { return o.Name }
+
+func (o *Object) field(name string) *Field {
+	return o.fields[name]
+}
+
+type InputField struct {
+	Name        string
+	Description string
+	Type        Type
+	Default     interface{}
+}
+
+type InputObject struct {
+	Name        string
+	Description string
+	Fields      []*InputField
+}
+
+func (o *InputObject) String() string { return o.Name }
+
+type List struct {
+	OfType Type
+}
+
+func NewList(t Type) *List { return &List{OfType: t} }
+
+func (l *List) String() string { return "[" + l.OfType.String() + "]" }
+
+type NonNull struct {
+	OfType Type
+}
+
+func NewNonNull(t Type) *NonNull { return &NonNull{OfType: t} }
+
+func (n *NonNull) String() string { return n.OfType.String() + "!" }
+
+// namedType strips lists and non-null from the type.
+func namedType(t Type) Type {
+	for {
+		switch wrapper := t.(type) {
+		case *List:
+			t = wrapper.OfType
+		case *NonNull:
+			t = wrapper.OfType
+		default:
+			return t
+		}
+	}
+}
+
+func isLeaf(t Type) bool {
+	switch namedType(t).(type) {
+	case *Scalar, *Enum:
+		return true
+	}
+
+	return false
+}
+
+// isList reports whether the type is a list, possibly non-null.
+func isList(t Type) bool {
+	if nonNull, ok := t.(*NonNull); ok {
+		t = nonNull.OfType
+	}
+	_, ok := t.(*List)
+	return ok
+}
+
+func isInputType(t Type) bool {
+	switch namedType(t).(type) {
+	case *Scalar, *Enum, *InputObject:
+		return true
+	}
+
+	return false
+}
+
+// Schema holds the types reachable from the root types. It is safe for
+// concurrent use once built.
+type Schema struct {
+	query    *Object
+	mutation *Object
+	types    map[string]Type
+	// meta holds the __schema and __type fields of the query root.
+	meta map[string]*Field
+}
+
+var nameRe = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// NewSchema checks the types reachable from the roots, mutation may be
+// nil.
+func NewSchema(query, mutation *Object) (*Schema, error) {
+	if query == nil {
+		return nil, fmt.Errorf("graphql: the schema needs a query type")
+	}
+
+	s := &Schema{query: query, mutation: mutation, types: make(map[string]Type)}
+	for _, scalar := range []*Scalar{Int, Float, String, Boolean, ID} {
+		s.types[scalar.Name] = scalar
+	}
+
+	roots := []Type{query}
+	if mutation != nil {
+		roots = append(roots, mutation)
+	}
+	for _, root := range roots {
+		if err := s.add(root); err != nil {
+			return nil, err
+		}
+	}
+	s.addIntrospection()
+
+	return s, nil
+}
+
+func (s *Schema) add(t Type) error {
+	named := namedType(t)
+
+	name := named.String()
+	if !nameRe.MatchString(name) || strings.HasPrefix(name, "__") {
+		return fmt.Errorf("graphql: invalid type name %q", name)
+	}
+	if existing, ok := s.types[name]; ok {
+		if existing != named {
+			return fmt.Errorf("graphql: two types are named %s", name)
+		}
+		return nil
+	}
+	s.types[name] = named
+
+	switch named := named.(type) {
+	case *Scalar:
+		return fmt.Errorf("graphql: custom scalar %s is not supported", name)
+	case *Enum:
+		if len(named.Values) == 0 {
+			return fmt.Errorf("graphql: enum %s has no values", name)
+		}
+		for _, value := range named.Values {
+			if !nameRe.MatchString(value.Name) || value.Name == "true" || value.Name == "false" || value.Name == "null" {
+				return fmt.Errorf("graphql: invalid value %q of enum %s", value.Name, name)
+			}
+		}
+	case *Object:
+		return s.addObject(named)
+	case *InputObject:
+		for _, f := range named.Fields {
+			if !nameRe.MatchString(f.Name) || f.Type == nil || !isInputType(f.Type) {
+				return fmt.Errorf("graphql: input field %s.%s needs a valid name and an input type", name, f.Name)
+			}
+			if err := s.add(f.Type); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Schema) addObject(o *Object) error {
+	if len(o.Fields) == 0 {
+		return fmt.Errorf("graphql: object %s has no fields", o.Name)
+	}
+
+	o.fields = make(map[string]*Field, len(o.Fields))
+	for _, f := range o.Fields {
+		if !nameRe.MatchString(f.Name) || strings.HasPrefix(f.Name, "__") {
+			return fmt.Errorf("graphql: invalid field name %s.%s", o.Name, f.Name)
+		}
+		if _, ok := o.fields[f.Name]; ok {
+			return fmt.Errorf("graphql: field %s.%s is defined twice", o.Name, f.Name)
+		}
+		if f.Type == nil {
+			return fmt.Errorf("graphql: field %s.%s has no type", o.Name, f.Name)
+		}
+		if _, ok := namedType(f.Type).(*InputObject); ok {
+			return fmt.Errorf("graphql: field %s.%s has input type %s", o.Name, f.Name, f.Type)
+		}
+		o.fields[f.Name] = f
+
+		for _, arg := range f.Args {
+			if !nameRe.MatchString(arg.Name) || arg.Type == nil || !isInputType(arg.Type) {
+				return fmt.Errorf("graphql: argument %s of %s.%s needs a valid name and an input type", arg.Name, o.Name, f.Name)
+			}
+			if err := s.add(arg.Type); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range o.Fields {
+		if err := s.add(f.Type); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lookup resolves a type written in a document to a schema input type.
+func (s *Schema) lookup(ref *typeRef) (Type, bool) {
+	var t Type
+	if ref.elem != nil {
+		elem, ok := s.lookup(ref.elem)
+		if !ok {
+			return nil, false
+		}
+		t = NewList(elem)
+	} else {
+		named, ok := s.types[ref.name]
+		if !ok || !isInputType(named) {
+			return nil, false
+		}
+		t = named
+	}
+
+	if ref.nonNull {
+		t = NewNonNull(t)
+	}
+
+	return t, true
+}
+
+// coerceValue coerces an input value decoded from JSON to the type.
+func coerceValue(t Type, v interface{}) (interface{}, error) {
+	if nonNull, ok := t.(*NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("expected non-null value of type %s", t)
+		}
+		return coerceValue(nonNull.OfType, v)
+	}
+	if v == nil {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		items, ok := v.([]interface{})
+		if !ok {
+			item, err := coerceValue(t.OfType, v)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if list[i], err = coerceValue(t.OfType, item); err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+		}
+		return list, nil
+	case *InputObject:
+		fields, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object of type %s, found %s", t.Name, describe(v))
+		}
+
+		known := make(map[string]bool, len(t.Fields))
+		result := make(map[string]interface{}, len(t.Fields))
+		for _, f := range t.Fields {
+			known[f.Name] = true
+
+			fv, present := fields[f.Name]
+			if !present {
+				if f.Default != nil {
+					result[f.Name] = f.Default
+				} else if _, required := f.Type.(*NonNull); required {
+					return nil, fmt.Errorf("field %s.%s of required type %s was not provided", t.Name, f.Name, f.Type)
+				}
+				continue
+			}
+
+			coerced, err := coerceValue(f.Type, fv)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", t.Name, f.Name, err)
+			}
+			result[f.Name] = coerced
+		}
+		for name := range fields {
+			if !known[name] {
+				return nil, fmt.Errorf("field %q is not defined by type %s", name, t.Name)
+			}
+		}
+		return result, nil
+	case *Enum:
+		return t.parse(v)
+	case *Scalar:
+		if _, ok := v.(enumLiteral); ok {
+			return nil, fmt.Errorf("%s can not represent enum value %s", t.Name, v)
+		}
+		return t.parse(v)
+	}
+
+	return nil, fmt.Errorf("%s is not an input type", t)
+}
+
+// literalValue turns a literal of a document into the value JSON would
+// have decoded, variables are taken from vars. A missing variable reads
+// as missing so defaults apply.
+func literalValue(v *value, vars map[string]interface{}) (interface{}, bool) {
+	switch v.kind {
+	case valueVariable:
+		value, ok := vars[v.raw]
+		return value, ok
+	case valueInt, valueFloat:
+		return json.Number(v.raw), true
+	case valueString:
+		return v.raw, true
+	case valueBoolean:
+		return v.raw == "true", true
+	case valueNull:
+		return nil, true
+	case valueEnum:
+		return enumLiteral(v.raw), true
+	case valueList:
+		list := make([]interface{}, 0, len(v.list))
+		for _, item := range v.list {
+			value, _ := literalValue(item, vars)
+			list = append(list, value)
+		}
+		return list, true
+	case valueObject:
+		fields := make(map[string]interface{}, len(v.fields))
+		for _, f := range v.fields {
+			if value, ok := literalValue(f.value, vars); ok {
+				fields[f.name] = value
+			}
+		}
+		return fields, true
+	}
+
+	return nil, false
+}
+
+// coerceLiteral coerces a literal of the document. Variables were coerced
+// on their own already and are checked for null only; a float literal is
+// never taken for an Int.
+func coerceLiteral(t Type, v *value, vars map[string]interface{}) (interface{}, error) {
+	if v.kind == valueVariable {
+		value := vars[v.raw]
+		if _, ok := t.(*NonNull); ok && value == nil {
+			return nil, fmt.Errorf("variable $%s must not be null", v.raw)
+		}
+		return value, nil
+	}
+
+	if nonNull, ok := t.(*NonNull); ok {
+		if v.kind == valueNull {
+			return nil, fmt.Errorf("expected non-null value of type %s", t)
+		}
+		t = nonNull.OfType
+	}
+	if v.kind == valueNull {
+		return nil, nil
+	}
+
+	switch t := t.(type) {
+	case *List:
+		if v.kind != valueList {
+			item, err := coerceLiteral(t.OfType, v, vars)
+			if err != nil {
+				return nil, err
+			}
+			return []interface{}{item}, nil
+		}
+
+		list := make([]interface{}, len(v.list))
+		for i, item := range v.list {
+			var err error
+			if list[i], err = coerceLiteral(t.OfType, item, vars); err != nil {
+				return nil, fmt.Errorf("at index %d: %w", i, err)
+			}
+		}
+		return list, nil
+	case *InputObject:
+		if v.kind != valueObject {
+			return nil, fmt.Errorf("expected an object of type %s, found %s", t.Name, v)
+		}
+
+		given := make(map[string]*value, len(v.fields))
+		for _, f := range v.fields {
+			if _, ok := given[f.name]; ok {
+				return nil, fmt.Errorf("field %q is given twice", f.name)
+			}
+			given[f.name] = f.value
+		}
+
+		result := make(map[string]interface{}, len(t.Fields))
+		for _, f := range t.Fields {
+			fv, present := given[f.Name]
+			if present && fv.kind == valueVariable {
+				_, present = vars[fv.raw]
+			}
+			delete(given, f.Name)
+
+			if !present {
+				if f.Default != nil {
+					result[f.Name] = f.Default
+				} else if _, required := f.Type.(*NonNull); required {
+					return nil, fmt.Errorf("field %s.%s of required type %s was not provided", t.Name, f.Name, f.Type)
+				}
+				continue
+			}
+
+			coerced, err := coerceLiteral(f.Type, fv, vars)
+			if err != nil {
+				return nil, fmt.Errorf("field %s.%s: %w", t.Name, f.Name, err)
+			}
+			result[f.Name] = coerced
+		}
+		for _, f := range v.fields {
+			if _, unknown := given[f.name]; unknown {
+				return nil, fmt.Errorf("field %q is not defined by type %s", f.name, t.Name)
+			}
+		}
+		return result, nil
+	case *Scalar:
+		// Decoded numbers do not tell 1 from 1.0, the literal does.
+		if t == Int && v.kind == valueFloat || t == ID && v.kind == valueFloat {
+			return nil, fmt.Errorf("%s can not represent %s", t.Name, v)
+		}
+	case *Enum:
+		if v.kind != valueEnum {
+			return nil, fmt.Errorf("enum %s can not represent %s", t.Name, v)
+		}
+	}
+
+	value, _ := literalValue(v, vars)
+	return coerceValue(t, value)
+}
+
+// SDL prints the schema in the schema definition language.
+func (s *Schema) SDL() string {
+	names := make([]string, 0, len(s.types))
+	for name, t := range s.types {
+		if _, builtin := t.(*Scalar); !builtin && !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	if s.query.Name != "Query" || s.mutation != nil && s.mutation.Name != "Mutation" {
+		b.WriteString("schema {\n  query: " + s.query.Name + "\n")
+		if s.mutation != nil {
+			b.WriteString("  mutation: " + s.mutation.Name + "\n")
+		}
+		b.WriteString("}\n\n")
+	}
+
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		switch t := s.types[name].(type) {
+		case *Enum:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("enum " + t.Name + " {\n")
+			for _, value := range t.Values {
+				writeDescription(&b, "  ", value.Description)
+				b.WriteString("  " + value.Name + "\n")
+			}
+		case *Object:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("type " + t.Name + " {\n")
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + f.Name)
+				if len(f.Args) > 0 {
+					args := make([]string, len(f.Args))
+					for i, arg := range f.Args {
+						args[i] = arg.Name + ": " + arg.Type.String() + formatDefault(arg.Default)
+					}
+					b.WriteString("(" + strings.Join(args, ", ") + ")")
+				}
+				b.WriteString(": " + f.Type.String() + "\n")
+			}
+		case *InputObject:
+			writeDescription(&b, "", t.Description)
+			b.WriteString("input " + t.Name + " {\n")
+			for _, f := range t.Fields {
+				writeDescription(&b, "  ", f.Description)
+				b.WriteString("  " + f.Name + ": " + f.Type.String() + formatDefault(f.Default) + "\n")
+			}
+		}
+		b.WriteString("}\n")
+	}
+
+	return b.String()
+}
+
+func writeDescription(b *strings.Builder, indent, description string) {
+	if description == "" {
+		return
+	}
+
+	b.WriteString(indent + strconv.Quote(description) + "\n")
+}
+
+func formatDefault(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+
+	return " = " + string(buf)
+}
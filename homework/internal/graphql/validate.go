@@ -0,0 +1,470 @@
+package graphql
+
+import (
+	"sort"
+	"strings"
+)
+
+// validator checks a document against the schema before anything runs.
+// It covers the rules a client can break with this type system: there
+// are no interfaces or unions, so fragments apply to exactly one type.
+//
+// Every fragment is checked once on its own, spreads are not followed,
+// so documents spreading fragments many times can not blow up the work.
+type validator struct {
+	schema *Schema
+	doc    *document
+	errs   []*Error
+
+	// cur collects the variables and fragments used by the operation or
+	// fragment being checked.
+	cur       *scope
+	fragments map[string]*scope
+}
+
+type scope struct {
+	usages  []*usage
+	spreads []string
+}
+
+// usage is a variable written where a value of type t is expected. t is
+// nil for variables nested in list and object literals, those are only
+// checked for being defined and coerced at execution.
+type usage struct {
+	ref        *value
+	t          Type
+	hasDefault bool
+	what       string
+}
+
+func validate(schema *Schema, doc *document) []*Error {
+	v := &validator{schema: schema, doc: doc, fragments: make(map[string]*scope)}
+
+	for _, frag := range doc.fragmentOrder {
+		v.fragment(frag)
+	}
+	v.fragmentCycles()
+
+	names := make(map[string]bool)
+	reachable := make(map[string]bool)
+	for _, op := range doc.operations {
+		if op.name == "" && len(doc.operations) > 1 {
+			v.report(op.loc, "an anonymous operation must be the only operation of the document")
+		}
+		if op.name != "" {
+			if names[op.name] {
+				v.report(op.loc, "there can be only one operation named %q", op.name)
+			}
+			names[op.name] = true
+		}
+
+		v.operation(op, reachable)
+	}
+
+	for _, frag := range doc.fragmentOrder {
+		if !reachable[frag.name] {
+			v.report(frag.loc, "fragment %q is never used", frag.name)
+		}
+	}
+
+	return dedupe(v.errs)
+}
+
+func (v *validator) report(loc Location, format string, args ...interface{}) {
+	v.errs = append(v.errs, validationError(loc, format, args...))
+}
+
+func (v *validator) fragment(frag *fragment) {
+	v.cur = &scope{}
+	v.fragments[frag.name] = v.cur
+
+	v.directives(frag.directives, "fragment definition")
+
+	t, ok := v.schema.types[frag.typeCondition].(*Object)
+	if !ok {
+		v.report(frag.loc, "fragment %q is on unknown type %q", frag.name, frag.typeCondition)
+		return
+	}
+
+	v.selectionSet(t, frag.selectionSet)
+}
+
+func (v *validator) operation(op *operation, reachable map[string]bool) {
+	v.cur = &scope{}
+
+	defs := make(map[string]*variableDefinition, len(op.variables))
+	for _, def := range op.variables {
+		if _, ok := defs[def.name]; ok {
+			v.report(def.loc, "there can be only one variable named $%s", def.name)
+		}
+		defs[def.name] = def
+
+		t, ok := v.schema.lookup(def.typ)
+		if !ok {
+			v.report(def.loc, "variable $%s has unknown or non-input type %s", def.name, def.typ)
+			continue
+		}
+		if def.fallback != nil {
+			if _, err := coerceLiteral(t, def.fallback, nil); err != nil {
+				v.report(def.fallback.loc, "invalid default of variable $%s: %s", def.name, err)
+			}
+		}
+	}
+
+	v.directives(op.directives, "operation")
+
+	root := v.rootType(op)
+	if root != nil {
+		v.selectionSet(root, op.selectionSet)
+	}
+
+	// The variables of the fragments the operation reaches count for it.
+	usages := v.cur.usages
+	for name := range v.closure(v.cur.spreads) {
+		reachable[name] = true
+		usages = append(usages, v.fragments[name].usages...)
+	}
+
+	used := make(map[string]bool, len(usages))
+	for _, u := range usages {
+		used[u.ref.raw] = true
+
+		def, ok := defs[u.ref.raw]
+		if !ok {
+			v.report(u.ref.loc, "variable $%s is not defined by operation %s", u.ref.raw, operationName(op))
+			continue
+		}
+		if u.t == nil {
+			continue
+		}
+		if vt, ok := v.schema.lookup(def.typ); ok && !allowed(vt, def.fallback != nil || u.hasDefault, u.t) {
+			v.report(u.ref.loc, "variable $%s of type %s can not be used as %s of type %s", u.ref.raw, def.typ, u.what, u.t)
+		}
+	}
+
+	for _, def := range op.variables {
+		if !used[def.name] {
+			v.report(def.loc, "variable $%s is never used by operation %s", def.name, operationName(op))
+		}
+	}
+}
+
+func operationName(op *operation) string {
+	if op.name == "" {
+		return "(anonymous)"
+	}
+
+	return op.name
+}
+
+// closure lists the fragments reached from spreads, through fragments
+// spreading other fragments too.
+func (v *validator) closure(spreads []string) map[string]bool {
+	reached := make(map[string]bool)
+
+	stack := append([]string(nil), spreads...)
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		s, ok := v.fragments[name]
+		if !ok || reached[name] {
+			continue
+		}
+		reached[name] = true
+		stack = append(stack, s.spreads...)
+	}
+
+	return reached
+}
+
+func (v *validator) rootType(op *operation) *Object {
+	switch op.kind {
+	case "query":
+		return v.schema.query
+	case "mutation":
+		if v.schema.mutation == nil {
+			v.report(op.loc, "the schema does not support mutations")
+		}
+		return v.schema.mutation
+	}
+
+	v.report(op.loc, "%s operations are not supported", op.kind)
+	return nil
+}
+
+func (v *validator) selectionSet(parent *Object, set []selection) {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *field:
+			v.field(parent, sel)
+		case *inlineFragment:
+			v.directives(sel.directives, "inline fragment")
+			if sel.typeCondition != "" && sel.typeCondition != parent.Name {
+				v.typeCondition(sel.loc, sel.typeCondition, parent)
+				continue
+			}
+			v.selectionSet(parent, sel.selectionSet)
+		case *fragmentSpread:
+			v.directives(sel.directives, "fragment spread")
+			v.cur.spreads = append(v.cur.spreads, sel.name)
+
+			frag, ok := v.doc.fragments[sel.name]
+			if !ok {
+				v.report(sel.loc, "unknown fragment %q", sel.name)
+				continue
+			}
+			if frag.typeCondition != parent.Name {
+				v.typeCondition(sel.loc, frag.typeCondition, parent)
+			}
+		}
+	}
+
+	v.mergeable(set)
+}
+
+func (v *validator) typeCondition(loc Location, condition string, parent *Object) {
+	if _, ok := v.schema.types[condition].(*Object); !ok {
+		v.report(loc, "unknown type %q in type condition", condition)
+		return
+	}
+
+	v.report(loc, "a fragment on %s can not be spread within %s", condition, parent.Name)
+}
+
+func (v *validator) field(parent *Object, f *field) {
+	v.directives(f.directives, "field")
+
+	if f.name == "__typename" {
+		if len(f.arguments) > 0 || f.selectionSet != nil {
+			v.report(f.loc, "field __typename takes no arguments and no selection")
+		}
+		return
+	}
+
+	def := v.schema.field(parent, f.name)
+	if def == nil {
+		v.report(f.loc, "cannot query field %q on type %s", f.name, parent.Name)
+		return
+	}
+
+	v.arguments(def.Args, f.arguments, f.loc, "field "+parent.Name+"."+f.name)
+
+	child, composite := namedType(def.Type).(*Object)
+	switch {
+	case composite && f.selectionSet == nil:
+		v.report(f.loc, "field %q of type %s must have a selection of subfields", f.name, def.Type)
+	case !composite && f.selectionSet != nil:
+		v.report(f.loc, "field %q of type %s must not have a selection of subfields", f.name, def.Type)
+	case composite:
+		v.selectionSet(child, f.selectionSet)
+	}
+}
+
+func (v *validator) arguments(defs []*Argument, args []*argument, loc Location, owner string) {
+	given := make(map[string]bool, len(args))
+	for _, arg := range args {
+		if given[arg.name] {
+			v.report(arg.loc, "there can be only one argument named %q", arg.name)
+		}
+		given[arg.name] = true
+
+		var def *Argument
+		for _, candidate := range defs {
+			if candidate.Name == arg.name {
+				def = candidate
+			}
+		}
+		if def == nil {
+			v.report(arg.loc, "unknown argument %q on %s", arg.name, owner)
+			continue
+		}
+
+		v.value(def.Type, def.Default != nil, arg.value, "argument "+arg.name)
+	}
+
+	for _, def := range defs {
+		if _, required := def.Type.(*NonNull); required && def.Default == nil && !given[def.Name] {
+			v.report(loc, "%s argument %q of type %s is required but not provided", owner, def.Name, def.Type)
+		}
+	}
+}
+
+// value checks a literal for the type and records the variables in it.
+func (v *validator) value(t Type, hasDefault bool, val *value, what string) {
+	if val.kind == valueVariable {
+		v.cur.usages = append(v.cur.usages, &usage{ref: val, t: t, hasDefault: hasDefault, what: what})
+		return
+	}
+
+	nested := variables(val)
+	for _, ref := range nested {
+		v.cur.usages = append(v.cur.usages, &usage{ref: ref})
+	}
+
+	// Literals holding variables are coerced at execution.
+	if len(nested) > 0 {
+		return
+	}
+	if _, err := coerceLiteral(t, val, nil); err != nil {
+		v.report(val.loc, "invalid value for %s: %s", what, err)
+	}
+}
+
+func variables(val *value) []*value {
+	switch val.kind {
+	case valueVariable:
+		return []*value{val}
+	case valueList:
+		var refs []*value
+		for _, item := range val.list {
+			refs = append(refs, variables(item)...)
+		}
+		return refs
+	case valueObject:
+		var refs []*value
+		for _, f := range val.fields {
+			refs = append(refs, variables(f.value)...)
+		}
+		return refs
+	}
+
+	return nil
+}
+
+// allowed reports whether a variable of type vt may be used where t is
+// expected. A nullable variable fits a non-null position only with a
+// default to fall back to.
+func allowed(vt Type, hasDefault bool, t Type) bool {
+	if nonNull, ok := t.(*NonNull); ok {
+		vNonNull, vIsNonNull := vt.(*NonNull)
+		if !vIsNonNull {
+			return hasDefault && allowed(vt, false, nonNull.OfType)
+		}
+		return allowed(vNonNull.OfType, false, nonNull.OfType)
+	}
+	if vNonNull, ok := vt.(*NonNull); ok {
+		return allowed(vNonNull.OfType, false, t)
+	}
+
+	if list, ok := t.(*List); ok {
+		vList, vIsList := vt.(*List)
+		return vIsList && allowed(vList.OfType, false, list.OfType)
+	}
+	if _, ok := vt.(*List); ok {
+		return false
+	}
+
+	return vt == t
+}
+
+func (v *validator) directives(list []*directive, location string) {
+	seen := make(map[string]bool, len(list))
+	for _, d := range list {
+		if d.name != "skip" && d.name != "include" {
+			v.report(d.loc, "unknown directive @%s", d.name)
+			continue
+		}
+		if location != "field" && location != "fragment spread" && location != "inline fragment" {
+			v.report(d.loc, "directive @%s may not be used on %s", d.name, location)
+			continue
+		}
+		if seen[d.name] {
+			v.report(d.loc, "directive @%s may be used only once at a location", d.name)
+		}
+		seen[d.name] = true
+
+		v.arguments(ifArgument, d.arguments, d.loc, "directive @"+d.name)
+	}
+}
+
+// mergeable checks that fields sharing a response key in the selection
+// set select the same field with the same arguments.
+func (v *validator) mergeable(set []selection) {
+	byKey := make(map[string]*field)
+	for _, f := range v.flatten(set, make(map[string]bool), nil) {
+		other, ok := byKey[f.responseKey()]
+		if !ok {
+			byKey[f.responseKey()] = f
+			continue
+		}
+
+		if other.name != f.name {
+			v.report(f.loc, "fields %q conflict because %s and %s are different fields", f.responseKey(), other.name, f.name)
+		} else if formatArguments(other.arguments) != formatArguments(f.arguments) {
+			v.report(f.loc, "fields %q conflict because they have differing arguments", f.responseKey())
+		}
+	}
+}
+
+// flatten lists the fields of a selection set with those of its
+// fragments, every fragment is expanded once.
+func (v *validator) flatten(set []selection, expanded map[string]bool, fields []*field) []*field {
+	for _, sel := range set {
+		switch sel := sel.(type) {
+		case *field:
+			fields = append(fields, sel)
+		case *inlineFragment:
+			fields = v.flatten(sel.selectionSet, expanded, fields)
+		case *fragmentSpread:
+			frag, ok := v.doc.fragments[sel.name]
+			if ok && !expanded[sel.name] {
+				expanded[sel.name] = true
+				fields = v.flatten(frag.selectionSet, expanded, fields)
+			}
+		}
+	}
+
+	return fields
+}
+
+func formatArguments(args []*argument) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.name + ":" + arg.value.String()
+	}
+	sort.Strings(parts)
+
+	return strings.Join(parts, ",")
+}
+
+// fragmentCycles reports fragments that spread themselves, directly or
+// through other fragments.
+func (v *validator) fragmentCycles() {
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[string]int, len(v.fragments))
+
+	var visit func(name string) bool
+	visit = func(name string) bool {
+		state[name] = inProgress
+		defer func() { state[name] = done }()
+
+		for _, next := range v.fragments[name].spreads {
+			if _, ok := v.fragments[next]; !ok {
+				continue
+			}
+
+			switch state[next] {
+			case inProgress:
+				v.report(v.doc.fragments[next].loc, "fragment %q spreads itself", next)
+				return true
+			case unvisited:
+				if visit(next) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	for _, frag := range v.doc.fragmentOrder {
+		if state[frag.name] == unvisited {
+			visit(frag.name)
+		}
+	}
+}
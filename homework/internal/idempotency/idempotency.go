@@ -0,0 +1,173 @@
+// Package idempotency remembers the responses of requests sent with an
+// idempotency key, so a retried request is answered with the original
+// response instead of being executed again.
+package idempotency
+
+import (
+	"container/list"
+	"crypto/sha256"
+	stderrors "errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCapacity = 10000
+	defaultTTL      = 24 * time.Hour
+)
+
+var (
+	// ErrInProgress is returned while the first request with a key has not
+	// completed yet.
+	ErrInProgress = stderrors.New("a request with this idempotency key is in progress")
+	// ErrMismatch is returned when a key is reused for a different request.
+	ErrMismatch = stderrors.New("idempotency key was used for a different request")
+)
+
+type Options struct {
+	Capacity int
+	TTL      time.Duration
+}
+
+// Fingerprint identifies a request by its method, path and body.
+type Fingerprint [sha256.Size]byte
+
+func NewFingerprint(method, path string, body []byte) Fingerprint {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+
+	var fp Fingerprint
+	copy(fp[:], h.Sum(nil))
+
+	return fp
+}
+
+// Response is a recorded response, replayed for retries.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+type entry struct {
+	key         string
+	fingerprint Fingerprint
+	response    *Response
+	expiresAt   time.Time
+}
+
+// Store keeps the responses of the most recent keys for the TTL. Keys of
+// requests still in progress are never evicted.
+type Store struct {
+	opts Options
+	now  func() time.Time
+
+	mu    sync.Mutex
+	items map[string]*list.Element
+	order *list.List
+}
+
+func NewStore(opts Options) *Store {
+	if opts.Capacity < 1 {
+		opts.Capacity = defaultCapacity
+	}
+
+	if opts.TTL < 1 {
+		opts.TTL = defaultTTL
+	}
+
+	return &Store{
+		opts:  opts,
+		now:   time.Now,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// Begin claims the key for a request. It returns the recorded response
+// when the same request completed before, ErrInProgress while it is still
+// running and ErrMismatch when the key belongs to another request. With
+// a nil response and error the caller runs the request and must call
+// Complete or Abort.
+func (s *Store) Begin(key string, fp Fingerprint) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if elem, ok := s.items[key]; ok {
+		e := elem.Value.(*entry)
+		switch {
+		case e.response != nil && !now.Before(e.expiresAt):
+			s.remove(elem)
+		case e.fingerprint != fp:
+			return nil, ErrMismatch
+		case e.response == nil:
+			return nil, ErrInProgress
+		default:
+			return e.response, nil
+		}
+	}
+
+	s.evict(now)
+	s.items[key] = s.order.PushBack(&entry{key: key, fingerprint: fp})
+
+	return nil, nil
+}
+
+// Complete records the response of a request claimed with Begin.
+func (s *Store) Complete(key string, response *Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok {
+		e := elem.Value.(*entry)
+		e.response = response
+		e.expiresAt = s.now().Add(s.opts.TTL)
+		s.order.MoveToBack(elem)
+	}
+}
+
+// Abort releases a key claimed with Begin without recording a response,
+// so a retry runs the request again.
+func (s *Store) Abort(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, ok := s.items[key]; ok && elem.Value.(*entry).response == nil {
+		s.remove(elem)
+	}
+}
+
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.items)
+}
+
+// evict drops expired responses, and the oldest ones while the store is
+// full. Completed entries are ordered by completion, so the scan stops at
+// the first live one.
+func (s *Store) evict(now time.Time) {
+	for elem := s.order.Front(); elem != nil; {
+		next := elem.Next()
+		e := elem.Value.(*entry)
+		if e.response != nil {
+			if now.Before(e.expiresAt) && len(s.items) < s.opts.Capacity {
+				break
+			}
+			s.remove(elem)
+		}
+		elem = next
+	}
+}
+
+func (s *Store) remove(elem *list.Element) {
+	delete(s.items, elem.Value.(*entry).key)
+	s.order.Remove(elem)
+}
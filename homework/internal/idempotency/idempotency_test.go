@@ -0,0 +1,70 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := NewStore(Options{Capacity: 2, TTL: time.Minute})
+	store.now = func() time.Time { return now }
+
+	create := NewFingerprint("POST", "/devices", []byte(`{"serial_num":"1"}`))
+	other := NewFingerprint("POST", "/devices", []byte(`{"serial_num":"2"}`))
+	response := &Response{Status: 200, Body: []byte("ok")}
+
+	actual, err := store.Begin("a", create)
+	require.NoError(t, err)
+	require.Nil(t, actual)
+
+	_, err = store.Begin("a", create)
+	require.ErrorIs(t, err, ErrInProgress)
+	_, err = store.Begin("a", other)
+	require.ErrorIs(t, err, ErrMismatch)
+
+	store.Complete("a", response)
+	actual, err = store.Begin("a", create)
+	require.NoError(t, err)
+	require.Equal(t, response, actual)
+	_, err = store.Begin("a", other)
+	require.ErrorIs(t, err, ErrMismatch)
+
+	// An aborted key is free for the next attempt.
+	_, err = store.Begin("b", other)
+	require.NoError(t, err)
+	store.Abort("b")
+	_, err = store.Begin("b", create)
+	require.NoError(t, err)
+	store.Complete("b", response)
+
+	// Abort does not drop a completed response.
+	store.Abort("a")
+	actual, err = store.Begin("a", create)
+	require.NoError(t, err)
+	require.Equal(t, response, actual)
+
+	// The oldest response is evicted when the store is full.
+	_, err = store.Begin("c", create)
+	require.NoError(t, err)
+	require.Equal(t, 2, store.Len())
+	actual, err = store.Begin("a", other)
+	require.NoError(t, err)
+	require.Nil(t, actual)
+	store.Abort("a")
+
+	// Responses expire after the TTL.
+	store.Complete("c", response)
+	now = now.Add(time.Minute)
+	actual, err = store.Begin("c", other)
+	require.NoError(t, err)
+	require.Nil(t, actual)
+}
+
+func TestFingerprint(t *testing.T) {
+	require.Equal(t, NewFingerprint("POST", "/devices", []byte("{}")), NewFingerprint("POST", "/devices", []byte("{}")))
+	require.NotEqual(t, NewFingerprint("POST", "/devices", []byte("{}")), NewFingerprint("POST", "/models", []byte("{}")))
+	require.NotEqual(t, NewFingerprint("POST", "/devices", nil), NewFingerprint("POST", "/device", []byte("s")))
+}
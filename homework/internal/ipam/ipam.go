@@ -0,0 +1,392 @@
+package ipam
+
+import (
+	"math"
+	"math/big"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+
+	"homework/internal/errors"
+)
+
+type Pool struct {
+	Name  string `json:"name"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Subnet is an address block managed by IPAM. Addresses are allocated from
+// its pools, a subnet without pools allocates from the whole block except
+// the network and broadcast addresses.
+type Subnet struct {
+	ID    string `json:"id"`
+	CIDR  string `json:"cidr"`
+	Pools []Pool `json:"pools,omitempty"`
+}
+
+type Utilization struct {
+	SubnetID  string  `json:"subnet_id"`
+	CIDR      string  `json:"cidr"`
+	Size      uint64  `json:"size"`
+	Allocated uint64  `json:"allocated"`
+	Free      uint64  `json:"free"`
+	Percent   float64 `json:"percent"`
+}
+
+type addrRange struct {
+	start netip.Addr
+	end   netip.Addr
+}
+
+func (r addrRange) contains(addr netip.Addr) bool {
+	return r.start.Compare(addr) <= 0 && addr.Compare(r.end) <= 0
+}
+
+func (r addrRange) overlaps(other addrRange) bool {
+	return r.start.Compare(other.end) <= 0 && other.start.Compare(r.end) <= 0
+}
+
+func (r addrRange) size() *big.Int {
+	start := new(big.Int).SetBytes(r.start.AsSlice())
+	end := new(big.Int).SetBytes(r.end.AsSlice())
+
+	return end.Sub(end, start).Add(end, big.NewInt(1))
+}
+
+type subnet struct {
+	Subnet
+	prefix netip.Prefix
+	pools  []addrRange
+}
+
+// ranges returns the address ranges allocation is done from.
+func (s *subnet) ranges() []addrRange {
+	if len(s.pools) > 0 {
+		return s.pools
+	}
+
+	return []addrRange{hostRange(s.prefix)}
+}
+
+// Manager keeps subnets and address allocations in memory.
+type Manager struct {
+	mu        sync.RWMutex
+	subnets   map[string]*subnet
+	allocated map[netip.Addr]string
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		subnets:   make(map[string]*subnet),
+		allocated: make(map[netip.Addr]string),
+	}
+}
+
+func (m *Manager) CreateSubnet(s *Subnet) error {
+	prefix, err := netip.ParsePrefix(s.CIDR)
+	if err != nil {
+		return errors.NewValidationError("invalid cidr %q", s.CIDR)
+	}
+	prefix = prefix.Masked()
+
+	// IDs are used as a path segment of the subnet routes, so the default
+	// one spells the prefix length without a slash: 10.0.0.0-24.
+	if s.ID == "" {
+		s.ID = strings.Replace(prefix.String(), "/", "-", 1)
+	}
+	if strings.Contains(s.ID, "/") {
+		return errors.NewValidationError("subnet id %q must not contain '/'", s.ID)
+	}
+
+	created := &subnet{
+		Subnet: Subnet{ID: s.ID, CIDR: prefix.String()},
+		prefix: prefix,
+	}
+
+	for _, pool := range s.Pools {
+		if err = created.addPool(pool); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.subnets[s.ID]; ok {
+		return errors.NewConflictErrorf("subnet with 'ID' = %s already exist", s.ID)
+	}
+
+	for _, existing := range m.subnets {
+		if existing.prefix.Overlaps(prefix) {
+			return errors.NewConflictErrorf("subnet %s overlaps subnet %s", prefix, existing.ID)
+		}
+	}
+
+	m.subnets[s.ID] = created
+	*s = created.copy()
+
+	return nil
+}
+
+func (m *Manager) GetSubnet(id string) (*Subnet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.subnets[id]
+	if !ok {
+		return nil, errors.NewEntityNotFoundError("subnet", "ID", id)
+	}
+
+	result := s.copy()
+
+	return &result, nil
+}
+
+func (m *Manager) ListSubnets() []*Subnet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]*Subnet, 0, len(m.subnets))
+	for _, s := range m.sortedSubnets() {
+		result := s.copy()
+		list = append(list, &result)
+	}
+
+	return list
+}
+
+func (m *Manager) DeleteSubnet(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.subnets[id]
+	if !ok {
+		return errors.NewEntityNotFoundError("subnet", "ID", id)
+	}
+
+	if allocated := m.countAllocated(s); allocated > 0 {
+		return errors.NewConflictErrorf("subnet %s still has %d allocated addresses", id, allocated)
+	}
+
+	delete(m.subnets, id)
+
+	return nil
+}
+
+func (m *Manager) AddPool(id string, pool Pool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.subnets[id]
+	if !ok {
+		return errors.NewEntityNotFoundError("subnet", "ID", id)
+	}
+
+	return s.addPool(pool)
+}
+
+// Allocate assigns the first free address of the first subnet, in ID
+// order, that has one.
+func (m *Manager) Allocate(serialNum string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, s := range m.sortedSubnets() {
+		for _, r := range s.ranges() {
+			for addr := r.start; addr.IsValid() && addr.Compare(r.end) <= 0; addr = addr.Next() {
+				if _, taken := m.allocated[addr]; !taken {
+					m.allocated[addr] = serialNum
+					return addr.String(), nil
+				}
+			}
+		}
+	}
+
+	return "", errors.NewConflictErrorf("no free addresses left in any subnet")
+}
+
+// Reserve marks an explicitly chosen address as used. Addresses outside
+// every managed subnet are not tracked.
+func (m *Manager) Reserve(ip, serialNum string) error {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return errors.NewValidationError("invalid ip %q", ip)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.subnetFor(addr) == nil {
+		return nil
+	}
+
+	if owner, taken := m.allocated[addr]; taken && owner != serialNum {
+		return errors.NewConflictError("IP", ip, owner)
+	}
+
+	m.allocated[addr] = serialNum
+
+	return nil
+}
+
+// Release frees an address if it is allocated to the given device.
+func (m *Manager) Release(ip, serialNum string) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if owner, taken := m.allocated[addr]; taken && owner == serialNum {
+		delete(m.allocated, addr)
+	}
+}
+
+func (m *Manager) Utilization(id string) (*Utilization, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.subnets[id]
+	if !ok {
+		return nil, errors.NewEntityNotFoundError("subnet", "ID", id)
+	}
+
+	return m.utilization(s), nil
+}
+
+func (m *Manager) ListUtilization() []*Utilization {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]*Utilization, 0, len(m.subnets))
+	for _, s := range m.sortedSubnets() {
+		list = append(list, m.utilization(s))
+	}
+
+	return list
+}
+
+func (m *Manager) utilization(s *subnet) *Utilization {
+	size := new(big.Int)
+	for _, r := range s.ranges() {
+		size.Add(size, r.size())
+	}
+
+	u := &Utilization{
+		SubnetID:  s.ID,
+		CIDR:      s.CIDR,
+		Size:      math.MaxUint64,
+		Allocated: m.countAllocated(s),
+	}
+	if size.IsUint64() {
+		u.Size = size.Uint64()
+	}
+
+	if u.Size > u.Allocated {
+		u.Free = u.Size - u.Allocated
+	}
+	if u.Size > 0 {
+		u.Percent = float64(u.Allocated) / float64(u.Size) * 100
+	}
+
+	return u
+}
+
+func (m *Manager) countAllocated(s *subnet) uint64 {
+	var count uint64
+	for addr := range m.allocated {
+		if s.prefix.Contains(addr) {
+			count++
+		}
+	}
+
+	return count
+}
+
+func (m *Manager) subnetFor(addr netip.Addr) *subnet {
+	for _, s := range m.subnets {
+		if s.prefix.Contains(addr) {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (m *Manager) sortedSubnets() []*subnet {
+	list := make([]*subnet, 0, len(m.subnets))
+	for _, s := range m.subnets {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+
+	return list
+}
+
+func (s *subnet) addPool(pool Pool) error {
+	start, err := netip.ParseAddr(pool.Start)
+	if err != nil {
+		return errors.NewValidationError("pool %s: invalid start address %q", pool.Name, pool.Start)
+	}
+
+	end, err := netip.ParseAddr(pool.End)
+	if err != nil {
+		return errors.NewValidationError("pool %s: invalid end address %q", pool.Name, pool.End)
+	}
+
+	r := addrRange{start: start, end: end}
+	if !s.prefix.Contains(start) || !s.prefix.Contains(end) || end.Less(start) {
+		return errors.NewValidationError("pool %s: range %s-%s is not within subnet %s", pool.Name, start, end, s.CIDR)
+	}
+
+	for i, existing := range s.pools {
+		if existing.overlaps(r) {
+			return errors.NewConflictErrorf("pool %s overlaps pool %s of subnet %s", pool.Name, s.Pools[i].Name, s.ID)
+		}
+	}
+
+	s.pools = append(s.pools, r)
+	s.Pools = append(s.Pools, Pool{Name: pool.Name, Start: start.String(), End: end.String()})
+
+	return nil
+}
+
+func (s *subnet) copy() Subnet {
+	result := s.Subnet
+	result.Pools = append([]Pool(nil), s.Pools...)
+
+	return result
+}
+
+// hostRange excludes the network address and, for IPv4, the broadcast
+// address unless the prefix is too small to have them.
+func hostRange(prefix netip.Prefix) addrRange {
+	first := prefix.Addr()
+	last := lastAddr(prefix)
+
+	if prefix.Bits() < first.BitLen()-1 {
+		first = first.Next()
+		if first.Is4() {
+			last = last.Prev()
+		}
+	}
+
+	return addrRange{start: first, end: last}
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 0x80 >> (bit % 8)
+	}
+
+	addr, _ := netip.AddrFromSlice(bytes)
+
+	return addr
+}
@@ -0,0 +1,131 @@
+package ipam
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/errors"
+)
+
+const (
+	testSerialNum1 = "test 1"
+	testSerialNum2 = "test 2"
+	testSerialNum3 = "test 3"
+)
+
+func TestCreateSubnet(t *testing.T) {
+	m := NewManager()
+
+	subnet := &Subnet{CIDR: "10.0.0.1/24"}
+	require.NoError(t, m.CreateSubnet(subnet))
+	require.Equal(t, "10.0.0.0-24", subnet.ID)
+	require.Equal(t, "10.0.0.0/24", subnet.CIDR)
+
+	require.IsType(t, &errors.ConflictError{}, m.CreateSubnet(&Subnet{CIDR: "10.0.0.128/25"}))
+	require.IsType(t, &errors.ValidationError{}, m.CreateSubnet(&Subnet{CIDR: "10.0.0.0/33"}))
+	require.IsType(t, &errors.ValidationError{}, m.CreateSubnet(&Subnet{ID: "a/b", CIDR: "10.3.0.0/24"}))
+	require.IsType(t, &errors.ValidationError{}, m.CreateSubnet(&Subnet{
+		CIDR:  "10.1.0.0/24",
+		Pools: []Pool{{Name: "outside", Start: "10.2.0.1", End: "10.2.0.10"}},
+	}))
+
+	require.Len(t, m.ListSubnets(), 1)
+}
+
+func TestAllocate(t *testing.T) {
+	m := NewManager()
+	require.NoError(t, m.CreateSubnet(&Subnet{ID: "small", CIDR: "192.168.0.0/30"}))
+
+	ip, err := m.Allocate(testSerialNum1)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.0.1", ip)
+
+	ip, err = m.Allocate(testSerialNum2)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.0.2", ip)
+
+	_, err = m.Allocate(testSerialNum3)
+	require.IsType(t, &errors.ConflictError{}, err)
+
+	m.Release("192.168.0.1", testSerialNum1)
+
+	ip, err = m.Allocate(testSerialNum3)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.0.1", ip)
+}
+
+func TestAllocateFromPools(t *testing.T) {
+	m := NewManager()
+	require.NoError(t, m.CreateSubnet(&Subnet{
+		ID:    "office",
+		CIDR:  "10.0.0.0/24",
+		Pools: []Pool{{Name: "dhcp", Start: "10.0.0.100", End: "10.0.0.101"}},
+	}))
+
+	require.IsType(t, &errors.ConflictError{}, m.AddPool("office", Pool{Name: "overlap", Start: "10.0.0.101", End: "10.0.0.110"}))
+	require.NoError(t, m.AddPool("office", Pool{Name: "extra", Start: "10.0.0.200", End: "10.0.0.200"}))
+
+	var ips []string
+	for _, serialNum := range []string{testSerialNum1, testSerialNum2, testSerialNum3} {
+		ip, err := m.Allocate(serialNum)
+		require.NoError(t, err)
+		ips = append(ips, ip)
+	}
+	require.Equal(t, []string{"10.0.0.100", "10.0.0.101", "10.0.0.200"}, ips)
+
+	u, err := m.Utilization("office")
+	require.NoError(t, err)
+	require.Equal(t, &Utilization{
+		SubnetID:  "office",
+		CIDR:      "10.0.0.0/24",
+		Size:      3,
+		Allocated: 3,
+		Free:      0,
+		Percent:   100,
+	}, u)
+}
+
+func TestReserve(t *testing.T) {
+	m := NewManager()
+	require.NoError(t, m.CreateSubnet(&Subnet{ID: "lan", CIDR: "10.0.0.0/29"}))
+
+	require.NoError(t, m.Reserve("10.0.0.1", testSerialNum1))
+	require.NoError(t, m.Reserve("10.0.0.1", testSerialNum1))
+	require.IsType(t, &errors.ConflictError{}, m.Reserve("10.0.0.1", testSerialNum2))
+	require.NoError(t, m.Reserve("172.16.0.1", testSerialNum2))
+	require.IsType(t, &errors.ValidationError{}, m.Reserve("not an ip", testSerialNum2))
+
+	ip, err := m.Allocate(testSerialNum2)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.2", ip)
+
+	m.Release("10.0.0.1", testSerialNum2)
+	require.IsType(t, &errors.ConflictError{}, m.Reserve("10.0.0.1", testSerialNum3))
+}
+
+func TestDeleteSubnet(t *testing.T) {
+	m := NewManager()
+	require.NoError(t, m.CreateSubnet(&Subnet{ID: "lan", CIDR: "10.0.0.0/29"}))
+	require.NoError(t, m.Reserve("10.0.0.3", testSerialNum1))
+
+	require.IsType(t, &errors.ConflictError{}, m.DeleteSubnet("lan"))
+
+	m.Release("10.0.0.3", testSerialNum1)
+	require.NoError(t, m.DeleteSubnet("lan"))
+	require.IsType(t, &errors.NotFoundError{}, m.DeleteSubnet("lan"))
+}
+
+func TestUtilizationIPv6(t *testing.T) {
+	m := NewManager()
+	require.NoError(t, m.CreateSubnet(&Subnet{ID: "v6", CIDR: "2001:db8::/120"}))
+
+	ip, err := m.Allocate(testSerialNum1)
+	require.NoError(t, err)
+	require.Equal(t, "2001:db8::1", ip)
+
+	list := m.ListUtilization()
+	require.Len(t, list, 1)
+	require.Equal(t, uint64(255), list[0].Size)
+	require.Equal(t, uint64(1), list[0].Allocated)
+}
@@ -0,0 +1,65 @@
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	maxNameLength   = 63
+	maxPrefixLength = 253
+)
+
+var (
+	nameRe   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]*[A-Za-z0-9])?$`)
+	prefixRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+)
+
+// ValidateKey checks a key of the form [prefix/]name, where the optional
+// prefix is a DNS subdomain and the name is at most 63 alphanumeric
+// characters, '-', '_' or '.', starting and ending with an alphanumeric.
+func ValidateKey(key string) error {
+	prefix, name, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix {
+		name, prefix = prefix, ""
+	}
+
+	if hasPrefix && (prefix == "" || len(prefix) > maxPrefixLength || !prefixRe.MatchString(prefix)) {
+		return fmt.Errorf("label key %q: prefix must be a lowercase dns subdomain", key)
+	}
+
+	if len(name) > maxNameLength || !nameRe.MatchString(name) {
+		return fmt.Errorf("label key %q: name must be 1-63 alphanumeric characters, '-', '_' or '.'", key)
+	}
+
+	return nil
+}
+
+// ValidateValue checks a label value, which is either empty or follows the
+// rules of a key name.
+func ValidateValue(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	if len(value) > maxNameLength || !nameRe.MatchString(value) {
+		return fmt.Errorf("label value %q must be at most 63 alphanumeric characters, '-', '_' or '.'", value)
+	}
+
+	return nil
+}
+
+func Validate(labels map[string]string) error {
+	for key, value := range labels {
+		if err := ValidateKey(key); err != nil {
+			return err
+		}
+
+		if err := ValidateValue(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
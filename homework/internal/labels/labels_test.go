@@ -0,0 +1,87 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(map[string]string{
+		"environment":          "prod",
+		"example.com/team":     "net-ops",
+		"rack.position_hint":   "",
+		"a":                    "B.c_d-e",
+		"netops.example.com/x": "1",
+	}))
+
+	for _, key := range []string{"", "-env", "env-", "Example.com/team", "/team", "a/b/c", "sp ace"} {
+		require.Error(t, ValidateKey(key), key)
+	}
+
+	require.Error(t, ValidateValue("with space"))
+	require.Error(t, ValidateValue("-prod"))
+}
+
+func TestParse(t *testing.T) {
+	selector, err := Parse("environment=prod, team != netops,tier in (web, db),zone notin (a),gpu,!legacy,app==api")
+
+	require.NoError(t, err)
+	require.Equal(t, Selector{
+		{Key: "environment", Operator: Equals, Values: []string{"prod"}},
+		{Key: "team", Operator: NotEquals, Values: []string{"netops"}},
+		{Key: "tier", Operator: In, Values: []string{"db", "web"}},
+		{Key: "zone", Operator: NotIn, Values: []string{"a"}},
+		{Key: "gpu", Operator: Exists},
+		{Key: "legacy", Operator: DoesNotExist},
+		{Key: "app", Operator: Equals, Values: []string{"api"}},
+	}, selector)
+	require.Equal(t, "environment=prod,team!=netops,tier in (db,web),zone notin (a),gpu,!legacy,app=api", selector.String())
+
+	empty, err := Parse("  ")
+	require.NoError(t, err)
+	require.True(t, empty.Empty())
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"env=prod,",
+		"env=pr od",
+		"tier in web",
+		"tier between (a,b)",
+		"!env=prod",
+		"bad key=1",
+	} {
+		_, err := Parse(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	labels := map[string]string{"environment": "prod", "team": "netops", "tier": "web"}
+
+	cases := []struct {
+		selector string
+		matches  bool
+	}{
+		{selector: "", matches: true},
+		{selector: "environment=prod", matches: true},
+		{selector: "environment=dev", matches: false},
+		{selector: "team!=netops", matches: false},
+		{selector: "owner!=bob", matches: true},
+		{selector: "tier in (web,db)", matches: true},
+		{selector: "tier notin (web)", matches: false},
+		{selector: "owner notin (bob)", matches: true},
+		{selector: "team", matches: true},
+		{selector: "owner", matches: false},
+		{selector: "!owner", matches: true},
+		{selector: "!team", matches: false},
+		{selector: "environment=prod,tier in (db)", matches: false},
+	}
+
+	for _, tCase := range cases {
+		selector, err := Parse(tCase.selector)
+		require.NoError(t, err)
+		require.Equal(t, tCase.matches, selector.Matches(labels), tCase.selector)
+	}
+}
@@ -0,0 +1,208 @@
+package labels
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+
+	switch r.Operator {
+	case Equals, In:
+		return ok && contains(r.Values, value)
+	case NotEquals, NotIn:
+		return !ok || !contains(r.Values, value)
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	default:
+		return false
+	}
+}
+
+// Positive reports whether only devices carrying the key can match, so an
+// index on the key can be used to find candidates.
+func (r Requirement) Positive() bool {
+	return r.Operator == Equals || r.Operator == In || r.Operator == Exists
+}
+
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	default:
+		return r.Key + string(r.Operator) + r.Values[0]
+	}
+}
+
+// Selector is a conjunction of requirements. The empty selector matches
+// every label set.
+type Selector []Requirement
+
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Parse reads a comma separated list of requirements:
+//
+//	key=value, key==value, key!=value, key in (a,b), key notin (a,b), key, !key
+func Parse(s string) (Selector, error) {
+	var selector Selector
+
+	for _, part := range splitRequirements(s) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			if strings.TrimSpace(s) == "" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("selector %q: empty requirement", s)
+		}
+
+		r, err := parseRequirement(part)
+		if err != nil {
+			return nil, fmt.Errorf("selector %q: %w", s, err)
+		}
+		selector = append(selector, r)
+	}
+
+	return selector, nil
+}
+
+// splitRequirements splits on commas outside of parentheses.
+func splitRequirements(s string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+func parseRequirement(s string) (Requirement, error) {
+	if strings.HasPrefix(s, "!") && !strings.Contains(s, "=") {
+		return newRequirement(strings.TrimSpace(s[1:]), DoesNotExist, nil)
+	}
+
+	for _, op := range []string{"!=", "==", "="} {
+		if key, value, ok := strings.Cut(s, op); ok {
+			operator := Equals
+			if op == "!=" {
+				operator = NotEquals
+			}
+			return newRequirement(strings.TrimSpace(key), operator, []string{strings.TrimSpace(value)})
+		}
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 1 {
+		return newRequirement(fields[0], Exists, nil)
+	}
+
+	key, rest := fields[0], strings.TrimSpace(strings.TrimPrefix(s, fields[0]))
+
+	var operator Operator
+	switch {
+	case strings.HasPrefix(rest, "notin"):
+		operator, rest = NotIn, rest[len("notin"):]
+	case strings.HasPrefix(rest, "in"):
+		operator, rest = In, rest[len("in"):]
+	default:
+		return Requirement{}, fmt.Errorf("requirement %q: unknown operator", s)
+	}
+
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "(") || !strings.HasSuffix(rest, ")") {
+		return Requirement{}, fmt.Errorf("requirement %q: values must be in parentheses", s)
+	}
+
+	var values []string
+	for _, value := range strings.Split(rest[1:len(rest)-1], ",") {
+		values = append(values, strings.TrimSpace(value))
+	}
+
+	return newRequirement(key, operator, values)
+}
+
+func newRequirement(key string, operator Operator, values []string) (Requirement, error) {
+	if err := ValidateKey(key); err != nil {
+		return Requirement{}, err
+	}
+
+	for _, value := range values {
+		if err := ValidateValue(value); err != nil {
+			return Requirement{}, err
+		}
+	}
+
+	sort.Strings(values)
+
+	return Requirement{Key: key, Operator: operator, Values: values}, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,31 @@
+package locations
+
+type Kind string
+
+const (
+	KindRegion Kind = "region"
+	KindSite   Kind = "site"
+	KindRoom   Kind = "room"
+	KindRack   Kind = "rack"
+)
+
+var parentKinds = map[Kind]Kind{
+	KindRegion: "",
+	KindSite:   KindRegion,
+	KindRoom:   KindSite,
+	KindRack:   KindRoom,
+}
+
+// ParentKind returns the kind a location of the given kind must be placed
+// under. Regions are roots and have no parent kind.
+func ParentKind(kind Kind) (Kind, bool) {
+	parent, ok := parentKinds[kind]
+	return parent, ok
+}
+
+type Location struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Kind     Kind   `json:"kind"`
+	ParentID string `json:"parent_id,omitempty"`
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("unknown log level %q", s)
+}
+
+// Logger is a leveled wrapper around the standard logger whose level can
+// be changed while it is in use.
+type Logger struct {
+	level  *atomic.Int32
+	output *log.Logger
+	prefix string
+}
+
+func New(w io.Writer, level Level) *Logger {
+	l := &Logger{
+		level:  &atomic.Int32{},
+		output: log.New(w, "", log.LstdFlags),
+	}
+	l.SetLevel(level)
+
+	return l
+}
+
+// With returns a logger that prefixes messages with prefix. It shares the
+// level with l, so SetLevel on either changes both.
+func (l *Logger) With(prefix string) *Logger {
+	return &Logger{
+		level:  l.level,
+		output: l.output,
+		prefix: l.prefix + prefix,
+	}
+}
+
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+func (l *Logger) Level() Level {
+	return Level(l.level.Load())
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, format, args...)
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args...)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, format, args...)
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args...)
+}
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if level < l.Level() {
+		return
+	}
+
+	_ = l.output.Output(3, strings.ToUpper(level.String())+" "+l.prefix+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("WARN")
+	require.NoError(t, err)
+	require.Equal(t, LevelWarn, level)
+
+	_, err = ParseLevel("verbose")
+	require.Error(t, err)
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, LevelWarn)
+
+	log.Infof("hidden %d", 1)
+	require.Empty(t, buf.String())
+
+	log.Errorf("shown %d", 2)
+	require.Contains(t, buf.String(), "ERROR shown 2")
+
+	buf.Reset()
+	log.SetLevel(LevelDebug)
+	log.Debugf("debug")
+	require.Contains(t, buf.String(), "DEBUG debug")
+	require.Equal(t, LevelDebug, log.Level())
+}
+
+func TestLoggerWith(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(&buf, LevelWarn)
+	tenantLog := log.With("tenant ops: ")
+
+	tenantLog.Infof("hidden")
+	require.Empty(t, buf.String())
+
+	log.SetLevel(LevelInfo)
+	tenantLog.Infof("shown")
+	require.Contains(t, buf.String(), "INFO tenant ops: shown")
+}
@@ -0,0 +1,65 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/app (interfaces: BackupService)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	backup "homework/internal/backup"
+	io "io"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockBackupService is a mock of BackupService interface.
+type MockBackupService struct {
+	ctrl     *gomock.Controller
+	recorder *MockBackupServiceMockRecorder
+}
+
+// MockBackupServiceMockRecorder is the mock recorder for MockBackupService.
+type MockBackupServiceMockRecorder struct {
+	mock *MockBackupService
+}
+
+// NewMockBackupService creates a new mock instance.
+func NewMockBackupService(ctrl *gomock.Controller) *MockBackupService {
+	mock := &MockBackupService{ctrl: ctrl}
+	mock.recorder = &MockBackupServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBackupService) EXPECT() *MockBackupServiceMockRecorder {
+	return m.recorder
+}
+
+// Backup mocks base method.
+func (m *MockBackupService) Backup(arg0 io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Backup", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Backup indicates an expected call of Backup.
+func (mr *MockBackupServiceMockRecorder) Backup(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Backup", reflect.TypeOf((*MockBackupService)(nil).Backup), arg0)
+}
+
+// Restore mocks base method.
+func (m *MockBackupService) Restore(arg0 io.Reader, arg1 backup.Mode) (*backup.Header, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", arg0, arg1)
+	ret0, _ := ret[0].(*backup.Header)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockBackupServiceMockRecorder) Restore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockBackupService)(nil).Restore), arg0, arg1)
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/app (interfaces: HistoryRepository)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	devices "homework/internal/devices"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockHistoryRepository is a mock of HistoryRepository interface.
+type MockHistoryRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockHistoryRepositoryMockRecorder
+}
+
+// MockHistoryRepositoryMockRecorder is the mock recorder for MockHistoryRepository.
+type MockHistoryRepositoryMockRecorder struct {
+	mock *MockHistoryRepository
+}
+
+// NewMockHistoryRepository creates a new mock instance.
+func NewMockHistoryRepository(ctrl *gomock.Controller) *MockHistoryRepository {
+	mock := &MockHistoryRepository{ctrl: ctrl}
+	mock.recorder = &MockHistoryRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHistoryRepository) EXPECT() *MockHistoryRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockHistoryRepository) Append(arg0 devices.Transition) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockHistoryRepositoryMockRecorder) Append(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockHistoryRepository)(nil).Append), arg0)
+}
+
+// List mocks base method.
+func (m *MockHistoryRepository) List(arg0 string) ([]devices.Transition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]devices.Transition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockHistoryRepositoryMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockHistoryRepository)(nil).List), arg0)
+}
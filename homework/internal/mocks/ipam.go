@@ -0,0 +1,176 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/app (interfaces: IPAMService)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	ipam "homework/internal/ipam"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIPAMService is a mock of IPAMService interface.
+type MockIPAMService struct {
+	ctrl     *gomock.Controller
+	recorder *MockIPAMServiceMockRecorder
+}
+
+// MockIPAMServiceMockRecorder is the mock recorder for MockIPAMService.
+type MockIPAMServiceMockRecorder struct {
+	mock *MockIPAMService
+}
+
+// NewMockIPAMService creates a new mock instance.
+func NewMockIPAMService(ctrl *gomock.Controller) *MockIPAMService {
+	mock := &MockIPAMService{ctrl: ctrl}
+	mock.recorder = &MockIPAMServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIPAMService) EXPECT() *MockIPAMServiceMockRecorder {
+	return m.recorder
+}
+
+// AddPool mocks base method.
+func (m *MockIPAMService) AddPool(arg0 string, arg1 ipam.Pool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPool", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPool indicates an expected call of AddPool.
+func (mr *MockIPAMServiceMockRecorder) AddPool(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPool", reflect.TypeOf((*MockIPAMService)(nil).AddPool), arg0, arg1)
+}
+
+// Allocate mocks base method.
+func (m *MockIPAMService) Allocate(arg0 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allocate", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Allocate indicates an expected call of Allocate.
+func (mr *MockIPAMServiceMockRecorder) Allocate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allocate", reflect.TypeOf((*MockIPAMService)(nil).Allocate), arg0)
+}
+
+// CreateSubnet mocks base method.
+func (m *MockIPAMService) CreateSubnet(arg0 *ipam.Subnet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSubnet", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSubnet indicates an expected call of CreateSubnet.
+func (mr *MockIPAMServiceMockRecorder) CreateSubnet(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSubnet", reflect.TypeOf((*MockIPAMService)(nil).CreateSubnet), arg0)
+}
+
+// DeleteSubnet mocks base method.
+func (m *MockIPAMService) DeleteSubnet(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubnet", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSubnet indicates an expected call of DeleteSubnet.
+func (mr *MockIPAMServiceMockRecorder) DeleteSubnet(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubnet", reflect.TypeOf((*MockIPAMService)(nil).DeleteSubnet), arg0)
+}
+
+// GetSubnet mocks base method.
+func (m *MockIPAMService) GetSubnet(arg0 string) (*ipam.Subnet, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubnet", arg0)
+	ret0, _ := ret[0].(*ipam.Subnet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubnet indicates an expected call of GetSubnet.
+func (mr *MockIPAMServiceMockRecorder) GetSubnet(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnet", reflect.TypeOf((*MockIPAMService)(nil).GetSubnet), arg0)
+}
+
+// ListSubnets mocks base method.
+func (m *MockIPAMService) ListSubnets() []*ipam.Subnet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubnets")
+	ret0, _ := ret[0].([]*ipam.Subnet)
+	return ret0
+}
+
+// ListSubnets indicates an expected call of ListSubnets.
+func (mr *MockIPAMServiceMockRecorder) ListSubnets() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubnets", reflect.TypeOf((*MockIPAMService)(nil).ListSubnets))
+}
+
+// ListUtilization mocks base method.
+func (m *MockIPAMService) ListUtilization() []*ipam.Utilization {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUtilization")
+	ret0, _ := ret[0].([]*ipam.Utilization)
+	return ret0
+}
+
+// ListUtilization indicates an expected call of ListUtilization.
+func (mr *MockIPAMServiceMockRecorder) ListUtilization() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUtilization", reflect.TypeOf((*MockIPAMService)(nil).ListUtilization))
+}
+
+// Release mocks base method.
+func (m *MockIPAMService) Release(arg0, arg1 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Release", arg0, arg1)
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockIPAMServiceMockRecorder) Release(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockIPAMService)(nil).Release), arg0, arg1)
+}
+
+// Reserve mocks base method.
+func (m *MockIPAMService) Reserve(arg0, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockIPAMServiceMockRecorder) Reserve(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockIPAMService)(nil).Reserve), arg0, arg1)
+}
+
+// Utilization mocks base method.
+func (m *MockIPAMService) Utilization(arg0 string) (*ipam.Utilization, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Utilization", arg0)
+	ret0, _ := ret[0].(*ipam.Utilization)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Utilization indicates an expected call of Utilization.
+func (mr *MockIPAMServiceMockRecorder) Utilization(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Utilization", reflect.TypeOf((*MockIPAMService)(nil).Utilization), arg0)
+}
@@ -0,0 +1,191 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/app (interfaces: HeartbeatRepository,LivenessService)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	devices "homework/internal/devices"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockHeartbeatRepository is a mock of HeartbeatRepository interface.
+type MockHeartbeatRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockHeartbeatRepositoryMockRecorder
+}
+
+// MockHeartbeatRepositoryMockRecorder is the mock recorder for MockHeartbeatRepository.
+type MockHeartbeatRepositoryMockRecorder struct {
+	mock *MockHeartbeatRepository
+}
+
+// NewMockHeartbeatRepository creates a new mock instance.
+func NewMockHeartbeatRepository(ctrl *gomock.Controller) *MockHeartbeatRepository {
+	mock := &MockHeartbeatRepository{ctrl: ctrl}
+	mock.recorder = &MockHeartbeatRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHeartbeatRepository) EXPECT() *MockHeartbeatRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockHeartbeatRepository) Delete(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockHeartbeatRepositoryMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockHeartbeatRepository)(nil).Delete), arg0)
+}
+
+// Get mocks base method.
+func (m *MockHeartbeatRepository) Get(arg0 string) (*devices.Heartbeat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0)
+	ret0, _ := ret[0].(*devices.Heartbeat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockHeartbeatRepositoryMockRecorder) Get(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockHeartbeatRepository)(nil).Get), arg0)
+}
+
+// List mocks base method.
+func (m *MockHeartbeatRepository) List() ([]*devices.Heartbeat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*devices.Heartbeat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockHeartbeatRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockHeartbeatRepository)(nil).List))
+}
+
+// MarkOffline mocks base method.
+func (m *MockHeartbeatRepository) MarkOffline(arg0 string, arg1 time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOffline", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkOffline indicates an expected call of MarkOffline.
+func (mr *MockHeartbeatRepositoryMockRecorder) MarkOffline(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOffline", reflect.TypeOf((*MockHeartbeatRepository)(nil).MarkOffline), arg0, arg1)
+}
+
+// Put mocks base method.
+func (m *MockHeartbeatRepository) Put(arg0 *devices.Heartbeat) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockHeartbeatRepositoryMockRecorder) Put(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockHeartbeatRepository)(nil).Put), arg0)
+}
+
+// MockLivenessService is a mock of LivenessService interface.
+type MockLivenessService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLivenessServiceMockRecorder
+}
+
+// MockLivenessServiceMockRecorder is the mock recorder for MockLivenessService.
+type MockLivenessServiceMockRecorder struct {
+	mock *MockLivenessService
+}
+
+// NewMockLivenessService creates a new mock instance.
+func NewMockLivenessService(ctrl *gomock.Controller) *MockLivenessService {
+	mock := &MockLivenessService{ctrl: ctrl}
+	mock.recorder = &MockLivenessServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLivenessService) EXPECT() *MockLivenessServiceMockRecorder {
+	return m.recorder
+}
+
+// CountLiveness mocks base method.
+func (m *MockLivenessService) CountLiveness() (*devices.LivenessCounts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountLiveness")
+	ret0, _ := ret[0].(*devices.LivenessCounts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountLiveness indicates an expected call of CountLiveness.
+func (mr *MockLivenessServiceMockRecorder) CountLiveness() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountLiveness", reflect.TypeOf((*MockLivenessService)(nil).CountLiveness))
+}
+
+// GetHeartbeat mocks base method.
+func (m *MockLivenessService) GetHeartbeat(arg0 string) (*devices.Heartbeat, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeartbeat", arg0)
+	ret0, _ := ret[0].(*devices.Heartbeat)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHeartbeat indicates an expected call of GetHeartbeat.
+func (mr *MockLivenessServiceMockRecorder) GetHeartbeat(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeartbeat", reflect.TypeOf((*MockLivenessService)(nil).GetHeartbeat), arg0)
+}
+
+// Heartbeat mocks base method.
+func (m *MockLivenessService) Heartbeat(arg0 string, arg1 map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Heartbeat", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Heartbeat indicates an expected call of Heartbeat.
+func (mr *MockLivenessServiceMockRecorder) Heartbeat(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Heartbeat", reflect.TypeOf((*MockLivenessService)(nil).Heartbeat), arg0, arg1)
+}
+
+// Sweep mocks base method.
+func (m *MockLivenessService) Sweep() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sweep")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sweep indicates an expected call of Sweep.
+func (mr *MockLivenessServiceMockRecorder) Sweep() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sweep", reflect.TypeOf((*MockLivenessService)(nil).Sweep))
+}
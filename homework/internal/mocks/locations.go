@@ -0,0 +1,217 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/app (interfaces: LocationRepository,LocationService)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	locations "homework/internal/locations"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockLocationRepository is a mock of LocationRepository interface.
+type MockLocationRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLocationRepositoryMockRecorder
+}
+
+// MockLocationRepositoryMockRecorder is the mock recorder for MockLocationRepository.
+type MockLocationRepositoryMockRecorder struct {
+	mock *MockLocationRepository
+}
+
+// NewMockLocationRepository creates a new mock instance.
+func NewMockLocationRepository(ctrl *gomock.Controller) *MockLocationRepository {
+	mock := &MockLocationRepository{ctrl: ctrl}
+	mock.recorder = &MockLocationRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLocationRepository) EXPECT() *MockLocationRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockLocationRepository) Create(arg0 *locations.Location) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockLocationRepositoryMockRecorder) Create(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockLocationRepository)(nil).Create), arg0)
+}
+
+// Delete mocks base method.
+func (m *MockLocationRepository) Delete(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockLocationRepositoryMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockLocationRepository)(nil).Delete), arg0)
+}
+
+// Get mocks base method.
+func (m *MockLocationRepository) Get(arg0 string) (*locations.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0)
+	ret0, _ := ret[0].(*locations.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockLocationRepositoryMockRecorder) Get(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockLocationRepository)(nil).Get), arg0)
+}
+
+// List mocks base method.
+func (m *MockLocationRepository) List() ([]*locations.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*locations.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockLocationRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockLocationRepository)(nil).List))
+}
+
+// ListChildren mocks base method.
+func (m *MockLocationRepository) ListChildren(arg0 string) ([]*locations.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListChildren", arg0)
+	ret0, _ := ret[0].([]*locations.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListChildren indicates an expected call of ListChildren.
+func (mr *MockLocationRepositoryMockRecorder) ListChildren(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListChildren", reflect.TypeOf((*MockLocationRepository)(nil).ListChildren), arg0)
+}
+
+// Update mocks base method.
+func (m *MockLocationRepository) Update(arg0 *locations.Location) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockLocationRepositoryMockRecorder) Update(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockLocationRepository)(nil).Update), arg0)
+}
+
+// MockLocationService is a mock of LocationService interface.
+type MockLocationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockLocationServiceMockRecorder
+}
+
+// MockLocationServiceMockRecorder is the mock recorder for MockLocationService.
+type MockLocationServiceMockRecorder struct {
+	mock *MockLocationService
+}
+
+// NewMockLocationService creates a new mock instance.
+func NewMockLocationService(ctrl *gomock.Controller) *MockLocationService {
+	mock := &MockLocationService{ctrl: ctrl}
+	mock.recorder = &MockLocationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLocationService) EXPECT() *MockLocationServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateLocation mocks base method.
+func (m *MockLocationService) CreateLocation(arg0 *locations.Location) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLocation", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateLocation indicates an expected call of CreateLocation.
+func (mr *MockLocationServiceMockRecorder) CreateLocation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLocation", reflect.TypeOf((*MockLocationService)(nil).CreateLocation), arg0)
+}
+
+// DeleteLocation mocks base method.
+func (m *MockLocationService) DeleteLocation(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLocation", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLocation indicates an expected call of DeleteLocation.
+func (mr *MockLocationServiceMockRecorder) DeleteLocation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLocation", reflect.TypeOf((*MockLocationService)(nil).DeleteLocation), arg0)
+}
+
+// GetLocation mocks base method.
+func (m *MockLocationService) GetLocation(arg0 string) (*locations.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLocation", arg0)
+	ret0, _ := ret[0].(*locations.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLocation indicates an expected call of GetLocation.
+func (mr *MockLocationServiceMockRecorder) GetLocation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLocation", reflect.TypeOf((*MockLocationService)(nil).GetLocation), arg0)
+}
+
+// ListLocations mocks base method.
+func (m *MockLocationService) ListLocations() ([]*locations.Location, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLocations")
+	ret0, _ := ret[0].([]*locations.Location)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLocations indicates an expected call of ListLocations.
+func (mr *MockLocationServiceMockRecorder) ListLocations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLocations", reflect.TypeOf((*MockLocationService)(nil).ListLocations))
+}
+
+// UpdateLocation mocks base method.
+func (m *MockLocationService) UpdateLocation(arg0 *locations.Location) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateLocation", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateLocation indicates an expected call of UpdateLocation.
+func (mr *MockLocationServiceMockRecorder) UpdateLocation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateLocation", reflect.TypeOf((*MockLocationService)(nil).UpdateLocation), arg0)
+}
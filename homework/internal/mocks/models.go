@@ -0,0 +1,202 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/app (interfaces: ModelRepository,ModelService)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	catalog "homework/internal/catalog"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockModelRepository is a mock of ModelRepository interface.
+type MockModelRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockModelRepositoryMockRecorder
+}
+
+// MockModelRepositoryMockRecorder is the mock recorder for MockModelRepository.
+type MockModelRepositoryMockRecorder struct {
+	mock *MockModelRepository
+}
+
+// NewMockModelRepository creates a new mock instance.
+func NewMockModelRepository(ctrl *gomock.Controller) *MockModelRepository {
+	mock := &MockModelRepository{ctrl: ctrl}
+	mock.recorder = &MockModelRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModelRepository) EXPECT() *MockModelRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockModelRepository) Create(arg0 *catalog.Model) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockModelRepositoryMockRecorder) Create(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockModelRepository)(nil).Create), arg0)
+}
+
+// Delete mocks base method.
+func (m *MockModelRepository) Delete(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockModelRepositoryMockRecorder) Delete(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockModelRepository)(nil).Delete), arg0)
+}
+
+// Get mocks base method.
+func (m *MockModelRepository) Get(arg0 string) (*catalog.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0)
+	ret0, _ := ret[0].(*catalog.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockModelRepositoryMockRecorder) Get(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockModelRepository)(nil).Get), arg0)
+}
+
+// List mocks base method.
+func (m *MockModelRepository) List() ([]*catalog.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*catalog.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockModelRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockModelRepository)(nil).List))
+}
+
+// Update mocks base method.
+func (m *MockModelRepository) Update(arg0 *catalog.Model) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockModelRepositoryMockRecorder) Update(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockModelRepository)(nil).Update), arg0)
+}
+
+// MockModelService is a mock of ModelService interface.
+type MockModelService struct {
+	ctrl     *gomock.Controller
+	recorder *MockModelServiceMockRecorder
+}
+
+// MockModelServiceMockRecorder is the mock recorder for MockModelService.
+type MockModelServiceMockRecorder struct {
+	mock *MockModelService
+}
+
+// NewMockModelService creates a new mock instance.
+func NewMockModelService(ctrl *gomock.Controller) *MockModelService {
+	mock := &MockModelService{ctrl: ctrl}
+	mock.recorder = &MockModelServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockModelService) EXPECT() *MockModelServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateModel mocks base method.
+func (m *MockModelService) CreateModel(arg0 *catalog.Model) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateModel indicates an expected call of CreateModel.
+func (mr *MockModelServiceMockRecorder) CreateModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateModel", reflect.TypeOf((*MockModelService)(nil).CreateModel), arg0)
+}
+
+// DeleteModel mocks base method.
+func (m *MockModelService) DeleteModel(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteModel indicates an expected call of DeleteModel.
+func (mr *MockModelServiceMockRecorder) DeleteModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteModel", reflect.TypeOf((*MockModelService)(nil).DeleteModel), arg0)
+}
+
+// GetModel mocks base method.
+func (m *MockModelService) GetModel(arg0 string) (*catalog.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetModel", arg0)
+	ret0, _ := ret[0].(*catalog.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetModel indicates an expected call of GetModel.
+func (mr *MockModelServiceMockRecorder) GetModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModel", reflect.TypeOf((*MockModelService)(nil).GetModel), arg0)
+}
+
+// ListModels mocks base method.
+func (m *MockModelService) ListModels() ([]*catalog.Model, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListModels")
+	ret0, _ := ret[0].([]*catalog.Model)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListModels indicates an expected call of ListModels.
+func (mr *MockModelServiceMockRecorder) ListModels() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListModels", reflect.TypeOf((*MockModelService)(nil).ListModels))
+}
+
+// UpdateModel mocks base method.
+func (m *MockModelService) UpdateModel(arg0 *catalog.Model) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateModel", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateModel indicates an expected call of UpdateModel.
+func (mr *MockModelServiceMockRecorder) UpdateModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateModel", reflect.TypeOf((*MockModelService)(nil).UpdateModel), arg0)
+}
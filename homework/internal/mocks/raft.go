@@ -0,0 +1,90 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/raft (interfaces: Cluster)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	context "context"
+	raft "homework/internal/raft"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockCluster is a mock of Cluster interface.
+type MockCluster struct {
+	ctrl     *gomock.Controller
+	recorder *MockClusterMockRecorder
+}
+
+// MockClusterMockRecorder is the mock recorder for MockCluster.
+type MockClusterMockRecorder struct {
+	mock *MockCluster
+}
+
+// NewMockCluster creates a new mock instance.
+func NewMockCluster(ctrl *gomock.Controller) *MockCluster {
+	mock := &MockCluster{ctrl: ctrl}
+	mock.recorder = &MockClusterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCluster) EXPECT() *MockClusterMockRecorder {
+	return m.recorder
+}
+
+// AddMember mocks base method.
+func (m *MockCluster) AddMember(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddMember", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddMember indicates an expected call of AddMember.
+func (mr *MockClusterMockRecorder) AddMember(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMember", reflect.TypeOf((*MockCluster)(nil).AddMember), arg0, arg1, arg2)
+}
+
+// RemoveMember mocks base method.
+func (m *MockCluster) RemoveMember(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveMember", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveMember indicates an expected call of RemoveMember.
+func (mr *MockClusterMockRecorder) RemoveMember(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMember", reflect.TypeOf((*MockCluster)(nil).RemoveMember), arg0, arg1)
+}
+
+// Status mocks base method.
+func (m *MockCluster) Status() raft.Status {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status")
+	ret0, _ := ret[0].(raft.Status)
+	return ret0
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockClusterMockRecorder) Status() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockCluster)(nil).Status))
+}
+
+// Step mocks base method.
+func (m *MockCluster) Step(arg0 raft.Message) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Step", arg0)
+}
+
+// Step indicates an expected call of Step.
+func (mr *MockClusterMockRecorder) Step(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Step", reflect.TypeOf((*MockCluster)(nil).Step), arg0)
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/app (interfaces: ReachabilityService)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	reachability "homework/internal/reachability"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockReachabilityService is a mock of ReachabilityService interface.
+type MockReachabilityService struct {
+	ctrl     *gomock.Controller
+	recorder *MockReachabilityServiceMockRecorder
+}
+
+// MockReachabilityServiceMockRecorder is the mock recorder for MockReachabilityService.
+type MockReachabilityServiceMockRecorder struct {
+	mock *MockReachabilityService
+}
+
+// NewMockReachabilityService creates a new mock instance.
+func NewMockReachabilityService(ctrl *gomock.Controller) *MockReachabilityService {
+	mock := &MockReachabilityService{ctrl: ctrl}
+	mock.recorder = &MockReachabilityServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReachabilityService) EXPECT() *MockReachabilityServiceMockRecorder {
+	return m.recorder
+}
+
+// Report mocks base method.
+func (m *MockReachabilityService) Report(arg0 string) (*reachability.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Report", arg0)
+	ret0, _ := ret[0].(*reachability.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Report indicates an expected call of Report.
+func (mr *MockReachabilityServiceMockRecorder) Report(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Report", reflect.TypeOf((*MockReachabilityService)(nil).Report), arg0)
+}
@@ -0,0 +1,103 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/replication (interfaces: Source,Replica)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	context "context"
+	replication "homework/internal/replication"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSource is a mock of Source interface.
+type MockSource struct {
+	ctrl     *gomock.Controller
+	recorder *MockSourceMockRecorder
+}
+
+// MockSourceMockRecorder is the mock recorder for MockSource.
+type MockSourceMockRecorder struct {
+	mock *MockSource
+}
+
+// NewMockSource creates a new mock instance.
+func NewMockSource(ctrl *gomock.Controller) *MockSource {
+	mock := &MockSource{ctrl: ctrl}
+	mock.recorder = &MockSourceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSource) EXPECT() *MockSourceMockRecorder {
+	return m.recorder
+}
+
+// Changes mocks base method.
+func (m *MockSource) Changes(arg0 context.Context, arg1 string, arg2 uint64, arg3 int) (*replication.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Changes", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*replication.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Changes indicates an expected call of Changes.
+func (mr *MockSourceMockRecorder) Changes(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Changes", reflect.TypeOf((*MockSource)(nil).Changes), arg0, arg1, arg2, arg3)
+}
+
+// Checkpoint mocks base method.
+func (m *MockSource) Checkpoint() (*replication.Checkpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Checkpoint")
+	ret0, _ := ret[0].(*replication.Checkpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Checkpoint indicates an expected call of Checkpoint.
+func (mr *MockSourceMockRecorder) Checkpoint() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Checkpoint", reflect.TypeOf((*MockSource)(nil).Checkpoint))
+}
+
+// MockReplica is a mock of Replica interface.
+type MockReplica struct {
+	ctrl     *gomock.Controller
+	recorder *MockReplicaMockRecorder
+}
+
+// MockReplicaMockRecorder is the mock recorder for MockReplica.
+type MockReplicaMockRecorder struct {
+	mock *MockReplica
+}
+
+// NewMockReplica creates a new mock instance.
+func NewMockReplica(ctrl *gomock.Controller) *MockReplica {
+	mock := &MockReplica{ctrl: ctrl}
+	mock.recorder = &MockReplicaMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReplica) EXPECT() *MockReplicaMockRecorder {
+	return m.recorder
+}
+
+// Status mocks base method.
+func (m *MockReplica) Status() replication.Status {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Status")
+	ret0, _ := ret[0].(replication.Status)
+	return ret0
+}
+
+// Status indicates an expected call of Status.
+func (mr *MockReplicaMockRecorder) Status() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockReplica)(nil).Status))
+}
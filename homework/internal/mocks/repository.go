@@ -5,35 +5,36 @@
 package internal
 
 import (
-	gomock "github.com/golang/mock/gomock"
 	devices "homework/internal/devices"
 	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
 )
 
-// MockRepository is a mock of Repository interface
+// MockRepository is a mock of Repository interface.
 type MockRepository struct {
 	ctrl     *gomock.Controller
 	recorder *MockRepositoryMockRecorder
 }
 
-// MockRepositoryMockRecorder is the mock recorder for MockRepository
+// MockRepositoryMockRecorder is the mock recorder for MockRepository.
 type MockRepositoryMockRecorder struct {
 	mock *MockRepository
 }
 
-// NewMockRepository creates a new mock instance
+// NewMockRepository creates a new mock instance.
 func NewMockRepository(ctrl *gomock.Controller) *MockRepository {
 	mock := &MockRepository{ctrl: ctrl}
 	mock.recorder = &MockRepositoryMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
-// Create mocks base method
+// Create mocks base method.
 func (m *MockRepository) Create(arg0 *devices.Device) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Create", arg0)
@@ -41,13 +42,13 @@ func (m *MockRepository) Create(arg0 *devices.Device) error {
 	return ret0
 }
 
-// Create indicates an expected call of Create
+// Create indicates an expected call of Create.
 func (mr *MockRepositoryMockRecorder) Create(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockRepository)(nil).Create), arg0)
 }
 
-// Delete mocks base method
+// Delete mocks base method.
 func (m *MockRepository) Delete(arg0 string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Delete", arg0)
@@ -55,13 +56,13 @@ func (m *MockRepository) Delete(arg0 string) error {
 	return ret0
 }
 
-// Delete indicates an expected call of Delete
+// Delete indicates an expected call of Delete.
 func (mr *MockRepositoryMockRecorder) Delete(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), arg0)
 }
 
-// Get mocks base method
+// Get mocks base method.
 func (m *MockRepository) Get(arg0 string) (*devices.Device, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", arg0)
@@ -70,13 +71,58 @@ func (m *MockRepository) Get(arg0 string) (*devices.Device, error) {
 	return ret0, ret1
 }
 
-// Get indicates an expected call of Get
+// Get indicates an expected call of Get.
 func (mr *MockRepositoryMockRecorder) Get(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRepository)(nil).Get), arg0)
 }
 
-// Update mocks base method
+// List mocks base method.
+func (m *MockRepository) List() ([]*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockRepositoryMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRepository)(nil).List))
+}
+
+// ListByIP mocks base method.
+func (m *MockRepository) ListByIP(arg0 string) ([]*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByIP", arg0)
+	ret0, _ := ret[0].([]*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByIP indicates an expected call of ListByIP.
+func (mr *MockRepositoryMockRecorder) ListByIP(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByIP", reflect.TypeOf((*MockRepository)(nil).ListByIP), arg0)
+}
+
+// ListByModel mocks base method.
+func (m *MockRepository) ListByModel(arg0 string) ([]*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByModel", arg0)
+	ret0, _ := ret[0].([]*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByModel indicates an expected call of ListByModel.
+func (mr *MockRepositoryMockRecorder) ListByModel(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByModel", reflect.TypeOf((*MockRepository)(nil).ListByModel), arg0)
+}
+
+// Update mocks base method.
 func (m *MockRepository) Update(arg0 *devices.Device) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Update", arg0)
@@ -84,7 +130,7 @@ func (m *MockRepository) Update(arg0 *devices.Device) error {
 	return ret0
 }
 
-// Update indicates an expected call of Update
+// Update indicates an expected call of Update.
 func (mr *MockRepositoryMockRecorder) Update(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockRepository)(nil).Update), arg0)
@@ -5,35 +5,36 @@
 package internal
 
 import (
-	gomock "github.com/golang/mock/gomock"
 	devices "homework/internal/devices"
 	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
 )
 
-// MockService is a mock of Service interface
+// MockService is a mock of Service interface.
 type MockService struct {
 	ctrl     *gomock.Controller
 	recorder *MockServiceMockRecorder
 }
 
-// MockServiceMockRecorder is the mock recorder for MockService
+// MockServiceMockRecorder is the mock recorder for MockService.
 type MockServiceMockRecorder struct {
 	mock *MockService
 }
 
-// NewMockService creates a new mock instance
+// NewMockService creates a new mock instance.
 func NewMockService(ctrl *gomock.Controller) *MockService {
 	mock := &MockService{ctrl: ctrl}
 	mock.recorder = &MockServiceMockRecorder{mock}
 	return mock
 }
 
-// EXPECT returns an object that allows the caller to indicate expected use
+// EXPECT returns an object that allows the caller to indicate expected use.
 func (m *MockService) EXPECT() *MockServiceMockRecorder {
 	return m.recorder
 }
 
-// CreateDevice mocks base method
+// CreateDevice mocks base method.
 func (m *MockService) CreateDevice(arg0 *devices.Device) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateDevice", arg0)
@@ -41,13 +42,13 @@ func (m *MockService) CreateDevice(arg0 *devices.Device) error {
 	return ret0
 }
 
-// CreateDevice indicates an expected call of CreateDevice
+// CreateDevice indicates an expected call of CreateDevice.
 func (mr *MockServiceMockRecorder) CreateDevice(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDevice", reflect.TypeOf((*MockService)(nil).CreateDevice), arg0)
 }
 
-// DeleteDevice mocks base method
+// DeleteDevice mocks base method.
 func (m *MockService) DeleteDevice(arg0 string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "DeleteDevice", arg0)
@@ -55,13 +56,13 @@ func (m *MockService) DeleteDevice(arg0 string) error {
 	return ret0
 }
 
-// DeleteDevice indicates an expected call of DeleteDevice
+// DeleteDevice indicates an expected call of DeleteDevice.
 func (mr *MockServiceMockRecorder) DeleteDevice(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDevice", reflect.TypeOf((*MockService)(nil).DeleteDevice), arg0)
 }
 
-// GetDevice mocks base method
+// GetDevice mocks base method.
 func (m *MockService) GetDevice(arg0 string) (*devices.Device, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetDevice", arg0)
@@ -70,13 +71,73 @@ func (m *MockService) GetDevice(arg0 string) (*devices.Device, error) {
 	return ret0, ret1
 }
 
-// GetDevice indicates an expected call of GetDevice
+// GetDevice indicates an expected call of GetDevice.
 func (mr *MockServiceMockRecorder) GetDevice(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDevice", reflect.TypeOf((*MockService)(nil).GetDevice), arg0)
 }
 
-// UpdateDevice mocks base method
+// ListDevices mocks base method.
+func (m *MockService) ListDevices(arg0 devices.Filter) ([]*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDevices", arg0)
+	ret0, _ := ret[0].([]*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDevices indicates an expected call of ListDevices.
+func (mr *MockServiceMockRecorder) ListDevices(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDevices", reflect.TypeOf((*MockService)(nil).ListDevices), arg0)
+}
+
+// ListTransitions mocks base method.
+func (m *MockService) ListTransitions(arg0 string) ([]devices.Transition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransitions", arg0)
+	ret0, _ := ret[0].([]devices.Transition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransitions indicates an expected call of ListTransitions.
+func (mr *MockServiceMockRecorder) ListTransitions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransitions", reflect.TypeOf((*MockService)(nil).ListTransitions), arg0)
+}
+
+// SearchDevices mocks base method.
+func (m *MockService) SearchDevices(arg0 string, arg1 int) ([]devices.SearchHit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchDevices", arg0, arg1)
+	ret0, _ := ret[0].([]devices.SearchHit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchDevices indicates an expected call of SearchDevices.
+func (mr *MockServiceMockRecorder) SearchDevices(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchDevices", reflect.TypeOf((*MockService)(nil).SearchDevices), arg0, arg1)
+}
+
+// TransitionDevice mocks base method.
+func (m *MockService) TransitionDevice(arg0 string, arg1 devices.Status, arg2 string) (*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransitionDevice", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransitionDevice indicates an expected call of TransitionDevice.
+func (mr *MockServiceMockRecorder) TransitionDevice(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransitionDevice", reflect.TypeOf((*MockService)(nil).TransitionDevice), arg0, arg1, arg2)
+}
+
+// UpdateDevice mocks base method.
 func (m *MockService) UpdateDevice(arg0 *devices.Device) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateDevice", arg0)
@@ -84,7 +145,7 @@ func (m *MockService) UpdateDevice(arg0 *devices.Device) error {
 	return ret0
 }
 
-// UpdateDevice indicates an expected call of UpdateDevice
+// UpdateDevice indicates an expected call of UpdateDevice.
 func (mr *MockServiceMockRecorder) UpdateDevice(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDevice", reflect.TypeOf((*MockService)(nil).UpdateDevice), arg0)
@@ -0,0 +1,133 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: homework/internal/app (interfaces: VersionRepository,VersionService)
+
+// Package internal is a generated GoMock package.
+package internal
+
+import (
+	devices "homework/internal/devices"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockVersionRepository is a mock of VersionRepository interface.
+type MockVersionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockVersionRepositoryMockRecorder
+}
+
+// MockVersionRepositoryMockRecorder is the mock recorder for MockVersionRepository.
+type MockVersionRepositoryMockRecorder struct {
+	mock *MockVersionRepository
+}
+
+// NewMockVersionRepository creates a new mock instance.
+func NewMockVersionRepository(ctrl *gomock.Controller) *MockVersionRepository {
+	mock := &MockVersionRepository{ctrl: ctrl}
+	mock.recorder = &MockVersionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVersionRepository) EXPECT() *MockVersionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Append mocks base method.
+func (m *MockVersionRepository) Append(arg0 devices.Version) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Append", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Append indicates an expected call of Append.
+func (mr *MockVersionRepositoryMockRecorder) Append(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Append", reflect.TypeOf((*MockVersionRepository)(nil).Append), arg0)
+}
+
+// List mocks base method.
+func (m *MockVersionRepository) List(arg0 string) ([]devices.Version, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0)
+	ret0, _ := ret[0].([]devices.Version)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockVersionRepositoryMockRecorder) List(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockVersionRepository)(nil).List), arg0)
+}
+
+// MockVersionService is a mock of VersionService interface.
+type MockVersionService struct {
+	ctrl     *gomock.Controller
+	recorder *MockVersionServiceMockRecorder
+}
+
+// MockVersionServiceMockRecorder is the mock recorder for MockVersionService.
+type MockVersionServiceMockRecorder struct {
+	mock *MockVersionService
+}
+
+// NewMockVersionService creates a new mock instance.
+func NewMockVersionService(ctrl *gomock.Controller) *MockVersionService {
+	mock := &MockVersionService{ctrl: ctrl}
+	mock.recorder = &MockVersionServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVersionService) EXPECT() *MockVersionServiceMockRecorder {
+	return m.recorder
+}
+
+// DiffVersions mocks base method.
+func (m *MockVersionService) DiffVersions(arg0 string, arg1, arg2 int) ([]devices.Change, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DiffVersions", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]devices.Change)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DiffVersions indicates an expected call of DiffVersions.
+func (mr *MockVersionServiceMockRecorder) DiffVersions(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DiffVersions", reflect.TypeOf((*MockVersionService)(nil).DiffVersions), arg0, arg1, arg2)
+}
+
+// GetDeviceAsOf mocks base method.
+func (m *MockVersionService) GetDeviceAsOf(arg0 string, arg1 time.Time) (*devices.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeviceAsOf", arg0, arg1)
+	ret0, _ := ret[0].(*devices.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeviceAsOf indicates an expected call of GetDeviceAsOf.
+func (mr *MockVersionServiceMockRecorder) GetDeviceAsOf(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeviceAsOf", reflect.TypeOf((*MockVersionService)(nil).GetDeviceAsOf), arg0, arg1)
+}
+
+// ListVersions mocks base method.
+func (m *MockVersionService) ListVersions(arg0 string) ([]devices.Version, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListVersions", arg0)
+	ret0, _ := ret[0].([]devices.Version)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListVersions indicates an expected call of ListVersions.
+func (mr *MockVersionServiceMockRecorder) ListVersions(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListVersions", reflect.TypeOf((*MockVersionService)(nil).ListVersions), arg0)
+}
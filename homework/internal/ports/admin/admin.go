@@ -0,0 +1,220 @@
+// Package admin serves diagnostics of the running process on a listener
+// apart from the device api: pprof profiles, goroutine dumps, runtime and
+// build information, the effective config and the log level. The device
+// api routes that must not be public, such as backups, are served here
+// too.
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"homework/internal/config"
+	"homework/internal/logger"
+)
+
+const readHeaderTimeout = 10 * time.Second
+
+type Config struct {
+	Host string
+	Port string
+	// Log is the logger whose level is served, changes last until the next
+	// config reload of log.level.
+	Log *logger.Logger
+	// Current returns the config the process runs with.
+	Current func() *config.Config
+	// API serves the admin routes of the device api, nil serves none.
+	API http.Handler
+}
+
+type Handler struct {
+	log     *logger.Logger
+	current func() *config.Config
+	api     http.Handler
+	started time.Time
+}
+
+func NewHandler(cfg *Config) *Handler {
+	return &Handler{log: cfg.Log, current: cfg.Current, api: cfg.API, started: time.Now()}
+}
+
+// NewServer has no write timeout, cpu profiles and traces stream for as
+// long as the caller asks.
+func NewServer(cfg *Config) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
+		Handler:           NewHandler(cfg).Routes(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+}
+
+func (h *Handler) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/debug/pprof/cmdline", pprof.Cmdline)
+	r.Get("/debug/pprof/profile", pprof.Profile)
+	r.Get("/debug/pprof/symbol", pprof.Symbol)
+	r.Post("/debug/pprof/symbol", pprof.Symbol)
+	r.Get("/debug/pprof/trace", pprof.Trace)
+	r.Get("/debug/pprof/*", pprof.Index)
+
+	r.Get("/debug/goroutines", h.dumpGoroutines)
+	r.Get("/debug/runtime", h.getRuntime)
+	r.Get("/buildinfo", h.getBuildInfo)
+	r.Get("/config", h.getConfig)
+	r.Get("/log/level", h.getLogLevel)
+	r.Put("/log/level", h.setLogLevel)
+
+	if h.api != nil {
+		r.Mount("/", h.api)
+	}
+
+	return r
+}
+
+// dumpGoroutines writes the stack of every goroutine as text.
+func (h *Handler) dumpGoroutines(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	_ = rpprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+type Runtime struct {
+	GoVersion  string    `json:"go_version"`
+	StartedAt  time.Time `json:"started_at"`
+	Uptime     string    `json:"uptime"`
+	Goroutines int       `json:"goroutines"`
+	GOMAXPROCS int       `json:"gomaxprocs"`
+	CPUs       int       `json:"cpus"`
+	HeapAlloc  uint64    `json:"heap_alloc_bytes"`
+	HeapInuse  uint64    `json:"heap_inuse_bytes"`
+	HeapObjs   uint64    `json:"heap_objects"`
+	Sys        uint64    `json:"sys_bytes"`
+	NumGC      uint32    `json:"num_gc"`
+	PauseTotal string    `json:"gc_pause_total"`
+}
+
+func (h *Handler) getRuntime(w http.ResponseWriter, _ *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeJSON(w, http.StatusOK, Runtime{
+		GoVersion:  runtime.Version(),
+		StartedAt:  h.started,
+		Uptime:     time.Since(h.started).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		CPUs:       runtime.NumCPU(),
+		HeapAlloc:  mem.HeapAlloc,
+		HeapInuse:  mem.HeapInuse,
+		HeapObjs:   mem.HeapObjects,
+		Sys:        mem.Sys,
+		NumGC:      mem.NumGC,
+		PauseTotal: time.Duration(mem.PauseTotalNs).String(),
+	})
+}
+
+type BuildInfo struct {
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings,omitempty"`
+	Deps      map[string]string `json:"deps,omitempty"`
+}
+
+func (h *Handler) getBuildInfo(w http.ResponseWriter, _ *http.Request) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		writeError(w, "build info is not available", http.StatusNotFound)
+		return
+	}
+
+	build := BuildInfo{
+		GoVersion: info.GoVersion,
+		Path:      info.Main.Path,
+		Version:   info.Main.Version,
+		Settings:  make(map[string]string, len(info.Settings)),
+		Deps:      make(map[string]string, len(info.Deps)),
+	}
+	for _, setting := range info.Settings {
+		build.Settings[setting.Key] = setting.Value
+	}
+	for _, dep := range info.Deps {
+		build.Deps[dep.Path] = dep.Version
+	}
+
+	writeJSON(w, http.StatusOK, build)
+}
+
+// getConfig lists the effective config, secrets are redacted.
+func (h *Handler) getConfig(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, config.Values(h.current()))
+}
+
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+func (h *Handler) getLogLevel(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, LogLevel{Level: h.log.Level().String()})
+}
+
+func (h *Handler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, "can not read request body", http.StatusBadRequest)
+		return
+	}
+
+	var request LogLevel
+	if err = json.Unmarshal(buf, &request); err != nil {
+		writeError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return
+	}
+
+	level, err := logger.ParseLevel(request.Level)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	old := h.log.Level()
+	h.log.SetLevel(level)
+	h.log.Warnf("log level changed from %s to %s by the admin api", old, level)
+
+	writeJSON(w, http.StatusOK, LogLevel{Level: level.String()})
+}
+
+type errorBody struct {
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, value interface{}) {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		writeError(w, "can not marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(buf)
+}
+
+func writeError(w http.ResponseWriter, msg string, code int) {
+	buf, _ := json.Marshal(errorBody{Message: msg})
+
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	_, _ = w.Write(buf)
+}
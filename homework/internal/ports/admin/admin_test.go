@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/config"
+	"homework/internal/logger"
+)
+
+func TestHandlerLogLevel(t *testing.T) {
+	log := logger.New(io.Discard, logger.LevelInfo)
+	router := NewHandler(&Config{Log: log}).Routes()
+
+	cases := []struct {
+		method string
+		body   string
+		code   int
+		level  string
+	}{
+		{method: http.MethodGet, code: http.StatusOK, level: "info"},
+		{method: http.MethodPut, body: `{"level":"debug"}`, code: http.StatusOK, level: "debug"},
+		{method: http.MethodPut, body: `{"level":"verbose"}`, code: http.StatusBadRequest},
+		{method: http.MethodPut, body: `{`, code: http.StatusBadRequest},
+		{method: http.MethodGet, code: http.StatusOK, level: "debug"},
+	}
+
+	for _, tCase := range cases {
+		r := httptest.NewRequest(tCase.method, "/log/level", bytes.NewBufferString(tCase.body))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+		require.Equal(t, tCase.code, w.Code, tCase.method+" "+tCase.body)
+
+		if tCase.code == http.StatusOK {
+			var actual LogLevel
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			require.Equal(t, tCase.level, actual.Level)
+		}
+	}
+	require.Equal(t, logger.LevelDebug, log.Level())
+}
+
+func TestHandlerConfigRedacted(t *testing.T) {
+	cfg := config.Default()
+	cfg.Tenancy.APIKeys = []string{"s3cr3t=ops"}
+
+	router := NewHandler(&Config{Current: func() *config.Config { return cfg }}).Routes()
+
+	r := httptest.NewRequest(http.MethodGet, "/config", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotContains(t, w.Body.String(), "s3cr3t")
+
+	var values []config.Value
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&values))
+	require.NotEmpty(t, values)
+}
+
+func TestHandlerDiagnostics(t *testing.T) {
+	router := NewHandler(&Config{}).Routes()
+
+	for _, tCase := range []struct {
+		url      string
+		contains string
+	}{
+		{url: "/debug/goroutines", contains: "goroutine "},
+		{url: "/debug/runtime", contains: `"go_version"`},
+		{url: "/debug/pprof/", contains: "goroutine"},
+		{url: "/debug/pprof/heap?debug=1", contains: "heap profile"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, tCase.url, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code, tCase.url)
+		require.Contains(t, w.Body.String(), tCase.contains, tCase.url)
+	}
+}
+
+func TestHandlerAPI(t *testing.T) {
+	api := http.NewServeMux()
+	api.HandleFunc("/admin/backup", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("archive"))
+	})
+	router := NewHandler(&Config{API: api}).Routes()
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/backup", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "archive", w.Body.String())
+
+	// The diagnostics still take precedence.
+	r = httptest.NewRequest(http.MethodGet, "/debug/runtime", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"go_version"`)
+}
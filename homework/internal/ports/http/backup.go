@@ -0,0 +1,45 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"homework/internal/backup"
+)
+
+// backupDevices answers with a backup archive. The archive is built before
+// anything is written, so a failed backup still gets an error status.
+func (h *Handler) backupDevices(w http.ResponseWriter, _ *http.Request) {
+	var buf bytes.Buffer
+	if err := h.backup.Backup(&buf); err != nil {
+		w.Header().Set("content-type", "application/json")
+		h.processError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", "application/gzip")
+	w.Header().Set("content-disposition",
+		fmt.Sprintf("attachment; filename=devices-%s.backup", time.Now().UTC().Format("20060102T150405Z")))
+	w.WriteHeader(http.StatusOK)
+	_, _ = buf.WriteTo(w)
+}
+
+func (h *Handler) restoreDevices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	mode, err := backup.ParseMode(r.URL.Query().Get("mode"))
+	if err != nil {
+		h.processError(w, "'mode' must be replace or merge", http.StatusBadRequest)
+		return
+	}
+
+	header, err := h.backup.Restore(r.Body, mode)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, header)
+}
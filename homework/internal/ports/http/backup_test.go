@@ -0,0 +1,101 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/backup"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerBackup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	backupService := deviceMock.NewMockBackupService(ctrl)
+
+	backupService.EXPECT().Backup(gomock.Any()).DoAndReturn(func(w io.Writer) error {
+		_, err := w.Write([]byte("archive"))
+		return err
+	}).Times(1)
+
+	handler := &Handler{
+		backup: backupService,
+	}
+	router := chi.NewRouter()
+	router.Get("/admin/backup", handler.backupDevices)
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/backup", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "application/gzip", w.Header().Get("content-type"))
+	require.Equal(t, "archive", w.Body.String())
+}
+
+func TestHandlerRestore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	backupService := deviceMock.NewMockBackupService(ctrl)
+
+	expect := &backup.Header{Format: backup.Format, Version: backup.Version, Devices: 2}
+	backupService.EXPECT().Restore(gomock.Any(), backup.Merge).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		backup: backupService,
+	}
+	router := chi.NewRouter()
+	router.Post("/admin/restore", handler.restoreDevices)
+
+	r := httptest.NewRequest(http.MethodPost, "/admin/restore?mode=merge", bytes.NewReader([]byte("archive")))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var actual backup.Header
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+	require.Equal(t, *expect, actual)
+
+	r = httptest.NewRequest(http.MethodPost, "/admin/restore", bytes.NewReader([]byte("archive")))
+	w = httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandlerBackupAdminRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	backupService := deviceMock.NewMockBackupService(ctrl)
+
+	backupService.EXPECT().Backup(gomock.Any()).Return(nil).Times(1)
+
+	handler := NewHandler(&Config{
+		Tenants: map[string]*Config{"ops": {Backup: backupService}},
+		APIKeys: map[string]string{"key": "ops"},
+	})
+
+	// Backups are not served to api clients, even with a valid key.
+	r := httptest.NewRequest(http.MethodGet, "/tenants/ops/admin/backup", nil)
+	r.Header.Set(apiKeyHeader, "key")
+	w := httptest.NewRecorder()
+	handler.NewServer().Handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/tenants/ops/admin/backup", nil)
+	w = httptest.NewRecorder()
+	handler.AdminRoutes().ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+}
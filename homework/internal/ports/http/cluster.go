@@ -0,0 +1,121 @@
+package http
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"homework/internal/raft"
+)
+
+type Member struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+}
+
+// stepMessage hands a message of another node to the raft node, the
+// sender does not wait for the outcome. Only nodes that know the cluster
+// secret are heard.
+func (h *Handler) stepMessage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	secret := r.Header.Get(raft.SecretHeader)
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(h.secret)) != 1 {
+		h.processError(w, "invalid cluster secret", http.StatusUnauthorized)
+		return
+	}
+
+	var msg raft.Message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return
+	}
+
+	h.cluster.Step(msg)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) getClusterStatus(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	h.writeJSON(w, h.cluster.Status())
+}
+
+func (h *Handler) addMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.processError(w, "can not read request body", http.StatusBadRequest)
+		return
+	}
+
+	var member Member
+	if err = json.Unmarshal(buf, &member); err != nil {
+		h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return
+	}
+
+	if member.ID == "" || member.Address == "" {
+		h.processError(w, "'id' and 'address' are required", http.StatusBadRequest)
+		return
+	}
+
+	if err = h.cluster.AddMember(r.Context(), member.ID, member.Address); err != nil {
+		h.processClusterError(w, err)
+		return
+	}
+
+	h.writeJSON(w, h.cluster.Status())
+}
+
+func (h *Handler) removeMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	if err := h.cluster.RemoveMember(r.Context(), chi.URLParam(r, "id")); err != nil {
+		h.processClusterError(w, err)
+		return
+	}
+
+	h.writeJSON(w, h.cluster.Status())
+}
+
+// processClusterError answers 503 while the node can not change the
+// cluster, the request may succeed on the leader or later.
+func (h *Handler) processClusterError(w http.ResponseWriter, err error) {
+	var notLeader *raft.NotLeaderError
+	if stderrors.As(err, &notLeader) || stderrors.Is(err, raft.ErrConfigChangePending) {
+		h.processError(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	h.processError(w, err.Error(), http.StatusBadRequest)
+}
+
+// redirectToLeader sends every request that may write to the current raft
+// leader. Raft messages and requests to a node that knows no leader are
+// served locally.
+func (h *Handler) redirectToLeader(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		status := h.cluster.Status()
+		address := status.Members[status.Leader]
+		if r.URL.Path == raft.MessagePath || status.State == raft.Leader || address == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		http.Redirect(w, r, strings.TrimSuffix(address, "/")+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+	})
+}
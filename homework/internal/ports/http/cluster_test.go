@@ -0,0 +1,112 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	deviceMock "homework/internal/mocks"
+	"homework/internal/raft"
+)
+
+func TestHandlerClusterMembers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cluster := deviceMock.NewMockCluster(ctrl)
+
+	status := raft.Status{ID: "a", State: raft.Leader, Leader: "a", Members: map[string]string{
+		"a": "http://a:8080",
+		"b": "http://b:8080",
+	}}
+	cluster.EXPECT().Status().Return(status).AnyTimes()
+	cluster.EXPECT().AddMember(gomock.Any(), "b", "http://b:8080").Return(nil).Times(1)
+	cluster.EXPECT().AddMember(gomock.Any(), "a", "http://a:8080").Return(fmt.Errorf("raft: a is already a member")).Times(1)
+	cluster.EXPECT().RemoveMember(gomock.Any(), "c").Return(raft.ErrConfigChangePending).Times(1)
+	cluster.EXPECT().RemoveMember(gomock.Any(), "d").Return(&raft.NotLeaderError{}).Times(1)
+	cluster.EXPECT().Step(raft.Message{Type: raft.MsgApp, From: "b", To: "a", Term: 2}).Times(1)
+
+	handler := NewHandler(&Config{Cluster: cluster, ClusterSecret: "s3cret"})
+	api := handler.NewServer().Handler
+	admin := handler.AdminRoutes()
+
+	cases := []struct {
+		router http.Handler
+		method string
+		url    string
+		body   string
+		secret string
+		code   int
+	}{
+		{router: admin, method: http.MethodGet, url: "/admin/cluster", code: http.StatusOK},
+		{router: admin, method: http.MethodPost, url: "/admin/cluster/members", body: `{"id":"b","address":"http://b:8080"}`, code: http.StatusOK},
+		{router: admin, method: http.MethodPost, url: "/admin/cluster/members", body: `{"id":"a","address":"http://a:8080"}`, code: http.StatusBadRequest},
+		{router: admin, method: http.MethodPost, url: "/admin/cluster/members", body: `{"id":"b"}`, code: http.StatusBadRequest},
+		{router: admin, method: http.MethodDelete, url: "/admin/cluster/members/c", code: http.StatusServiceUnavailable},
+		{router: admin, method: http.MethodDelete, url: "/admin/cluster/members/d", code: http.StatusServiceUnavailable},
+		// Membership is not served to api clients.
+		{router: api, method: http.MethodGet, url: "/admin/cluster", code: http.StatusNotFound},
+		{router: api, method: http.MethodPost, url: "/admin/cluster/members", body: `{"id":"e","address":"http://e:8080"}`, code: http.StatusNotFound},
+		{router: api, method: http.MethodPost, url: raft.MessagePath, body: `{"type":"app","from":"b","to":"a","term":2}`, secret: "s3cret", code: http.StatusAccepted},
+		{router: api, method: http.MethodPost, url: raft.MessagePath, body: `{`, secret: "s3cret", code: http.StatusBadRequest},
+		{router: api, method: http.MethodPost, url: raft.MessagePath, body: `{"type":"app","from":"x","to":"a","term":9}`, code: http.StatusUnauthorized},
+		{router: api, method: http.MethodPost, url: raft.MessagePath, body: `{"type":"app","from":"x","to":"a","term":9}`, secret: "guess", code: http.StatusUnauthorized},
+	}
+
+	for _, tCase := range cases {
+		r := httptest.NewRequest(tCase.method, tCase.url, bytes.NewBufferString(tCase.body))
+		if tCase.secret != "" {
+			r.Header.Set(raft.SecretHeader, tCase.secret)
+		}
+		w := httptest.NewRecorder()
+
+		tCase.router.ServeHTTP(w, r)
+		require.Equal(t, tCase.code, w.Code, tCase.method+" "+tCase.url+" "+tCase.body)
+
+		if tCase.code == http.StatusOK {
+			var actual raft.Status
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			require.Equal(t, status, actual)
+		}
+	}
+}
+
+func TestHandlerRedirectToLeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	cluster := deviceMock.NewMockCluster(ctrl)
+
+	cluster.EXPECT().Status().Return(raft.Status{ID: "b", State: raft.Follower, Leader: "a", Members: map[string]string{
+		"a": "http://a:8080/",
+		"b": "http://b:8080/",
+	}}).AnyTimes()
+
+	handler := &Handler{cluster: cluster}
+	router := chi.NewRouter()
+	router.Use(handler.redirectToLeader)
+	router.Get("/devices", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	router.Post("/devices", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	router.Post(raft.MessagePath, func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusAccepted) })
+
+	r := httptest.NewRequest(http.MethodGet, "/devices", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	r = httptest.NewRequest(http.MethodPost, "/devices?x=1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	require.Equal(t, "http://a:8080/devices?x=1", w.Header().Get("location"))
+
+	r = httptest.NewRequest(http.MethodPost, raft.MessagePath, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusAccepted, w.Code)
+}
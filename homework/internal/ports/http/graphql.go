@@ -0,0 +1,87 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"homework/internal/graphql"
+)
+
+const (
+	maxGraphQLBody = 1 << 20
+	// maxGraphQLQuery bounds the document, validation grows faster than
+	// linearly with documents spreading fragments in many places.
+	maxGraphQLQuery = 64 << 10
+)
+
+// postGraphQL runs queries and mutations sent as a JSON body.
+func (h *Handler) postGraphQL(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBody))
+	decoder.UseNumber()
+
+	var request graphql.Request
+	if err := decoder.Decode(&request); err != nil {
+		h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return
+	}
+
+	h.executeGraphQL(w, r, &request, h.limits)
+}
+
+// getGraphQL runs queries sent as url parameters, mutations must be
+// posted.
+func (h *Handler) getGraphQL(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	query := r.URL.Query()
+	request := graphql.Request{
+		Query:         query.Get("query"),
+		OperationName: query.Get("operationName"),
+	}
+
+	if variables := query.Get("variables"); variables != "" {
+		decoder := json.NewDecoder(strings.NewReader(variables))
+		decoder.UseNumber()
+		if err := decoder.Decode(&request.Variables); err != nil {
+			h.processError(w, "'variables' must be a JSON object", http.StatusBadRequest)
+			return
+		}
+	}
+
+	opts := h.limits
+	opts.QueriesOnly = true
+	h.executeGraphQL(w, r, &request, opts)
+}
+
+// executeGraphQL answers 400 to requests that failed before execution and
+// 200 otherwise, errors of single fields are part of the result then.
+func (h *Handler) executeGraphQL(w http.ResponseWriter, r *http.Request, request *graphql.Request, opts graphql.Options) {
+	if len(request.Query) > maxGraphQLQuery {
+		h.processError(w, "'query' is too long", http.StatusBadRequest)
+		return
+	}
+
+	result := h.schema.Execute(r.Context(), request, opts)
+
+	buf, err := json.Marshal(result)
+	if err != nil {
+		h.processError(w, "can not marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	if !result.Executed() {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	_, _ = w.Write(buf)
+}
+
+// getGraphQLSchema serves the schema in the schema definition language,
+// next to the __schema and __type introspection fields.
+func (h *Handler) getGraphQLSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+
+	_, _ = w.Write([]byte(h.schema.SDL()))
+}
@@ -0,0 +1,675 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	stderrors "errors"
+	"sort"
+	"time"
+
+	"homework/internal/catalog"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/graphql"
+	"homework/internal/locations"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// codedError sets the extensions.code of service errors returned by
+// resolvers, so clients can tell them apart without parsing messages.
+type codedError struct {
+	err  error
+	code string
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+
+func (e *codedError) Code() string { return e.code }
+
+func (e *codedError) Unwrap() error { return e.err }
+
+func graphQLError(err error) error {
+	var (
+		notFound   *errors.NotFoundError
+		exists     *errors.AlreadyExistDeviceError
+		conflict   *errors.ConflictError
+		validation *errors.ValidationError
+		illegal    *errors.IllegalTransitionError
+		quota      *errors.QuotaExceededError
+	)
+
+	switch {
+	case err == nil:
+		return nil
+	case stderrors.As(err, &notFound):
+		return &codedError{err: err, code: "NOT_FOUND"}
+	case stderrors.As(err, &exists):
+		return &codedError{err: err, code: "ALREADY_EXISTS"}
+	case stderrors.As(err, &conflict):
+		return &codedError{err: err, code: "CONFLICT"}
+	case stderrors.As(err, &validation):
+		return &codedError{err: err, code: graphql.CodeBadUserInput}
+	case stderrors.As(err, &illegal):
+		return &codedError{err: err, code: "ILLEGAL_TRANSITION"}
+	case stderrors.As(err, &quota):
+		return &codedError{err: err, code: "QUOTA_EXCEEDED"}
+	}
+
+	return err
+}
+
+// resolver adapts a function of the request context and arguments that
+// returns a service error.
+func resolver(resolve func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)) graphql.ResolveFunc {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		value, err := resolve(p.Context, p.Source, p.Args)
+		return value, graphQLError(err)
+	}
+}
+
+// property is a field read from the source without touching a service.
+func property(name string, t graphql.Type, get func(source interface{}) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Name: name,
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source), nil
+		},
+	}
+}
+
+// schemaTypes holds the types of the schema, fields referring to the
+// services of the handler are added only for the services it has.
+type schemaTypes struct {
+	status       *graphql.Enum
+	liveness     *graphql.Enum
+	locationKind *graphql.Enum
+	label        *graphql.Object
+	device       *graphql.Object
+	connection   *graphql.Object
+	transition   *graphql.Object
+	heartbeat    *graphql.Object
+	location     *graphql.Object
+	model        *graphql.Object
+}
+
+// newSchema builds the GraphQL schema over the services of the handler.
+func (h *Handler) newSchema() (*graphql.Schema, error) {
+	types := h.schemaTypes()
+
+	query := &graphql.Object{Name: "Query", Fields: h.queryFields(types)}
+	mutation := &graphql.Object{Name: "Mutation", Fields: h.mutationFields(types)}
+
+	return graphql.NewSchema(query, mutation)
+}
+
+func (h *Handler) schemaTypes() *schemaTypes {
+	types := &schemaTypes{
+		status: &graphql.Enum{Name: "DeviceStatus", Values: []*graphql.EnumValue{
+			{Name: "ORDERED", Value: devices.StatusOrdered},
+			{Name: "IN_STOCK", Value: devices.StatusInStock},
+			{Name: "PROVISIONED", Value: devices.StatusProvisioned},
+			{Name: "ACTIVE", Value: devices.StatusActive},
+			{Name: "MAINTENANCE", Value: devices.StatusMaintenance},
+			{Name: "DECOMMISSIONED", Value: devices.StatusDecommissioned},
+		}},
+		liveness: &graphql.Enum{Name: "Liveness", Values: []*graphql.EnumValue{
+			{Name: "ONLINE", Value: devices.Online},
+			{Name: "OFFLINE", Value: devices.Offline},
+		}},
+		locationKind: &graphql.Enum{Name: "LocationKind", Values: []*graphql.EnumValue{
+			{Name: "REGION", Value: locations.KindRegion},
+			{Name: "SITE", Value: locations.KindSite},
+			{Name: "ROOM", Value: locations.KindRoom},
+			{Name: "RACK", Value: locations.KindRack},
+		}},
+	}
+
+	types.label = &graphql.Object{
+		Name:        "Label",
+		Description: "A key and value of the labels, attributes or heartbeat metadata.",
+		Fields: []*graphql.Field{
+			{Name: "key", Type: graphql.NewNonNull(graphql.String)},
+			{Name: "value", Type: graphql.NewNonNull(graphql.String)},
+		},
+	}
+
+	types.transition = &graphql.Object{
+		Name: "Transition",
+		Fields: []*graphql.Field{
+			property("from", types.status, func(s interface{}) interface{} { return optionalStatus(s.(devices.Transition).From) }),
+			property("to", graphql.NewNonNull(types.status), func(s interface{}) interface{} { return s.(devices.Transition).To }),
+			property("reason", graphql.String, func(s interface{}) interface{} { return s.(devices.Transition).Reason }),
+			property("at", graphql.NewNonNull(graphql.String), func(s interface{}) interface{} { return formatTime(s.(devices.Transition).At) }),
+		},
+	}
+
+	types.device = &graphql.Object{
+		Name: "Device",
+		Fields: []*graphql.Field{
+			property("serialNum", graphql.NewNonNull(graphql.ID), func(s interface{}) interface{} { return s.(*devices.Device).SerialNum }),
+			property("model", graphql.NewNonNull(graphql.String), func(s interface{}) interface{} { return s.(*devices.Device).Model }),
+			property("ip", graphql.NewNonNull(graphql.String), func(s interface{}) interface{} { return s.(*devices.Device).IP }),
+			property("position", graphql.Int, func(s interface{}) interface{} {
+				if position := s.(*devices.Device).Position; position != 0 {
+					return position
+				}
+				return nil
+			}),
+			property("status", types.status, func(s interface{}) interface{} { return optionalStatus(s.(*devices.Device).Status) }),
+			property("labels", labelList(types), func(s interface{}) interface{} { return pairs(s.(*devices.Device).Labels) }),
+			property("attributes", labelList(types), func(s interface{}) interface{} { return pairs(s.(*devices.Device).Attributes) }),
+			{
+				Name: "transitions",
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.transition))),
+				Resolve: resolver(func(ctx context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					return h.serviceFor(ctx).ListTransitions(source.(*devices.Device).SerialNum)
+				}),
+			},
+		},
+	}
+
+	types.connection = &graphql.Object{
+		Name:        "DeviceConnection",
+		Description: "A page of devices ordered by serial number.",
+		Fields: []*graphql.Field{
+			property("totalCount", graphql.NewNonNull(graphql.Int), func(s interface{}) interface{} { return s.(*connection).total }),
+			property("nodes", graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.device))), func(s interface{}) interface{} { return s.(*connection).nodes }),
+			property("pageInfo", graphql.NewNonNull(&graphql.Object{
+				Name: "PageInfo",
+				Fields: []*graphql.Field{
+					property("endCursor", graphql.String, func(s interface{}) interface{} { return s.(*connection).endCursor() }),
+					property("hasNextPage", graphql.NewNonNull(graphql.Boolean), func(s interface{}) interface{} { return s.(*connection).hasNext }),
+				},
+			}), func(s interface{}) interface{} { return s }),
+		},
+	}
+
+	if h.liveness != nil {
+		h.addLivenessTypes(types)
+	}
+	if h.locations != nil {
+		h.addLocationTypes(types)
+	}
+	if h.models != nil {
+		h.addModelTypes(types)
+	}
+
+	return types
+}
+
+func (h *Handler) addLivenessTypes(types *schemaTypes) {
+	types.heartbeat = &graphql.Object{
+		Name: "Heartbeat",
+		Fields: []*graphql.Field{
+			property("liveness", graphql.NewNonNull(types.liveness), func(s interface{}) interface{} { return s.(*devices.Heartbeat).Liveness }),
+			property("lastSeen", graphql.NewNonNull(graphql.String), func(s interface{}) interface{} { return formatTime(s.(*devices.Heartbeat).LastSeen) }),
+			property("metadata", labelList(types), func(s interface{}) interface{} { return pairs(s.(*devices.Heartbeat).Metadata) }),
+		},
+	}
+
+	types.device.Fields = append(types.device.Fields, &graphql.Field{
+		Name:        "heartbeat",
+		Description: "The last report of the device, null when it never reported.",
+		Type:        types.heartbeat,
+		Resolve: resolver(func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			heartbeat, err := h.liveness.GetHeartbeat(source.(*devices.Device).SerialNum)
+			var notFound *errors.NotFoundError
+			if stderrors.As(err, &notFound) {
+				return nil, nil
+			}
+			return heartbeat, err
+		}),
+	})
+}
+
+func (h *Handler) addLocationTypes(types *schemaTypes) {
+	types.location = &graphql.Object{
+		Name: "Location",
+		Fields: []*graphql.Field{
+			property("id", graphql.NewNonNull(graphql.ID), func(s interface{}) interface{} { return s.(*locations.Location).ID }),
+			property("name", graphql.NewNonNull(graphql.String), func(s interface{}) interface{} { return s.(*locations.Location).Name }),
+			property("kind", graphql.NewNonNull(types.locationKind), func(s interface{}) interface{} { return s.(*locations.Location).Kind }),
+		},
+	}
+
+	types.location.Fields = append(types.location.Fields,
+		&graphql.Field{
+			Name: "parent",
+			Type: types.location,
+			Resolve: resolver(func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+				parentID := source.(*locations.Location).ParentID
+				if parentID == "" {
+					return nil, nil
+				}
+				return h.locations.GetLocation(parentID)
+			}),
+		},
+		&graphql.Field{
+			Name: "children",
+			Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.location))),
+			Resolve: resolver(func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+				list, err := h.locations.ListLocations()
+				if err != nil {
+					return nil, err
+				}
+
+				var children []*locations.Location
+				for _, location := range list {
+					if location.ParentID == source.(*locations.Location).ID {
+						children = append(children, location)
+					}
+				}
+				return children, nil
+			}),
+		},
+		h.devicesField(types, "Devices placed at the location or anywhere below it.", func(source interface{}) devices.Filter {
+			return devices.Filter{LocationID: source.(*locations.Location).ID}
+		}),
+	)
+
+	types.device.Fields = append(types.device.Fields, &graphql.Field{
+		Name: "location",
+		Type: types.location,
+		Resolve: resolver(func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			locationID := source.(*devices.Device).LocationID
+			if locationID == "" {
+				return nil, nil
+			}
+			return h.locations.GetLocation(locationID)
+		}),
+	})
+}
+
+func (h *Handler) addModelTypes(types *schemaTypes) {
+	types.model = &graphql.Object{
+		Name:        "Model",
+		Description: "A catalog model.",
+		Fields: []*graphql.Field{
+			property("name", graphql.NewNonNull(graphql.ID), func(s interface{}) interface{} { return s.(*catalog.Model).Name }),
+			property("vendor", graphql.NewNonNull(graphql.String), func(s interface{}) interface{} { return s.(*catalog.Model).Vendor }),
+			property("ports", graphql.NewNonNull(graphql.Int), func(s interface{}) interface{} { return s.(*catalog.Model).Specs.Ports }),
+			property("rackUnits", graphql.NewNonNull(graphql.Int), func(s interface{}) interface{} { return s.(*catalog.Model).Specs.RackUnits }),
+			property("powerWatts", graphql.NewNonNull(graphql.Int), func(s interface{}) interface{} { return s.(*catalog.Model).Specs.PowerWatts }),
+			property("attributes", graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String))), func(s interface{}) interface{} {
+				if attributes := s.(*catalog.Model).Attributes; attributes != nil {
+					return attributes
+				}
+				return []string{}
+			}),
+			h.devicesField(types, "Devices of the model.", func(source interface{}) devices.Filter {
+				return devices.Filter{Model: source.(*catalog.Model).Name}
+			}),
+		},
+	}
+
+	types.device.Fields = append(types.device.Fields, &graphql.Field{
+		Name:        "catalogModel",
+		Description: "The catalog entry of the model, null when the model is not in the catalog.",
+		Type:        types.model,
+		Resolve: resolver(func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			model, err := h.models.GetModel(source.(*devices.Device).Model)
+			var notFound *errors.NotFoundError
+			if stderrors.As(err, &notFound) {
+				return nil, nil
+			}
+			return model, err
+		}),
+	})
+}
+
+func labelList(types *schemaTypes) graphql.Type {
+	return graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.label)))
+}
+
+// pairs lists the entries of a map sorted by key.
+func pairs(m map[string]string) []interface{} {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]interface{}, len(keys))
+	for i, key := range keys {
+		result[i] = map[string]interface{}{"key": key, "value": m[key]}
+	}
+
+	return result
+}
+
+func optionalStatus(status devices.Status) interface{} {
+	if status == "" {
+		return nil
+	}
+
+	return status
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// pageArgs are the arguments of paginated fields. The complexity of a
+// query counts the fields below them "first" times.
+func pageArgs() []*graphql.Argument {
+	return []*graphql.Argument{
+		{Name: "first", Type: graphql.Int, Default: defaultPageSize, Description: "The number of devices to return, at most 100."},
+		{Name: "after", Type: graphql.String, Description: "The endCursor of the previous page."},
+	}
+}
+
+// devicesField is a connection of the devices matching the filter built
+// from the source.
+func (h *Handler) devicesField(types *schemaTypes, description string, filter func(source interface{}) devices.Filter) *graphql.Field {
+	return &graphql.Field{
+		Name:        "devices",
+		Description: description,
+		Type:        graphql.NewNonNull(types.connection),
+		Args:        pageArgs(),
+		Resolve: resolver(func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+			list, err := h.serviceFor(ctx).ListDevices(filter(source))
+			if err != nil {
+				return nil, err
+			}
+			return paginate(list, args)
+		}),
+	}
+}
+
+// connection is a page of devices.
+type connection struct {
+	total   int
+	nodes   []*devices.Device
+	hasNext bool
+}
+
+func (c *connection) endCursor() interface{} {
+	if len(c.nodes) == 0 {
+		return nil
+	}
+
+	return encodeCursor(c.nodes[len(c.nodes)-1].SerialNum)
+}
+
+func encodeCursor(serialNum string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(serialNum))
+}
+
+// paginate orders the devices by serial number and returns the page
+// following the cursor of the "after" argument.
+func paginate(list []*devices.Device, args map[string]interface{}) (*connection, error) {
+	first, _ := args["first"].(int)
+	if first < 1 || first > maxPageSize {
+		return nil, errors.NewValidationError("'first' must be between 1 and %d", maxPageSize)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].SerialNum < list[j].SerialNum })
+
+	start := 0
+	if after, ok := args["after"].(string); ok {
+		serialNum, err := base64.RawURLEncoding.DecodeString(after)
+		if err != nil {
+			return nil, errors.NewValidationError("invalid cursor %q", after)
+		}
+		start = sort.Search(len(list), func(i int) bool { return list[i].SerialNum > string(serialNum) })
+	}
+
+	end := start + first
+	if end > len(list) {
+		end = len(list)
+	}
+
+	return &connection{total: len(list), nodes: list[start:end], hasNext: end < len(list)}, nil
+}
+
+func (h *Handler) queryFields(types *schemaTypes) []*graphql.Field {
+	deviceFilter := &graphql.InputObject{
+		Name:        "DeviceFilter",
+		Description: "Narrows the devices down, fields left out match every device.",
+		Fields: []*graphql.InputField{
+			{Name: "ip", Type: graphql.String},
+			{Name: "model", Type: graphql.String},
+			{Name: "location", Type: graphql.ID, Description: "Matches devices at the location or anywhere below it."},
+			{Name: "selector", Type: graphql.String, Description: `A label selector such as "env=prod,team in (a,b)".`},
+			{Name: "liveness", Type: types.liveness},
+			{Name: "query", Type: graphql.String, Description: "An expression of the filter language."},
+		},
+	}
+
+	searchHit := &graphql.Object{
+		Name: "SearchHit",
+		Fields: []*graphql.Field{
+			property("device", graphql.NewNonNull(types.device), func(s interface{}) interface{} { return s.(devices.SearchHit).Device }),
+			property("score", graphql.NewNonNull(graphql.Float), func(s interface{}) interface{} { return s.(devices.SearchHit).Score }),
+		},
+	}
+
+	fields := []*graphql.Field{
+		{
+			Name: "device",
+			Type: types.device,
+			Args: []*graphql.Argument{{Name: "serialNum", Type: graphql.NewNonNull(graphql.ID)}},
+			Resolve: resolver(func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				return h.serviceFor(ctx).GetDevice(args["serialNum"].(string))
+			}),
+		},
+		{
+			Name: "devices",
+			Type: graphql.NewNonNull(types.connection),
+			Args: append([]*graphql.Argument{{Name: "filter", Type: deviceFilter}}, pageArgs()...),
+			Resolve: resolver(func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				list, err := h.serviceFor(ctx).ListDevices(deviceFilterOf(args["filter"]))
+				if err != nil {
+					return nil, err
+				}
+				return paginate(list, args)
+			}),
+		},
+		{
+			Name:        "search",
+			Description: "Full text search over devices, best matches first.",
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(searchHit))),
+			Args: []*graphql.Argument{
+				{Name: "query", Type: graphql.NewNonNull(graphql.String)},
+				{Name: "first", Type: graphql.Int, Default: defaultPageSize},
+			},
+			Resolve: resolver(func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				first, _ := args["first"].(int)
+				if first < 1 {
+					return nil, errors.NewValidationError("'first' must be between 1 and %d", maxPageSize)
+				}
+				return h.serviceFor(ctx).SearchDevices(args["query"].(string), first)
+			}),
+		},
+	}
+
+	if h.liveness != nil {
+		fields = append(fields, &graphql.Field{
+			Name: "liveness",
+			Type: graphql.NewNonNull(&graphql.Object{
+				Name: "LivenessCounts",
+				Fields: []*graphql.Field{
+					property("online", graphql.NewNonNull(graphql.Int), func(s interface{}) interface{} { return s.(*devices.LivenessCounts).Online }),
+					property("offline", graphql.NewNonNull(graphql.Int), func(s interface{}) interface{} { return s.(*devices.LivenessCounts).Offline }),
+				},
+			}),
+			Resolve: resolver(func(context.Context, interface{}, map[string]interface{}) (interface{}, error) {
+				return h.liveness.CountLiveness()
+			}),
+		})
+	}
+
+	if h.locations != nil {
+		fields = append(fields,
+			&graphql.Field{
+				Name: "location",
+				Type: types.location,
+				Args: []*graphql.Argument{{Name: "id", Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: resolver(func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					return h.locations.GetLocation(args["id"].(string))
+				}),
+			},
+			&graphql.Field{
+				Name: "locations",
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.location))),
+				Resolve: resolver(func(context.Context, interface{}, map[string]interface{}) (interface{}, error) {
+					return h.locations.ListLocations()
+				}),
+			},
+		)
+	}
+
+	if h.models != nil {
+		fields = append(fields,
+			&graphql.Field{
+				Name: "model",
+				Type: types.model,
+				Args: []*graphql.Argument{{Name: "name", Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: resolver(func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					return h.models.GetModel(args["name"].(string))
+				}),
+			},
+			&graphql.Field{
+				Name: "models",
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(types.model))),
+				Resolve: resolver(func(context.Context, interface{}, map[string]interface{}) (interface{}, error) {
+					return h.models.ListModels()
+				}),
+			},
+		)
+	}
+
+	return fields
+}
+
+func deviceFilterOf(arg interface{}) devices.Filter {
+	fields, _ := arg.(map[string]interface{})
+
+	str := func(name string) string {
+		s, _ := fields[name].(string)
+		return s
+	}
+	liveness, _ := fields["liveness"].(devices.Liveness)
+
+	return devices.Filter{
+		IP:         str("ip"),
+		Model:      str("model"),
+		LocationID: str("location"),
+		Selector:   str("selector"),
+		Liveness:   liveness,
+		Query:      str("query"),
+	}
+}
+
+func (h *Handler) mutationFields(types *schemaTypes) []*graphql.Field {
+	labelInput := &graphql.InputObject{
+		Name: "LabelInput",
+		Fields: []*graphql.InputField{
+			{Name: "key", Type: graphql.NewNonNull(graphql.String)},
+			{Name: "value", Type: graphql.NewNonNull(graphql.String)},
+		},
+	}
+	deviceInput := &graphql.InputObject{
+		Name: "DeviceInput",
+		Fields: []*graphql.InputField{
+			{Name: "serialNum", Type: graphql.NewNonNull(graphql.ID)},
+			{Name: "model", Type: graphql.NewNonNull(graphql.String)},
+			{Name: "ip", Type: graphql.String},
+			{Name: "location", Type: graphql.ID},
+			{Name: "position", Type: graphql.Int},
+			{Name: "status", Type: types.status, Description: "The initial status on create, only ORDERED. Updates keep the status, it changes with transitionDevice."},
+			{Name: "labels", Type: graphql.NewList(graphql.NewNonNull(labelInput))},
+			{Name: "attributes", Type: graphql.NewList(graphql.NewNonNull(labelInput))},
+		},
+	}
+	deviceArg := []*graphql.Argument{{Name: "input", Type: graphql.NewNonNull(deviceInput)}}
+	serialArg := &graphql.Argument{Name: "serialNum", Type: graphql.NewNonNull(graphql.ID)}
+
+	return []*graphql.Field{
+		{
+			Name: "createDevice",
+			Type: graphql.NewNonNull(types.device),
+			Args: deviceArg,
+			Resolve: resolver(func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				device := deviceOf(args["input"])
+				if err := h.serviceFor(ctx).CreateDevice(device); err != nil {
+					return nil, err
+				}
+				return device, nil
+			}),
+		},
+		{
+			Name: "updateDevice",
+			Type: graphql.NewNonNull(types.device),
+			Args: deviceArg,
+			Resolve: resolver(func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				device := deviceOf(args["input"])
+				if err := h.serviceFor(ctx).UpdateDevice(device); err != nil {
+					return nil, err
+				}
+				return device, nil
+			}),
+		},
+		{
+			Name:        "deleteDevice",
+			Description: "Deletes the device and returns its serial number.",
+			Type:        graphql.NewNonNull(graphql.ID),
+			Args:        []*graphql.Argument{serialArg},
+			Resolve: resolver(func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				serialNum := args["serialNum"].(string)
+				return serialNum, h.serviceFor(ctx).DeleteDevice(serialNum)
+			}),
+		},
+		{
+			Name: "transitionDevice",
+			Type: graphql.NewNonNull(types.device),
+			Args: []*graphql.Argument{
+				serialArg,
+				{Name: "status", Type: graphql.NewNonNull(types.status)},
+				{Name: "reason", Type: graphql.String},
+			},
+			Resolve: resolver(func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+				reason, _ := args["reason"].(string)
+				return h.serviceFor(ctx).TransitionDevice(args["serialNum"].(string), args["status"].(devices.Status), reason)
+			}),
+		},
+	}
+}
+
+func deviceOf(arg interface{}) *devices.Device {
+	fields := arg.(map[string]interface{})
+
+	device := &devices.Device{
+		SerialNum: fields["serialNum"].(string),
+		Model:     fields["model"].(string),
+	}
+	device.IP, _ = fields["ip"].(string)
+	device.LocationID, _ = fields["location"].(string)
+	device.Position, _ = fields["position"].(int)
+	device.Status, _ = fields["status"].(devices.Status)
+	device.Labels = mapOf(fields["labels"])
+	device.Attributes = mapOf(fields["attributes"])
+
+	return device
+}
+
+// mapOf turns a list of LabelInput back into a map, later keys win.
+func mapOf(arg interface{}) map[string]string {
+	list, _ := arg.([]interface{})
+	if len(list) == 0 {
+		return nil
+	}
+
+	result := make(map[string]string, len(list))
+	for _, item := range list {
+		pair := item.(map[string]interface{})
+		result[pair["key"].(string)] = pair["value"].(string)
+	}
+
+	return result
+}
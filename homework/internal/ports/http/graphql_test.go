@@ -0,0 +1,174 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/graphql"
+	"homework/internal/locations"
+	deviceMock "homework/internal/mocks"
+)
+
+func newGraphQLHandler(config *Config) *Handler {
+	config.GraphQL = &graphql.Options{MaxDepth: 5, MaxComplexity: 100}
+	handler := NewHandler(config)
+
+	return &handler
+}
+
+func postGraphQL(handler *Handler, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	handler.routes().ServeHTTP(w, r)
+
+	return w
+}
+
+func TestHandlerGraphQLQuery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+	locationService := deviceMock.NewMockLocationService(ctrl)
+
+	list := []*devices.Device{
+		{SerialNum: "3", Model: testModel1, LocationID: "rack"},
+		{SerialNum: "1", Model: testModel1, Status: devices.StatusActive, Labels: map[string]string{"team": "a", "env": "prod"}},
+		{SerialNum: "2", Model: testModel1},
+	}
+	deviceService.EXPECT().ListDevices(devices.Filter{Model: testModel1, Liveness: devices.Online}).Return(list, nil).Times(2)
+	locationService.EXPECT().GetLocation("rack").Return(&locations.Location{ID: "rack", Name: "r1", Kind: locations.KindRack}, nil).Times(1)
+
+	handler := newGraphQLHandler(&Config{Service: deviceService, Locations: locationService})
+
+	query := `query($model: String) {
+		devices(filter: {model: $model, liveness: ONLINE}, first: 2) {
+			totalCount
+			nodes { serialNum status labels { key value } }
+			pageInfo { endCursor hasNextPage }
+		}
+	}`
+	w := postGraphQL(handler, `{"query": `+strconv.Quote(query)+`, "variables": {"model": "`+testModel1+`"}}`)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"data": {"devices": {
+		"totalCount": 3,
+		"nodes": [
+			{"serialNum": "1", "status": "ACTIVE", "labels": [{"key": "env", "value": "prod"}, {"key": "team", "value": "a"}]},
+			{"serialNum": "2", "status": null, "labels": []}
+		],
+		"pageInfo": {"endCursor": "Mg", "hasNextPage": true}
+	}}}`, w.Body.String())
+
+	query = `{ devices(filter: {model: "` + testModel1 + `", liveness: ONLINE}, after: "Mg") { nodes { serialNum location { name kind } } } }`
+	w = postGraphQL(handler, `{"query": `+strconv.Quote(query)+`}`)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"data": {"devices": {"nodes": [{"serialNum": "3", "location": {"name": "r1", "kind": "RACK"}}]}}}`, w.Body.String())
+}
+
+func TestHandlerGraphQLMutationErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	deviceService.EXPECT().DeleteDevice(testSeqNum1).Return(errors.NewNotFoundError(testSeqNum1)).Times(1)
+	deviceService.EXPECT().
+		TransitionDevice(testSeqNum1, devices.StatusMaintenance, "broken").
+		Return(nil, errors.NewIllegalTransitionError(testSeqNum1, "ordered", "maintenance")).Times(1)
+
+	handler := newGraphQLHandler(&Config{Service: deviceService})
+
+	// A failed non-null root field leaves no data, the mutations after it
+	// do not run.
+	query := `mutation {
+		deleteDevice(serialNum: "` + testSeqNum1 + `")
+		other: deleteDevice(serialNum: "2")
+	}`
+	w := postGraphQL(handler, `{"query": `+strconv.Quote(query)+`}`)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"data": null, "errors": [
+		{"message": "device with 'SerialNum' = 1 not found", "locations": [{"line": 2, "column": 3}], "path": ["deleteDevice"], "extensions": {"code": "NOT_FOUND"}}
+	]}`, w.Body.String())
+
+	query = `mutation { transitionDevice(serialNum: "` + testSeqNum1 + `", status: MAINTENANCE, reason: "broken") { status } }`
+	w = postGraphQL(handler, `{"query": `+strconv.Quote(query)+`}`)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), `"extensions":{"code":"ILLEGAL_TRANSITION"}`)
+}
+
+func TestHandlerGraphQLCreateDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	device := &devices.Device{SerialNum: testSeqNum1, Model: testModel1, IP: testIP1, Position: 4, Attributes: map[string]string{"ports": "48"}}
+	deviceService.EXPECT().CreateDevice(device).DoAndReturn(func(device *devices.Device) error {
+		device.Status = devices.StatusInStock
+		return nil
+	}).Times(1)
+
+	handler := newGraphQLHandler(&Config{Service: deviceService})
+
+	query := `mutation($input: DeviceInput!) { createDevice(input: $input) { serialNum status position } }`
+	w := postGraphQL(handler, `{"query": `+strconv.Quote(query)+`, "variables": {"input": {
+		"serialNum": "`+testSeqNum1+`", "model": "`+testModel1+`", "ip": "`+testIP1+`", "position": 4,
+		"attributes": [{"key": "ports", "value": "48"}]
+	}}}`)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"data": {"createDevice": {"serialNum": "1", "status": "IN_STOCK", "position": 4}}}`, w.Body.String())
+}
+
+func TestHandlerGraphQLRejectedRequests(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	handler := newGraphQLHandler(&Config{Service: deviceService})
+
+	w := postGraphQL(handler, `{"query": "{ device(serialNum: 1) { serialNum transitions { to } } "}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), graphql.CodeParseFailed)
+
+	w = postGraphQL(handler, `{"query": "{ device { serialNum } }"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), graphql.CodeValidationFailed)
+
+	w = postGraphQL(handler, `{"query": "{ devices(first: 100) { nodes { serialNum } } }"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), graphql.CodeQueryTooComplex)
+
+	w = postGraphQL(handler, `not json`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	r := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`mutation { deleteDevice(serialNum: "1") }`), nil)
+	w = httptest.NewRecorder()
+	handler.routes().ServeHTTP(w, r)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), "only queries are allowed")
+}
+
+func TestHandlerGraphQLSchema(t *testing.T) {
+	handler := newGraphQLHandler(&Config{})
+
+	r := httptest.NewRequest(http.MethodGet, "/graphql/schema", nil)
+	w := httptest.NewRecorder()
+	handler.routes().ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Contains(t, w.Body.String(), "devices(filter: DeviceFilter, first: Int = 20, after: String): DeviceConnection!")
+	// Types of services the handler does not have are left out.
+	require.NotContains(t, w.Body.String(), "type Location")
+}
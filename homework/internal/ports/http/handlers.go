@@ -26,7 +26,7 @@ func (h *Handler) createDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.CreateDevice(&device)
+	err = h.deviceService(r).CreateDevice(&device)
 	if err != nil {
 		h.processError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -44,7 +44,12 @@ func (h *Handler) getDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	device, err := h.service.GetDevice(id)
+	if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+		h.getDeviceAsOf(w, id, asOf)
+		return
+	}
+
+	device, err := h.deviceService(r).GetDevice(id)
 	if err != nil {
 		h.processError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -60,6 +65,44 @@ func (h *Handler) getDevice(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(buf)
 }
 
+func (h *Handler) listDevices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	query := r.URL.Query()
+	filter := devices.Filter{
+		IP:         query.Get("ip"),
+		Model:      query.Get("model"),
+		LocationID: query.Get("location"),
+		Selector:   query.Get("selector"),
+		Liveness:   devices.Liveness(query.Get("liveness")),
+		Query:      query.Get("filter"),
+	}
+
+	h.writeDevices(w, r, filter)
+}
+
+func (h *Handler) getDevicesByIP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	ip := chi.URLParam(r, "ip")
+	if len(ip) == 0 {
+		h.processError(w, "'ip' is required param", http.StatusBadRequest)
+		return
+	}
+
+	h.writeDevices(w, r, devices.Filter{IP: ip})
+}
+
+func (h *Handler) writeDevices(w http.ResponseWriter, r *http.Request, filter devices.Filter) {
+	list, err := h.deviceService(r).ListDevices(filter)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, list)
+}
+
 func (h *Handler) deleteDevice(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "application/json")
 
@@ -69,7 +112,7 @@ func (h *Handler) deleteDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.service.DeleteDevice(id)
+	err := h.deviceService(r).DeleteDevice(id)
 	if err != nil {
 		h.processError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -94,7 +137,7 @@ func (h *Handler) updateDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.UpdateDevice(&device)
+	err = h.deviceService(r).UpdateDevice(&device)
 	if err != nil {
 		h.processError(w, err.Error(), http.StatusBadRequest)
 		return
@@ -103,6 +146,17 @@ func (h *Handler) updateDevice(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+func (h *Handler) writeJSON(w http.ResponseWriter, value interface{}) {
+	buf, err := json.Marshal(value)
+	if err != nil {
+		h.processError(w, "can not marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf)
+}
+
 type ErrorBody struct {
 	Message string `json:"message"`
 }
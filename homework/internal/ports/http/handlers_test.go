@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -332,3 +333,139 @@ func TestHandlerUpdateDeviceNilBody(t *testing.T) {
 
 	require.Equal(t, http.StatusBadRequest, res.StatusCode)
 }
+
+func TestHandlerListDevicesSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := []*devices.Device{
+		{
+			SerialNum: testSeqNum1,
+			IP:        testIP1,
+			Model:     testModel1,
+		},
+	}
+	deviceService.EXPECT().ListDevices(devices.Filter{Model: testModel1}).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices", handler.listDevices)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices?model=test+model+1", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []*devices.Device
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+}
+
+func TestHandlerGetDevicesByIP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := []*devices.Device{
+		{
+			SerialNum: testSeqNum1,
+			IP:        "10.1.2.3",
+			Model:     testModel1,
+		},
+	}
+	deviceService.EXPECT().ListDevices(devices.Filter{IP: "10.1.2.3"}).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices/by-ip/{ip}", handler.getDevicesByIP)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/by-ip/10.1.2.3", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []*devices.Device
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+}
+
+func TestHandlerListDevicesBySelector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := []*devices.Device{
+		{
+			SerialNum: testSeqNum1,
+			IP:        testIP1,
+			Model:     testModel1,
+			Labels:    map[string]string{"env": "prod", "tier": "web"},
+		},
+	}
+	deviceService.EXPECT().ListDevices(devices.Filter{Selector: "env=prod,tier in (web,db)"}).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices", handler.listDevices)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices?selector=env%3Dprod%2Ctier+in+%28web%2Cdb%29", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []*devices.Device
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+}
+
+func TestListDevicesByQuery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := []*devices.Device{{SerialNum: testSeqNum1, IP: testIP1, Model: testModel1}}
+	deviceService.EXPECT().ListDevices(devices.Filter{Query: `model == "RT-100" and ip in 10.0.0.0/8`}).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices", handler.listDevices)
+
+	query := url.Values{"filter": {`model == "RT-100" and ip in 10.0.0.0/8`}}
+	r := httptest.NewRequest(http.MethodGet, "/devices?"+query.Encode(), nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []*devices.Device
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+}
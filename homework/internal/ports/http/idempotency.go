@@ -0,0 +1,153 @@
+package http
+
+import (
+	"bytes"
+	stderrors "errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"homework/internal/idempotency"
+	"homework/internal/tracing"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	replayedHeader       = "Idempotent-Replayed"
+	maxIdempotencyKey    = 255
+)
+
+// idempotent answers a POST retried with the same Idempotency-Key header
+// with the response of the first request instead of running it again.
+// Server errors are not kept, so a retry after one runs the request again.
+// Requests without the header are served as usual.
+func (h *Handler) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("content-type", "application/json")
+
+		if len(key) > maxIdempotencyKey {
+			h.processError(w, "idempotency key is longer than 255 characters", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.processError(w, "can not read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := idempotency.NewFingerprint(r.Method, routePath(r), body)
+		response, err := h.idempotency.Begin(key, fingerprint)
+		switch {
+		case stderrors.Is(err, idempotency.ErrInProgress):
+			h.processError(w, err.Error(), http.StatusConflict)
+			return
+		case stderrors.Is(err, idempotency.ErrMismatch):
+			h.processError(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		case response != nil:
+			replay(w, response)
+			return
+		}
+
+		// A panicking handler releases the key as well.
+		completed := false
+		defer func() {
+			if !completed {
+				h.idempotency.Abort(key)
+			}
+		}()
+
+		rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r)
+		completed = true
+
+		if rw.status >= http.StatusInternalServerError {
+			h.idempotency.Abort(key)
+			return
+		}
+
+		h.idempotency.Complete(key, &idempotency.Response{
+			Status: rw.status,
+			Header: rw.recordedHeader(),
+			Body:   rw.body.Bytes(),
+		})
+	})
+}
+
+// routePath is the path below the tenant prefix, so a retry through
+// /tenants/{tenant} matches the first request sent with an api key.
+func routePath(r *http.Request) string {
+	path := r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePath != "" {
+		path = rctx.RoutePath
+	}
+
+	if r.URL.RawQuery != "" {
+		path += "?" + r.URL.RawQuery
+	}
+
+	return path
+}
+
+func replay(w http.ResponseWriter, response *idempotency.Response) {
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(replayedHeader, "true")
+
+	w.WriteHeader(response.Status)
+	_, _ = w.Write(response.Body)
+}
+
+// recordingWriter keeps a copy of the response it passes through. The
+// traceparent header belongs to the request that produced the response
+// and is not kept.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.header == nil {
+		w.status = code
+		w.header = w.recordedHeader()
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(buf []byte) (int, error) {
+	if w.header == nil {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(buf)
+
+	return w.ResponseWriter.Write(buf)
+}
+
+func (w *recordingWriter) recordedHeader() http.Header {
+	if w.header != nil {
+		return w.header
+	}
+
+	header := w.Header().Clone()
+	header.Del(tracing.TraceparentHeader)
+
+	return header
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (w *recordingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
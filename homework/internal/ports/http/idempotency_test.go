@@ -0,0 +1,95 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/idempotency"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerIdempotencyKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	opsService := deviceMock.NewMockService(ctrl)
+	labService := deviceMock.NewMockService(ctrl)
+
+	first := opsService.EXPECT().CreateDevice(&devices.Device{SerialNum: "1"}).Return(nil).Times(1)
+	opsService.EXPECT().CreateDevice(&devices.Device{SerialNum: "1"}).Return(fmt.Errorf("device already exist")).Times(1).After(first)
+	labService.EXPECT().CreateDevice(&devices.Device{SerialNum: "1"}).Return(nil).Times(1)
+
+	handler := NewHandler(&Config{
+		Tenants: map[string]*Config{
+			"ops": {Service: opsService, Idempotency: idempotency.NewStore(idempotency.Options{})},
+			"lab": {Service: labService, Idempotency: idempotency.NewStore(idempotency.Options{})},
+		},
+		APIKeys: map[string]string{"ops-key": "ops", "lab-key": "lab"},
+	})
+	server := handler.NewServer()
+
+	cases := []struct {
+		name     string
+		path     string
+		apiKey   string
+		key      string
+		body     string
+		code     int
+		replayed bool
+	}{
+		{name: "first request", path: "/devices", apiKey: "ops-key", key: "k1", body: `{"serial_num":"1"}`, code: http.StatusOK},
+		{name: "retry", path: "/devices", apiKey: "ops-key", key: "k1", body: `{"serial_num":"1"}`, code: http.StatusOK, replayed: true},
+		{name: "retry through the tenant prefix", path: "/tenants/ops/devices", apiKey: "ops-key", key: "k1", body: `{"serial_num":"1"}`, code: http.StatusOK, replayed: true},
+		{name: "key reused for another body", path: "/devices", apiKey: "ops-key", key: "k1", body: `{"serial_num":"2"}`, code: http.StatusUnprocessableEntity},
+		{name: "key reused for another route", path: "/models", apiKey: "ops-key", key: "k1", body: `{"serial_num":"1"}`, code: http.StatusUnprocessableEntity},
+		{name: "real conflict", path: "/devices", apiKey: "ops-key", key: "k2", body: `{"serial_num":"1"}`, code: http.StatusBadRequest},
+		{name: "conflict retried", path: "/devices", apiKey: "ops-key", key: "k2", body: `{"serial_num":"1"}`, code: http.StatusBadRequest, replayed: true},
+		{name: "keys are per tenant", path: "/devices", apiKey: "lab-key", key: "k1", body: `{"serial_num":"1"}`, code: http.StatusOK},
+		{name: "key too long", path: "/devices", apiKey: "ops-key", key: string(bytes.Repeat([]byte("k"), 256)), code: http.StatusBadRequest},
+	}
+
+	for _, tCase := range cases {
+		r := httptest.NewRequest(http.MethodPost, tCase.path, bytes.NewBufferString(tCase.body))
+		r.Header.Set(apiKeyHeader, tCase.apiKey)
+		r.Header.Set(idempotencyKeyHeader, tCase.key)
+		w := httptest.NewRecorder()
+
+		server.Handler.ServeHTTP(w, r)
+
+		require.Equal(t, tCase.code, w.Code, tCase.name)
+		require.Equal(t, tCase.replayed, w.Header().Get(replayedHeader) == "true", tCase.name)
+	}
+}
+
+func TestHandlerIdempotencyServerError(t *testing.T) {
+	store := idempotency.NewStore(idempotency.Options{})
+	handler := &Handler{idempotency: store}
+
+	code := http.StatusServiceUnavailable
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(code) })
+
+	for _, expected := range []int{http.StatusServiceUnavailable, http.StatusCreated, http.StatusCreated} {
+		r := httptest.NewRequest(http.MethodPost, "/devices", nil)
+		r.Header.Set(idempotencyKeyHeader, "k")
+		w := httptest.NewRecorder()
+
+		handler.idempotent(next).ServeHTTP(w, r)
+		require.Equal(t, expected, w.Code)
+
+		code = http.StatusCreated
+	}
+	require.Equal(t, 1, store.Len())
+
+	// A panicking handler does not leave the key in progress.
+	panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { panic("broken") })
+	r := httptest.NewRequest(http.MethodPost, "/devices", nil)
+	r.Header.Set(idempotencyKeyHeader, "p")
+	require.Panics(t, func() { handler.idempotent(panicking).ServeHTTP(httptest.NewRecorder(), r) })
+	require.Equal(t, 1, store.Len())
+}
@@ -0,0 +1,115 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"homework/internal/ipam"
+)
+
+func (h *Handler) createSubnet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.processError(w, "can not read request body", http.StatusBadRequest)
+		return
+	}
+
+	var subnet ipam.Subnet
+	err = json.Unmarshal(buf, &subnet)
+	if err != nil {
+		h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return
+	}
+
+	err = h.ipam.CreateSubnet(&subnet)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, subnet)
+}
+
+func (h *Handler) listSubnets(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	h.writeJSON(w, h.ipam.ListSubnets())
+}
+
+func (h *Handler) getSubnet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	subnet, err := h.ipam.GetSubnet(chi.URLParam(r, "id"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, subnet)
+}
+
+func (h *Handler) deleteSubnet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	err := h.ipam.DeleteSubnet(chi.URLParam(r, "id"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) addPool(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.processError(w, "can not read request body", http.StatusBadRequest)
+		return
+	}
+
+	var pool ipam.Pool
+	err = json.Unmarshal(buf, &pool)
+	if err != nil {
+		h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err = h.ipam.AddPool(id, pool); err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	subnet, err := h.ipam.GetSubnet(id)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, subnet)
+}
+
+func (h *Handler) getUtilization(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	utilization, err := h.ipam.Utilization(chi.URLParam(r, "id"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, utilization)
+}
+
+func (h *Handler) listUtilization(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	h.writeJSON(w, h.ipam.ListUtilization())
+}
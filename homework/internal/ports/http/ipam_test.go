@@ -0,0 +1,119 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/errors"
+	"homework/internal/ipam"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerCreateSubnet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ipamService := deviceMock.NewMockIPAMService(ctrl)
+
+	subnet := &ipam.Subnet{ID: "lan", CIDR: "10.0.0.0/24"}
+	ipamService.EXPECT().CreateSubnet(subnet).Return(nil).Times(1)
+
+	handler := &Handler{
+		ipam: ipamService,
+	}
+	router := chi.NewRouter()
+	router.Post("/subnets", handler.createSubnet)
+
+	subnetBytes, _ := json.Marshal(subnet)
+	r := httptest.NewRequest(http.MethodPost, "/subnets", bytes.NewReader(subnetBytes))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestHandlerCreateSubnetError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ipamService := deviceMock.NewMockIPAMService(ctrl)
+
+	ipamService.EXPECT().CreateSubnet(gomock.Any()).Return(errors.NewValidationError("invalid cidr")).Times(1)
+
+	handler := &Handler{
+		ipam: ipamService,
+	}
+	router := chi.NewRouter()
+	router.Post("/subnets", handler.createSubnet)
+
+	r := httptest.NewRequest(http.MethodPost, "/subnets", bytes.NewReader([]byte(`{"cidr":"x"}`)))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestHandlerGetUtilization(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ipamService := deviceMock.NewMockIPAMService(ctrl)
+
+	expect := &ipam.Utilization{SubnetID: "lan", CIDR: "10.0.0.0/24", Size: 254, Allocated: 1, Free: 253}
+	ipamService.EXPECT().Utilization("lan").Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		ipam: ipamService,
+	}
+	router := chi.NewRouter()
+	router.Get("/subnets/{id}/utilization", handler.getUtilization)
+
+	r := httptest.NewRequest(http.MethodGet, "/subnets/lan/utilization", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual ipam.Utilization
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, &actual)
+}
+
+func TestHandlerSubnetDefaultID(t *testing.T) {
+	handler := NewHandler(&Config{IPAM: ipam.NewManager()})
+	router := handler.routes()
+
+	r := httptest.NewRequest(http.MethodPost, "/subnets", bytes.NewReader([]byte(`{"cidr":"10.0.0.0/24"}`)))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var created ipam.Subnet
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	require.Equal(t, "10.0.0.0-24", created.ID)
+
+	r = httptest.NewRequest(http.MethodGet, "/subnets/"+created.ID, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var actual ipam.Subnet
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+	require.Equal(t, "10.0.0.0/24", actual.CIDR)
+}
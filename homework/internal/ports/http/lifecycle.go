@@ -0,0 +1,60 @@
+package http
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+)
+
+type TransitionRequest struct {
+	Status devices.Status `json:"status"`
+	Reason string         `json:"reason,omitempty"`
+}
+
+func (h *Handler) transitionDevice(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.processError(w, "can not read request body", http.StatusBadRequest)
+		return
+	}
+
+	var request TransitionRequest
+	err = json.Unmarshal(buf, &request)
+	if err != nil {
+		h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return
+	}
+
+	device, err := h.deviceService(r).TransitionDevice(chi.URLParam(r, "id"), request.Status, request.Reason)
+	var illegal *errors.IllegalTransitionError
+	if stderrors.As(err, &illegal) {
+		h.processError(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, device)
+}
+
+func (h *Handler) listTransitions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	list, err := h.deviceService(r).ListTransitions(chi.URLParam(r, "id"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, list)
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerTransitionDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := &devices.Device{SerialNum: testSeqNum1, Status: devices.StatusActive}
+	deviceService.EXPECT().TransitionDevice(testSeqNum1, devices.StatusActive, "racked").Return(expect, nil).Times(1)
+	deviceService.EXPECT().TransitionDevice(testSeqNum1, devices.StatusOrdered, "").
+		Return(nil, errors.NewIllegalTransitionError(testSeqNum1, "active", "ordered")).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Post("/devices/{id}/transitions", handler.transitionDevice)
+
+	r := httptest.NewRequest(http.MethodPost, "/devices/1/transitions", strings.NewReader(`{"status":"active","reason":"racked"}`))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual devices.Device
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, &actual)
+
+	r = httptest.NewRequest(http.MethodPost, "/devices/1/transitions", strings.NewReader(`{"status":"ordered"}`))
+	w = httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestHandlerListTransitions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := []devices.Transition{{SerialNum: testSeqNum1, To: devices.StatusOrdered, Reason: "created"}}
+	deviceService.EXPECT().ListTransitions(testSeqNum1).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices/{id}/transitions", handler.listTransitions)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/1/transitions", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []devices.Transition
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type HeartbeatRequest struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (h *Handler) heartbeat(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.processError(w, "can not read request body", http.StatusBadRequest)
+		return
+	}
+
+	// Devices may report without a body.
+	var request HeartbeatRequest
+	if len(buf) > 0 {
+		if err = json.Unmarshal(buf, &request); err != nil {
+			h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = h.liveness.Heartbeat(chi.URLParam(r, "id"), request.Metadata)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) getHeartbeat(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	heartbeat, err := h.liveness.GetHeartbeat(chi.URLParam(r, "id"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, heartbeat)
+}
+
+func (h *Handler) countLiveness(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	counts, err := h.liveness.CountLiveness()
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, counts)
+}
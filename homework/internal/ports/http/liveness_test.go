@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerHeartbeat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	livenessService := deviceMock.NewMockLivenessService(ctrl)
+
+	livenessService.EXPECT().Heartbeat("1", map[string]string{"uptime": "42"}).Return(nil).Times(1)
+	livenessService.EXPECT().Heartbeat("1", nil).Return(nil).Times(1)
+
+	handler := &Handler{
+		liveness: livenessService,
+	}
+	router := chi.NewRouter()
+	router.Post("/devices/{id}/heartbeat", handler.heartbeat)
+
+	for _, body := range []string{`{"metadata":{"uptime":"42"}}`, ``} {
+		r := httptest.NewRequest(http.MethodPost, "/devices/1/heartbeat", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code, body)
+	}
+}
+
+func TestHandlerCountLiveness(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	livenessService := deviceMock.NewMockLivenessService(ctrl)
+
+	expect := &devices.LivenessCounts{Online: 3, Offline: 1}
+	livenessService.EXPECT().CountLiveness().Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		liveness: livenessService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices/liveness", handler.countLiveness)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/liveness", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual devices.LivenessCounts
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, &actual)
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"homework/internal/devices"
+	"homework/internal/locations"
+)
+
+func (h *Handler) createLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	location, ok := h.readLocation(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.locations.CreateLocation(location)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) updateLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	location, ok := h.readLocation(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.locations.UpdateLocation(location)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) readLocation(w http.ResponseWriter, r *http.Request) (*locations.Location, bool) {
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.processError(w, "can not read request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var location locations.Location
+	err = json.Unmarshal(buf, &location)
+	if err != nil {
+		h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &location, true
+}
+
+func (h *Handler) listLocations(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	list, err := h.locations.ListLocations()
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, list)
+}
+
+func (h *Handler) getLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	location, err := h.locations.GetLocation(chi.URLParam(r, "id"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, location)
+}
+
+func (h *Handler) deleteLocation(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	err := h.locations.DeleteLocation(chi.URLParam(r, "id"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) listLocationDevices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	h.writeDevices(w, r, devices.Filter{LocationID: chi.URLParam(r, "id")})
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+	"homework/internal/locations"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerCreateLocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	locationService := deviceMock.NewMockLocationService(ctrl)
+
+	location := &locations.Location{ID: "eu", Name: "Europe", Kind: locations.KindRegion}
+	locationService.EXPECT().CreateLocation(location).Return(nil).Times(1)
+
+	handler := &Handler{
+		locations: locationService,
+	}
+	router := chi.NewRouter()
+	router.Post("/locations", handler.createLocation)
+
+	locationBytes, _ := json.Marshal(location)
+	r := httptest.NewRequest(http.MethodPost, "/locations", bytes.NewReader(locationBytes))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestHandlerDeleteLocationError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	locationService := deviceMock.NewMockLocationService(ctrl)
+
+	locationService.EXPECT().DeleteLocation("eu").Return(errors.NewConflictErrorf("in use")).Times(1)
+
+	handler := &Handler{
+		locations: locationService,
+	}
+	router := chi.NewRouter()
+	router.Delete("/locations/{id}", handler.deleteLocation)
+
+	r := httptest.NewRequest(http.MethodDelete, "/locations/eu", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestHandlerListLocationDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := []*devices.Device{{SerialNum: testSeqNum1, LocationID: "ams"}}
+	deviceService.EXPECT().ListDevices(devices.Filter{LocationID: "eu"}).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Get("/locations/{id}/devices", handler.listLocationDevices)
+
+	r := httptest.NewRequest(http.MethodGet, "/locations/eu/devices", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []*devices.Device
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+}
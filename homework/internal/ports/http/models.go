@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"homework/internal/catalog"
+	"homework/internal/devices"
+)
+
+func (h *Handler) createModel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	model, ok := h.readModel(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.models.CreateModel(model)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) updateModel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	model, ok := h.readModel(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.models.UpdateModel(model)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) readModel(w http.ResponseWriter, r *http.Request) (*catalog.Model, bool) {
+	buf, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.processError(w, "can not read request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var model catalog.Model
+	err = json.Unmarshal(buf, &model)
+	if err != nil {
+		h.processError(w, "can not unmarshal request body", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return &model, true
+}
+
+func (h *Handler) listModels(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	list, err := h.models.ListModels()
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, list)
+}
+
+func (h *Handler) getModel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	model, err := h.models.GetModel(chi.URLParam(r, "name"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, model)
+}
+
+func (h *Handler) deleteModel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	err := h.models.DeleteModel(chi.URLParam(r, "name"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) listModelDevices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	h.writeDevices(w, r, devices.Filter{Model: chi.URLParam(r, "name")})
+}
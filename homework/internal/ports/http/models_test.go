@@ -0,0 +1,97 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/catalog"
+	"homework/internal/devices"
+	"homework/internal/errors"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerCreateModel(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	modelService := deviceMock.NewMockModelService(ctrl)
+
+	model := &catalog.Model{Name: "RT-100", Vendor: "acme", Specs: catalog.Specs{Ports: 4}, Attributes: []string{"firmware"}}
+	modelService.EXPECT().CreateModel(model).Return(nil).Times(1)
+
+	handler := &Handler{
+		models: modelService,
+	}
+	router := chi.NewRouter()
+	router.Post("/models", handler.createModel)
+
+	modelBytes, _ := json.Marshal(model)
+	r := httptest.NewRequest(http.MethodPost, "/models", bytes.NewReader(modelBytes))
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+}
+
+func TestHandlerDeleteModelError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	modelService := deviceMock.NewMockModelService(ctrl)
+
+	modelService.EXPECT().DeleteModel("RT-100").Return(errors.NewConflictErrorf("in use")).Times(1)
+
+	handler := &Handler{
+		models: modelService,
+	}
+	router := chi.NewRouter()
+	router.Delete("/models/{name}", handler.deleteModel)
+
+	r := httptest.NewRequest(http.MethodDelete, "/models/RT-100", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestHandlerListModelDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := []*devices.Device{{SerialNum: testSeqNum1, Model: "RT 100"}}
+	deviceService.EXPECT().ListDevices(devices.Filter{Model: "RT 100"}).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Get("/models/{name}/devices", handler.listModelDevices)
+
+	r := httptest.NewRequest(http.MethodGet, "/models/RT%20100/devices", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []*devices.Device
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+}
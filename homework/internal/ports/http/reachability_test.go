@@ -0,0 +1,50 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	deviceMock "homework/internal/mocks"
+	"homework/internal/reachability"
+)
+
+func TestHandlerGetReachability(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	reachabilityService := deviceMock.NewMockReachabilityService(ctrl)
+
+	expect := &reachability.Report{
+		SerialNum: "1",
+		Address:   "127.0.0.1:22",
+		Reachable: true,
+		History:   []reachability.Result{{Reachable: true, LatencyMS: 0.5}},
+	}
+	reachabilityService.EXPECT().Report("1").Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		reachability: reachabilityService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices/{id}/reachability", handler.getReachability)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/1/reachability", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual reachability.Report
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect.History[0].LatencyMS, actual.History[0].LatencyMS)
+	require.Equal(t, expect.Address, actual.Address)
+}
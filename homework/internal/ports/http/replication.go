@@ -0,0 +1,107 @@
+package http
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"homework/internal/replication"
+)
+
+const (
+	defaultChangesWait = 20 * time.Second
+	// changesWaitMargin keeps a long poll within the write timeout.
+	changesWaitMargin = time.Second
+)
+
+func (h *Handler) getCheckpoint(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	checkpoint, err := h.source.Checkpoint()
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, checkpoint)
+}
+
+// getChanges waits for changes after the position for up to wait, a
+// position the log no longer holds is answered with 410 Gone.
+func (h *Handler) getChanges(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	query := r.URL.Query()
+
+	after, err := strconv.ParseUint(query.Get("after"), 10, 64)
+	if err != nil {
+		h.processError(w, "'after' must be a log position", http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if query.Get("limit") != "" {
+		if limit, err = strconv.Atoi(query.Get("limit")); err != nil || limit < 1 {
+			h.processError(w, "'limit' must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	wait := defaultChangesWait
+	if query.Get("wait") != "" {
+		if wait, err = time.ParseDuration(query.Get("wait")); err != nil || wait < 0 {
+			h.processError(w, "'wait' must be a duration such as 30s", http.StatusBadRequest)
+			return
+		}
+	}
+	if maxWait := maxChangesWait(time.Duration(h.timeouts.write.Load())); wait > maxWait {
+		wait = maxWait
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), wait)
+	defer cancel()
+
+	batch, err := h.source.Changes(ctx, query.Get("log"), after, limit)
+	if stderrors.Is(err, replication.ErrCheckpointRequired) {
+		h.processError(w, err.Error(), http.StatusGone)
+		return
+	}
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.writeJSON(w, batch)
+}
+
+// maxChangesWait keeps a long poll within the write timeout. Timeouts too
+// short for the margin still get half of them, a wait of zero would answer
+// every poll at once.
+func maxChangesWait(writeTimeout time.Duration) time.Duration {
+	if maxWait := writeTimeout - changesWaitMargin; maxWait >= writeTimeout/2 {
+		return maxWait
+	}
+
+	return writeTimeout / 2
+}
+
+func (h *Handler) getReplicationStatus(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	h.writeJSON(w, h.replica.Status())
+}
+
+// redirectWrites sends every request that may write to the leader, with
+// 307 so clients repeat the method and body there.
+func (h *Handler) redirectWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			http.Redirect(w, r, h.leader+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	deviceMock "homework/internal/mocks"
+	"homework/internal/replication"
+)
+
+func TestHandlerGetChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	source := deviceMock.NewMockSource(ctrl)
+
+	expect := &replication.Batch{LogID: "log", Seq: 3, Entries: []replication.Entry{
+		{Seq: 3, Op: replication.OpPut, SerialNum: "1", Device: &devices.Device{SerialNum: "1"}},
+	}}
+	source.EXPECT().Changes(gomock.Any(), "log", uint64(2), 10).Return(expect, nil).Times(1)
+	source.EXPECT().Changes(gomock.Any(), "old", uint64(2), 0).Return(nil, replication.ErrCheckpointRequired).Times(1)
+
+	handler := &Handler{
+		source:   source,
+		timeouts: &timeouts{},
+	}
+	handler.SetTimeouts(0, 0)
+	router := chi.NewRouter()
+	router.Get("/replication/changes", handler.getChanges)
+
+	cases := []struct {
+		url  string
+		code int
+	}{
+		{url: "/replication/changes?log=log&after=2&limit=10&wait=1ms", code: http.StatusOK},
+		{url: "/replication/changes?log=old&after=2", code: http.StatusGone},
+		{url: "/replication/changes?log=log", code: http.StatusBadRequest},
+		{url: "/replication/changes?log=log&after=2&wait=soon", code: http.StatusBadRequest},
+	}
+
+	for _, tCase := range cases {
+		r := httptest.NewRequest(http.MethodGet, tCase.url, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+		require.Equal(t, tCase.code, w.Code, tCase.url)
+
+		if tCase.code == http.StatusOK {
+			var actual replication.Batch
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			require.Equal(t, expect.Entries[0].Device, actual.Entries[0].Device)
+			require.Equal(t, expect.Seq, actual.Seq)
+		}
+	}
+}
+
+func TestMaxChangesWait(t *testing.T) {
+	require.Equal(t, 29*time.Second, maxChangesWait(30*time.Second))
+	require.Equal(t, time.Second, maxChangesWait(2*time.Second))
+	require.Equal(t, 750*time.Millisecond, maxChangesWait(1500*time.Millisecond))
+	require.Equal(t, 250*time.Millisecond, maxChangesWait(500*time.Millisecond))
+}
+
+func TestHandlerRedirectWrites(t *testing.T) {
+	handler := &Handler{
+		leader: "http://leader:8080",
+	}
+	router := chi.NewRouter()
+	router.Use(handler.redirectWrites)
+	router.Get("/devices", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+	router.Post("/devices", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusOK) })
+
+	r := httptest.NewRequest(http.MethodGet, "/devices", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	r = httptest.NewRequest(http.MethodPost, "/devices?x=1", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	require.Equal(t, "http://leader:8080/devices?x=1", w.Header().Get("location"))
+}
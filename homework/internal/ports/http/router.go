@@ -3,11 +3,19 @@ package http
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
 
 	"homework/internal/app"
+	"homework/internal/graphql"
+	"homework/internal/idempotency"
+	"homework/internal/ports/web"
+	"homework/internal/raft"
+	"homework/internal/replication"
 )
 
 const (
@@ -17,13 +25,76 @@ const (
 
 type Handler struct {
 	service      app.Service
+	ipam         app.IPAMService
+	locations    app.LocationService
+	models       app.ModelService
+	liveness     app.LivenessService
+	reachability app.ReachabilityService
+	versions     app.VersionService
+	backup       app.BackupService
+	source       replication.Source
+	replica      replication.Replica
+	leader       string
+	cluster      raft.Cluster
+	secret       string
+	tracer       trace.Tracer
+	idempotency  *idempotency.Store
+	schema       *graphql.Schema
+	limits       graphql.Options
+	ui           bool
+	tenants      map[string]*Handler
+	apiKeys      *apiKeys
 	fullAddress  string
-	readTimeout  time.Duration
-	writeTimeout time.Duration
+	timeouts     *timeouts
+}
+
+type timeouts struct {
+	read  atomic.Int64
+	write atomic.Int64
+}
+
+// apiKeys maps the keys accepted in the X-API-Key header to tenants, the
+// map is replaced as a whole when the keys are reloaded.
+type apiKeys struct {
+	keys atomic.Pointer[map[string]string]
 }
 
 type Config struct {
 	Service      app.Service
+	IPAM         app.IPAMService
+	Locations    app.LocationService
+	Models       app.ModelService
+	Liveness     app.LivenessService
+	Reachability app.ReachabilityService
+	Versions     app.VersionService
+	Backup       app.BackupService
+	// ReplicationSource serves the change log to followers.
+	ReplicationSource replication.Source
+	// Replica and Leader are set on followers, writes are redirected to
+	// the leader base url.
+	Replica replication.Replica
+	Leader  string
+	// Cluster is the raft node of a clustered repository, writes are
+	// redirected to the current leader.
+	Cluster raft.Cluster
+	// ClusterSecret must be sent by other nodes in the X-Cluster-Secret
+	// header of raft messages.
+	ClusterSecret string
+	// Tracer records a span per request, nil disables tracing.
+	Tracer trace.Tracer
+	// Idempotency keeps the responses of POST requests sent with an
+	// Idempotency-Key header, nil ignores the header.
+	Idempotency *idempotency.Store
+	// GraphQL serves the services at /graphql with these limits, nil
+	// disables it.
+	GraphQL *graphql.Options
+	// UI serves the web UI under /ui/.
+	UI bool
+	// Tenants serves every tenant with its own services under
+	// /tenants/{tenant}, the services above are unused then.
+	Tenants map[string]*Config
+	// APIKeys maps the keys accepted in the X-API-Key header to tenants.
+	APIKeys      map[string]string
 	Port         string
 	Host         string
 	ReadTimeout  time.Duration
@@ -31,40 +102,233 @@ type Config struct {
 }
 
 func NewHandler(config *Config) Handler {
-	readTimeout := config.ReadTimeout
+	fullAddress := fmt.Sprintf("%s:%s", config.Host, config.Port)
+
+	handler := Handler{
+		service:      config.Service,
+		ipam:         config.IPAM,
+		locations:    config.Locations,
+		models:       config.Models,
+		liveness:     config.Liveness,
+		reachability: config.Reachability,
+		versions:     config.Versions,
+		backup:       config.Backup,
+		source:       config.ReplicationSource,
+		replica:      config.Replica,
+		leader:       strings.TrimSuffix(config.Leader, "/"),
+		cluster:      config.Cluster,
+		secret:       config.ClusterSecret,
+		tracer:       config.Tracer,
+		idempotency:  config.Idempotency,
+		ui:           config.UI,
+		fullAddress:  fullAddress,
+		timeouts:     &timeouts{},
+		apiKeys:      &apiKeys{},
+	}
+	handler.SetTimeouts(config.ReadTimeout, config.WriteTimeout)
+	handler.SetAPIKeys(config.APIKeys)
+
+	// Tenants get their schema from their own config.
+	if config.GraphQL != nil && config.Tenants == nil {
+		schema, err := handler.newSchema()
+		if err != nil {
+			// The schema is fixed, an error is a bug of the schema.
+			panic(err)
+		}
+		handler.schema = schema
+		handler.limits = *config.GraphQL
+	}
+
+	if config.Tenants != nil {
+		handler.tenants = make(map[string]*Handler, len(config.Tenants))
+		for tenant, tenantConfig := range config.Tenants {
+			tenantHandler := NewHandler(tenantConfig)
+			handler.tenants[tenant] = &tenantHandler
+		}
+	}
+
+	return handler
+}
+
+// SetTimeouts changes the read and write timeouts of a running server,
+// they take effect starting from the next request.
+func (h *Handler) SetTimeouts(readTimeout, writeTimeout time.Duration) {
 	if readTimeout < 1 {
 		readTimeout = defaultReadTimeout
 	}
 
-	writeTimeout := config.WriteTimeout
 	if writeTimeout < 1 {
 		writeTimeout = defaultWriteTimeout
 	}
 
-	fullAddress := fmt.Sprintf("%s:%s", config.Host, config.Port)
+	h.timeouts.read.Store(int64(readTimeout))
+	h.timeouts.write.Store(int64(writeTimeout))
+}
 
-	return Handler{
-		service:      config.Service,
-		writeTimeout: writeTimeout,
-		readTimeout:  readTimeout,
-		fullAddress:  fullAddress,
-	}
+// SetAPIKeys replaces the api keys of a running server, requests that
+// arrive after it returns are authenticated with the new keys.
+func (h *Handler) SetAPIKeys(keys map[string]string) {
+	h.apiKeys.keys.Store(&keys)
 }
 
 func (h *Handler) NewServer() *http.Server {
 	mux := chi.NewRouter()
+	mux.Use(h.deadlines)
+	if h.tracer != nil {
+		mux.Use(h.trace)
+	}
+	if h.leader != "" {
+		mux.Use(h.redirectWrites)
+	}
+	if h.cluster != nil {
+		mux.Use(h.redirectToLeader)
+	}
 
-	mux.Route("/", func(r chi.Router) {
-		r.Post("/devices", h.createDevice)
-		r.Get("/devices/{id}", h.getDevice)
-		r.Delete("/devices/{id}", h.deleteDevice)
-		r.Put("/devices", h.updateDevice)
-	})
+	// The UI calls the api with the api key of the user, so it is served
+	// the same with and without tenants.
+	if h.ui {
+		mux.Mount("/ui", web.Handler())
+	}
+
+	if h.tenants != nil {
+		h.mountTenants(mux)
+	} else {
+		mux.Mount("/", h.routes())
+	}
 
 	return &http.Server{
 		Addr:         h.fullAddress,
 		Handler:      mux,
-		ReadTimeout:  h.readTimeout,
-		WriteTimeout: h.writeTimeout,
+		ReadTimeout:  time.Duration(h.timeouts.read.Load()),
+		WriteTimeout: time.Duration(h.timeouts.write.Load()),
+	}
+}
+
+func (h *Handler) routes() chi.Router {
+	r := chi.NewRouter()
+	if h.idempotency != nil {
+		r.Use(h.idempotent)
+	}
+
+	r.Post("/devices", h.createDevice)
+	r.Get("/devices", h.listDevices)
+	// by-ip, search and liveness take precedence over /devices/{id}, the
+	// service does not accept them as serial numbers.
+	r.Get("/devices/by-ip/{ip}", h.getDevicesByIP)
+	r.Get("/devices/search", h.searchDevices)
+	r.Get("/devices/{id}", h.getDevice)
+	r.Delete("/devices/{id}", h.deleteDevice)
+	r.Put("/devices", h.updateDevice)
+	r.Post("/devices/{id}/transitions", h.transitionDevice)
+	r.Get("/devices/{id}/transitions", h.listTransitions)
+
+	if h.liveness != nil {
+		r.Get("/devices/liveness", h.countLiveness)
+		r.Post("/devices/{id}/heartbeat", h.heartbeat)
+		r.Get("/devices/{id}/heartbeat", h.getHeartbeat)
+	}
+
+	if h.reachability != nil {
+		r.Get("/devices/{id}/reachability", h.getReachability)
+	}
+
+	if h.versions != nil {
+		r.Get("/devices/{id}/versions", h.listVersions)
+		r.Get("/devices/{id}/versions/diff", h.diffVersions)
+	}
+
+	if h.source != nil {
+		r.Get("/replication/checkpoint", h.getCheckpoint)
+		r.Get("/replication/changes", h.getChanges)
+	}
+
+	if h.replica != nil {
+		r.Get("/replication/status", h.getReplicationStatus)
+	}
+
+	if h.cluster != nil {
+		r.Post(raft.MessagePath, h.stepMessage)
 	}
+
+	if h.schema != nil {
+		r.Post("/graphql", h.postGraphQL)
+		r.Get("/graphql", h.getGraphQL)
+		r.Get("/graphql/schema", h.getGraphQLSchema)
+	}
+
+	if h.ipam != nil {
+		r.Post("/subnets", h.createSubnet)
+		r.Get("/subnets", h.listSubnets)
+		r.Get("/subnets/utilization", h.listUtilization)
+		r.Get("/subnets/{id}", h.getSubnet)
+		r.Delete("/subnets/{id}", h.deleteSubnet)
+		r.Post("/subnets/{id}/pools", h.addPool)
+		r.Get("/subnets/{id}/utilization", h.getUtilization)
+	}
+
+	if h.locations != nil {
+		r.Post("/locations", h.createLocation)
+		r.Get("/locations", h.listLocations)
+		r.Put("/locations", h.updateLocation)
+		r.Get("/locations/{id}", h.getLocation)
+		r.Delete("/locations/{id}", h.deleteLocation)
+		r.Get("/locations/{id}/devices", h.listLocationDevices)
+	}
+
+	if h.models != nil {
+		r.Post("/models", h.createModel)
+		r.Get("/models", h.listModels)
+		r.Put("/models", h.updateModel)
+		r.Get("/models/{name}", h.getModel)
+		r.Delete("/models/{name}", h.deleteModel)
+		r.Get("/models/{name}/devices", h.listModelDevices)
+	}
+
+	return r
+}
+
+// AdminRoutes serves the routes that must not be reachable by api clients,
+// they are mounted on the admin listener. Tenants are served under
+// /tenants/{tenant} there without an api key.
+func (h *Handler) AdminRoutes() chi.Router {
+	r := chi.NewRouter()
+
+	if h.tenants == nil {
+		h.adminRoutes(r)
+		return r
+	}
+
+	for tenant, handler := range h.tenants {
+		r.Route("/tenants/"+tenant, handler.adminRoutes)
+	}
+
+	return r
+}
+
+func (h *Handler) adminRoutes(r chi.Router) {
+	if h.backup != nil {
+		r.Get("/admin/backup", h.backupDevices)
+		r.Post("/admin/restore", h.restoreDevices)
+	}
+
+	if h.cluster != nil {
+		r.Get("/admin/cluster", h.getClusterStatus)
+		r.Post("/admin/cluster/members", h.addMember)
+		r.Delete("/admin/cluster/members/{id}", h.removeMember)
+	}
+}
+
+// deadlines applies the current timeouts to every request. The server
+// timeouts are fixed at start-up and only bound reading the request
+// headers, the connection deadlines set here override them afterwards.
+func (h *Handler) deadlines(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		rc := http.NewResponseController(w)
+
+		_ = rc.SetReadDeadline(now.Add(time.Duration(h.timeouts.read.Load())))
+		_ = rc.SetWriteDeadline(now.Add(time.Duration(h.timeouts.write.Load())))
+
+		next.ServeHTTP(w, r)
+	})
 }
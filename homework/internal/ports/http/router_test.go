@@ -84,3 +84,13 @@ func TestNewHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerSetTimeouts(t *testing.T) {
+	handler := NewHandler(&Config{ReadTimeout: time.Second, WriteTimeout: time.Second})
+	require.Equal(t, time.Second, handler.NewServer().ReadTimeout)
+
+	handler.SetTimeouts(2*time.Second, 0)
+
+	require.Equal(t, int64(2*time.Second), handler.timeouts.read.Load())
+	require.Equal(t, int64(defaultWriteTimeout), handler.timeouts.write.Load())
+}
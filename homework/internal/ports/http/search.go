@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+)
+
+func (h *Handler) searchDevices(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	query := r.URL.Query()
+
+	var limit int
+	if s := query.Get("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil {
+			h.processError(w, "'limit' must be a number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	hits, err := h.deviceService(r).SearchDevices(query.Get("q"), limit)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, hits)
+}
@@ -0,0 +1,51 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerSearchDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	deviceService := deviceMock.NewMockService(ctrl)
+
+	expect := []devices.SearchHit{{Device: &devices.Device{SerialNum: testSeqNum1, Model: testModel1}, Score: 2}}
+	deviceService.EXPECT().SearchDevices("model 1", 5).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		service: deviceService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices/search", handler.searchDevices)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/search?q=model+1&limit=5", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []devices.SearchHit
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+
+	r = httptest.NewRequest(http.MethodGet, "/devices/search?q=x&limit=many", nil)
+	w = httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
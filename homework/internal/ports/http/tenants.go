@@ -0,0 +1,78 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// mountTenants serves each tenant under /tenants/{tenant} and, for callers
+// with an api key, at the root with the tenant of the key.
+func (h *Handler) mountTenants(mux chi.Router) {
+	routers := make(map[string]http.Handler, len(h.tenants))
+	for tenant, handler := range h.tenants {
+		routers[tenant] = handler.routes()
+	}
+
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/json")
+
+		tenant, code, msg := h.resolveTenant(r)
+		if code != http.StatusOK {
+			h.processError(w, msg, code)
+			return
+		}
+
+		router, ok := routers[tenant]
+		if !ok {
+			h.processError(w, "tenant "+tenant+" not found", http.StatusNotFound)
+			return
+		}
+
+		router.ServeHTTP(w, r)
+	}
+
+	mux.Mount("/tenants/{tenant}", http.HandlerFunc(serve))
+	mux.Mount("/", http.HandlerFunc(serve))
+}
+
+// resolveTenant returns the tenant named by the path prefix or, without
+// one, the tenant of the api key. When api keys are configured the caller
+// must present a key of the tenant it addresses.
+func (h *Handler) resolveTenant(r *http.Request) (string, int, string) {
+	keys := *h.apiKeys.keys.Load()
+
+	keyTenant, authenticated := "", false
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		if keyTenant, authenticated = lookupKey(keys, key); !authenticated {
+			return "", http.StatusUnauthorized, "invalid api key"
+		}
+	}
+
+	tenant := chi.URLParam(r, "tenant")
+	switch {
+	case tenant == "" && !authenticated:
+		return "", http.StatusUnauthorized, "api key or tenant path prefix is required"
+	case tenant == "":
+		return keyTenant, http.StatusOK, ""
+	case authenticated && keyTenant != tenant:
+		return "", http.StatusForbidden, "api key is not valid for tenant " + tenant
+	case !authenticated && len(keys) > 0:
+		return "", http.StatusUnauthorized, "api key is required"
+	}
+
+	return tenant, http.StatusOK, ""
+}
+
+func lookupKey(keys map[string]string, key string) (string, bool) {
+	for candidate, tenant := range keys {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(key)) == 1 {
+			return tenant, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestTenantRouting(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	opsService := deviceMock.NewMockService(ctrl)
+	labService := deviceMock.NewMockService(ctrl)
+
+	opsService.EXPECT().GetDevice("1").Return(&devices.Device{SerialNum: "1"}, nil).Times(2)
+	labService.EXPECT().GetDevice("1").Return(&devices.Device{SerialNum: "1"}, nil).Times(1)
+
+	handler := NewHandler(&Config{
+		Tenants: map[string]*Config{
+			"ops": {Service: opsService},
+			"lab": {Service: labService},
+		},
+		APIKeys: map[string]string{"ops-key": "ops", "lab-key": "lab"},
+	})
+	server := handler.NewServer()
+
+	cases := []struct {
+		name string
+		path string
+		key  string
+		code int
+	}{
+		{name: "key", path: "/devices/1", key: "ops-key", code: http.StatusOK},
+		{name: "prefix and key", path: "/tenants/ops/devices/1", key: "ops-key", code: http.StatusOK},
+		{name: "other tenant", path: "/tenants/lab/devices/1", key: "lab-key", code: http.StatusOK},
+		{name: "key of another tenant", path: "/tenants/lab/devices/1", key: "ops-key", code: http.StatusForbidden},
+		{name: "prefix without key", path: "/tenants/ops/devices/1", code: http.StatusUnauthorized},
+		{name: "no tenant", path: "/devices/1", code: http.StatusUnauthorized},
+		{name: "invalid key", path: "/devices/1", key: "guess", code: http.StatusUnauthorized},
+	}
+
+	for _, tCase := range cases {
+		r := httptest.NewRequest(http.MethodGet, tCase.path, nil)
+		if tCase.key != "" {
+			r.Header.Set(apiKeyHeader, tCase.key)
+		}
+		w := httptest.NewRecorder()
+
+		server.Handler.ServeHTTP(w, r)
+
+		require.Equal(t, tCase.code, w.Code, tCase.name)
+	}
+}
+
+func TestTenantRoutingWithoutKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	opsService := deviceMock.NewMockService(ctrl)
+
+	opsService.EXPECT().ListDevices(devices.Filter{}).Return(nil, nil).Times(1)
+
+	handler := NewHandler(&Config{
+		Tenants: map[string]*Config{"ops": {Service: opsService}},
+	})
+	server := handler.NewServer()
+
+	r := httptest.NewRequest(http.MethodGet, "/tenants/ops/devices", nil)
+	w := httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "/tenants/lab/devices", nil)
+	w = httptest.NewRecorder()
+	server.Handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTenantKeysReload(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	opsService := deviceMock.NewMockService(ctrl)
+
+	opsService.EXPECT().GetDevice("1").Return(&devices.Device{SerialNum: "1"}, nil).Times(2)
+
+	handler := NewHandler(&Config{
+		Tenants: map[string]*Config{"ops": {Service: opsService}},
+		APIKeys: map[string]string{"old-key": "ops"},
+	})
+	server := handler.NewServer()
+
+	get := func(key string) int {
+		r := httptest.NewRequest(http.MethodGet, "/devices/1", nil)
+		r.Header.Set(apiKeyHeader, key)
+		w := httptest.NewRecorder()
+		server.Handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusOK, get("old-key"))
+
+	// The running server picks up the new keys.
+	handler.SetAPIKeys(map[string]string{"new-key": "ops"})
+
+	require.Equal(t, http.StatusUnauthorized, get("old-key"))
+	require.Equal(t, http.StatusOK, get("new-key"))
+}
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"homework/internal/app"
+	"homework/internal/tracing"
+)
+
+// deviceService binds the service to the request, so its spans join the
+// trace of the request.
+func (h *Handler) deviceService(r *http.Request) app.Service {
+	return h.serviceFor(r.Context())
+}
+
+// serviceFor binds the device service to the context, resolvers of the
+// GraphQL schema use it as they only get the context of the request.
+func (h *Handler) serviceFor(ctx context.Context) app.Service {
+	if binder, ok := h.service.(app.ContextBinder); ok {
+		return binder.WithContext(ctx)
+	}
+
+	return h.service
+}
+
+// trace records a server span per request named after the route pattern.
+// A valid traceparent header makes the span a child of the caller's span.
+func (h *Handler) trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := h.tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path)))
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		tracing.Propagator.Inject(ctx, propagation.HeaderCarrier(sw.Header()))
+
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		// The pattern is known once chi routed the request.
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+
+		var err error
+		if sw.status >= http.StatusInternalServerError {
+			err = statusError(sw.status)
+		}
+		tracing.End(span, err)
+	})
+}
+
+// statusError fails the span of a request answered with a server error,
+// the status code is its error type.
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("%d %s", int(e), http.StatusText(int(e)))
+}
+
+func (e statusError) ErrorType() string {
+	return strconv.Itoa(int(e))
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
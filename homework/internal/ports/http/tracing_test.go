@@ -0,0 +1,66 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"homework/internal/tracing"
+)
+
+func TestHandlerTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	handler := &Handler{tracer: provider.Tracer("test")}
+
+	router := chi.NewRouter()
+	router.Use(handler.trace)
+	router.Get("/devices/{id}", func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, trace.SpanFromContext(r.Context()).IsRecording())
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Post("/devices", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/123", nil)
+	r.Header.Set(tracing.TraceparentHeader, parent)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	r = httptest.NewRequest(http.MethodPost, "/devices", nil)
+	r.Header.Set(tracing.TraceparentHeader, "garbage")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "GET /devices/{id}", spans[0].Name())
+	require.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	require.Contains(t, spans[0].Attributes(), attribute.String("http.route", "/devices/{id}"))
+	require.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	require.Equal(t, "POST /devices", spans[1].Name())
+	require.False(t, spans[1].Parent().IsValid())
+	require.Contains(t, spans[1].Attributes(), attribute.String("error.type", "500"))
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+
+	sc := spans[1].SpanContext()
+	require.Equal(t, "00-"+sc.TraceID().String()+"-"+sc.SpanID().String()+"-01", w.Header().Get(tracing.TraceparentHeader))
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) listVersions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	list, err := h.versions.ListVersions(chi.URLParam(r, "id"))
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, list)
+}
+
+func (h *Handler) diffVersions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+
+	query := r.URL.Query()
+
+	from, err := strconv.Atoi(query.Get("from"))
+	if err != nil {
+		h.processError(w, "'from' must be a version number", http.StatusBadRequest)
+		return
+	}
+
+	to, err := strconv.Atoi(query.Get("to"))
+	if err != nil {
+		h.processError(w, "'to' must be a version number", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.versions.DiffVersions(chi.URLParam(r, "id"), from, to)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, changes)
+}
+
+// getDeviceAsOf serves GET /devices/{id}?as_of=<RFC 3339 timestamp>.
+func (h *Handler) getDeviceAsOf(w http.ResponseWriter, id, asOf string) {
+	if h.versions == nil {
+		h.processError(w, "'as_of' requires device versions to be enabled", http.StatusBadRequest)
+		return
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, asOf)
+	if err != nil {
+		h.processError(w, "'as_of' must be an RFC 3339 timestamp", http.StatusBadRequest)
+		return
+	}
+
+	device, err := h.versions.GetDeviceAsOf(id, at)
+	if err != nil {
+		h.processError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, device)
+}
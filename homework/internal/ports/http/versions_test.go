@@ -0,0 +1,120 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	deviceMock "homework/internal/mocks"
+)
+
+func TestHandlerListVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	versionService := deviceMock.NewMockVersionService(ctrl)
+
+	expect := []devices.Version{
+		{SerialNum: "1", Number: 1, At: time.Unix(10, 0).UTC(), Device: &devices.Device{SerialNum: "1", IP: "10.0.0.1"}},
+		{SerialNum: "1", Number: 2, At: time.Unix(20, 0).UTC(), Deleted: true},
+	}
+	versionService.EXPECT().ListVersions("1").Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		versions: versionService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices/{id}/versions", handler.listVersions)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/1/versions", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var actual []devices.Version
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+	require.Equal(t, expect, actual)
+}
+
+func TestHandlerDiffVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	versionService := deviceMock.NewMockVersionService(ctrl)
+
+	expect := []devices.Change{{Field: "ip", From: "10.0.0.1", To: "10.0.0.2"}}
+	versionService.EXPECT().DiffVersions("1", 1, 2).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		versions: versionService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices/{id}/versions/diff", handler.diffVersions)
+
+	cases := []struct {
+		url  string
+		code int
+	}{
+		{url: "/devices/1/versions/diff?from=1&to=2", code: http.StatusOK},
+		{url: "/devices/1/versions/diff?from=1", code: http.StatusBadRequest},
+		{url: "/devices/1/versions/diff?from=x&to=2", code: http.StatusBadRequest},
+	}
+
+	for _, tCase := range cases {
+		r := httptest.NewRequest(http.MethodGet, tCase.url, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+		require.Equal(t, tCase.code, w.Code, tCase.url)
+
+		if tCase.code == http.StatusOK {
+			var actual []devices.Change
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+			require.Equal(t, expect, actual)
+		}
+	}
+}
+
+func TestHandlerGetDeviceAsOf(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	versionService := deviceMock.NewMockVersionService(ctrl)
+
+	at := time.Date(2024, 5, 14, 9, 30, 0, 0, time.UTC)
+	expect := &devices.Device{SerialNum: "1", IP: "10.0.0.1"}
+	versionService.EXPECT().GetDeviceAsOf("1", at).Return(expect, nil).Times(1)
+
+	handler := &Handler{
+		versions: versionService,
+	}
+	router := chi.NewRouter()
+	router.Get("/devices/{id}", handler.getDevice)
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/1?as_of=2024-05-14T09:30:00Z", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var actual devices.Device
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&actual))
+	require.Equal(t, expect, &actual)
+
+	r = httptest.NewRequest(http.MethodGet, "/devices/1?as_of=last+tuesday", nil)
+	w = httptest.NewRecorder()
+
+	router.ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
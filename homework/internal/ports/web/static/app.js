@@ -0,0 +1,361 @@
+// Device UI. Pages are picked by the location hash and every change goes
+// through the JSON api, the UI keeps no state besides the api key.
+"use strict";
+
+const keyStorage = "devices.apiKey";
+
+const view = document.getElementById("view");
+const notice = document.getElementById("notice");
+const apiKeyInput = document.getElementById("api-key");
+
+apiKeyInput.value = localStorage.getItem(keyStorage) || "";
+apiKeyInput.addEventListener("change", () => {
+  localStorage.setItem(keyStorage, apiKeyInput.value);
+  route();
+});
+
+// pendingNotice is shown on the page navigate goes to.
+let pendingNotice = null;
+
+window.addEventListener("hashchange", () => {
+  hideNotice();
+  if (pendingNotice) {
+    showNotice(pendingNotice, true);
+    pendingNotice = null;
+  }
+  route();
+});
+route();
+
+// api calls the JSON api and fails with the message of its error body.
+async function api(method, path, body) {
+  const headers = {};
+  if (apiKeyInput.value) {
+    headers["X-API-Key"] = apiKeyInput.value;
+  }
+  if (body !== undefined) {
+    headers["Content-Type"] = "application/json";
+  }
+
+  const response = await fetch(path, {
+    method: method,
+    headers: headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+
+  const text = await response.text();
+  let value = null;
+  if (text) {
+    try {
+      value = JSON.parse(text);
+    } catch (e) {
+      value = null;
+    }
+  }
+
+  if (!response.ok) {
+    const message = value && value.message ? value.message : response.status + " " + response.statusText;
+    throw new Error(message);
+  }
+
+  return value;
+}
+
+function devicePath(serialNum) {
+  return "/devices/" + encodeURIComponent(serialNum);
+}
+
+function route() {
+  const [path, query] = location.hash.replace(/^#/, "").split("?");
+  const parts = (path || "/").split("/").filter(Boolean).map(decodeURIComponent);
+
+  let page;
+  if (parts.length === 0) {
+    page = listPage(new URLSearchParams(query || ""));
+  } else if (parts.length === 1 && parts[0] === "new") {
+    page = formPage(null);
+  } else if (parts[0] === "devices" && parts.length === 2) {
+    page = detailPage(parts[1]);
+  } else if (parts[0] === "devices" && parts.length === 3 && parts[2] === "edit") {
+    page = formPage(parts[1]);
+  } else {
+    page = Promise.resolve(el("p", {}, "Page not found."));
+  }
+
+  page.then(
+    (node) => view.replaceChildren(node),
+    (err) => {
+      view.replaceChildren();
+      showNotice(err.message);
+    },
+  );
+}
+
+// el builds an element, text is always set as text and never as html.
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [name, value] of Object.entries(attrs || {})) {
+    if (name.startsWith("on")) {
+      node.addEventListener(name.slice(2), value);
+    } else if (value === true) {
+      node.setAttribute(name, "");
+    } else if (value !== false && value !== undefined && value !== null) {
+      node.setAttribute(name, value);
+    }
+  }
+  for (const child of children) {
+    node.append(child instanceof Node ? child : String(child));
+  }
+
+  return node;
+}
+
+function navigate(hash, message) {
+  pendingNotice = message;
+  location.hash = hash;
+}
+
+function showNotice(message, ok) {
+  notice.textContent = message;
+  notice.className = ok ? "ok" : "";
+  notice.hidden = false;
+}
+
+function hideNotice() {
+  notice.hidden = true;
+}
+
+// Pages.
+
+async function listPage(params) {
+  const q = params.get("q") || "";
+  const filter = params.get("filter") || "";
+
+  let list;
+  if (q) {
+    const hits = await api("GET", "/devices/search?" + new URLSearchParams({ q: q, limit: "100" }));
+    list = (hits || []).map((hit) => hit.device);
+  } else {
+    list = await api("GET", "/devices?" + new URLSearchParams(filter ? { filter: filter } : {}));
+  }
+  list = list || [];
+
+  const qInput = el("input", { name: "q", type: "search", placeholder: "Search serial, model, ip, labels", value: q });
+  const filterInput = el("input", { name: "filter", placeholder: 'Filter, e.g. model == "RT-100"', value: filter });
+  const form = el("form", {
+    class: "search",
+    onsubmit: (event) => {
+      event.preventDefault();
+      const query = new URLSearchParams();
+      if (qInput.value) query.set("q", qInput.value);
+      if (filterInput.value) query.set("filter", filterInput.value);
+      location.hash = "#/" + (query.toString() ? "?" + query : "");
+    },
+  }, qInput, filterInput, el("button", { type: "submit" }, "Search"));
+
+  if (list.length === 0) {
+    return el("section", {}, form, el("p", { class: "muted" }, "No devices."));
+  }
+
+  const rows = list.map((device) => el("tr", {},
+    el("td", {}, el("a", { href: "#" + devicePath(device.serial_num) }, device.serial_num)),
+    el("td", {}, device.model || ""),
+    el("td", {}, device.ip || ""),
+    el("td", {}, device.status || ""),
+    el("td", {}, device.location_id || ""),
+  ));
+
+  return el("section", {}, form,
+    el("table", {},
+      el("thead", {}, el("tr", {}, ...["Serial", "Model", "IP", "Status", "Location"].map((name) => el("th", {}, name)))),
+      el("tbody", {}, ...rows)),
+    el("p", { class: "muted" }, list.length + " device(s)"));
+}
+
+async function detailPage(serialNum) {
+  const device = await api("GET", devicePath(serialNum));
+
+  const dialog = el("dialog", {},
+    el("p", {}, "Delete device " + device.serial_num + "? This can not be undone."),
+    el("form", { method: "dialog", class: "actions" },
+      el("button", { value: "cancel" }, "Cancel"),
+      el("button", { value: "delete", class: "danger" }, "Delete")));
+  dialog.addEventListener("close", async () => {
+    if (dialog.returnValue !== "delete") {
+      return;
+    }
+    try {
+      await api("DELETE", devicePath(device.serial_num));
+      navigate("#/", "Device " + device.serial_num + " deleted.");
+    } catch (err) {
+      showNotice(err.message);
+    }
+  });
+
+  const fields = [
+    ["Serial", device.serial_num],
+    ["Model", device.model],
+    ["IP", device.ip],
+    ["Status", device.status],
+    ["Location", device.location_id],
+    ["Position", device.position],
+    ["Labels", formatPairs(device.labels)],
+    ["Attributes", formatPairs(device.attributes)],
+  ];
+
+  return el("section", {},
+    el("h2", {}, device.serial_num),
+    el("dl", {}, ...fields.flatMap(([name, value]) => [el("dt", {}, name), el("dd", {}, value || "")])),
+    el("div", { class: "actions" },
+      el("a", { class: "button", href: "#" + devicePath(device.serial_num) + "/edit" }, "Edit"),
+      el("button", { class: "danger", type: "button", onclick: () => dialog.showModal() }, "Delete")),
+    dialog);
+}
+
+async function formPage(serialNum) {
+  const editing = serialNum !== null;
+  const device = editing ? await api("GET", devicePath(serialNum)) : {};
+
+  const inputs = {
+    serial_num: el("input", { name: "serial_num", value: device.serial_num || "", readonly: editing, required: true }),
+    model: el("input", { name: "model", value: device.model || "" }),
+    ip: el("input", { name: "ip", value: device.ip || "", placeholder: "allocated when empty and ipam is on" }),
+    location_id: el("input", { name: "location_id", value: device.location_id || "" }),
+    position: el("input", { name: "position", type: "number", min: "0", value: device.position || "" }),
+    labels: el("textarea", { name: "labels", placeholder: "one key=value per line" }),
+    attributes: el("textarea", { name: "attributes", placeholder: "one key=value per line" }),
+  };
+  inputs.labels.value = formatPairs(device.labels, "\n");
+  inputs.attributes.value = formatPairs(device.attributes, "\n");
+
+  const errors = {};
+  const rows = [];
+  for (const [name, label] of [
+    ["serial_num", "Serial"],
+    ["model", "Model"],
+    ["ip", "IP"],
+    ["location_id", "Location"],
+    ["position", "Position"],
+    ["labels", "Labels"],
+    ["attributes", "Attributes"],
+  ]) {
+    errors[name] = el("div", { class: "error", hidden: true });
+    rows.push(el("label", { for: name }, label), inputs[name], errors[name]);
+    inputs[name].id = name;
+  }
+
+  const form = el("form", { class: "device", novalidate: true }, ...rows,
+    el("div", { class: "actions" },
+      el("button", { class: "primary", type: "submit" }, editing ? "Save" : "Create"),
+      el("a", { class: "button", href: editing ? "#" + devicePath(serialNum) : "#/" }, "Cancel")));
+
+  form.addEventListener("submit", async (event) => {
+    event.preventDefault();
+    hideNotice();
+
+    const result = readForm(inputs);
+    for (const name of Object.keys(errors)) {
+      const message = result.errors[name];
+      errors[name].textContent = message || "";
+      errors[name].hidden = !message;
+      inputs[name].setAttribute("aria-invalid", message ? "true" : "false");
+    }
+    if (Object.keys(result.errors).length > 0) {
+      return;
+    }
+
+    // Status changes go through transitions, an edit keeps the status.
+    if (editing) {
+      result.device.status = device.status;
+    }
+
+    try {
+      await api(editing ? "PUT" : "POST", "/devices", result.device);
+      navigate("#" + devicePath(result.device.serial_num), "Device " + result.device.serial_num + (editing ? " saved." : " created."));
+    } catch (err) {
+      showNotice(err.message);
+    }
+  });
+
+  return el("section", {}, el("h2", {}, editing ? "Edit " + serialNum : "New device"), form);
+}
+
+// readForm checks the fields the api can not explain well on its own,
+// everything else is left to the api.
+function readForm(inputs) {
+  const errors = {};
+  const device = {};
+
+  device.serial_num = inputs.serial_num.value.trim();
+  if (!device.serial_num) {
+    errors.serial_num = "Serial is required.";
+  } else if (/[\s\/?#]/.test(device.serial_num)) {
+    errors.serial_num = "Serial must not contain spaces, '/', '?' or '#'.";
+  }
+
+  device.model = inputs.model.value.trim();
+
+  device.ip = inputs.ip.value.trim();
+  if (device.ip && !isIP(device.ip)) {
+    errors.ip = "Not an IPv4 or IPv6 address.";
+  }
+
+  const locationID = inputs.location_id.value.trim();
+  if (locationID) {
+    device.location_id = locationID;
+  }
+
+  const position = inputs.position.value.trim();
+  if (position) {
+    if (!/^\d+$/.test(position)) {
+      errors.position = "Position must be a whole number of zero or more.";
+    } else {
+      device.position = Number(position);
+    }
+  }
+
+  for (const name of ["labels", "attributes"]) {
+    const parsed = parsePairs(inputs[name].value);
+    if (parsed.error) {
+      errors[name] = parsed.error;
+    } else if (Object.keys(parsed.pairs).length > 0) {
+      device[name] = parsed.pairs;
+    }
+  }
+
+  return { device: device, errors: errors };
+}
+
+function isIP(value) {
+  const v4 = value.split(".");
+  if (v4.length === 4) {
+    return v4.every((part) => /^\d{1,3}$/.test(part) && Number(part) <= 255);
+  }
+
+  return value.includes(":") && /^[0-9a-fA-F:.]+$/.test(value) && (value.match(/::/g) || []).length <= 1;
+}
+
+function parsePairs(text) {
+  const pairs = {};
+  const lines = text.split("\n").map((line) => line.trim()).filter(Boolean);
+  for (const line of lines) {
+    const i = line.indexOf("=");
+    if (i <= 0) {
+      return { error: '"' + line + '" is not a key=value pair.' };
+    }
+    const key = line.slice(0, i).trim();
+    if (key in pairs) {
+      return { error: 'Key "' + key + '" is given twice.' };
+    }
+    pairs[key] = line.slice(i + 1).trim();
+  }
+
+  return { pairs: pairs };
+}
+
+function formatPairs(pairs, separator) {
+  return Object.entries(pairs || {})
+    .sort(([a], [b]) => a.localeCompare(b))
+    .map(([key, value]) => key + "=" + value)
+    .join(separator || ", ");
+}
@@ -0,0 +1,49 @@
+// Package web serves a browser UI for listing, searching and editing
+// devices. The UI is embedded in the binary and only calls the JSON api.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed static
+var static embed.FS
+
+// contentSecurityPolicy keeps the UI to its own scripts and the api.
+const contentSecurityPolicy = "default-src 'self'; frame-ancestors 'none'"
+
+// Handler serves the UI, it is meant to be mounted under a path prefix
+// such as /ui.
+func Handler() http.Handler {
+	files, err := fs.Sub(static, "static")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.FileServer(http.FS(files))
+
+	r := chi.NewRouter()
+	r.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "*")
+
+		// Assets are linked relative to the page, so the page must be
+		// served from the prefix with a trailing slash.
+		if name == "" && !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+
+		w.Header().Set("content-security-policy", contentSecurityPolicy)
+		w.Header().Set("x-content-type-options", "nosniff")
+
+		r = r.Clone(r.Context())
+		r.URL.Path = "/" + name
+		fileServer.ServeHTTP(w, r)
+	})
+
+	return r
+}
@@ -0,0 +1,45 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	router := chi.NewRouter()
+	router.Mount("/ui", Handler())
+
+	cases := []struct {
+		url         string
+		code        int
+		contentType string
+		contains    string
+	}{
+		{url: "/ui", code: http.StatusMovedPermanently},
+		{url: "/ui/", code: http.StatusOK, contentType: "text/html; charset=utf-8", contains: `<script src="app.js" defer></script>`},
+		{url: "/ui/app.js", code: http.StatusOK, contentType: "text/javascript; charset=utf-8", contains: "async function api("},
+		{url: "/ui/style.css", code: http.StatusOK, contentType: "text/css; charset=utf-8"},
+		{url: "/ui/missing.js", code: http.StatusNotFound},
+	}
+
+	for _, tCase := range cases {
+		r := httptest.NewRequest(http.MethodGet, tCase.url, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, r)
+		require.Equal(t, tCase.code, w.Code, tCase.url)
+
+		if tCase.code == http.StatusMovedPermanently {
+			require.Equal(t, "/ui/", w.Header().Get("location"))
+		}
+		if tCase.code == http.StatusOK {
+			require.Equal(t, tCase.contentType, w.Header().Get("content-type"), tCase.url)
+			require.Equal(t, contentSecurityPolicy, w.Header().Get("content-security-policy"))
+			require.Contains(t, w.Body.String(), tCase.contains, tCase.url)
+		}
+	}
+}
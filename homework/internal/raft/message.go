@@ -0,0 +1,65 @@
+package raft
+
+type MessageType string
+
+const (
+	MsgVote     MessageType = "vote"
+	MsgVoteResp MessageType = "vote_resp"
+	// MsgApp carries entries after Index, whose term is LogTerm. Without
+	// entries it is a heartbeat.
+	MsgApp     MessageType = "app"
+	MsgAppResp MessageType = "app_resp"
+	MsgSnap    MessageType = "snap"
+)
+
+type EntryType string
+
+const (
+	EntryCommand EntryType = "command"
+	// EntryNoop is appended by a new leader to commit the entries of
+	// earlier terms.
+	EntryNoop EntryType = "noop"
+	// EntryConfig holds the members of the cluster, it takes effect as
+	// soon as it is appended to a log.
+	EntryConfig EntryType = "config"
+)
+
+type Entry struct {
+	Index uint64    `json:"index"`
+	Term  uint64    `json:"term"`
+	Type  EntryType `json:"type"`
+	Data  []byte    `json:"data,omitempty"`
+}
+
+// Snapshot replaces the log up to Index. Members maps node ids to
+// addresses as of Index.
+type Snapshot struct {
+	Index   uint64            `json:"index"`
+	Term    uint64            `json:"term"`
+	Members map[string]string `json:"members"`
+	Data    []byte            `json:"data"`
+}
+
+type Message struct {
+	Type MessageType `json:"type"`
+	From string      `json:"from"`
+	To   string      `json:"to"`
+	Term uint64      `json:"term"`
+	// Address is where the sender can be reached.
+	Address string `json:"address,omitempty"`
+	// Index and LogTerm are the last log position of a vote request, the
+	// position before Entries of an append, and the matched position of
+	// an append response.
+	Index    uint64    `json:"index,omitempty"`
+	LogTerm  uint64    `json:"log_term,omitempty"`
+	Entries  []Entry   `json:"entries,omitempty"`
+	Commit   uint64    `json:"commit,omitempty"`
+	Success  bool      `json:"success,omitempty"`
+	Snapshot *Snapshot `json:"snapshot,omitempty"`
+}
+
+// Transport delivers messages to other nodes. Send must not block, a
+// message may be lost, delayed or reordered.
+type Transport interface {
+	Send(address string, msg Message)
+}
@@ -0,0 +1,84 @@
+package raft
+
+import (
+	"sync"
+)
+
+// Network connects nodes of one process, it can partition them to test
+// the cluster against failures. Messages are delivered asynchronously.
+type Network struct {
+	mu      sync.Mutex
+	nodes   map[string]*Node
+	blocked map[[2]string]bool
+}
+
+func NewNetwork() *Network {
+	return &Network{
+		nodes:   make(map[string]*Node),
+		blocked: make(map[[2]string]bool),
+	}
+}
+
+// Transport returns the transport of the node with the id, the node has
+// to be attached before it receives messages.
+func (n *Network) Transport(id string) Transport {
+	return &networkTransport{network: n, id: id}
+}
+
+func (n *Network) Attach(id string, node *Node) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.nodes[id] = node
+}
+
+// Partition splits the nodes into groups that only reach each other.
+// Nodes not listed in any group are isolated.
+func (n *Network) Partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	group := make(map[string]int)
+	for i, ids := range groups {
+		for _, id := range ids {
+			group[id] = i + 1
+		}
+	}
+
+	n.blocked = make(map[[2]string]bool)
+	for from := range n.nodes {
+		for to := range n.nodes {
+			if from != to && (group[from] == 0 || group[from] != group[to]) {
+				n.blocked[[2]string{from, to}] = true
+			}
+		}
+	}
+}
+
+// Heal removes all partitions.
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.blocked = make(map[[2]string]bool)
+}
+
+func (n *Network) deliver(from string, msg Message) {
+	n.mu.Lock()
+	node, ok := n.nodes[msg.To]
+	blocked := n.blocked[[2]string{from, msg.To}]
+	n.mu.Unlock()
+
+	if ok && !blocked {
+		go node.Step(msg)
+	}
+}
+
+type networkTransport struct {
+	network *Network
+	id      string
+}
+
+func (t *networkTransport) Send(_ string, msg Message) {
+	t.network.deliver(t.id, msg)
+}
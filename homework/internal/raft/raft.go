@@ -0,0 +1,1049 @@
+// Package raft replicates a state machine across a cluster of nodes with
+// the Raft consensus algorithm.
+//
+// A node only appends to the log while it is the leader of the cluster, an
+// entry is applied to the state machine of every node once a majority of
+// the members have it. Members change one at a time through config
+// entries that take effect as soon as they are appended. Applied entries
+// are periodically compacted into a snapshot of the state machine, nodes
+// that fall behind the compacted log receive the snapshot instead.
+//
+// A node with a Storage saves its term, vote, log and snapshot before it
+// answers the messages that depend on them, so it can restart and rejoin
+// the cluster as the same member. Without one they live in memory and a
+// restarted node has to be removed and added back as a new member.
+package raft
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StateMachine is the replicated state. Apply is called with the data of
+// every committed command in log order, its result is returned to the
+// proposer on the leader.
+type StateMachine interface {
+	Apply(data []byte) interface{}
+	Snapshot() ([]byte, error)
+	Restore(data []byte) error
+}
+
+// Cluster is what the admin api needs of a node.
+//
+//go:generate mockgen -package internal -destination ../mocks/raft.go . Cluster
+type Cluster interface {
+	Status() Status
+	AddMember(ctx context.Context, id, address string) error
+	RemoveMember(ctx context.Context, id string) error
+	Step(msg Message)
+}
+
+type State string
+
+const (
+	Follower  State = "follower"
+	Candidate State = "candidate"
+	Leader    State = "leader"
+)
+
+type Status struct {
+	ID            string            `json:"id"`
+	State         State             `json:"state"`
+	Term          uint64            `json:"term"`
+	Leader        string            `json:"leader,omitempty"`
+	Commit        uint64            `json:"commit"`
+	Applied       uint64            `json:"applied"`
+	LastIndex     uint64            `json:"last_index"`
+	SnapshotIndex uint64            `json:"snapshot_index"`
+	Members       map[string]string `json:"members"`
+}
+
+var (
+	ErrStopped             = stderrors.New("raft: node is stopped")
+	ErrDropped             = stderrors.New("raft: entry was replaced by another leader")
+	ErrConfigChangePending = stderrors.New("raft: another membership change is in progress")
+)
+
+// NotLeaderError is returned for proposals to a node that is not the
+// leader. Leader is empty while there is no known leader.
+type NotLeaderError struct {
+	Leader  string
+	Address string
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.Leader == "" {
+		return "raft: not the leader, no leader is known"
+	}
+	return fmt.Sprintf("raft: not the leader, the leader is %s at %s", e.Leader, e.Address)
+}
+
+type Config struct {
+	ID string
+	// Address is where other nodes reach this one.
+	Address string
+	// Members maps the node ids of a new cluster to their addresses. A
+	// node without members waits to be added to an existing cluster.
+	Members map[string]string
+	// Tick is the unit of the election and heartbeat timeouts.
+	Tick time.Duration
+	// ElectionTicks is the minimum number of ticks without a leader
+	// before a follower campaigns, the actual timeout is randomized up to
+	// twice as long.
+	ElectionTicks  int
+	HeartbeatTicks int
+	// SnapshotEntries is the number of applied entries that triggers a
+	// snapshot and compacts the log.
+	SnapshotEntries int
+	// MaxEntries limits the entries of one append message.
+	MaxEntries int
+	// Storage keeps the state across restarts, nil keeps it in memory.
+	Storage Storage
+}
+
+// outgoing is a message waiting for the state it depends on to be saved.
+type outgoing struct {
+	address string
+	msg     Message
+}
+
+// batch is what one flush saves, the entries from first to last and the
+// messages that wait for them.
+type batch struct {
+	state       *HardState
+	snapshot    *Snapshot
+	first, last uint64
+	entries     []Entry
+	outbox      []outgoing
+}
+
+type waiter struct {
+	term   uint64
+	result chan interface{}
+}
+
+type Node struct {
+	cfg       Config
+	sm        StateMachine
+	transport Transport
+
+	// saveMu serializes flushes, the storage is written without holding
+	// mu. It is taken before mu.
+	saveMu sync.Mutex
+
+	mu      sync.Mutex
+	state   State
+	term    uint64
+	vote    string
+	leader  string
+	members map[string]string
+	// addresses remembers the senders of messages, nodes that are not
+	// members yet still get responses.
+	addresses map[string]string
+	// log[0] stands for the last entry of the snapshot.
+	log      []Entry
+	snapshot *Snapshot
+	commit   uint64
+	applied  uint64
+	stopped  bool
+
+	// stateDirty and snapshotDirty mark the term, vote and snapshot
+	// changed since they were last saved, unsaved is the first entry not
+	// handed to the storage yet and saved the last one it holds. outbox
+	// holds the messages sent meanwhile.
+	stateDirty    bool
+	snapshotDirty bool
+	unsaved       uint64
+	saved         uint64
+	outbox        []outgoing
+	onError       func(error)
+
+	next  map[string]uint64
+	match map[string]uint64
+	votes map[string]bool
+	// active holds the members the leader heard from during the current
+	// election timeout.
+	active  map[string]bool
+	waiters map[uint64]*waiter
+
+	electionElapsed   int
+	heartbeatElapsed  int
+	randomizedTimeout int
+	rand              *rand.Rand
+}
+
+// NewNode restores the state saved in the storage of the config, the
+// members of the config only count for a node that saved none.
+func NewNode(cfg Config, sm StateMachine, transport Transport) (*Node, error) {
+	if cfg.ElectionTicks <= 0 {
+		cfg.ElectionTicks = 10
+	}
+	if cfg.HeartbeatTicks <= 0 {
+		cfg.HeartbeatTicks = 1
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 256
+	}
+
+	members := make(map[string]string, len(cfg.Members))
+	for id, address := range cfg.Members {
+		members[id] = address
+	}
+
+	n := &Node{
+		cfg:       cfg,
+		sm:        sm,
+		transport: transport,
+		state:     Follower,
+		members:   members,
+		addresses: make(map[string]string),
+		log:       []Entry{{}},
+		snapshot:  &Snapshot{Members: members},
+		unsaved:   1,
+		waiters:   make(map[uint64]*waiter),
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	n.resetElectionTimeout()
+
+	if cfg.Storage != nil {
+		if err := n.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return n, nil
+}
+
+func (n *Node) load() error {
+	state, entries, snapshot, err := n.cfg.Storage.Load()
+	if err != nil {
+		return err
+	}
+
+	if snapshot != nil {
+		if err = n.sm.Restore(snapshot.Data); err != nil {
+			return fmt.Errorf("raft: restore snapshot %d: %w", snapshot.Index, err)
+		}
+		n.snapshot = snapshot
+		n.log = []Entry{{Index: snapshot.Index, Term: snapshot.Term}}
+		n.commit = snapshot.Index
+		n.applied = snapshot.Index
+	}
+
+	if state != nil {
+		n.term = state.Term
+		n.vote = state.Vote
+	}
+
+	// Entries up to the snapshot were saved before it was taken.
+	for _, e := range entries {
+		if e.Index == n.lastIndex()+1 {
+			n.appendEntries([]Entry{e})
+		}
+	}
+
+	n.members = n.membersAt(n.lastIndex())
+	n.unsaved = n.lastIndex() + 1
+	n.saved = n.lastIndex()
+
+	return nil
+}
+
+// Run ticks the node until the context is done, pending proposals fail
+// with ErrStopped afterwards. onError receives the failures to save the
+// state, saving is retried on the next tick.
+func (n *Node) Run(ctx context.Context, onError func(error)) {
+	n.mu.Lock()
+	n.onError = onError
+	n.mu.Unlock()
+
+	ticker := time.NewTicker(n.cfg.Tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			n.stop()
+			return
+		case <-ticker.C:
+			n.Tick()
+		}
+	}
+}
+
+// stop waits for a running flush, the storage is not written once it
+// returns.
+func (n *Node) stop() {
+	n.saveMu.Lock()
+	defer n.saveMu.Unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.stopped = true
+	for index, w := range n.waiters {
+		w.result <- ErrStopped
+		delete(n.waiters, index)
+	}
+}
+
+func (n *Node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	members := make(map[string]string, len(n.members))
+	for id, address := range n.members {
+		members[id] = address
+	}
+
+	return Status{
+		ID:            n.cfg.ID,
+		State:         n.state,
+		Term:          n.term,
+		Leader:        n.leader,
+		Commit:        n.commit,
+		Applied:       n.applied,
+		LastIndex:     n.lastIndex(),
+		SnapshotIndex: n.log[0].Index,
+		Members:       members,
+	}
+}
+
+// Propose appends a command to the log and waits until it is applied,
+// the result is what the state machine returned for it.
+func (n *Node) Propose(ctx context.Context, data []byte) (interface{}, error) {
+	return n.propose(ctx, EntryCommand, data)
+}
+
+func (n *Node) AddMember(ctx context.Context, id, address string) error {
+	return n.changeMembers(ctx, func(members map[string]string) error {
+		if _, ok := members[id]; ok {
+			return fmt.Errorf("raft: %s is already a member", id)
+		}
+		members[id] = address
+		return nil
+	})
+}
+
+// RemoveMember removes a node from the cluster, a leader removing itself
+// steps down once the change is committed.
+func (n *Node) RemoveMember(ctx context.Context, id string) error {
+	return n.changeMembers(ctx, func(members map[string]string) error {
+		if _, ok := members[id]; !ok {
+			return fmt.Errorf("raft: %s is not a member", id)
+		}
+		if len(members) == 1 {
+			return fmt.Errorf("raft: can not remove the last member")
+		}
+		delete(members, id)
+		return nil
+	})
+}
+
+func (n *Node) changeMembers(ctx context.Context, change func(map[string]string) error) error {
+	n.mu.Lock()
+	if err := n.checkLeader(); err != nil {
+		n.mu.Unlock()
+		return err
+	}
+
+	if n.configPending() {
+		n.mu.Unlock()
+		return ErrConfigChangePending
+	}
+
+	members := make(map[string]string, len(n.members))
+	for id, address := range n.members {
+		members[id] = address
+	}
+	if err := change(members); err != nil {
+		n.mu.Unlock()
+		return err
+	}
+	n.mu.Unlock()
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.propose(ctx, EntryConfig, data)
+	return err
+}
+
+func (n *Node) propose(ctx context.Context, entryType EntryType, data []byte) (interface{}, error) {
+	n.mu.Lock()
+	if err := n.checkLeader(); err != nil {
+		n.mu.Unlock()
+		return nil, err
+	}
+
+	index := n.appendEntry(entryType, data)
+	w := &waiter{term: n.term, result: make(chan interface{}, 1)}
+	n.waiters[index] = w
+	n.broadcastAppend()
+	n.mu.Unlock()
+
+	// The leader counts itself towards the majority once the entry is
+	// saved. Proposals arriving while another flush writes are saved
+	// together by the next one.
+	n.flush()
+
+	select {
+	case result := <-w.result:
+		if err, ok := result.(error); ok && (err == ErrStopped || err == ErrDropped) {
+			return nil, err
+		}
+		return result, nil
+	case <-ctx.Done():
+		n.mu.Lock()
+		if n.waiters[index] == w {
+			delete(n.waiters, index)
+		}
+		n.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (n *Node) checkLeader() error {
+	if n.stopped {
+		return ErrStopped
+	}
+	if n.state != Leader {
+		return &NotLeaderError{Leader: n.leader, Address: n.members[n.leader]}
+	}
+	return nil
+}
+
+// Tick advances the election and heartbeat timers by one tick.
+func (n *Node) Tick() {
+	defer n.flush()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stopped {
+		return
+	}
+
+	if n.state == Leader {
+		// A leader that lost the majority steps down instead of
+		// accepting proposals it can not commit.
+		n.electionElapsed++
+		if n.electionElapsed >= n.cfg.ElectionTicks {
+			n.electionElapsed = 0
+			n.active[n.cfg.ID] = true
+			if !n.hasQuorum(n.active) {
+				n.becomeFollower(n.term, "")
+				return
+			}
+			n.active = make(map[string]bool)
+		}
+
+		n.heartbeatElapsed++
+		if n.heartbeatElapsed >= n.cfg.HeartbeatTicks {
+			n.heartbeatElapsed = 0
+			n.broadcastAppend()
+		}
+		return
+	}
+
+	n.electionElapsed++
+	if n.electionElapsed >= n.randomizedTimeout {
+		n.electionElapsed = 0
+		if _, ok := n.members[n.cfg.ID]; ok {
+			n.campaign()
+		}
+	}
+}
+
+// Step handles a message from another node.
+func (n *Node) Step(msg Message) {
+	defer n.flush()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.stopped {
+		return
+	}
+
+	if msg.Address != "" {
+		n.addresses[msg.From] = msg.Address
+	}
+
+	if msg.Term > n.term {
+		// A follower that recently heard from its leader ignores
+		// candidates, so removed or partitioned nodes do not disrupt
+		// the cluster when they come back.
+		if msg.Type == MsgVote && n.leader != "" && n.electionElapsed < n.cfg.ElectionTicks {
+			return
+		}
+
+		leader := ""
+		if msg.Type == MsgApp || msg.Type == MsgSnap {
+			leader = msg.From
+		}
+		n.becomeFollower(msg.Term, leader)
+	}
+
+	if msg.Term < n.term {
+		switch msg.Type {
+		case MsgApp, MsgSnap:
+			// Tells a stale leader about the newer term.
+			n.send(msg.From, Message{Type: MsgAppResp, Index: n.commit})
+		case MsgVote:
+			n.send(msg.From, Message{Type: MsgVoteResp})
+		}
+		return
+	}
+
+	switch msg.Type {
+	case MsgVote:
+		n.handleVote(msg)
+	case MsgVoteResp:
+		n.handleVoteResp(msg)
+	case MsgApp:
+		n.handleAppend(msg)
+	case MsgAppResp:
+		n.handleAppendResp(msg)
+	case MsgSnap:
+		n.handleSnapshot(msg)
+	}
+}
+
+func (n *Node) campaign() {
+	n.state = Candidate
+	n.term++
+	n.vote = n.cfg.ID
+	n.stateDirty = true
+	n.leader = ""
+	n.votes = map[string]bool{n.cfg.ID: true}
+	n.resetElectionTimeout()
+
+	if n.hasQuorum(n.votes) {
+		n.becomeLeader()
+		return
+	}
+
+	for id := range n.members {
+		if id != n.cfg.ID {
+			n.send(id, Message{Type: MsgVote, Index: n.lastIndex(), LogTerm: n.lastTerm()})
+		}
+	}
+}
+
+func (n *Node) handleVote(msg Message) {
+	upToDate := msg.LogTerm > n.lastTerm() || (msg.LogTerm == n.lastTerm() && msg.Index >= n.lastIndex())
+
+	if (n.vote == "" || n.vote == msg.From) && n.leader == "" && upToDate {
+		n.vote = msg.From
+		n.stateDirty = true
+		n.electionElapsed = 0
+		n.send(msg.From, Message{Type: MsgVoteResp, Success: true})
+		return
+	}
+
+	n.send(msg.From, Message{Type: MsgVoteResp})
+}
+
+func (n *Node) handleVoteResp(msg Message) {
+	if n.state != Candidate || !msg.Success {
+		return
+	}
+
+	n.votes[msg.From] = true
+	if n.hasQuorum(n.votes) {
+		n.becomeLeader()
+	}
+}
+
+func (n *Node) becomeFollower(term uint64, leader string) {
+	n.state = Follower
+	n.term = term
+	n.vote = ""
+	n.stateDirty = true
+	n.leader = leader
+	n.electionElapsed = 0
+	n.resetElectionTimeout()
+}
+
+func (n *Node) becomeLeader() {
+	n.state = Leader
+	n.leader = n.cfg.ID
+	n.heartbeatElapsed = 0
+	n.electionElapsed = 0
+	n.active = make(map[string]bool)
+	n.next = make(map[string]uint64)
+	n.match = make(map[string]uint64)
+
+	// The no-op commits the entries of earlier terms.
+	n.appendEntry(EntryNoop, nil)
+	n.broadcastAppend()
+	n.maybeCommit()
+}
+
+func (n *Node) handleAppend(msg Message) {
+	n.state = Follower
+	n.leader = msg.From
+	n.electionElapsed = 0
+
+	prev, entries := msg.Index, msg.Entries
+
+	// Entries up to the snapshot are committed and already here.
+	if first := n.log[0].Index; prev < first {
+		for len(entries) > 0 && entries[0].Index <= first {
+			entries = entries[1:]
+		}
+		prev = first
+	} else if prev > n.lastIndex() || n.entry(prev).Term != msg.LogTerm {
+		hint := prev - 1
+		if last := n.lastIndex(); hint > last {
+			hint = last
+		}
+		n.send(msg.From, Message{Type: MsgAppResp, Index: hint})
+		return
+	}
+
+	for i, e := range entries {
+		if e.Index <= n.lastIndex() {
+			if n.entry(e.Index).Term == e.Term {
+				continue
+			}
+			n.truncate(e.Index)
+		}
+		n.appendEntries(entries[i:])
+		break
+	}
+
+	last := prev + uint64(len(entries))
+	if commit := min64(msg.Commit, last); commit > n.commit {
+		n.commit = commit
+		n.apply()
+	}
+
+	n.send(msg.From, Message{Type: MsgAppResp, Index: last, Success: true})
+}
+
+func (n *Node) handleAppendResp(msg Message) {
+	if n.state != Leader {
+		return
+	}
+
+	n.active[msg.From] = true
+
+	if !msg.Success {
+		next := n.nextIndex(msg.From)
+		if next > msg.Index+1 {
+			next = msg.Index + 1
+		}
+		if next < 1 {
+			next = 1
+		}
+		n.next[msg.From] = next
+		n.sendAppend(msg.From)
+		return
+	}
+
+	if msg.Index > n.match[msg.From] {
+		n.match[msg.From] = msg.Index
+	}
+	n.next[msg.From] = n.match[msg.From] + 1
+	n.maybeCommit()
+
+	if n.next[msg.From] <= n.lastIndex() {
+		n.sendAppend(msg.From)
+	}
+}
+
+func (n *Node) handleSnapshot(msg Message) {
+	n.state = Follower
+	n.leader = msg.From
+	n.electionElapsed = 0
+
+	snapshot := msg.Snapshot
+	if snapshot.Index <= n.commit {
+		n.send(msg.From, Message{Type: MsgAppResp, Index: n.commit, Success: true})
+		return
+	}
+
+	if err := n.sm.Restore(snapshot.Data); err != nil {
+		n.send(msg.From, Message{Type: MsgAppResp, Index: n.commit})
+		return
+	}
+
+	n.snapshot = snapshot
+	n.log = []Entry{{Index: snapshot.Index, Term: snapshot.Term}}
+	n.members = snapshot.Members
+	n.snapshotDirty = true
+	n.unsaved = snapshot.Index + 1
+	n.saved = snapshot.Index
+	n.commit = snapshot.Index
+	n.applied = snapshot.Index
+
+	for index, w := range n.waiters {
+		if index <= snapshot.Index {
+			w.result <- ErrDropped
+			delete(n.waiters, index)
+		}
+	}
+
+	n.send(msg.From, Message{Type: MsgAppResp, Index: snapshot.Index, Success: true})
+}
+
+func (n *Node) broadcastAppend() {
+	for id := range n.members {
+		if id != n.cfg.ID {
+			n.sendAppend(id)
+		}
+	}
+}
+
+func (n *Node) sendAppend(id string) {
+	next := n.nextIndex(id)
+
+	if next <= n.log[0].Index {
+		n.send(id, Message{Type: MsgSnap, Snapshot: n.snapshot})
+		return
+	}
+
+	last := n.lastIndex()
+	if max := next + uint64(n.cfg.MaxEntries) - 1; last > max {
+		last = max
+	}
+
+	var entries []Entry
+	if next <= last {
+		entries = append([]Entry(nil), n.log[next-n.log[0].Index:last-n.log[0].Index+1]...)
+	}
+
+	n.send(id, Message{
+		Type:    MsgApp,
+		Index:   next - 1,
+		LogTerm: n.entry(next - 1).Term,
+		Entries: entries,
+		Commit:  n.commit,
+	})
+}
+
+func (n *Node) nextIndex(id string) uint64 {
+	if next, ok := n.next[id]; ok {
+		return next
+	}
+	n.next[id] = n.lastIndex() + 1
+	return n.next[id]
+}
+
+// maybeCommit commits the highest entry of the current term that a
+// majority of the members have, the leader has the entries it saved.
+func (n *Node) maybeCommit() {
+	n.match[n.cfg.ID] = n.saved
+
+	matched := make([]uint64, 0, len(n.members))
+	for id := range n.members {
+		matched = append(matched, n.match[id])
+	}
+	if len(matched) == 0 {
+		return
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i] > matched[j] })
+
+	index := matched[len(matched)/2]
+	if index <= n.commit || n.entry(index).Term != n.term {
+		return
+	}
+
+	n.commit = index
+	n.apply()
+
+	if _, ok := n.members[n.cfg.ID]; !ok && n.state == Leader && !n.configPending() {
+		n.becomeFollower(n.term, "")
+		return
+	}
+
+	n.broadcastAppend()
+}
+
+func (n *Node) configPending() bool {
+	for i := n.commit + 1; i <= n.lastIndex(); i++ {
+		if n.entry(i).Type == EntryConfig {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Node) apply() {
+	for n.applied < n.commit {
+		n.applied++
+		e := n.entry(n.applied)
+
+		var result interface{}
+		if e.Type == EntryCommand {
+			result = n.sm.Apply(e.Data)
+		}
+
+		if w, ok := n.waiters[e.Index]; ok {
+			if w.term != e.Term {
+				result = ErrDropped
+			}
+			w.result <- result
+			delete(n.waiters, e.Index)
+		}
+	}
+
+	if n.cfg.SnapshotEntries > 0 && n.applied-n.log[0].Index >= uint64(n.cfg.SnapshotEntries) {
+		n.compact()
+	}
+}
+
+// compact replaces the applied entries with a snapshot of the state
+// machine. A failed snapshot keeps the log and is retried later.
+func (n *Node) compact() {
+	data, err := n.sm.Snapshot()
+	if err != nil {
+		return
+	}
+
+	applied := n.entry(n.applied)
+	n.snapshot = &Snapshot{
+		Index:   applied.Index,
+		Term:    applied.Term,
+		Members: n.membersAt(applied.Index),
+		Data:    data,
+	}
+
+	n.log = append([]Entry{{Index: applied.Index, Term: applied.Term}}, n.log[applied.Index-n.log[0].Index+1:]...)
+	n.snapshotDirty = true
+	// A follower may apply entries before it saved them, the snapshot
+	// saves them then.
+	if n.unsaved <= applied.Index {
+		n.unsaved = applied.Index + 1
+	}
+}
+
+func (n *Node) appendEntry(entryType EntryType, data []byte) uint64 {
+	index := n.lastIndex() + 1
+	n.appendEntries([]Entry{{Index: index, Term: n.term, Type: entryType, Data: data}})
+	return index
+}
+
+func (n *Node) appendEntries(entries []Entry) {
+	for _, e := range entries {
+		n.log = append(n.log, e)
+		if e.Type == EntryConfig {
+			n.members = decodeMembers(e.Data)
+		}
+	}
+}
+
+// truncate drops the entries from index on, they were never committed.
+func (n *Node) truncate(index uint64) {
+	n.log = n.log[:index-n.log[0].Index]
+	n.members = n.membersAt(n.lastIndex())
+	if n.unsaved > index {
+		n.unsaved = index
+	}
+	if n.saved >= index {
+		n.saved = index - 1
+	}
+
+	for i, w := range n.waiters {
+		if i >= index {
+			w.result <- ErrDropped
+			delete(n.waiters, i)
+		}
+	}
+}
+
+// membersAt returns the members as of the config entry at or before index.
+func (n *Node) membersAt(index uint64) map[string]string {
+	for i := index; i > n.log[0].Index; i-- {
+		if e := n.entry(i); e.Type == EntryConfig {
+			return decodeMembers(e.Data)
+		}
+	}
+	return n.snapshot.Members
+}
+
+func decodeMembers(data []byte) map[string]string {
+	members := make(map[string]string)
+	_ = json.Unmarshal(data, &members)
+	return members
+}
+
+func (n *Node) hasQuorum(granted map[string]bool) bool {
+	count := 0
+	for id := range n.members {
+		if granted[id] {
+			count++
+		}
+	}
+	return count > len(n.members)/2
+}
+
+func (n *Node) send(to string, msg Message) {
+	msg.From = n.cfg.ID
+	msg.To = to
+	msg.Term = n.term
+	msg.Address = n.cfg.Address
+
+	address, ok := n.members[to]
+	if !ok {
+		address = n.addresses[to]
+	}
+	n.outbox = append(n.outbox, outgoing{address: address, msg: msg})
+}
+
+// flush saves the changed state and then sends the messages that depend
+// on it. The state is collected under mu and written without it, so
+// proposals and messages keep arriving while the storage syncs, the next
+// flush saves all of them at once. The messages are dropped when saving
+// fails, votes and appends must not be acknowledged before they are
+// durable. The state is saved again on the next flush and the dropped
+// messages are retried like lost ones.
+func (n *Node) flush() {
+	n.saveMu.Lock()
+	defer n.saveMu.Unlock()
+
+	for {
+		n.mu.Lock()
+		b := n.ready()
+		n.mu.Unlock()
+		if b == nil {
+			return
+		}
+
+		err := n.save(b)
+
+		n.mu.Lock()
+		n.finish(b, err)
+		onError := n.onError
+		n.mu.Unlock()
+
+		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
+			return
+		}
+
+		for _, out := range b.outbox {
+			n.transport.Send(out.address, out.msg)
+		}
+	}
+}
+
+// ready takes what changed since the last flush, nil when nothing did.
+func (n *Node) ready() *batch {
+	if n.stopped {
+		return nil
+	}
+
+	b := &batch{outbox: n.outbox}
+	n.outbox = nil
+
+	if n.stateDirty {
+		b.state = &HardState{Term: n.term, Vote: n.vote}
+		n.stateDirty = false
+	}
+
+	if n.snapshotDirty {
+		b.snapshot = n.snapshot
+		n.snapshotDirty = false
+	}
+
+	if last := n.lastIndex(); n.unsaved <= last {
+		b.first, b.last = n.unsaved, last
+		if n.cfg.Storage != nil {
+			b.entries = append([]Entry(nil), n.log[b.first-n.log[0].Index:]...)
+		}
+		n.unsaved = last + 1
+	}
+
+	if b.state == nil && b.snapshot == nil && b.last == 0 && len(b.outbox) == 0 {
+		return nil
+	}
+
+	return b
+}
+
+// save writes the snapshot before the entries, the saved entries then
+// always continue the saved snapshot.
+func (n *Node) save(b *batch) error {
+	storage := n.cfg.Storage
+	if storage == nil {
+		return nil
+	}
+
+	if b.snapshot != nil {
+		if err := storage.SaveSnapshot(b.snapshot); err != nil {
+			return err
+		}
+	}
+
+	if b.state != nil {
+		if err := storage.SaveState(b.state); err != nil {
+			return err
+		}
+	}
+
+	if len(b.entries) > 0 {
+		if err := storage.Append(b.entries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// finish records the outcome of saving the batch. What failed is saved
+// again by the next flush, saved entries count towards the commit of the
+// leader.
+func (n *Node) finish(b *batch, err error) {
+	if err != nil {
+		n.stateDirty = n.stateDirty || b.state != nil
+		n.snapshotDirty = n.snapshotDirty || b.snapshot != nil
+		if b.last > 0 && b.first < n.unsaved {
+			n.unsaved = b.first
+			if first := n.log[0].Index + 1; n.unsaved < first {
+				n.unsaved = first
+			}
+		}
+		return
+	}
+
+	// Entries truncated meanwhile are not saved.
+	if saved := min64(b.last, n.unsaved-1); saved > n.saved {
+		n.saved = saved
+		if n.state == Leader && !n.stopped {
+			n.maybeCommit()
+		}
+	}
+}
+
+func (n *Node) entry(index uint64) Entry {
+	return n.log[index-n.log[0].Index]
+}
+
+func (n *Node) lastIndex() uint64 {
+	return n.log[len(n.log)-1].Index
+}
+
+func (n *Node) lastTerm() uint64 {
+	return n.log[len(n.log)-1].Term
+}
+
+func (n *Node) resetElectionTimeout() {
+	n.randomizedTimeout = n.cfg.ElectionTicks + n.rand.Intn(n.cfg.ElectionTicks)
+}
+
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,500 @@
+package raft
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTick    = 2 * time.Millisecond
+	testTimeout = 5 * time.Second
+)
+
+// testMachine records the applied commands, a command "fail" is rejected
+// with an error.
+type testMachine struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (m *testMachine) Apply(data []byte) interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if string(data) == "fail" {
+		return fmt.Errorf("rejected")
+	}
+	m.commands = append(m.commands, string(data))
+	return len(m.commands)
+}
+
+func (m *testMachine) Snapshot() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return json.Marshal(m.commands)
+}
+
+func (m *testMachine) Restore(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return json.Unmarshal(data, &m.commands)
+}
+
+func (m *testMachine) list() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.commands...)
+}
+
+type testCluster struct {
+	t        *testing.T
+	network  *Network
+	nodes    map[string]*Node
+	machines map[string]*testMachine
+	storages map[string]Storage
+	cancel   map[string]context.CancelFunc
+}
+
+func newTestCluster(t *testing.T, snapshotEntries int, ids ...string) *testCluster {
+	c := &testCluster{
+		t:        t,
+		network:  NewNetwork(),
+		nodes:    make(map[string]*Node),
+		machines: make(map[string]*testMachine),
+		storages: make(map[string]Storage),
+		cancel:   make(map[string]context.CancelFunc),
+	}
+
+	members := make(map[string]string)
+	for _, id := range ids {
+		members[id] = id
+	}
+	for _, id := range ids {
+		c.start(id, members, snapshotEntries)
+	}
+
+	t.Cleanup(func() {
+		for _, cancel := range c.cancel {
+			cancel()
+		}
+	})
+
+	return c
+}
+
+func (c *testCluster) start(id string, members map[string]string, snapshotEntries int) {
+	if c.storages[id] == nil {
+		storage, err := NewFileStorage(c.t.TempDir())
+		require.NoError(c.t, err)
+		c.storages[id] = storage
+	}
+
+	machine := &testMachine{}
+	node, err := NewNode(Config{
+		ID:              id,
+		Address:         id,
+		Members:         members,
+		Tick:            testTick,
+		ElectionTicks:   10,
+		SnapshotEntries: snapshotEntries,
+		Storage:         c.storages[id],
+	}, machine, c.network.Transport(id))
+	require.NoError(c.t, err)
+
+	c.network.Attach(id, node)
+	c.nodes[id] = node
+	c.machines[id] = machine
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel[id] = cancel
+	go node.Run(ctx, nil)
+}
+
+// restart stops the node and starts it again from its storage with an
+// empty state machine.
+func (c *testCluster) restart(id string, snapshotEntries int) {
+	c.cancel[id]()
+	c.nodes[id].stop()
+
+	c.start(id, nil, snapshotEntries)
+}
+
+// leader waits until exactly one of the nodes leads.
+func (c *testCluster) leader(ids ...string) *Node {
+	var leader *Node
+
+	c.eventually(func() bool {
+		leader = nil
+		for _, id := range ids {
+			if status := c.nodes[id].Status(); status.State == Leader {
+				if leader != nil {
+					return false
+				}
+				leader = c.nodes[id]
+			}
+		}
+		return leader != nil
+	}, "no leader among %v", ids)
+
+	return leader
+}
+
+func (c *testCluster) propose(node *Node, command string) (interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	return node.Propose(ctx, []byte(command))
+}
+
+func (c *testCluster) eventually(cond func() bool, format string, args ...interface{}) {
+	c.t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			c.t.Fatalf(format, args...)
+		}
+		time.Sleep(testTick)
+	}
+}
+
+func (c *testCluster) applied(command []string, ids ...string) {
+	c.t.Helper()
+
+	for _, id := range ids {
+		c.eventually(func() bool {
+			list := c.machines[id].list()
+			return fmt.Sprint(list) == fmt.Sprint(command)
+		}, "%s applied %v, want %v", id, c.machines[id].list(), command)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	c := newTestCluster(t, 0, "a", "b", "c")
+	leader := c.leader("a", "b", "c")
+
+	result, err := c.propose(leader, "first")
+	require.NoError(t, err)
+	require.Equal(t, 1, result)
+
+	result, err = c.propose(leader, "fail")
+	require.NoError(t, err)
+	require.EqualError(t, result.(error), "rejected")
+
+	_, err = c.propose(leader, "second")
+	require.NoError(t, err)
+
+	c.applied([]string{"first", "second"}, "a", "b", "c")
+
+	for id, node := range c.nodes {
+		if node != leader {
+			_, err = c.propose(node, "third")
+			require.Equal(t, &NotLeaderError{Leader: leader.cfg.ID, Address: leader.cfg.ID}, err, id)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	c := newTestCluster(t, 0, "a", "b", "c")
+	old := c.leader("a", "b", "c")
+
+	_, err := c.propose(old, "first")
+	require.NoError(t, err)
+
+	var rest []string
+	for id := range c.nodes {
+		if id != old.cfg.ID {
+			rest = append(rest, id)
+		}
+	}
+	c.network.Partition([]string{old.cfg.ID}, rest)
+
+	// The isolated leader can not commit and eventually steps down.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	_, err = old.Propose(ctx, []byte("lost"))
+	cancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	leader := c.leader(rest...)
+	_, err = c.propose(leader, "second")
+	require.NoError(t, err)
+
+	c.eventually(func() bool { return old.Status().State != Leader }, "isolated leader did not step down")
+
+	c.network.Heal()
+	c.applied([]string{"first", "second"}, "a", "b", "c")
+}
+
+func TestMembership(t *testing.T) {
+	c := newTestCluster(t, 0, "a", "b", "c")
+	leader := c.leader("a", "b", "c")
+
+	_, err := c.propose(leader, "first")
+	require.NoError(t, err)
+
+	c.start("d", nil, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	require.NoError(t, leader.AddMember(ctx, "d", "d"))
+	require.Error(t, leader.AddMember(ctx, "d", "d"))
+
+	c.applied([]string{"first"}, "d")
+	c.eventually(func() bool { return len(c.nodes["d"].Status().Members) == 4 }, "d did not learn the members")
+
+	require.NoError(t, leader.RemoveMember(ctx, leader.cfg.ID))
+	require.Equal(t, Follower, leader.Status().State)
+
+	var rest []string
+	for id := range c.nodes {
+		if id != leader.cfg.ID {
+			rest = append(rest, id)
+		}
+	}
+
+	next := c.leader(rest...)
+	_, err = c.propose(next, "second")
+	require.NoError(t, err)
+
+	c.applied([]string{"first", "second"}, rest...)
+	require.Equal(t, []string{"first"}, c.machines[leader.cfg.ID].list())
+}
+
+func TestSnapshot(t *testing.T) {
+	c := newTestCluster(t, 5, "a", "b", "c")
+	leader := c.leader("a", "b", "c")
+
+	var lagging string
+	for id := range c.nodes {
+		if id != leader.cfg.ID {
+			lagging = id
+		}
+	}
+
+	var rest []string
+	for id := range c.nodes {
+		if id != lagging {
+			rest = append(rest, id)
+		}
+	}
+	c.network.Partition(rest, []string{lagging})
+
+	var commands []string
+	for i := 0; i < 12; i++ {
+		command := fmt.Sprintf("command %d", i)
+		_, err := c.propose(leader, command)
+		require.NoError(t, err)
+		commands = append(commands, command)
+	}
+
+	require.NotZero(t, leader.Status().SnapshotIndex)
+
+	c.network.Heal()
+	c.applied(commands, "a", "b", "c")
+	require.NotZero(t, c.nodes[lagging].Status().SnapshotIndex)
+}
+
+func TestRestart(t *testing.T) {
+	c := newTestCluster(t, 3, "a", "b", "c")
+	leader := c.leader("a", "b", "c")
+
+	commands := []string{"first", "second", "third", "fourth"}
+	for _, command := range commands {
+		_, err := c.propose(leader, command)
+		require.NoError(t, err)
+	}
+	c.applied(commands, "a", "b", "c")
+	term := leader.Status().Term
+
+	// Restarted nodes know neither the members nor the commands, unless
+	// they saved them.
+	for _, id := range []string{"a", "b", "c"} {
+		c.restart(id, 3)
+	}
+
+	leader = c.leader("a", "b", "c")
+	require.Greater(t, leader.Status().Term, term)
+	require.Len(t, leader.Status().Members, 3)
+	c.applied(commands, "a", "b", "c")
+
+	_, err := c.propose(leader, "fifth")
+	require.NoError(t, err)
+	c.applied(append(commands, "fifth"), "a", "b", "c")
+}
+
+// testStorage counts the appends and holds them while blocked, they fail
+// while failing is set.
+type testStorage struct {
+	Storage
+
+	mu      sync.Mutex
+	appends int
+	failing bool
+	blocked chan struct{}
+}
+
+func (s *testStorage) Append(entries []Entry) error {
+	s.mu.Lock()
+	blocked, failing := s.blocked, s.failing
+	s.appends++
+	s.mu.Unlock()
+
+	if blocked != nil {
+		<-blocked
+	}
+	if failing {
+		return fmt.Errorf("disk full")
+	}
+
+	return s.Storage.Append(entries)
+}
+
+func (s *testStorage) set(update func(s *testStorage)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update(s)
+}
+
+func startSingle(t *testing.T, storage Storage, onError func(error)) *Node {
+	node, err := NewNode(Config{
+		ID:            "a",
+		Address:       "a",
+		Members:       map[string]string{"a": "a"},
+		Tick:          testTick,
+		ElectionTicks: 2,
+		Storage:       storage,
+	}, &testMachine{}, NewNetwork().Transport("a"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go node.Run(ctx, onError)
+
+	c := &testCluster{t: t}
+	c.eventually(func() bool { return node.Status().Commit > 0 }, "no leader")
+
+	return node
+}
+
+func TestProposalsSavedTogether(t *testing.T) {
+	files, err := NewFileStorage(t.TempDir())
+	require.NoError(t, err)
+	storage := &testStorage{Storage: files}
+	node := startSingle(t, storage, nil)
+
+	// The first proposal holds the storage, the others queue up behind it.
+	blocked := make(chan struct{})
+	storage.set(func(s *testStorage) { s.appends, s.blocked = 0, blocked })
+
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+			_, err := node.Propose(ctx, []byte(fmt.Sprint(i)))
+			errs <- err
+		}(i)
+	}
+
+	c := &testCluster{t: t}
+	c.eventually(func() bool { return node.Status().LastIndex == 11 }, "proposals were not appended")
+	storage.set(func(s *testStorage) { s.blocked = nil })
+	close(blocked)
+	for i := 0; i < 10; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	storage.set(func(s *testStorage) { require.LessOrEqual(t, s.appends, 2) })
+	require.Equal(t, uint64(11), node.Status().Commit)
+}
+
+func TestSaveFailure(t *testing.T) {
+	files, err := NewFileStorage(t.TempDir())
+	require.NoError(t, err)
+	storage := &testStorage{Storage: files}
+
+	errs := make(chan error, 100)
+	node := startSingle(t, storage, func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	})
+
+	storage.set(func(s *testStorage) { s.failing = true })
+
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := node.Propose(ctx, []byte("first"))
+		result <- err
+	}()
+
+	// The entry is not committed before it is saved, saving is retried.
+	require.EqualError(t, <-errs, "disk full")
+	require.Equal(t, uint64(1), node.Status().Commit)
+
+	storage.set(func(s *testStorage) { s.failing = false })
+	require.NoError(t, <-result)
+	require.Equal(t, uint64(2), node.Status().Commit)
+}
+
+func TestFileStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage, err := NewFileStorage(dir)
+	require.NoError(t, err)
+
+	state, entries, snapshot, err := storage.Load()
+	require.NoError(t, err)
+	require.Nil(t, state)
+	require.Nil(t, entries)
+	require.Nil(t, snapshot)
+
+	entry := func(index, term uint64) Entry {
+		return Entry{Index: index, Term: term, Type: EntryCommand, Data: []byte(fmt.Sprint(index))}
+	}
+
+	require.NoError(t, storage.SaveState(&HardState{Term: 2, Vote: "b"}))
+	require.NoError(t, storage.Append([]Entry{entry(1, 1), entry(2, 1), entry(3, 1)}))
+	// Appended entries replace the saved ones from their index on.
+	require.NoError(t, storage.Append([]Entry{entry(2, 2)}))
+	require.NoError(t, storage.Append([]Entry{entry(3, 2), entry(4, 2)}))
+
+	// A line cut short by a crash is dropped.
+	file, err := os.OpenFile(filepath.Join(dir, logFile), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString(`[{"index":5`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	storage, err = NewFileStorage(dir)
+	require.NoError(t, err)
+	state, entries, _, err = storage.Load()
+	require.NoError(t, err)
+	require.Equal(t, &HardState{Term: 2, Vote: "b"}, state)
+	require.Equal(t, []Entry{entry(1, 1), entry(2, 2), entry(3, 2), entry(4, 2)}, entries)
+
+	require.NoError(t, storage.Append([]Entry{entry(5, 2)}))
+	require.NoError(t, storage.SaveSnapshot(&Snapshot{Index: 3, Term: 2, Data: []byte("state")}))
+	require.NoError(t, storage.Append([]Entry{entry(6, 2)}))
+
+	_, entries, snapshot, err = storage.Load()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), snapshot.Index)
+	require.Equal(t, []Entry{entry(4, 2), entry(5, 2), entry(6, 2)}, entries)
+}
@@ -0,0 +1,301 @@
+package raft
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const (
+	stateFile    = "state.json"
+	logFile      = "log.jsonl"
+	snapshotFile = "snapshot.json"
+)
+
+// HardState is the term and the vote in it, a node must not forget them
+// across restarts.
+type HardState struct {
+	Term uint64 `json:"term"`
+	Vote string `json:"vote,omitempty"`
+}
+
+// Storage keeps the state of a node. Append adds entries to the saved log,
+// they replace the saved entries from the index of the first one on. A
+// node saves its snapshot before the entries that follow it, so the saved
+// entries may overlap the snapshot. Load returns nil for what was never
+// saved.
+type Storage interface {
+	Load() (*HardState, []Entry, *Snapshot, error)
+	SaveState(state *HardState) error
+	Append(entries []Entry) error
+	SaveSnapshot(snapshot *Snapshot) error
+}
+
+// FileStorage keeps the state, the log and the snapshot in three files of
+// a directory. The state and the snapshot are replaced atomically, a crash
+// leaves either the old or the new version. The log is only appended to,
+// one line per call, a line cut short by a crash is dropped on Load. Saving
+// a snapshot rewrites the log without the entries it covers.
+type FileStorage struct {
+	dir string
+
+	mu sync.Mutex
+	// log is opened by the first Append, size is its length up to the last
+	// complete line.
+	log  *os.File
+	size int64
+}
+
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("raft: %w", err)
+	}
+
+	return &FileStorage{dir: dir}, nil
+}
+
+func (s *FileStorage) Load() (*HardState, []Entry, *Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var state *HardState
+	if err := s.read(stateFile, &state); err != nil {
+		return nil, nil, nil, err
+	}
+
+	var snapshot *Snapshot
+	if err := s.read(snapshotFile, &snapshot); err != nil {
+		return nil, nil, nil, err
+	}
+
+	entries, size, err := s.readLog()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err = s.closeLog(); err != nil {
+		return nil, nil, nil, err
+	}
+	if err = os.Truncate(filepath.Join(s.dir, logFile), size); err != nil && !stderrors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil, fmt.Errorf("raft: %w", err)
+	}
+
+	return state, entries, snapshot, nil
+}
+
+func (s *FileStorage) SaveState(state *HardState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return s.write(stateFile, data)
+}
+
+// Append writes the entries as one line and syncs the log. A failed
+// append is cut off again, the lines appended later would be hidden
+// behind it otherwise.
+func (s *FileStorage) Append(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if s.log == nil {
+		if err = s.openLog(); err != nil {
+			return err
+		}
+	}
+
+	if _, err = s.log.Write(data); err == nil {
+		err = s.log.Sync()
+	}
+	if err != nil {
+		_ = s.log.Truncate(s.size)
+		return fmt.Errorf("raft: %w", err)
+	}
+	s.size += int64(len(data))
+
+	return nil
+}
+
+// SaveSnapshot replaces the snapshot and then the log with the entries
+// after it. A crash in between leaves entries the snapshot covers, Load
+// still returns them.
+func (s *FileStorage) SaveSnapshot(snapshot *Snapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	if err = s.write(snapshotFile, data); err != nil {
+		return err
+	}
+
+	entries, _, err := s.readLog()
+	if err != nil {
+		return err
+	}
+
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Index > snapshot.Index })
+	data = nil
+	if entries = entries[i:]; len(entries) > 0 {
+		if data, err = json.Marshal(entries); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+
+	if err = s.closeLog(); err != nil {
+		return err
+	}
+
+	return s.write(logFile, data)
+}
+
+func (s *FileStorage) read(name string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if stderrors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("raft: %w", err)
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("raft: %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// readLog replays the lines of the log and returns the entries and the
+// length of the complete lines.
+func (s *FileStorage) readLog() ([]Entry, int64, error) {
+	file, err := os.Open(filepath.Join(s.dir, logFile))
+	if stderrors.Is(err, os.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("raft: %w", err)
+	}
+	defer file.Close()
+
+	var (
+		entries []Entry
+		size    int64
+	)
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return entries, size, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("raft: %w", err)
+		}
+
+		var appended []Entry
+		if err = json.Unmarshal(bytes.TrimSpace(line), &appended); err != nil {
+			return nil, 0, fmt.Errorf("raft: %s at %d: %w", logFile, size, err)
+		}
+		size += int64(len(line))
+
+		if len(appended) > 0 {
+			i := sort.Search(len(entries), func(i int) bool { return entries[i].Index >= appended[0].Index })
+			entries = append(entries[:i], appended...)
+		}
+	}
+}
+
+func (s *FileStorage) openLog() error {
+	file, err := os.OpenFile(filepath.Join(s.dir, logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("raft: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err == nil {
+		// The log may just have been created.
+		err = s.syncDir()
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("raft: %w", err)
+	}
+
+	s.log, s.size = file, info.Size()
+	return nil
+}
+
+func (s *FileStorage) closeLog() error {
+	if s.log == nil {
+		return nil
+	}
+
+	err := s.log.Close()
+	s.log = nil
+	if err != nil {
+		return fmt.Errorf("raft: %w", err)
+	}
+
+	return nil
+}
+
+// write replaces the file with a synced temporary file and syncs the
+// directory, so the rename is durable too.
+func (s *FileStorage) write(name string, data []byte) error {
+	tmp, err := os.CreateTemp(s.dir, name+".*")
+	if err != nil {
+		return fmt.Errorf("raft: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("raft: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("raft: %w", err)
+	}
+
+	if err = s.syncDir(); err != nil {
+		return fmt.Errorf("raft: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FileStorage) syncDir() error {
+	dir, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
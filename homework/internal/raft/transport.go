@@ -0,0 +1,82 @@
+package raft
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MessagePath is where nodes accept messages over http.
+const MessagePath = "/raft/message"
+
+// SecretHeader carries the secret shared by the nodes of a cluster, nodes
+// reject messages without it.
+const SecretHeader = "X-Cluster-Secret"
+
+const transportQueue = 1024
+
+// HTTPTransport posts messages to MessagePath of the address, one queue
+// and connection per address keeps the order of messages. Messages are
+// dropped while the queue of an address is full.
+type HTTPTransport struct {
+	client *http.Client
+	secret string
+
+	mu     sync.Mutex
+	queues map[string]chan Message
+}
+
+func NewHTTPTransport(timeout time.Duration, secret string) *HTTPTransport {
+	return &HTTPTransport{
+		client: &http.Client{Timeout: timeout},
+		secret: secret,
+		queues: make(map[string]chan Message),
+	}
+}
+
+func (t *HTTPTransport) Send(address string, msg Message) {
+	if address == "" {
+		return
+	}
+
+	t.mu.Lock()
+	queue, ok := t.queues[address]
+	if !ok {
+		queue = make(chan Message, transportQueue)
+		t.queues[address] = queue
+		go t.run(address, queue)
+	}
+	t.mu.Unlock()
+
+	select {
+	case queue <- msg:
+	default:
+	}
+}
+
+func (t *HTTPTransport) run(address string, queue chan Message) {
+	url := strings.TrimSuffix(address, "/") + MessagePath
+
+	for msg := range queue {
+		body, err := json.Marshal(msg)
+		if err != nil {
+			continue
+		}
+
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		req.Header.Set("content-type", "application/json")
+		req.Header.Set(SecretHeader, t.secret)
+
+		res, err := t.client.Do(req)
+		if err != nil {
+			continue
+		}
+		_ = res.Body.Close()
+	}
+}
@@ -0,0 +1,282 @@
+package reachability
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"homework/internal/devices"
+)
+
+type Result struct {
+	At        time.Time `json:"at"`
+	Reachable bool      `json:"reachable"`
+	LatencyMS float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Report holds the probe results of a device, oldest first.
+type Report struct {
+	SerialNum string   `json:"serial_num"`
+	Address   string   `json:"address,omitempty"`
+	Reachable bool     `json:"reachable"`
+	History   []Result `json:"history"`
+}
+
+type Prober interface {
+	Probe(ctx context.Context, address string) error
+}
+
+// TCPProber considers an address reachable when a tcp connection to it
+// can be established within the timeout.
+type TCPProber struct {
+	Timeout time.Duration
+}
+
+func (p TCPProber) Probe(ctx context.Context, address string) error {
+	dialer := net.Dialer{Timeout: p.Timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// Source provides the devices to probe, app.Repository satisfies it.
+type Source interface {
+	Get(string) (*devices.Device, error)
+	List() ([]*devices.Device, error)
+}
+
+type Options struct {
+	Interval time.Duration
+	// Jitter spreads the probes of a round randomly over the duration so
+	// devices are not all probed at once.
+	Jitter      time.Duration
+	Timeout     time.Duration
+	Workers     int
+	History     int
+	DefaultPort int
+	// Ports maps device models to the port probed for them.
+	Ports map[string]int
+}
+
+type Checker struct {
+	source Source
+	prober Prober
+	opts   Options
+	jitter func(time.Duration) time.Duration
+	now    func() time.Time
+
+	mu      sync.RWMutex
+	history map[string][]Result
+}
+
+func NewChecker(source Source, prober Prober, opts Options) *Checker {
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+
+	if opts.History < 1 {
+		opts.History = 1
+	}
+
+	return &Checker{
+		source: source,
+		prober: prober,
+		opts:   opts,
+		jitter: func(max time.Duration) time.Duration {
+			if max <= 0 {
+				return 0
+			}
+			return time.Duration(rand.Int63n(int64(max)))
+		},
+		now:     time.Now,
+		history: make(map[string][]Result),
+	}
+}
+
+// Run probes all devices every interval until the context is done.
+func (c *Checker) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.Round(ctx); err != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type job struct {
+	serialNum string
+	address   string
+	delay     time.Duration
+}
+
+// Round probes every device with an IP once and waits for the results.
+func (c *Checker) Round(ctx context.Context) error {
+	list, err := c.source.List()
+	if err != nil {
+		return err
+	}
+
+	c.prune(list)
+
+	jobs := make([]job, 0, len(list))
+	for _, device := range list {
+		if device.IP == "" {
+			continue
+		}
+		jobs = append(jobs, job{
+			serialNum: device.SerialNum,
+			address:   c.address(device),
+			delay:     c.jitter(c.opts.Jitter),
+		})
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].delay < jobs[j].delay
+	})
+
+	queue := make(chan job)
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				c.record(j.serialNum, c.probe(ctx, j.address))
+			}
+		}()
+	}
+
+	c.dispatch(ctx, jobs, queue)
+	close(queue)
+	wg.Wait()
+
+	return nil
+}
+
+func (c *Checker) dispatch(ctx context.Context, jobs []job, queue chan<- job) {
+	start := time.Now()
+
+	for _, j := range jobs {
+		if wait := time.Until(start.Add(j.delay)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case queue <- j:
+		}
+	}
+}
+
+func (c *Checker) probe(ctx context.Context, address string) Result {
+	result := Result{At: c.now()}
+
+	if address == "" {
+		result.Error = "device ip is not a valid address"
+		return result
+	}
+
+	start := time.Now()
+	err := c.prober.Probe(ctx, address)
+	result.LatencyMS = float64(time.Since(start)) / float64(time.Millisecond)
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Reachable = true
+
+	return result
+}
+
+// address returns the ip and port probed for the device, or an empty
+// string when its IP is not an address.
+func (c *Checker) address(device *devices.Device) string {
+	ip, err := netip.ParseAddr(device.IP)
+	if err != nil {
+		return ""
+	}
+
+	port, ok := c.opts.Ports[device.Model]
+	if !ok {
+		port = c.opts.DefaultPort
+	}
+
+	return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+}
+
+func (c *Checker) record(serialNum string, result Result) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	history := append(c.history[serialNum], result)
+	if len(history) > c.opts.History {
+		history = history[len(history)-c.opts.History:]
+	}
+	c.history[serialNum] = history
+}
+
+// prune drops the results of devices that no longer exist.
+func (c *Checker) prune(list []*devices.Device) {
+	exists := make(map[string]bool, len(list))
+	for _, device := range list {
+		exists[device.SerialNum] = true
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for serialNum := range c.history {
+		if !exists[serialNum] {
+			delete(c.history, serialNum)
+		}
+	}
+}
+
+func (c *Checker) Report(serialNum string) (*Report, error) {
+	device, err := c.source.Get(serialNum)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	report := &Report{
+		SerialNum: serialNum,
+		Address:   c.address(device),
+		History:   make([]Result, len(c.history[serialNum])),
+	}
+	copy(report.History, c.history[serialNum])
+
+	if n := len(report.History); n > 0 {
+		report.Reachable = report.History[n-1].Reachable
+	}
+
+	return report, nil
+}
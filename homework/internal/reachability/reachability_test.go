@@ -0,0 +1,177 @@
+package reachability
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"homework/internal/devices"
+	"homework/internal/errors"
+)
+
+type testSource struct {
+	mu   sync.Mutex
+	list []*devices.Device
+}
+
+func (s *testSource) Get(serialNum string) (*devices.Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, device := range s.list {
+		if device.SerialNum == serialNum {
+			return device, nil
+		}
+	}
+
+	return nil, errors.NewNotFoundError(serialNum)
+}
+
+func (s *testSource) List() ([]*devices.Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*devices.Device(nil), s.list...), nil
+}
+
+func listen(t *testing.T) (net.Listener, int) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	return listener, listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestRoundTCP(t *testing.T) {
+	up, upPort := listen(t)
+	defer up.Close()
+
+	down, downPort := listen(t)
+	require.NoError(t, down.Close())
+
+	source := &testSource{list: []*devices.Device{
+		{SerialNum: "up", Model: "RT-100", IP: "127.0.0.1"},
+		{SerialNum: "down", Model: "SW-48", IP: "127.0.0.1"},
+		{SerialNum: "bad", Model: "RT-100", IP: "not an ip"},
+		{SerialNum: "none", Model: "RT-100"},
+	}}
+
+	checker := NewChecker(source, TCPProber{Timeout: time.Second}, Options{
+		Timeout: time.Second,
+		Workers: 2,
+		History: 2,
+		Ports:   map[string]int{"RT-100": upPort, "SW-48": downPort},
+	})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, checker.Round(context.Background()))
+	}
+
+	report, err := checker.Report("up")
+	require.NoError(t, err)
+	require.True(t, report.Reachable)
+	require.Equal(t, "127.0.0.1:"+strconv.Itoa(upPort), report.Address)
+	require.Len(t, report.History, 2)
+	require.Empty(t, report.History[1].Error)
+
+	report, err = checker.Report("down")
+	require.NoError(t, err)
+	require.False(t, report.Reachable)
+	require.NotEmpty(t, report.History[1].Error)
+
+	report, err = checker.Report("bad")
+	require.NoError(t, err)
+	require.False(t, report.Reachable)
+	require.Len(t, report.History, 2)
+
+	report, err = checker.Report("none")
+	require.NoError(t, err)
+	require.Empty(t, report.History)
+
+	_, err = checker.Report("unknown")
+	require.IsType(t, &errors.NotFoundError{}, err)
+
+	source.list = source.list[1:]
+	require.NoError(t, checker.Round(context.Background()))
+	checker.mu.RLock()
+	require.NotContains(t, checker.history, "up")
+	checker.mu.RUnlock()
+}
+
+type countingProber struct {
+	active  atomic.Int32
+	maximum atomic.Int32
+	probes  atomic.Int32
+}
+
+func (p *countingProber) Probe(context.Context, string) error {
+	n := p.active.Add(1)
+	defer p.active.Add(-1)
+
+	for {
+		max := p.maximum.Load()
+		if n <= max || p.maximum.CompareAndSwap(max, n) {
+			break
+		}
+	}
+	p.probes.Add(1)
+	time.Sleep(5 * time.Millisecond)
+
+	return nil
+}
+
+func TestRoundBoundsWorkers(t *testing.T) {
+	source := &testSource{}
+	for i := 0; i < 20; i++ {
+		source.list = append(source.list, &devices.Device{SerialNum: strconv.Itoa(i), IP: "10.0.0.1"})
+	}
+
+	prober := &countingProber{}
+	checker := NewChecker(source, prober, Options{Workers: 3, History: 1, Jitter: 10 * time.Millisecond})
+
+	var delays []time.Duration
+	jitter := checker.jitter
+	checker.jitter = func(max time.Duration) time.Duration {
+		d := jitter(max)
+		delays = append(delays, d)
+		return d
+	}
+
+	require.NoError(t, checker.Round(context.Background()))
+
+	require.Equal(t, int32(20), prober.probes.Load())
+	require.LessOrEqual(t, prober.maximum.Load(), int32(3))
+	require.Len(t, delays, 20)
+	for _, d := range delays {
+		require.Less(t, d, 10*time.Millisecond)
+	}
+}
+
+func TestRoundStopsOnCancel(t *testing.T) {
+	source := &testSource{list: []*devices.Device{{SerialNum: "1", IP: "10.0.0.1"}}}
+	prober := &countingProber{}
+	checker := NewChecker(source, prober, Options{Jitter: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.NoError(t, checker.Round(ctx))
+	require.Zero(t, prober.probes.Load())
+}
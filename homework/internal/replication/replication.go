@@ -0,0 +1,77 @@
+// Package replication defines how a leader copies its devices to
+// followers.
+//
+// The leader numbers every write in the order it was applied. Followers
+// start from a Checkpoint, a snapshot of all devices at a log position,
+// and then apply the changes after it. A follower whose position the
+// leader no longer knows, because the log was trimmed, the leader
+// restarted or its devices were replaced, starts over from a new
+// checkpoint. Package changelog implements both sides.
+package replication
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"homework/internal/devices"
+)
+
+type Op string
+
+const (
+	// OpPut creates the device or overwrites it.
+	OpPut    Op = "put"
+	OpDelete Op = "delete"
+)
+
+type Entry struct {
+	Seq       uint64          `json:"seq"`
+	Op        Op              `json:"op"`
+	SerialNum string          `json:"serial_num"`
+	Device    *devices.Device `json:"device,omitempty"`
+	At        time.Time       `json:"at"`
+}
+
+// Checkpoint holds every device as of log position Seq.
+type Checkpoint struct {
+	LogID   string            `json:"log_id"`
+	Seq     uint64            `json:"seq"`
+	Devices []*devices.Device `json:"devices"`
+}
+
+// Batch holds changes after a position, Seq is the last position of the
+// leader when the batch was taken.
+type Batch struct {
+	LogID   string  `json:"log_id"`
+	Seq     uint64  `json:"seq"`
+	Entries []Entry `json:"entries"`
+}
+
+// ErrCheckpointRequired means the requested position is not in the log, the
+// follower has to start over from a checkpoint.
+var ErrCheckpointRequired = stderrors.New("replication: position is not in the log, a checkpoint is required")
+
+// Source is what the leader serves to followers.
+//
+//go:generate mockgen -package internal -destination ../mocks/replication.go . Source,Replica
+type Source interface {
+	Checkpoint() (*Checkpoint, error)
+	// Changes waits until there are changes after the position or the
+	// context is done, then returns at most limit of them.
+	Changes(ctx context.Context, logID string, after uint64, limit int) (*Batch, error)
+}
+
+// Replica reports the replication state of a follower.
+type Replica interface {
+	Status() Status
+}
+
+type Status struct {
+	Leader    string    `json:"leader"`
+	LogID     string    `json:"log_id,omitempty"`
+	Seq       uint64    `json:"seq"`
+	LeaderSeq uint64    `json:"leader_seq"`
+	LastSync  time.Time `json:"last_sync"`
+	Error     string    `json:"error,omitempty"`
+}
@@ -0,0 +1,40 @@
+package search
+
+import (
+	"homework/internal/devices"
+)
+
+// Weights of the device fields, a match on the serial number ranks above
+// one on the model, which ranks above one on the ip.
+const (
+	serialNumWeight = 3
+	modelWeight     = 2
+	ipWeight        = 1
+)
+
+func DeviceFields(device *devices.Device) []Field {
+	return []Field{
+		{Value: device.SerialNum, Weight: serialNumWeight},
+		{Value: device.Model, Weight: modelWeight},
+		{Value: device.IP, Weight: ipWeight},
+	}
+}
+
+// SearchDevices ranks the devices against the query without a persistent
+// index, for repositories that do not keep one.
+func SearchDevices(list []*devices.Device, query string, limit int) []devices.SearchHit {
+	idx := NewIndex()
+	byID := make(map[string]*devices.Device, len(list))
+	for _, device := range list {
+		idx.Add(device.SerialNum, DeviceFields(device)...)
+		byID[device.SerialNum] = device
+	}
+
+	hits := idx.Search(query, limit)
+	result := make([]devices.SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, devices.SearchHit{Device: byID[hit.ID], Score: hit.Score})
+	}
+
+	return result
+}
@@ -0,0 +1,334 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scores of the ways a query term can match an indexed term, multiplied
+// by the weight of the field the term was indexed from.
+const (
+	exactScore     = 1.0
+	prefixScore    = 0.8
+	substringScore = 0.6
+	fuzzyScore     = 0.4
+)
+
+type Field struct {
+	Value  string
+	Weight float64
+}
+
+type Hit struct {
+	ID    string
+	Score float64
+}
+
+// Index is an inverted index over short text fields with exact, prefix,
+// substring and typo tolerant term matching. Substring candidates are
+// found through a trigram index, fuzzy matching scans the vocabulary.
+// It is not safe for concurrent use.
+type Index struct {
+	// postings maps a term to the ids it was indexed for and the highest
+	// weight of the fields it occurred in.
+	postings map[string]map[string]float64
+	trigrams map[string]map[string]struct{}
+	terms    map[string][]string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		postings: make(map[string]map[string]float64),
+		trigrams: make(map[string]map[string]struct{}),
+		terms:    make(map[string][]string),
+	}
+}
+
+// Add indexes the fields under id, replacing what was indexed for it.
+func (idx *Index) Add(id string, fields ...Field) {
+	idx.Remove(id)
+
+	weights := make(map[string]float64)
+	for _, field := range fields {
+		for _, term := range Tokenize(field.Value) {
+			if field.Weight > weights[term] {
+				weights[term] = field.Weight
+			}
+		}
+	}
+
+	terms := make([]string, 0, len(weights))
+	for term, weight := range weights {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[string]float64)
+			for _, gram := range trigramsOf(term) {
+				if idx.trigrams[gram] == nil {
+					idx.trigrams[gram] = make(map[string]struct{})
+				}
+				idx.trigrams[gram][term] = struct{}{}
+			}
+		}
+		idx.postings[term][id] = weight
+		terms = append(terms, term)
+	}
+	idx.terms[id] = terms
+}
+
+func (idx *Index) Remove(id string) {
+	for _, term := range idx.terms[id] {
+		delete(idx.postings[term], id)
+		if len(idx.postings[term]) > 0 {
+			continue
+		}
+
+		delete(idx.postings, term)
+		for _, gram := range trigramsOf(term) {
+			delete(idx.trigrams[gram], term)
+			if len(idx.trigrams[gram]) == 0 {
+				delete(idx.trigrams, gram)
+			}
+		}
+	}
+	delete(idx.terms, id)
+}
+
+func (idx *Index) Len() int {
+	return len(idx.terms)
+}
+
+// Search returns up to limit ids matching every term of the query, best
+// first. A limit below one returns all matches.
+func (idx *Index) Search(query string, limit int) []Hit {
+	queryTerms := strings.Fields(strings.ToLower(query))
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	var scores map[string]float64
+	for _, queryTerm := range queryTerms {
+		termScores := idx.match(queryTerm)
+
+		if scores == nil {
+			scores = termScores
+		} else {
+			for id := range scores {
+				if score, ok := termScores[id]; ok {
+					scores[id] += score
+				} else {
+					delete(scores, id)
+				}
+			}
+		}
+
+		if len(scores) == 0 {
+			return nil
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
+
+// match scores every id with an indexed term matching the query term by
+// its best match.
+func (idx *Index) match(queryTerm string) map[string]float64 {
+	scores := make(map[string]float64)
+
+	for term := range idx.candidates(queryTerm) {
+		score := termScore(queryTerm, term)
+		if score == 0 {
+			continue
+		}
+
+		for id, weight := range idx.postings[term] {
+			if s := score * weight; s > scores[id] {
+				scores[id] = s
+			}
+		}
+	}
+
+	return scores
+}
+
+// candidates returns the terms that can match the query term. Terms
+// containing it are looked up by trigrams, terms within the edit distance
+// have to be found by a scan of the vocabulary.
+func (idx *Index) candidates(queryTerm string) map[string]struct{} {
+	result := make(map[string]struct{})
+
+	if max := maxDistance(queryTerm); max > 0 {
+		for term := range idx.postings {
+			if abs(len(term)-len(queryTerm)) <= max {
+				result[term] = struct{}{}
+			}
+		}
+	}
+
+	grams := trigramsOf(queryTerm)
+	if len(grams) == 0 {
+		for term := range idx.postings {
+			if strings.Contains(term, queryTerm) {
+				result[term] = struct{}{}
+			}
+		}
+		return result
+	}
+
+	contained := idx.trigrams[grams[0]]
+	for _, gram := range grams[1:] {
+		next := make(map[string]struct{})
+		for term := range idx.trigrams[gram] {
+			if _, ok := contained[term]; ok {
+				next[term] = struct{}{}
+			}
+		}
+		contained = next
+	}
+
+	for term := range contained {
+		result[term] = struct{}{}
+	}
+
+	return result
+}
+
+func termScore(queryTerm, term string) float64 {
+	switch {
+	case term == queryTerm:
+		return exactScore
+	case strings.HasPrefix(term, queryTerm):
+		return prefixScore
+	case strings.Contains(term, queryTerm):
+		return substringScore
+	}
+
+	max := maxDistance(queryTerm)
+	if max == 0 {
+		return 0
+	}
+
+	if d := distance(queryTerm, term, max); d <= max {
+		return fuzzyScore / float64(d)
+	}
+
+	return 0
+}
+
+// maxDistance is the number of typos tolerated in a query term, short
+// terms have to match exactly.
+func maxDistance(term string) int {
+	switch n := len(term); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// distance is the optimal string alignment distance of a and b, an edit
+// distance that counts swapping two adjacent characters as one edit. It
+// returns max+1 when the distance is known to exceed max.
+func distance(a, b string, max int) int {
+	if abs(len(a)-len(b)) > max {
+		return max + 1
+	}
+
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && prev2[j-2]+1 < cur[j] {
+				cur[j] = prev2[j-2] + 1
+			}
+
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+
+	return prev[len(b)]
+}
+
+// Tokenize lowercases a field value and returns it whole together with
+// its alphanumeric parts, so "RT-100" is found by "rt-100", "rt" and
+// "100".
+func Tokenize(value string) []string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return nil
+	}
+
+	tokens := []string{value}
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(parts) == 1 && parts[0] == value {
+		return tokens
+	}
+
+	return append(tokens, parts...)
+}
+
+func trigramsOf(term string) []string {
+	if len(term) < 3 {
+		return nil
+	}
+
+	grams := make([]string, 0, len(term)-2)
+	for i := 0; i+3 <= len(term); i++ {
+		grams = append(grams, term[i:i+3])
+	}
+
+	return grams
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndex() *Index {
+	idx := NewIndex()
+	idx.Add("SN-0001", Field{Value: "SN-0001", Weight: 3}, Field{Value: "RT-100", Weight: 2}, Field{Value: "10.0.0.1", Weight: 1})
+	idx.Add("SN-0002", Field{Value: "SN-0002", Weight: 3}, Field{Value: "SW-48", Weight: 2}, Field{Value: "10.0.0.2", Weight: 1})
+	idx.Add("XK-7731", Field{Value: "XK-7731", Weight: 3}, Field{Value: "RT-100", Weight: 2}, Field{Value: "192.168.1.10", Weight: 1})
+
+	return idx
+}
+
+func ids(hits []Hit) []string {
+	result := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, hit.ID)
+	}
+
+	return result
+}
+
+func TestTokenize(t *testing.T) {
+	require.Equal(t, []string{"rt-100", "rt", "100"}, Tokenize(" RT-100 "))
+	require.Equal(t, []string{"router"}, Tokenize("Router"))
+	require.Empty(t, Tokenize(""))
+}
+
+func TestSearch(t *testing.T) {
+	idx := newTestIndex()
+
+	cases := []struct {
+		query  string
+		expect []string
+	}{
+		{query: "sn-0002", expect: []string{"SN-0002", "SN-0001"}},
+		{query: "SN", expect: []string{"SN-0001", "SN-0002"}},
+		{query: "7731", expect: []string{"XK-7731"}},
+		{query: "773", expect: []string{"XK-7731"}},
+		{query: "rt-100", expect: []string{"SN-0001", "XK-7731"}},
+		{query: "10.0.0", expect: []string{"SN-0001", "SN-0002"}},
+		{query: "168.1", expect: []string{"XK-7731"}},
+		{query: "rt-100 10.0", expect: []string{"SN-0001", "XK-7731"}},
+		{query: "xk-7713", expect: []string{"XK-7731"}},
+		{query: "sw-84", expect: []string{"SN-0002"}},
+		{query: "sw-9", expect: nil},
+		{query: "nothing", expect: nil},
+		{query: "  ", expect: nil},
+	}
+
+	for _, tCase := range cases {
+		hits := idx.Search(tCase.query, 0)
+		if tCase.expect == nil {
+			require.Empty(t, hits, tCase.query)
+			continue
+		}
+		require.Equal(t, tCase.expect, ids(hits), tCase.query)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("fuzzy", Field{Value: "routes", Weight: 1})
+	idx.Add("substring", Field{Value: "corouter", Weight: 1})
+	idx.Add("prefix", Field{Value: "routers", Weight: 1})
+	idx.Add("exact", Field{Value: "router", Weight: 1})
+	idx.Add("model", Field{Value: "x", Weight: 3}, Field{Value: "router", Weight: 2})
+
+	hits := idx.Search("router", 0)
+	require.Equal(t, []string{"model", "exact", "prefix", "substring", "fuzzy"}, ids(hits))
+	require.Equal(t, 2.0, hits[0].Score)
+
+	require.Len(t, idx.Search("router", 2), 2)
+}
+
+func TestRemove(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.Remove("SN-0001")
+	require.Equal(t, []string{"XK-7731"}, ids(idx.Search("rt-100", 0)))
+	require.Equal(t, []string{"SN-0002"}, ids(idx.Search("sn-0001", 0)))
+
+	idx.Add("XK-7731", Field{Value: "XK-7731", Weight: 3}, Field{Value: "SW-48", Weight: 2})
+	require.Empty(t, idx.Search("rt-100", 0))
+	require.Equal(t, []string{"SN-0002", "XK-7731"}, ids(idx.Search("sw-48", 0)))
+
+	idx.Remove("SN-0002")
+	idx.Remove("XK-7731")
+	require.Zero(t, idx.Len())
+	require.Empty(t, idx.postings)
+	require.Empty(t, idx.trigrams)
+}
+
+func TestDistance(t *testing.T) {
+	require.Equal(t, 0, distance("router", "router", 2))
+	require.Equal(t, 1, distance("router", "routes", 2))
+	require.Equal(t, 1, distance("7713", "7731", 2))
+	require.Equal(t, 2, distance("7713", "7371", 2))
+	require.Equal(t, 3, distance("abc", "abcdefgh", 2))
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"homework/internal/adapters/changelog"
+	"homework/internal/adapters/hashmap"
+	"homework/internal/app"
+	"homework/internal/devices"
+	"homework/internal/ports/http"
+	"homework/internal/replication"
+)
+
+// waitFor polls the condition until it holds or the test runs out of time.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func listSerialNums(t *testing.T, url string) []string {
+	t.Helper()
+
+	res, err := nethttp.Get(url + "/devices")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	var list []*devices.Device
+	if err = json.NewDecoder(res.Body).Decode(&list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	serialNums := make([]string, len(list))
+	for i, device := range list {
+		serialNums[i] = device.SerialNum
+	}
+
+	return serialNums
+}
+
+func equalSerialNums(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestReplication(t *testing.T) {
+	changeLog := changelog.NewLog(hashmap.NewHash(), 2)
+	leaderService := app.NewService(changeLog)
+	leaderHandler := http.NewHandler(&http.Config{Service: leaderService, ReplicationSource: changeLog})
+	leader := httptest.NewServer(leaderHandler.NewServer().Handler)
+	defer leader.Close()
+
+	// Devices created before the follower starts arrive with the checkpoint.
+	for _, serialNum := range []string{"123", "124"} {
+		if err := leaderService.CreateDevice(&devices.Device{SerialNum: serialNum, Model: "model1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	followerRepo := hashmap.NewHash()
+	follower := changelog.NewFollower(followerRepo, leader.URL, changelog.FollowerOptions{
+		Wait:  100 * time.Millisecond,
+		Retry: 10 * time.Millisecond,
+	})
+	followerHandler := http.NewHandler(&http.Config{
+		Service: app.NewService(followerRepo),
+		Replica: follower,
+		Leader:  leader.URL,
+	})
+	followerServer := httptest.NewServer(followerHandler.NewServer().Handler)
+	defer followerServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go follower.Run(ctx, func(err error) { t.Logf("replication: %v", err) })
+
+	waitFor(t, "the checkpoint", func() bool {
+		return equalSerialNums(listSerialNums(t, followerServer.URL), []string{"123", "124"})
+	})
+
+	// Writes sent to the follower are redirected to the leader and come
+	// back with the change log.
+	body, _ := json.Marshal(&devices.Device{SerialNum: "125", Model: "model1"})
+	res, err := nethttp.Post(followerServer.URL+"/devices", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != nethttp.StatusOK {
+		t.Fatalf("create through follower: want status 200, got %d", res.StatusCode)
+	}
+
+	if err = leaderService.DeleteDevice("123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, "the changes", func() bool {
+		return equalSerialNums(listSerialNums(t, followerServer.URL), []string{"124", "125"})
+	})
+
+	if status := follower.Status(); status.Seq != 4 || status.LeaderSeq != 4 {
+		t.Errorf("want follower at position 4 of 4, got %+v", status)
+	}
+
+	// Replacing the leader devices starts a new log, the follower loads
+	// a new checkpoint.
+	if err = changeLog.Replace([]*devices.Device{{SerialNum: "200", Model: "model2"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	waitFor(t, "the new checkpoint", func() bool {
+		return equalSerialNums(listSerialNums(t, followerServer.URL), []string{"200"})
+	})
+}
+
+func TestFollowerBacksOffEmptyBatches(t *testing.T) {
+	var requests int32
+	leader := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path == "/replication/checkpoint" {
+			_ = json.NewEncoder(w).Encode(replication.Checkpoint{LogID: "log"})
+			return
+		}
+
+		// A leader with a write timeout shorter than the wait answers at
+		// once.
+		atomic.AddInt32(&requests, 1)
+		_ = json.NewEncoder(w).Encode(replication.Batch{LogID: "log"})
+	}))
+	defer leader.Close()
+
+	follower := changelog.NewFollower(hashmap.NewHash(), leader.URL, changelog.FollowerOptions{
+		Wait:  time.Minute,
+		Retry: 50 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	follower.Run(ctx, nil)
+
+	if n := atomic.LoadInt32(&requests); n < 1 || n > 5 {
+		t.Errorf("want a request per retry interval, got %d requests", n)
+	}
+}
@@ -2,10 +2,17 @@ package tests
 
 import (
 	"homework/internal/adapters/hashmap"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"homework/internal/app"
+	"homework/internal/catalog"
 	"homework/internal/devices"
+	"homework/internal/ipam"
+	"homework/internal/locations"
 )
 
 func TestCreateDevice(t *testing.T) {
@@ -206,3 +213,459 @@ func TestUpdateDeviceUnexsting(t *testing.T) {
 		t.Errorf("want err, but got nil")
 	}
 }
+
+func TestListDevicesByIP(t *testing.T) {
+	hash := hashmap.NewHash(hashmap.WithUniqueIP())
+	service := app.NewService(hash)
+	wantDevice := &devices.Device{
+		SerialNum: "123",
+		Model:     "model1",
+		IP:        "10.1.2.3",
+	}
+
+	err := service.CreateDevice(wantDevice)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err = service.CreateDevice(&devices.Device{SerialNum: "124", Model: "model1", IP: "10.1.2.3"})
+	if err == nil {
+		t.Errorf("expected conflict error, got nil")
+	}
+
+	gotDevices, err := service.ListDevices(devices.Filter{IP: "10.1.2.3"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(gotDevices) != 1 || gotDevices[0] != wantDevice {
+		t.Errorf("want devices [%+#v] not equal got %+#v", wantDevice, gotDevices)
+	}
+}
+
+func TestCreateDeviceWithIPAM(t *testing.T) {
+	manager := ipam.NewManager()
+	if err := manager.CreateSubnet(&ipam.Subnet{CIDR: "10.0.0.0/30"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	service := app.NewService(hashmap.NewHash(), app.WithIPAM(manager))
+
+	first := &devices.Device{SerialNum: "123", Model: "model1"}
+	if err := service.CreateDevice(first); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if first.IP != "10.0.0.1" {
+		t.Errorf("want ip 10.0.0.1, got %s", first.IP)
+	}
+
+	if err := service.CreateDevice(&devices.Device{SerialNum: "124", Model: "model1", IP: "10.0.0.1"}); err == nil {
+		t.Errorf("expected conflict error, got nil")
+	}
+
+	if err := service.DeleteDevice(first.SerialNum); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	u, err := manager.Utilization("10.0.0.0-30")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if u.Allocated != 0 {
+		t.Errorf("want no allocated addresses, got %d", u.Allocated)
+	}
+}
+
+func TestListDevicesByLocationSubtree(t *testing.T) {
+	hash := hashmap.NewHash()
+	locationRepo := hashmap.NewLocationHash()
+	refs := app.NewReferences()
+	locationService := app.NewLocationService(locationRepo, hash, refs)
+	service := app.NewService(hash, app.WithLocations(locationRepo), app.WithReferences(refs))
+
+	for _, location := range []*locations.Location{
+		{ID: "eu", Kind: locations.KindRegion},
+		{ID: "ams", Kind: locations.KindSite, ParentID: "eu"},
+		{ID: "ams-1", Kind: locations.KindRoom, ParentID: "ams"},
+		{ID: "ams-1-a", Kind: locations.KindRack, ParentID: "ams-1"},
+		{ID: "ber", Kind: locations.KindSite, ParentID: "eu"},
+	} {
+		if err := locationService.CreateLocation(location); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	inRack := &devices.Device{SerialNum: "123", Model: "model1", LocationID: "ams-1-a", Position: 1}
+	inSite := &devices.Device{SerialNum: "124", Model: "model1", LocationID: "ams"}
+	elsewhere := &devices.Device{SerialNum: "125", Model: "model1", LocationID: "ber"}
+
+	for _, device := range []*devices.Device{inRack, inSite, elsewhere} {
+		if err := service.CreateDevice(device); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	gotDevices, err := service.ListDevices(devices.Filter{LocationID: "ams"})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(gotDevices) != 2 || gotDevices[0] != inRack || gotDevices[1] != inSite {
+		t.Errorf("want devices under ams, got %+#v", gotDevices)
+	}
+
+	if err = locationService.DeleteLocation("ams-1-a"); err == nil {
+		t.Errorf("expected error deleting a rack with devices, got nil")
+	}
+}
+
+func TestModelCatalog(t *testing.T) {
+	hash := hashmap.NewHash()
+	modelRepo := hashmap.NewModelHash()
+	refs := app.NewReferences()
+	modelService := app.NewModelService(modelRepo, hash, refs)
+	service := app.NewService(hash, app.WithModels(modelRepo), app.WithReferences(refs))
+
+	if err := modelService.CreateModel(&catalog.Model{Name: "RT-100", Vendor: "acme"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := service.CreateDevice(&devices.Device{SerialNum: "123", Model: "rt100"}); err == nil {
+		t.Errorf("expected error creating a device with an unknown model, got nil")
+	}
+
+	if err := service.CreateDevice(&devices.Device{SerialNum: "123", Model: "RT-100"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := modelService.DeleteModel("RT-100"); err == nil {
+		t.Errorf("expected error deleting a model in use, got nil")
+	}
+
+	if err := service.DeleteDevice("123"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := modelService.DeleteModel("RT-100"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// pausingRepository lets a test act while a create has checked what the
+// device references but not yet stored it. The create goes on once the
+// test resumes it or after a while, as the test may be blocked by it.
+type pausingRepository struct {
+	app.Repository
+	checked chan struct{}
+	resume  chan struct{}
+}
+
+func (r pausingRepository) Create(device *devices.Device) error {
+	r.checked <- struct{}{}
+	select {
+	case <-r.resume:
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	return r.Repository.Create(device)
+}
+
+func TestConcurrentModelDelete(t *testing.T) {
+	hash := pausingRepository{hashmap.NewHash(), make(chan struct{}), make(chan struct{})}
+	modelRepo := hashmap.NewModelHash()
+	refs := app.NewReferences()
+	modelService := app.NewModelService(modelRepo, hash, refs)
+	service := app.NewService(hash, app.WithModels(modelRepo), app.WithReferences(refs))
+
+	if err := modelService.CreateModel(&catalog.Model{Name: "RT-100", Vendor: "acme"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- service.CreateDevice(&devices.Device{SerialNum: "123", Model: "RT-100"})
+	}()
+
+	<-hash.checked
+	deleteErr := modelService.DeleteModel("RT-100")
+	select {
+	case hash.resume <- struct{}{}:
+	default:
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleteErr == nil {
+		t.Errorf("expected error deleting a model a device is being created with, got nil")
+	}
+
+	if _, err := modelService.GetModel("RT-100"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConcurrentLocationDelete(t *testing.T) {
+	hash := pausingRepository{hashmap.NewHash(), make(chan struct{}), make(chan struct{})}
+	locationRepo := hashmap.NewLocationHash()
+	refs := app.NewReferences()
+	locationService := app.NewLocationService(locationRepo, hash, refs)
+	service := app.NewService(hash, app.WithLocations(locationRepo), app.WithReferences(refs))
+
+	if err := locationService.CreateLocation(&locations.Location{ID: "eu", Kind: locations.KindRegion}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- service.CreateDevice(&devices.Device{SerialNum: "123", Model: "model1", LocationID: "eu"})
+	}()
+
+	<-hash.checked
+	deleteErr := locationService.DeleteLocation("eu")
+	select {
+	case hash.resume <- struct{}{}:
+	default:
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if deleteErr == nil {
+		t.Errorf("expected error deleting a location a device is being created in, got nil")
+	}
+}
+
+func TestDeviceLifecycle(t *testing.T) {
+	hash := hashmap.NewHash()
+	service := app.NewService(hash, app.WithHistory(hashmap.NewHistoryHash()))
+
+	if err := service.CreateDevice(&devices.Device{SerialNum: "123", Model: "model1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, status := range []devices.Status{devices.StatusInStock, devices.StatusProvisioned, devices.StatusActive} {
+		if _, err := service.TransitionDevice("123", status, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := service.TransitionDevice("123", devices.StatusOrdered, ""); err == nil {
+		t.Errorf("expected error moving an active device back to ordered, got nil")
+	}
+
+	gotDevice, err := service.GetDevice("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotDevice.Status != devices.StatusActive {
+		t.Errorf("want status %s, got %s", devices.StatusActive, gotDevice.Status)
+	}
+
+	history, err := service.ListTransitions("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(history) != 4 || history[0].To != devices.StatusOrdered || history[3].From != devices.StatusProvisioned {
+		t.Errorf("unexpected history %+#v", history)
+	}
+}
+
+// slowRepository widens the window between reading a device and writing
+// it back.
+type slowRepository struct {
+	app.Repository
+}
+
+func (r slowRepository) Get(serialNum string) (*devices.Device, error) {
+	device, err := r.Repository.Get(serialNum)
+	time.Sleep(time.Millisecond)
+
+	return device, err
+}
+
+func TestConcurrentTransitions(t *testing.T) {
+	hash := slowRepository{hashmap.NewHash()}
+	service := app.NewService(hash, app.WithHistory(hashmap.NewHistoryHash()))
+
+	// Updates without a status keep the current one, they must not write
+	// back the status a concurrent transition just changed.
+	for round := 0; round < 10; round++ {
+		serialNum := strconv.Itoa(round)
+		if err := service.CreateDevice(&devices.Device{SerialNum: serialNum, Model: "model1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, status := range []devices.Status{devices.StatusInStock, devices.StatusProvisioned, devices.StatusActive} {
+			if _, err := service.TransitionDevice(serialNum, status, ""); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		var wg sync.WaitGroup
+		var transitioned int32
+		for i := 0; i < 4; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				if _, err := service.TransitionDevice(serialNum, devices.StatusMaintenance, ""); err == nil {
+					atomic.AddInt32(&transitioned, 1)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				if err := service.UpdateDevice(&devices.Device{SerialNum: serialNum, Model: "model2"}); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if transitioned != 1 {
+			t.Fatalf("want exactly one transition, got %d", transitioned)
+		}
+
+		gotDevice, err := service.GetDevice(serialNum)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if gotDevice.Status != devices.StatusMaintenance {
+			t.Fatalf("want status %s, got %s", devices.StatusMaintenance, gotDevice.Status)
+		}
+	}
+}
+
+func TestConcurrentDeleteWithIPAM(t *testing.T) {
+	manager := ipam.NewManager()
+	if err := manager.CreateSubnet(&ipam.Subnet{CIDR: "10.0.0.0/29"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	service := app.NewService(slowRepository{hashmap.NewHash()}, app.WithIPAM(manager))
+
+	// A delete racing an update that moves the IP must release the address
+	// the device holds when it is deleted, not the one it read before.
+	for round := 0; round < 10; round++ {
+		serialNum := strconv.Itoa(round)
+		if err := service.CreateDevice(&devices.Device{SerialNum: serialNum, Model: "model1", IP: "10.0.0.1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = service.UpdateDevice(&devices.Device{SerialNum: serialNum, Model: "model1", IP: "10.0.0.2"})
+		}()
+		go func() {
+			defer wg.Done()
+			if err := service.DeleteDevice(serialNum); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+		wg.Wait()
+
+		u, err := manager.Utilization("10.0.0.0-29")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if u.Allocated != 0 {
+			t.Fatalf("want no allocated addresses, got %d", u.Allocated)
+		}
+	}
+}
+
+func TestHeartbeatLiveness(t *testing.T) {
+	hash := hashmap.NewHash()
+	heartbeats := hashmap.NewHeartbeatHash()
+	service := app.NewService(hash, app.WithLiveness(heartbeats))
+	liveness := app.NewLivenessService(heartbeats, hash, time.Nanosecond)
+
+	for _, serialNum := range []string{"123", "124"} {
+		if err := service.CreateDevice(&devices.Device{SerialNum: serialNum, Model: "model1"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := liveness.Heartbeat("123", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	counts, err := liveness.CountLiveness()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if counts.Online != 1 || counts.Offline != 1 {
+		t.Errorf("want 1 online and 1 offline, got %+v", counts)
+	}
+
+	time.Sleep(time.Millisecond)
+	if marked, _ := liveness.Sweep(); marked != 1 {
+		t.Errorf("want 1 device marked offline, got %d", marked)
+	}
+
+	online, err := service.ListDevices(devices.Filter{Liveness: devices.Online})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(online) != 0 {
+		t.Errorf("want no online devices, got %+#v", online)
+	}
+}
+
+func TestDeviceVersions(t *testing.T) {
+	hash := hashmap.NewHash()
+	versionRepo := hashmap.NewVersionHash(0, 0)
+	service := app.NewService(hash, app.WithVersions(versionRepo))
+	versions := app.NewVersionService(versionRepo)
+
+	if err := service.CreateDevice(&devices.Device{SerialNum: "123", Model: "model1", IP: "10.0.0.1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	between := time.Now()
+
+	if err := service.UpdateDevice(&devices.Device{SerialNum: "123", Model: "model1", IP: "10.0.0.2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list, err := versions.ListVersions("123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(list) != 2 || list[1].Number != 2 {
+		t.Fatalf("want 2 versions, got %+v", list)
+	}
+
+	// Versions taken within the same clock tick would make as_of ambiguous.
+	if !list[0].At.Before(between) || list[1].At.Before(between) {
+		t.Skip("versions were taken too close together")
+	}
+
+	device, err := versions.GetDeviceAsOf("123", between)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if device.IP != "10.0.0.1" {
+		t.Errorf("want ip 10.0.0.1 as of the first version, got %s", device.IP)
+	}
+
+	changes, err := versions.DiffVersions("123", 1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(changes) != 1 || changes[0] != (devices.Change{Field: "ip", From: "10.0.0.1", To: "10.0.0.2"}) {
+		t.Errorf("want the ip change, got %+v", changes)
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"homework/internal/adapters/hashmap"
+	"homework/internal/app"
+	"homework/internal/devices"
+	"homework/internal/ports/http"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	handler := http.NewHandler(&http.Config{
+		Tenants: map[string]*http.Config{
+			"ops": {Service: app.NewService(hashmap.NewHash(), app.WithQuota(1))},
+			"lab": {Service: app.NewService(hashmap.NewHash())},
+		},
+	})
+	server := httptest.NewServer(handler.NewServer().Handler)
+	defer server.Close()
+
+	create := func(tenant, serialNum string) int {
+		body, _ := json.Marshal(&devices.Device{SerialNum: serialNum, Model: "model1"})
+		res, err := nethttp.Post(server.URL+"/tenants/"+tenant+"/devices", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer res.Body.Close()
+
+		return res.StatusCode
+	}
+
+	if code := create("ops", "123"); code != nethttp.StatusOK {
+		t.Errorf("want status 200, got %d", code)
+	}
+
+	if code := create("lab", "123"); code != nethttp.StatusOK {
+		t.Errorf("same serial number in another tenant: want status 200, got %d", code)
+	}
+
+	if code := create("ops", "124"); code != nethttp.StatusBadRequest {
+		t.Errorf("over quota: want status 400, got %d", code)
+	}
+
+	res, err := nethttp.Get(server.URL + "/tenants/lab/devices")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+
+	var list []*devices.Device
+	if err = json.NewDecoder(res.Body).Decode(&list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(list) != 1 || list[0].SerialNum != "123" {
+		t.Errorf("want only the lab device, got %+#v", list)
+	}
+}
@@ -0,0 +1,192 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// OTLPFileExporter writes every batch of spans as one line of OTLP JSON,
+// an ExportTraceServiceRequest, the format of the file exporter of the
+// OpenTelemetry collector. The file can be replayed to any collector.
+type OTLPFileExporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewOTLPFileExporter(w io.Writer) *OTLPFileExporter {
+	return &OTLPFileExporter{w: w}
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	SchemaURL  string           `json:"schemaUrl,omitempty"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue holds one of the fields, 64 bit integers are strings in OTLP
+// JSON.
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Status codes of the OTLP protocol, span kinds share the numbers of the
+// otel trace API.
+const (
+	otlpStatusOK    = 1
+	otlpStatusError = 2
+)
+
+// ExportSpans groups the spans by resource and instrumentation scope,
+// spans of one provider share the resource.
+func (e *OTLPFileExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	var request otlpRequest
+	resources := make(map[attribute.Distinct]int)
+
+	for _, span := range spans {
+		key := span.Resource().Equivalent()
+		r, ok := resources[key]
+		if !ok {
+			r = len(request.ResourceSpans)
+			resources[key] = r
+			request.ResourceSpans = append(request.ResourceSpans, otlpResourceSpans{
+				Resource:  otlpResource{Attributes: otlpAttrs(span.Resource().Attributes())},
+				SchemaURL: span.Resource().SchemaURL(),
+			})
+		}
+
+		scope := otlpScope{Name: span.InstrumentationScope().Name, Version: span.InstrumentationScope().Version}
+		scopeSpans := scopeSpansOf(&request.ResourceSpans[r], scope)
+		scopeSpans.Spans = append(scopeSpans.Spans, otlpSpanOf(span))
+	}
+
+	if len(request.ResourceSpans) == 0 {
+		return nil
+	}
+
+	buf, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, err = e.w.Write(append(buf, '\n'))
+	return err
+}
+
+func (e *OTLPFileExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func scopeSpansOf(resourceSpans *otlpResourceSpans, scope otlpScope) *otlpScopeSpans {
+	for i := range resourceSpans.ScopeSpans {
+		if resourceSpans.ScopeSpans[i].Scope == scope {
+			return &resourceSpans.ScopeSpans[i]
+		}
+	}
+
+	resourceSpans.ScopeSpans = append(resourceSpans.ScopeSpans, otlpScopeSpans{Scope: scope})
+	return &resourceSpans.ScopeSpans[len(resourceSpans.ScopeSpans)-1]
+}
+
+func otlpSpanOf(span sdktrace.ReadOnlySpan) otlpSpan {
+	out := otlpSpan{
+		TraceID:           span.SpanContext().TraceID().String(),
+		SpanID:            span.SpanContext().SpanID().String(),
+		Name:              span.Name(),
+		Kind:              int(span.SpanKind()),
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime().UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime().UnixNano(), 10),
+		Attributes:        otlpAttrs(span.Attributes()),
+	}
+	if span.Parent().SpanID().IsValid() {
+		out.ParentSpanID = span.Parent().SpanID().String()
+	}
+
+	switch status := span.Status(); status.Code {
+	case codes.Error:
+		out.Status = otlpStatus{Code: otlpStatusError, Message: status.Description}
+	case codes.Ok:
+		out.Status = otlpStatus{Code: otlpStatusOK}
+	}
+
+	return out
+}
+
+func otlpAttrs(attrs []attribute.KeyValue) []otlpAttribute {
+	out := make([]otlpAttribute, 0, len(attrs))
+
+	for _, attr := range attrs {
+		var value otlpValue
+
+		switch attr.Value.Type() {
+		case attribute.INT64:
+			s := strconv.FormatInt(attr.Value.AsInt64(), 10)
+			value.IntValue = &s
+		case attribute.FLOAT64:
+			f := attr.Value.AsFloat64()
+			value.DoubleValue = &f
+		case attribute.BOOL:
+			b := attr.Value.AsBool()
+			value.BoolValue = &b
+		default:
+			s := attr.Value.Emit()
+			value.StringValue = &s
+		}
+
+		out = append(out, otlpAttribute{Key: string(attr.Key), Value: value})
+	}
+
+	return out
+}
@@ -0,0 +1,59 @@
+// Package tracing sets up the OpenTelemetry SDK. Spans are recorded with
+// the otel trace API, the provider of this package exports the spans of
+// sampled traces with the service name as resource. Incoming and outgoing
+// trace context is carried in the W3C traceparent header.
+package tracing
+
+import (
+	"fmt"
+	"io"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name is the instrumentation scope of the spans of the service.
+const Name = "homework"
+
+// TraceparentHeader carries the span context of the caller.
+const TraceparentHeader = "traceparent"
+
+// Propagator reads and writes the traceparent header.
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// NewProvider batches the ended spans of sampled traces to the exporter.
+// Traces continue the sampling decision of a remote parent, new traces
+// are always sampled. Shutdown flushes the pending spans.
+func NewProvider(exporter sdktrace.SpanExporter, service string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service))),
+	)
+}
+
+// NewStdoutExporter writes every span as one JSON line meant for people.
+func NewStdoutExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// End ends the span. A non-nil err marks the span as failed, its Go type
+// or the result of its ErrorType method is recorded as error.type.
+func End(span trace.Span, err error) {
+	if err != nil {
+		errorType := fmt.Sprintf("%T", err)
+		if typed, ok := err.(interface{ ErrorType() string }); ok {
+			errorType = typed.ErrorType()
+		}
+
+		span.SetAttributes(attribute.String("error.type", errorType))
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type typedError struct{}
+
+func (typedError) Error() string     { return "typed" }
+func (typedError) ErrorType() string { return "custom" }
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	_, grandchild := tracer.Start(ctx, "grandchild")
+
+	End(grandchild, typedError{})
+	End(child, fmt.Errorf("failed"))
+	End(root, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	require.Contains(t, spans[0].Attributes(), attribute.String("error.type", "custom"))
+	require.Equal(t, sdktrace.Status{Code: codes.Error, Description: "typed"}, spans[0].Status())
+	require.Contains(t, spans[1].Attributes(), attribute.String("error.type", "*errors.errorString"))
+	require.Empty(t, spans[2].Attributes())
+	require.Equal(t, codes.Unset, spans[2].Status().Code)
+}
+
+func TestProviderFollowsSampling(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProvider(exporter, "devices")
+	tracer := provider.Tracer(Name)
+
+	// An unsampled caller is followed but not exported.
+	for _, flags := range []string{"01", "00"} {
+		header := propagation.MapCarrier{TraceparentHeader: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-" + flags}
+		_, span := tracer.Start(Propagator.Extract(context.Background(), header), "GET /devices")
+		require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		span.End()
+	}
+	require.NoError(t, provider.ForceFlush(context.Background()))
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Equal(t, "00f067aa0ba902b7", spans[0].Parent.SpanID().String())
+	require.Contains(t, spans[0].Resource.Attributes(), attribute.String("service.name", "devices"))
+}
+
+func TestOTLPFileExporter(t *testing.T) {
+	var buf bytes.Buffer
+	provider := NewProvider(NewOTLPFileExporter(&buf), "devices")
+	tracer := provider.Tracer(Name)
+
+	ctx, root := tracer.Start(context.Background(), "GET /devices", trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.Int("http.response.status_code", 500)))
+	_, child := tracer.Start(ctx, "repository.List")
+	End(child, fmt.Errorf("broken"))
+	End(root, nil)
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	// The batch of both spans is one line.
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 1)
+
+	var request otlpRequest
+	require.NoError(t, json.Unmarshal(lines[0], &request))
+	require.Len(t, request.ResourceSpans, 1)
+
+	serviceName := "devices"
+	require.Contains(t, request.ResourceSpans[0].Resource.Attributes, otlpAttribute{Key: "service.name", Value: otlpValue{StringValue: &serviceName}})
+	require.Len(t, request.ResourceSpans[0].ScopeSpans, 1)
+	require.Equal(t, Name, request.ResourceSpans[0].ScopeSpans[0].Scope.Name)
+
+	spans := request.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "repository.List", spans[0].Name)
+	require.Equal(t, root.SpanContext().SpanID().String(), spans[0].ParentSpanID)
+	require.Equal(t, otlpStatus{Code: otlpStatusError, Message: "broken"}, spans[0].Status)
+
+	require.Equal(t, int(trace.SpanKindServer), spans[1].Kind)
+	require.Empty(t, spans[1].ParentSpanID)
+	require.Equal(t, "500", *spans[1].Attributes[0].Value.IntValue)
+}